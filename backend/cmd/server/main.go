@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -29,7 +30,9 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
+	"github.com/vicepalma/roma-system/backend/internal/blobstore"
 	"github.com/vicepalma/roma-system/backend/internal/middleware"
+	"github.com/vicepalma/roma-system/backend/internal/notify"
 	"github.com/vicepalma/roma-system/backend/internal/repository"
 	"github.com/vicepalma/roma-system/backend/internal/security"
 	"github.com/vicepalma/roma-system/backend/internal/service"
@@ -88,6 +91,32 @@ func main() {
 	}
 
 	port, dbURL, env := loadEnv()
+
+	// JWT_KEYS_DIR: firma asimétrica con rotación (ver security/keys.go);
+	// sin él, HS256 con JWT_SECRET.
+	var jwtKeys *security.KeyManager
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		km, err := security.NewKeyManager(dir, os.Getenv("JWT_ACTIVE_KID"))
+		if err != nil {
+			log.Fatalf("llaves JWT: %v", err)
+		}
+		km.AcceptHS256, _ = strconv.ParseBool(os.Getenv("JWT_ACCEPT_HS256"))
+		security.UseKeys(km)
+		jwtKeys = km
+		log.Printf("JWT firmados con kid=%s", km.ActiveKid())
+	}
+
+	// caché de rol/vínculos para los guards (AUTHZ_CACHE_TTL_SEC, por defecto
+	// 30; 0 la desactiva). Se invalida por NOTIFY roma_authz.
+	var authzCache *security.AuthzCache
+	authzTTL := 30 * time.Second
+	if n, err := strconv.Atoi(os.Getenv("AUTHZ_CACHE_TTL_SEC")); err == nil {
+		authzTTL = time.Duration(n) * time.Second
+	}
+	if authzTTL > 0 {
+		authzCache = security.NewAuthzCache(authzTTL)
+		security.UseAuthzCache(authzCache)
+	}
 	db := openDB(dbURL)
 	sqlDB, _ := db.DB()
 
@@ -129,6 +158,8 @@ func main() {
 
 	// Repos
 	userRepo := repository.NewUserRepository(db)
+	service.RefreshTTL = security.RefreshTTL()
+	authSessSvc := service.NewAuthSessionService(repository.NewAuthSessionRepository(db))
 	exRepo := repository.NewExerciseRepository(db)
 	progRepo := repository.NewProgramRepository(db)
 	progSvc := service.NewProgramService(progRepo)
@@ -138,31 +169,83 @@ func main() {
 	histRepo := repository.NewHistoryRepository(db)
 	histSvc := service.NewHistoryService(histRepo)
 	histH := httpHandlers.NewHistoryHandler(histSvc, "", db)
+	analyticsH := httpHandlers.NewAnalyticsHandler(service.NewAnalyticsService(histRepo), "", db)
+
+	// Notificaciones salientes: SMTP_ADDR y NOTIFY_WEBHOOK_URL habilitan cada canal
+	var channels []notify.Channel
+	if addr := os.Getenv("SMTP_ADDR"); addr != "" {
+		channels = append(channels, &notify.SMTP{
+			Addr:     addr,
+			From:     os.Getenv("SMTP_FROM"),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASS"),
+		})
+	}
+	if u := os.Getenv("NOTIFY_WEBHOOK_URL"); u != "" {
+		channels = append(channels, &notify.Webhook{
+			URL:    u,
+			Secret: os.Getenv("NOTIFY_WEBHOOK_SECRET"),
+			Client: &http.Client{Timeout: 15 * time.Second},
+		})
+	}
+	notifySvc := service.NewNotificationService(repository.NewNotificationRepository(db), channels...)
 
 	coachRepo := repository.NewCoachRepository(db)
-	coachSvc := service.NewCoachService(coachRepo, histSvc, db, assignRepo)
+	coachSvc := service.NewCoachService(coachRepo, histSvc, db, assignRepo, notifySvc)
 	coachH := httpHandlers.NewCoachHandler(coachSvc, histSvc, userRepo, db)
 
 	sessRepo := sr.NewSessionRepository(db)
-	sessSvc := ss.NewSessionService(sessRepo, coachSvc)
+	// SESSION_IDLE_CLOSE_MIN: minutos sin actividad para cerrar una sesión
+	// abierta (por defecto 240; 0 lo desactiva)
+	idleClose := 4 * time.Hour
+	if n, err := strconv.Atoi(os.Getenv("SESSION_IDLE_CLOSE_MIN")); err == nil && n >= 0 {
+		idleClose = time.Duration(n) * time.Minute
+	}
+	sessSvc := ss.NewSessionService(sessRepo, coachSvc, repository.NewRecordRepository(db), notifySvc, idleClose)
 	sessH := sh.NewSessionHandler(sessSvc, db)
+	sessSyncSvc := service.NewSessionSyncService(repository.NewSessionSyncRepository(db), repository.NewRecordRepository(db), notifySvc)
+	sessSyncH := httpHandlers.NewSessionSyncHandler(sessSyncSvc)
 
 	healthH := httpHandlers.NewHealthHandler(db)
 
 	inviteRepo := repository.NewInviteRepository(db)
-	inviteSvc := service.NewInviteService(inviteRepo, coachSvc, os.Getenv("INVITES_BASE_URL"))
-	inviteH := httpHandlers.NewInviteHandler(inviteSvc)
+	inviteSvc := service.NewInviteService(inviteRepo, db, coachSvc, os.Getenv("INVITES_BASE_URL"), notifySvc)
+	inviteH := httpHandlers.NewInviteHandler(inviteSvc, authSessSvc, db)
 
 	adRepo := repository.NewAssignmentDaysRepository(db)
 	adSvc := service.NewAssignmentDaysService(adRepo, coachSvc)
 	adH := httpHandlers.NewAssignmentDaysHandler(adSvc)
 
 	checkinRepo := repository.NewCheckinRepository(db)
-	checkinSvc := service.NewCheckinService(checkinRepo)
+	blobDir := os.Getenv("BLOB_DIR")
+	if blobDir == "" {
+		blobDir = "data/blobs"
+	}
+	var blobs blobstore.Store
+	if fs, err := blobstore.NewFS(blobDir); err != nil {
+		log.Printf("fotos de check-in deshabilitadas (%s): %v", blobDir, err)
+	} else {
+		blobs = fs
+	}
+	if h, err := strconv.Atoi(os.Getenv("CHECKIN_EDIT_WINDOW_H")); err == nil && h > 0 {
+		service.CheckinEditWindow = time.Duration(h) * time.Hour
+	}
+	checkinSvc := service.NewCheckinService(checkinRepo, db, blobs, notifySvc)
 	checkinH := httpHandlers.NewCheckinHandler(checkinSvc, db)
 
+	methodRepo := repository.NewMethodRepository(db)
+	methodSvc := service.NewMethodService(methodRepo)
+	methodH := httpHandlers.NewMethodHandler(methodSvc, db)
+
+	progIOSvc := service.NewProgramIOService(progRepo, exRepo, methodRepo)
+	progIOH := httpHandlers.NewProgramIOHandler(progIOSvc, db)
+
+	progressionSvc := service.NewProgressionService(repository.NewProgressionRepository(db), methodRepo)
+	progressionH := httpHandlers.NewProgressionHandler(progressionSvc, db)
+
 	// Handlers
-	authH := httpHandlers.NewAuthHandler(userRepo, db)
+	accountSvc := service.NewAccountService(userRepo, authSessSvc, security.ActionTokens{}, notifySvc, os.Getenv("APP_BASE_URL"))
+	authH := httpHandlers.NewAuthHandler(userRepo, authSessSvc, accountSvc, db)
 	meH := httpHandlers.NewMeHandler(histSvc, coachSvc, sessSvc)
 
 	// Servicio ejercicios
@@ -175,21 +258,43 @@ func main() {
 	// Grupo público con limiter para /auth
 	pubAuth := r.Group("/", middleware.NewLimiter(3, 5, 5*time.Minute).Gin())
 	authH.Register(pubAuth) // /auth/* y /me (me se auto-protege dentro del handler)                                              // /auth/* y /me (ojo: /me ya internamente requiere AuthRequired)
+	// /auth/signup/invite
+	inviteH.RegisterPublic(pubAuth)
 
 	// Health (elige una)
 	healthH.Register(r) // público
 	// healthH.Register(api) // protegido
 
+	// /.well-known/jwks.json público
+	httpHandlers.NewJWKSHandler().Register(r)
+
+	// autorización por políticas; las decisiones quedan en authz_audit
+	authzAudit := security.NewDBAudit(db, 4096)
+	security.UseAuthorizer(security.NewAuthorizer(security.NewSQLPolicyStore(db), authzAudit))
+	// access tokens de sesiones revocadas dejan de valer al instante
+	security.UseSessionCheck(db)
+
 	// Rutas protegidas
 	api := r.Group("/api", security.AuthRequired())
+	// REQUIRE_VERIFIED_EMAIL=true: cuentas sin verificar solo acceden a /auth/* y /me
+	if v, _ := strconv.ParseBool(os.Getenv("REQUIRE_VERIFIED_EMAIL")); v {
+		api.Use(security.RequireVerifiedEmail(db))
+	}
+	api.Use(security.Authorize(httpHandlers.RoutePolicies))
 	exH.Register(api)
+	methodH.Register(api)
 	progH.Register(api)
+	progIOH.Register(api)
+	progressionH.Register(api)
 	sessH.Register(api)
+	sessSyncH.Register(api)
 	histH.Register(api)
+	analyticsH.Register(api)
 	coachH.Register(api)
 	inviteH.Register(api)
 	adH.Register(api)
 	checkinH.Register(api)
+	checkinH.RegisterFiles(r) // URLs firmadas de fotos
 	meH.Register(api)
 
 	// start async
@@ -200,11 +305,62 @@ func main() {
 		}
 	}()
 
+	// tareas de fondo: barrido de invitaciones vencidas (INVITE_SWEEP_MIN,
+	// por defecto 60), despacho de la outbox (NOTIFY_POLL_SEC, por defecto 15),
+	// cierre de sesiones de entrenamiento inactivas (SESSION_IDLE_SWEEP_MIN,
+	// por defecto 15) y limpieza de sesiones de login viejas (SESSION_PURGE_H,
+	// por defecto 24), de Idempotency-Key de /sessions/sync (SESSION_SYNC_PURGE_H,
+	// por defecto 24) y de la auditoría de autorización (AUTHZ_AUDIT_RETENTION_D,
+	// por defecto 90)
+	bgCtx, stopBg := context.WithCancel(context.Background())
+	defer stopBg()
+	every := func(name string, def, unit time.Duration, job func(context.Context)) {
+		d := def
+		if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n > 0 {
+			d = time.Duration(n) * unit
+		}
+		go func() {
+			t := time.NewTicker(d)
+			defer t.Stop()
+			for {
+				job(bgCtx)
+				select {
+				case <-bgCtx.Done():
+					return
+				case <-t.C:
+				}
+			}
+		}()
+	}
+	every("INVITE_SWEEP_MIN", time.Hour, time.Minute, func(ctx context.Context) { _, _ = inviteSvc.ExpireStale(ctx) })
+	every("NOTIFY_POLL_SEC", 15*time.Second, time.Second, func(ctx context.Context) { _, _ = notifySvc.DispatchDue(ctx) })
+	every("SESSION_PURGE_H", 24*time.Hour, time.Hour, func(ctx context.Context) { _, _ = authSessSvc.Purge(ctx) })
+	every("SESSION_SYNC_PURGE_H", 24*time.Hour, time.Hour, func(ctx context.Context) { _, _ = sessSyncSvc.PurgeKeys(ctx) })
+	every("SESSION_IDLE_SWEEP_MIN", 15*time.Minute, time.Minute, func(ctx context.Context) { _, _ = sessSvc.CloseIdle(ctx) })
+	if authzCache != nil {
+		go authzCache.Listen(bgCtx, dbURL)
+	}
+	go authzAudit.Run(bgCtx)
+	auditRetention := 90 * 24 * time.Hour
+	if n, err := strconv.Atoi(os.Getenv("AUTHZ_AUDIT_RETENTION_D")); err == nil && n > 0 {
+		auditRetention = time.Duration(n) * 24 * time.Hour
+	}
+	every("AUTHZ_AUDIT_PURGE_H", 24*time.Hour, time.Hour, func(ctx context.Context) { _, _ = authzAudit.Purge(ctx, auditRetention) })
+	if jwtKeys != nil {
+		// recarga JWT_KEYS_DIR para tomar llaves rotadas sin reiniciar
+		every("JWT_KEYS_RELOAD_MIN", 5*time.Minute, time.Minute, func(context.Context) {
+			if err := jwtKeys.Reload(); err != nil {
+				log.Printf("[JWTKeys] reload: %v", err)
+			}
+		})
+	}
+
 	// wait for interrupt
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("apagando servidor...")
+	stopBg()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -0,0 +1,322 @@
+// Package adherence compara lo planificado (calendario del assignment y
+// series prescritas) con lo registrado (sesiones y set_logs). No toca la DB.
+package adherence
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/progression"
+	"github.com/vicepalma/roma-system/backend/internal/schedule"
+)
+
+const dateLayout = "2006-01-02"
+
+// Tolerancia del RPE registrado respecto del prescrito.
+const rpeTolerance = 1.0
+
+type Prescription struct {
+	ID       string
+	DayID    string
+	Exercise string
+	Series   int
+	Reps     string
+	RPE      *float64
+}
+
+// Session: Date es la fecha local (YYYY-MM-DD).
+type Session struct {
+	ID    string
+	DayID string
+	Date  string
+}
+
+type Set struct {
+	SessionID      string
+	PrescriptionID string
+	Reps           int
+	RPE            *float64
+}
+
+// Input: un assignment. From/To ya recortados a su vigencia (fechas locales, inclusive).
+type Input struct {
+	Plan          schedule.Plan
+	Start         time.Time
+	From, To      time.Time
+	Prescriptions []Prescription
+	Sessions      []Session
+	Sets          []Set
+}
+
+type counts struct {
+	scheduled, completed int
+	prescribed, logged   int
+	repsChecked, repsHit int
+	rpeChecked, rpeHit   int
+}
+
+func (c *counts) add(o counts) {
+	c.scheduled += o.scheduled
+	c.completed += o.completed
+	c.prescribed += o.prescribed
+	c.logged += o.logged
+	c.repsChecked += o.repsChecked
+	c.repsHit += o.repsHit
+	c.rpeChecked += o.rpeChecked
+	c.rpeHit += o.rpeHit
+}
+
+type Summary struct {
+	ScheduledDays  int      `json:"scheduled_days"` // días de entrenamiento del calendario
+	CompletedDays  int      `json:"completed_days"`
+	DayRate        float64  `json:"day_rate"`
+	PrescribedSets int      `json:"prescribed_sets"`
+	LoggedSets     int      `json:"logged_sets"` // con tope en las series prescritas
+	SetRate        float64  `json:"set_rate"`
+	RepsRate       *float64 `json:"reps_rate,omitempty"` // series que llegaron a las reps prescritas
+	RPERate        *float64 `json:"rpe_rate,omitempty"`  // series a ±1 del RPE prescrito
+	Rate           float64  `json:"rate"`                // set_rate; day_rate si no hay series prescritas
+
+	c counts
+}
+
+func summarize(c counts) Summary {
+	s := Summary{
+		ScheduledDays:  c.scheduled,
+		CompletedDays:  c.completed,
+		DayRate:        ratio(c.completed, c.scheduled),
+		PrescribedSets: c.prescribed,
+		LoggedSets:     c.logged,
+		SetRate:        ratio(c.logged, c.prescribed),
+		c:              c,
+	}
+	if c.repsChecked > 0 {
+		v := ratio(c.repsHit, c.repsChecked)
+		s.RepsRate = &v
+	}
+	if c.rpeChecked > 0 {
+		v := ratio(c.rpeHit, c.rpeChecked)
+		s.RPERate = &v
+	}
+	s.Rate = s.SetRate
+	if c.prescribed == 0 {
+		s.Rate = s.DayRate
+	}
+	return s
+}
+
+type Week struct {
+	Start string `json:"week_start"` // lunes
+	Summary
+}
+
+type PrescriptionStat struct {
+	PrescriptionID string   `json:"prescription_id"`
+	Exercise       string   `json:"exercise"`
+	PlannedSets    int      `json:"planned_sets"`
+	LoggedSets     int      `json:"logged_sets"`
+	RepsRate       *float64 `json:"reps_rate,omitempty"`
+	RPERate        *float64 `json:"rpe_rate,omitempty"`
+
+	c counts
+}
+
+type Report struct {
+	Summary
+	Weeks         []Week             `json:"weeks"`
+	Prescriptions []PrescriptionStat `json:"prescriptions"`
+}
+
+// Compute arma el reporte de un assignment. Las sesiones se emparejan con los
+// días programados de la misma semana: primero por day_id y luego cualquier
+// día pendiente (entrenar el martes lo del lunes cuenta como cumplido).
+func Compute(in Input) Report {
+	byDay := map[string][]Prescription{}
+	for _, p := range in.Prescriptions {
+		byDay[p.DayID] = append(byDay[p.DayID], p)
+	}
+	presc := map[string]Prescription{}
+	for _, p := range in.Prescriptions {
+		presc[p.ID] = p
+	}
+
+	weeks := map[string]*counts{}
+	week := func(k string) *counts {
+		if weeks[k] == nil {
+			weeks[k] = &counts{}
+		}
+		return weeks[k]
+	}
+	perPresc := map[string]*counts{}
+	pc := func(id string) *counts {
+		if perPresc[id] == nil {
+			perPresc[id] = &counts{}
+		}
+		return perPresc[id]
+	}
+
+	// días programados por semana
+	pending := map[string]map[string]int{} // semana -> day_id -> pendientes
+	for cur := dateOnly(in.From); !cur.After(dateOnly(in.To)); cur = cur.AddDate(0, 0, 1) {
+		slot, ok := schedule.OnDate(in.Plan, in.Start, cur)
+		if !ok {
+			break
+		}
+		if slot.Day.Rest {
+			continue
+		}
+		k := weekStart(cur)
+		w := week(k)
+		w.scheduled++
+		if pending[k] == nil {
+			pending[k] = map[string]int{}
+		}
+		pending[k][slot.Day.ID]++
+		for _, p := range byDay[slot.Day.ID] {
+			w.prescribed += p.Series
+			pc(p.ID).prescribed += p.Series
+		}
+	}
+
+	// sesiones: primero mismo día, luego cualquier pendiente
+	sessionWeek := map[string]string{}
+	var leftovers []Session
+	for _, s := range in.Sessions {
+		d, err := time.Parse(dateLayout, s.Date)
+		if err != nil || d.Before(dateOnly(in.From)) || d.After(dateOnly(in.To)) {
+			continue
+		}
+		k := weekStart(d)
+		sessionWeek[s.ID] = k
+		if pending[k][s.DayID] > 0 {
+			pending[k][s.DayID]--
+			week(k).completed++
+			continue
+		}
+		leftovers = append(leftovers, s)
+	}
+	for _, s := range leftovers {
+		k := sessionWeek[s.ID]
+		for day, n := range pending[k] {
+			if n > 0 {
+				pending[k][day]--
+				week(k).completed++
+				break
+			}
+		}
+	}
+
+	// series: tope en las prescritas por sesión y prescripción
+	bySession := map[string]map[string][]Set{}
+	for _, st := range in.Sets {
+		if _, ok := sessionWeek[st.SessionID]; !ok {
+			continue
+		}
+		if bySession[st.SessionID] == nil {
+			bySession[st.SessionID] = map[string][]Set{}
+		}
+		bySession[st.SessionID][st.PrescriptionID] = append(bySession[st.SessionID][st.PrescriptionID], st)
+	}
+	for sid, sets := range bySession {
+		w := week(sessionWeek[sid])
+		for pid, list := range sets {
+			p, ok := presc[pid]
+			if !ok {
+				continue
+			}
+			var c counts
+			c.logged = min(len(list), p.Series)
+			lo, _ := progression.RepRange(p.Reps)
+			for _, st := range list {
+				c.repsChecked++
+				if (lo == 0 && st.Reps > 0) || (lo > 0 && st.Reps >= lo) {
+					c.repsHit++
+				}
+				if p.RPE != nil && st.RPE != nil {
+					c.rpeChecked++
+					if math.Abs(*st.RPE-*p.RPE) <= rpeTolerance {
+						c.rpeHit++
+					}
+				}
+			}
+			w.add(c)
+			pc(pid).add(c)
+		}
+	}
+
+	var total counts
+	out := Report{Weeks: []Week{}, Prescriptions: []PrescriptionStat{}}
+	for k, c := range weeks {
+		// series de sesiones extra no suben la semana por sobre lo prescrito
+		c.logged = min(c.logged, c.prescribed)
+		c.completed = min(c.completed, c.scheduled)
+		total.add(*c)
+		out.Weeks = append(out.Weeks, Week{Start: k, Summary: summarize(*c)})
+	}
+	sort.Slice(out.Weeks, func(i, j int) bool { return out.Weeks[i].Start < out.Weeks[j].Start })
+	for _, p := range in.Prescriptions {
+		c, ok := perPresc[p.ID]
+		if !ok {
+			continue
+		}
+		out.Prescriptions = append(out.Prescriptions, prescriptionStat(p.ID, p.Exercise, *c))
+	}
+	out.Summary = summarize(total)
+	return out
+}
+
+// Merge suma reportes (p. ej. varios assignments activos en la ventana).
+func Merge(reports ...Report) Report {
+	var total counts
+	weeks := map[string]*counts{}
+	var order []string
+	out := Report{Weeks: []Week{}, Prescriptions: []PrescriptionStat{}}
+	for _, r := range reports {
+		total.add(r.c)
+		for _, w := range r.Weeks {
+			if weeks[w.Start] == nil {
+				weeks[w.Start] = &counts{}
+				order = append(order, w.Start)
+			}
+			weeks[w.Start].add(w.c)
+		}
+		out.Prescriptions = append(out.Prescriptions, r.Prescriptions...)
+	}
+	sort.Strings(order)
+	for _, k := range order {
+		out.Weeks = append(out.Weeks, Week{Start: k, Summary: summarize(*weeks[k])})
+	}
+	out.Summary = summarize(total)
+	return out
+}
+
+func prescriptionStat(id, exercise string, c counts) PrescriptionStat {
+	c.logged = min(c.logged, c.prescribed)
+	s := summarize(c)
+	return PrescriptionStat{
+		PrescriptionID: id,
+		Exercise:       exercise,
+		PlannedSets:    c.prescribed,
+		LoggedSets:     c.logged,
+		RepsRate:       s.RepsRate,
+		RPERate:        s.RPERate,
+		c:              c,
+	}
+}
+
+func weekStart(d time.Time) string {
+	offset := (int(d.Weekday()) + 6) % 7 // lunes = 0
+	return d.AddDate(0, 0, -offset).Format(dateLayout)
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func ratio(a, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return math.Round(float64(a)/float64(b)*1000) / 1000
+}
@@ -0,0 +1,86 @@
+package adherence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/schedule"
+)
+
+func f64(v float64) *float64 { return &v }
+
+// Semana de 3 días: A (lun), descanso (mar), B (mié); el plan se repite.
+func testInput() Input {
+	start := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC) // lunes
+	return Input{
+		Plan: schedule.Plan{Days: []schedule.Day{
+			{ID: "A", WeekIndex: 1, DayIndex: 1},
+			{ID: "R", WeekIndex: 1, DayIndex: 2, Rest: true},
+			{ID: "B", WeekIndex: 1, DayIndex: 3},
+		}, End: schedule.EndLoop},
+		Start: start,
+		From:  start,
+		To:    start.AddDate(0, 0, 8), // A R B A R B A | R B
+		Prescriptions: []Prescription{
+			{ID: "pa", DayID: "A", Exercise: "Sentadilla", Series: 3, Reps: "5", RPE: f64(8)},
+			{ID: "pb", DayID: "B", Exercise: "Press banca", Series: 2, Reps: "8-10"},
+		},
+	}
+}
+
+func TestComputeAgainstPlan(t *testing.T) {
+	in := testInput()
+	in.Sessions = []Session{
+		{ID: "s1", DayID: "A", Date: "2025-03-03"},
+		{ID: "s2", DayID: "B", Date: "2025-03-06"}, // B un día tarde: cuenta
+		{ID: "s3", DayID: "A", Date: "2025-03-11"}, // hizo A en el día de B: cuenta
+		{ID: "s4", DayID: "A", Date: "2025-03-12"}, // fuera de la ventana
+	}
+	in.Sets = []Set{
+		{SessionID: "s1", PrescriptionID: "pa", Reps: 5, RPE: f64(8)},
+		{SessionID: "s1", PrescriptionID: "pa", Reps: 5, RPE: f64(9.5)},
+		{SessionID: "s1", PrescriptionID: "pa", Reps: 4, RPE: f64(9)},
+		{SessionID: "s1", PrescriptionID: "pa", Reps: 5}, // serie extra: no suma
+		{SessionID: "s2", PrescriptionID: "pb", Reps: 10},
+		{SessionID: "s3", PrescriptionID: "pa", Reps: 5},
+	}
+	r := Compute(in)
+
+	// programados: A B A B A | B -> 6 días; prescritas 3+2+3+2+3 + 2 = 15
+	if r.ScheduledDays != 6 || r.CompletedDays != 3 || r.PrescribedSets != 15 || r.LoggedSets != 5 {
+		t.Fatalf("summary = %+v", r.Summary)
+	}
+	if r.Rate != r.SetRate || r.DayRate != 0.5 {
+		t.Fatalf("rates = %+v", r.Summary)
+	}
+	if len(r.Weeks) != 2 || r.Weeks[0].Start != "2025-03-03" || r.Weeks[1].Start != "2025-03-10" {
+		t.Fatalf("weeks = %+v", r.Weeks)
+	}
+	if r.Weeks[0].ScheduledDays != 5 || r.Weeks[0].CompletedDays != 2 || r.Weeks[1].CompletedDays != 1 {
+		t.Fatalf("week 1 = %+v", r.Weeks[0])
+	}
+	if len(r.Prescriptions) != 2 {
+		t.Fatalf("prescriptions = %+v", r.Prescriptions)
+	}
+	pa := r.Prescriptions[0]
+	// 4 de 5 series con las reps y 2 de 3 con RPE a ±1
+	if pa.PlannedSets != 9 || pa.LoggedSets != 4 || *pa.RepsRate != 0.8 || *pa.RPERate != 0.667 {
+		t.Fatalf("pa = %+v reps=%v rpe=%v", pa, *pa.RepsRate, *pa.RPERate)
+	}
+	if r.Prescriptions[1].RPERate != nil {
+		t.Fatal("no prescribed RPE -> no rpe_rate")
+	}
+}
+
+func TestMergeAndStop(t *testing.T) {
+	in := testInput()
+	in.Plan.End = schedule.EndStop // plan de 3 días y se termina
+	r := Compute(in)
+	if r.ScheduledDays != 2 || r.CompletedDays != 0 || r.Rate != 0 {
+		t.Fatalf("stop plan = %+v", r.Summary)
+	}
+	m := Merge(r, Compute(testInput()))
+	if m.ScheduledDays != 2+6 || len(m.Weeks) != 2 || m.Weeks[0].PrescribedSets != 5+13 {
+		t.Fatalf("merge = %+v weeks=%+v", m.Summary, m.Weeks)
+	}
+}
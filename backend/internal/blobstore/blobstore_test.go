@@ -0,0 +1,51 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestFSRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := s.Put(ctx, "checkins/a/b.jpg", strings.NewReader("hello"))
+	if err != nil || n != 5 {
+		t.Fatalf("put n=%d err=%v", n, err)
+	}
+	rc, err := s.Open(ctx, "checkins/a/b.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "hello" {
+		t.Fatalf("read %q", b)
+	}
+	if err := s.Delete(ctx, "checkins/a/b.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "checkins/a/b.jpg"); err != nil {
+		t.Fatalf("delete twice: %v", err)
+	}
+	if _, err := s.Open(ctx, "checkins/a/b.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("open deleted: %v", err)
+	}
+}
+
+func TestFSRejectsTraversal(t *testing.T) {
+	s, err := NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"", "/etc/passwd", "../x", "a/../../x", "a//b", `a\b`} {
+		if _, err := s.Put(context.Background(), k, strings.NewReader("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Fatalf("key %q accepted: %v", k, err)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package bodymetrics define las métricas de los check-ins (medidas, signos y
+// escalas subjetivas) y calcula medias móviles sobre sus series. No toca la DB.
+package bodymetrics
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Las claves coinciden con las columnas de checkins.
+const (
+	WeightKG   = "weight_kg"
+	WaistCM    = "waist_cm"
+	ChestCM    = "chest_cm"
+	ArmCM      = "arm_cm"
+	ThighCM    = "thigh_cm"
+	BodyFatPct = "body_fat_pct"
+	RestingHR  = "resting_hr"
+	SleepHours = "sleep_hours"
+	Energy     = "energy"
+	Stress     = "stress"
+	Soreness   = "soreness"
+)
+
+type Def struct {
+	Key     string  `json:"key"`
+	Unit    string  `json:"unit"`
+	Min     float64 `json:"min"` // exclusivo para medidas, inclusivo para escalas
+	Max     float64 `json:"max"`
+	Integer bool    `json:"integer,omitempty"`
+}
+
+// All en el orden en que se muestran.
+var All = []Def{
+	{Key: WeightKG, Unit: "kg", Min: 0, Max: 500},
+	{Key: WaistCM, Unit: "cm", Min: 0, Max: 300},
+	{Key: ChestCM, Unit: "cm", Min: 0, Max: 300},
+	{Key: ArmCM, Unit: "cm", Min: 0, Max: 150},
+	{Key: ThighCM, Unit: "cm", Min: 0, Max: 200},
+	{Key: BodyFatPct, Unit: "%", Min: 0, Max: 75},
+	{Key: RestingHR, Unit: "bpm", Min: 20, Max: 250, Integer: true},
+	{Key: SleepHours, Unit: "h", Min: 0, Max: 24},
+	{Key: Energy, Unit: "1-10", Min: 1, Max: 10, Integer: true},
+	{Key: Stress, Unit: "1-10", Min: 1, Max: 10, Integer: true},
+	{Key: Soreness, Unit: "1-10", Min: 1, Max: 10, Integer: true},
+}
+
+func Lookup(key string) (Def, bool) {
+	for _, d := range All {
+		if d.Key == key {
+			return d, true
+		}
+	}
+	return Def{}, false
+}
+
+// Valid: dentro del rango de la métrica. Las medidas deben ser > 0; el
+// sueño admite 0.
+func (d Def) Valid(v float64) bool {
+	if math.IsNaN(v) || v > d.Max || v < d.Min {
+		return false
+	}
+	if d.Integer && v != math.Trunc(v) {
+		return false
+	}
+	if d.Min == 0 && d.Key != SleepHours && v == 0 {
+		return false
+	}
+	return true
+}
+
+// Point: un check-in (fecha local YYYY-MM-DD).
+type Point struct {
+	Date  string
+	Value float64
+}
+
+type TrendPoint struct {
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+	Avg   float64 `json:"avg"` // media de los check-ins en los últimos `window` días
+	N     int     `json:"n"`   // check-ins promediados
+}
+
+type Trend struct {
+	Points  []TrendPoint `json:"points"`
+	Latest  *float64     `json:"latest,omitempty"`
+	PerWeek *float64     `json:"per_week,omitempty"` // pendiente por mínimos cuadrados
+}
+
+// MovingAverage: media móvil por tiempo (no por cantidad de check-ins), así
+// check-ins semanales o irregulares se comparan igual. Si hay varios valores
+// el mismo día se promedian antes.
+func MovingAverage(points []Point, windowDays int) Trend {
+	if windowDays <= 0 {
+		windowDays = 1
+	}
+	daily := byDay(points)
+	out := Trend{Points: make([]TrendPoint, 0, len(daily))}
+	start := 0
+	var sum float64
+	for i, p := range daily {
+		sum += p.v
+		for daily[start].d.Before(p.d.AddDate(0, 0, -(windowDays - 1))) {
+			sum -= daily[start].v
+			start++
+		}
+		n := i - start + 1
+		out.Points = append(out.Points, TrendPoint{
+			Date:  p.d.Format(dateLayout),
+			Value: round2(p.v),
+			Avg:   round2(sum / float64(n)),
+			N:     n,
+		})
+	}
+	if len(daily) > 0 {
+		v := round2(daily[len(daily)-1].v)
+		out.Latest = &v
+	}
+	if s, ok := slopePerDay(daily); ok {
+		v := round2(s * 7)
+		out.PerWeek = &v
+	}
+	return out
+}
+
+type dayValue struct {
+	d time.Time
+	v float64
+}
+
+func byDay(points []Point) []dayValue {
+	sums := map[string]float64{}
+	counts := map[string]int{}
+	for _, p := range points {
+		sums[p.Date] += p.Value
+		counts[p.Date]++
+	}
+	out := make([]dayValue, 0, len(sums))
+	for k, s := range sums {
+		d, err := time.Parse(dateLayout, k)
+		if err != nil {
+			continue
+		}
+		out = append(out, dayValue{d: d, v: s / float64(counts[k])})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].d.Before(out[j].d) })
+	return out
+}
+
+func slopePerDay(days []dayValue) (float64, bool) {
+	if len(days) < 2 {
+		return 0, false
+	}
+	var sx, sy, sxx, sxy float64
+	n := float64(len(days))
+	for _, p := range days {
+		x := p.d.Sub(days[0].d).Hours() / 24
+		sx += x
+		sy += p.v
+		sxx += x * x
+		sxy += x * p.v
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0, false
+	}
+	return (n*sxy - sx*sy) / den, true
+}
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
@@ -0,0 +1,66 @@
+package bodymetrics
+
+import "testing"
+
+func TestValid(t *testing.T) {
+	cases := []struct {
+		key  string
+		v    float64
+		want bool
+	}{
+		{WeightKG, 80.5, true},
+		{WeightKG, 0, false},
+		{WaistCM, -3, false},
+		{SleepHours, 0, true},
+		{SleepHours, 25, false},
+		{Energy, 10, true},
+		{Energy, 0, false},
+		{Stress, 5.5, false},
+		{RestingHR, 55, true},
+	}
+	for _, c := range cases {
+		d, ok := Lookup(c.key)
+		if !ok {
+			t.Fatalf("unknown %s", c.key)
+		}
+		if got := d.Valid(c.v); got != c.want {
+			t.Fatalf("%s(%v) = %v", c.key, c.v, got)
+		}
+	}
+	if _, ok := Lookup("shoe_size"); ok {
+		t.Fatal("unknown metric accepted")
+	}
+}
+
+func TestMovingAverageWeekly(t *testing.T) {
+	tr := MovingAverage([]Point{
+		{Date: "2025-03-15", Value: 79},
+		{Date: "2025-03-01", Value: 80},
+		{Date: "2025-03-08", Value: 80.4},
+		{Date: "2025-03-08", Value: 79.6}, // mismo día: se promedian
+		{Date: "2025-03-22", Value: 78},
+	}, 14)
+	if len(tr.Points) != 4 {
+		t.Fatalf("points = %+v", tr.Points)
+	}
+	// 14 días: cada punto promedia con el de la semana anterior
+	want := []struct {
+		avg float64
+		n   int
+	}{{80, 1}, {80, 2}, {79.5, 2}, {78.5, 2}}
+	for i, w := range want {
+		if p := tr.Points[i]; p.Avg != w.avg || p.N != w.n {
+			t.Fatalf("point %d = %+v want %+v", i, p, w)
+		}
+	}
+	if *tr.Latest != 78 || *tr.PerWeek != -0.7 {
+		t.Fatalf("latest=%v per_week=%v", *tr.Latest, *tr.PerWeek)
+	}
+}
+
+func TestMovingAverageSinglePoint(t *testing.T) {
+	tr := MovingAverage([]Point{{Date: "2025-03-01", Value: 7}}, 0)
+	if len(tr.Points) != 1 || tr.PerWeek != nil || tr.Points[0].Avg != 7 {
+		t.Fatalf("trend = %+v", tr)
+	}
+}
@@ -9,6 +9,78 @@ type Checkin struct {
 	CreatedAt  time.Time `gorm:"not null;default:now()" json:"created_at"`
 	WeightKG   *float64  `gorm:"column:weight_kg" json:"weight_kg,omitempty"`
 	Notes      *string   `gorm:"type:text" json:"notes,omitempty"`
+
+	// Medidas (cm) y composición
+	WaistCM    *float64 `gorm:"column:waist_cm" json:"waist_cm,omitempty"`
+	ChestCM    *float64 `gorm:"column:chest_cm" json:"chest_cm,omitempty"`
+	ArmCM      *float64 `gorm:"column:arm_cm" json:"arm_cm,omitempty"`
+	ThighCM    *float64 `gorm:"column:thigh_cm" json:"thigh_cm,omitempty"`
+	BodyFatPct *float64 `gorm:"column:body_fat_pct" json:"body_fat_pct,omitempty"`
+	RestingHR  *int     `gorm:"column:resting_hr" json:"resting_hr,omitempty"`
+	SleepHours *float64 `gorm:"column:sleep_hours" json:"sleep_hours,omitempty"`
+	// Escalas subjetivas 1-10
+	Energy   *int `gorm:"column:energy" json:"energy,omitempty"`
+	Stress   *int `gorm:"column:stress" json:"stress,omitempty"`
+	Soreness *int `gorm:"column:soreness" json:"soreness,omitempty"`
+
+	// Revisión del coach
+	ReviewStatus string     `gorm:"column:review_status;not null;default:pending" json:"review_status"` // pending | reviewed
+	ReviewedBy   *string    `gorm:"type:uuid" json:"reviewed_by,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+	CoachComment *string    `gorm:"type:text" json:"coach_comment,omitempty"`
+	UpdatedAt    time.Time  `gorm:"not null;default:now()" json:"updated_at"`
+
+	// Fotos de progreso (jsonb); se escribe solo vía CheckinRepository.AddPhoto/RemovePhoto
+	Photos []CheckinPhoto `gorm:"->;column:attachments;type:jsonb;serializer:json" json:"photos"`
+}
+
+// CheckinPhoto: metadata de la foto; los bytes viven en el blob store.
+// URL/ThumbURL son firmadas y de corta vida, se completan al responder.
+type CheckinPhoto struct {
+	ID          string    `json:"id"`
+	Key         string    `json:"key"`
+	ThumbKey    string    `json:"thumb_key"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+	URL         string    `json:"url,omitempty"`
+	ThumbURL    string    `json:"thumb_url,omitempty"`
 }
 
 func (Checkin) TableName() string { return "checkins" }
+
+const (
+	CheckinPending  = "pending"
+	CheckinReviewed = "reviewed"
+)
+
+// Metrics: valores informados, por clave de bodymetrics (= columna).
+func (c *Checkin) Metrics() map[string]float64 {
+	out := map[string]float64{}
+	for k, v := range map[string]*float64{
+		"weight_kg":    c.WeightKG,
+		"waist_cm":     c.WaistCM,
+		"chest_cm":     c.ChestCM,
+		"arm_cm":       c.ArmCM,
+		"thigh_cm":     c.ThighCM,
+		"body_fat_pct": c.BodyFatPct,
+		"sleep_hours":  c.SleepHours,
+	} {
+		if v != nil {
+			out[k] = *v
+		}
+	}
+	for k, v := range map[string]*int{
+		"resting_hr": c.RestingHR,
+		"energy":     c.Energy,
+		"stress":     c.Stress,
+		"soreness":   c.Soreness,
+	} {
+		if v != nil {
+			out[k] = float64(*v)
+		}
+	}
+	return out
+}
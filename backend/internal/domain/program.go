@@ -1,17 +1,30 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
 
 type Program struct {
-	ID         string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	OwnerID    string    `gorm:"type:uuid;not null;index" json:"owner_id"`
-	Title      string    `gorm:"type:text;not null" json:"title"`
-	Notes      *string   `gorm:"type:text" json:"notes,omitempty"`
-	Visibility string    `gorm:"type:text;not null;default:'private'" json:"visibility"`
-	Kind       string    `gorm:"type:text;not null;default:'coach_program'" json:"kind"`
-	Version    int       `gorm:"not null;default:1" json:"version"`
-	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID         string  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	OwnerID    string  `gorm:"type:uuid;not null;index" json:"owner_id"`
+	Title      string  `gorm:"type:text;not null" json:"title"`
+	Notes      *string `gorm:"type:text" json:"notes,omitempty"`
+	Visibility string  `gorm:"type:text;not null;default:'private'" json:"visibility"`
+	Kind       string  `gorm:"type:text;not null;default:'coach_program'" json:"kind"`
+	Version    int     `gorm:"not null;default:1" json:"version"`
+	// calendario: pasadas del bloque y qué pasa al terminar (stop|loop|hold)
+	BlockRepeats int    `gorm:"not null;default:1" json:"block_repeats"`
+	EndBehavior  string `gorm:"type:text;not null;default:'loop'" json:"end_behavior"`
+	// catálogo de plantillas (visibility=public) y procedencia de clones
+	Tags            pq.StringArray `gorm:"type:text[];not null;default:'{}'" json:"tags"`
+	Level           *string        `gorm:"type:text" json:"level,omitempty"`
+	Goal            *string        `gorm:"type:text" json:"goal,omitempty"`
+	SourceProgramID *string        `gorm:"type:uuid" json:"source_program_id,omitempty"`
+	SourceVersion   *int           `json:"source_version,omitempty"`
+	CreatedAt       time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 func (Program) TableName() string { return "programs" }
@@ -30,6 +43,7 @@ type ProgramDay struct {
 	DayIndex int     `gorm:"not null" json:"day_index"`
 	Title    *string `gorm:"type:text" json:"title,omitempty"`
 	Notes    *string `gorm:"type:text" json:"notes,omitempty"`
+	IsRest   bool    `gorm:"not null;default:false" json:"is_rest"`
 }
 
 func (ProgramDay) TableName() string { return "program_days" }
@@ -61,6 +75,7 @@ type Assignment struct {
 	StartDate      time.Time  `gorm:"type:date;not null" json:"start_date"`
 	EndDate        *time.Time `gorm:"type:date" json:"end_date,omitempty"`
 	IsActive       bool       `gorm:"not null;default:true" json:"is_active"`
+	ScheduleMode   string     `gorm:"type:text;not null;default:'calendar'" json:"schedule_mode"`
 	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
 }
 
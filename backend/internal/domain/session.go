@@ -1,6 +1,10 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
 
 type SessionLog struct {
 	ID           string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
@@ -13,10 +17,44 @@ type SessionLog struct {
 	UpdatedAt    time.Time  `gorm:"not null;default:now()" json:"updated_at"`
 	Status       string     `gorm:"type:varchar(20);not null;default:'open'" json:"status"`
 	EndedAt      *time.Time `json:"ended_at,omitempty"`
+	// reloj del dispositivo en la última escritura por /sessions/sync
+	ClientUpdatedAt *time.Time `json:"client_updated_at,omitempty"`
+	// última serie o cardio registrada; AutoClosed: la cerró el barrido por inactividad
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+	AutoClosed     bool       `gorm:"not null;default:false" json:"auto_closed"`
 }
 
 func (SessionLog) TableName() string { return "session_logs" }
 
+// Duration de la sesión (también como duration_sec en el JSON).
+func (s SessionLog) Duration() time.Duration {
+	return SessionDuration(s.PerformedAt, s.LastActivityAt, s.EndedAt)
+}
+
+func (s SessionLog) MarshalJSON() ([]byte, error) {
+	type plain SessionLog
+	return json.Marshal(struct {
+		plain
+		DurationSec int64 `json:"duration_sec"`
+	}{plain(s), int64(s.Duration().Seconds())})
+}
+
+// SessionDuration: del inicio al cierre; abierta, hasta la última actividad
+// (no hasta "ahora": una sesión olvidada no sigue sumando).
+func SessionDuration(start time.Time, lastActivity, end *time.Time) time.Duration {
+	stop := start
+	switch {
+	case end != nil:
+		stop = *end
+	case lastActivity != nil:
+		stop = *lastActivity
+	}
+	if stop.Before(start) {
+		return 0
+	}
+	return stop.Sub(start)
+}
+
 type SetLog struct {
 	ID             string   `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	SessionID      string   `gorm:"type:uuid;not null;index" json:"session_id"`
@@ -26,22 +64,103 @@ type SetLog struct {
 	Reps           int      `gorm:"not null" json:"reps"`
 	RPE            *float32 `json:"rpe,omitempty"`
 	ToFailure      bool     `gorm:"not null;default:false" json:"to_failure"`
+	SetType        string   `gorm:"type:text;not null;default:'working'" json:"set_type"`
+	// ParentSetID: serie madre de un drop (nil en el resto)
+	ParentSetID *string `gorm:"type:uuid" json:"parent_set_id,omitempty"`
+	// inicio (opcional) y fin de la serie; ver internal/timing
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	ClientUpdatedAt *time.Time `json:"client_updated_at,omitempty"`
 }
 
 func (SetLog) TableName() string { return "set_logs" }
 
+// Tipos de serie (set_logs.set_type).
+const (
+	SetWarmup  = "warmup"
+	SetWorking = "working"
+	SetDrop    = "drop"
+	SetBackoff = "backoff"
+	SetAMRAP   = "amrap"
+	SetFailure = "failure"
+)
+
+// WorkingSetTypes: las que cuentan como series efectivas en historial,
+// volumen y PR. Calentamiento y drops quedan fuera.
+var WorkingSetTypes = []string{SetWorking, SetBackoff, SetAMRAP, SetFailure}
+
+func ValidSetType(t string) bool {
+	return t == SetWarmup || t == SetDrop || IsWorkingSet(t)
+}
+
+// NormSetType: sin tipo, to_failure decide entre failure y working; failure
+// implica to_failure. ok=false si el tipo no existe.
+func NormSetType(t string, toFailure bool) (setType string, failure bool, ok bool) {
+	t = strings.ToLower(strings.TrimSpace(t))
+	switch {
+	case t == "" && toFailure:
+		return SetFailure, true, true
+	case t == "":
+		return SetWorking, false, true
+	case !ValidSetType(t):
+		return t, toFailure, false
+	}
+	return t, toFailure || t == SetFailure, true
+}
+
+func IsWorkingSet(t string) bool {
+	for _, w := range WorkingSetTypes {
+		if t == w {
+			return true
+		}
+	}
+	return false
+}
+
 type SetRow struct {
-	ID             string   `json:"id"`
-	SessionID      string   `json:"session_id"`
-	PrescriptionID string   `json:"prescription_id"`
-	SetIndex       int      `json:"set_index"`
-	Weight         *float64 `json:"weight,omitempty"`
-	Reps           int      `json:"reps"`
-	RPE            *float32 `json:"rpe,omitempty"`
-	ToFailure      bool     `json:"to_failure"`
+	ID             string     `json:"id"`
+	SessionID      string     `json:"session_id"`
+	PrescriptionID string     `json:"prescription_id"`
+	SetIndex       int        `json:"set_index"`
+	Weight         *float64   `json:"weight,omitempty"`
+	Reps           int        `json:"reps"`
+	RPE            *float32   `json:"rpe,omitempty"`
+	ToFailure      bool       `json:"to_failure"`
+	SetType        string     `json:"set_type"`
+	ParentSetID    *string    `json:"parent_set_id,omitempty"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	// drops de esta serie, en orden (ver GroupDrops)
+	Drops []SetRow `gorm:"-" json:"drops,omitempty"`
 
 	// Campos enriquecidos desde prescription/exercise
 	DayID        string `json:"day_id"`
 	ExerciseID   string `json:"exercise_id"`
 	ExerciseName string `json:"exercise_name"`
 }
+
+// GroupDrops cuelga cada drop de su serie madre y conserva el orden del
+// resto. Un drop cuya madre no está en rows queda en el primer nivel.
+func GroupDrops(rows []SetRow) []SetRow {
+	parents := map[string]bool{}
+	for _, r := range rows {
+		if r.ParentSetID == nil {
+			parents[r.ID] = true
+		}
+	}
+	pos := map[string]int{}
+	out := make([]SetRow, 0, len(rows))
+	for _, r := range rows {
+		if r.ParentSetID == nil || !parents[*r.ParentSetID] {
+			pos[r.ID] = len(out)
+			out = append(out, r)
+		}
+	}
+	for _, r := range rows {
+		if r.ParentSetID != nil && parents[*r.ParentSetID] {
+			i := pos[*r.ParentSetID]
+			out[i].Drops = append(out[i].Drops, r)
+		}
+	}
+	return out
+}
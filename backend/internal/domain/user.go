@@ -3,13 +3,15 @@ package domain
 import "time"
 
 type User struct {
-	ID           string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	Email        string    `gorm:"type:citext;uniqueIndex;not null" json:"email"`
-	PasswordHash string    `gorm:"type:text;not null" json:"-"`
-	Name         string    `gorm:"type:text;not null" json:"name"`
-	Role         string    `gorm:"type:text;not null;default:'disciple'" json:"role"`
-	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID           string `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Email        string `gorm:"type:citext;uniqueIndex;not null" json:"email"`
+	PasswordHash string `gorm:"type:text;not null" json:"-"`
+	Name         string `gorm:"type:text;not null" json:"name"`
+	Role         string `gorm:"type:text;not null;default:'disciple'" json:"role"`
+	// nil = email sin verificar
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 func (User) TableName() string { return "users" }
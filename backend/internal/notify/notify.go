@@ -0,0 +1,64 @@
+// Package notify entrega notificaciones salientes por canales enchufables
+// (email vía SMTP, webhook genérico). No conoce la base de datos: la cola
+// durable (outbox) y los reintentos viven en service/repository.
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Eventos con plantilla.
+const (
+	EventInviteSent        = "invite_sent"
+	EventAssignmentCreated = "assignment_created"
+	EventSessionCompleted  = "session_completed"
+	EventCheckinSubmitted  = "checkin_submitted"
+
+	// Privados: llevan un enlace secreto, solo van por email al propio usuario.
+	EventPasswordReset     = "password_reset"
+	EventEmailVerification = "email_verification"
+)
+
+// Private: eventos que no deben salir por canales compartidos (webhook):
+// llevan un link con token o código de un solo destinatario.
+func Private(event string) bool {
+	return event == EventPasswordReset || event == EventEmailVerification || event == EventInviteSent
+}
+
+var ErrUnknownEvent = errors.New("unknown_event")
+
+// Message ya renderizado. To es el email destino (canal email); el webhook
+// usa su URL configurada y envía Payload tal cual.
+type Message struct {
+	Event   string
+	To      string
+	Subject string
+	Body    string
+	Payload []byte
+}
+
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, m Message) error
+}
+
+// Reintentos: 30s, 1m, 2m... hasta 1h; tras MaxAttempts el mensaje queda failed.
+const MaxAttempts = 8
+
+func Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := 30 * time.Second
+	for i := 1; i < attempt && d < time.Hour; i++ {
+		d *= 2
+	}
+	return min(d, time.Hour)
+}
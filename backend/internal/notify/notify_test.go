@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP: servidor mínimo en 127.0.0.1 que acepta una conexión por
+// mensaje y guarda lo recibido. rejectRcpt simula un buzón inexistente.
+type fakeSMTP struct {
+	ln         net.Listener
+	rejectRcpt bool
+	got        chan received
+}
+
+type received struct {
+	from, to string
+	data     string
+}
+
+func newFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeSMTP{ln: ln, got: make(chan received, 4)}
+	t.Cleanup(func() { ln.Close() })
+	go f.serve()
+	return f
+}
+
+func (f *fakeSMTP) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeSMTP) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+	reply("220 fake ESMTP")
+	var msg received
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch up := strings.ToUpper(cmd); {
+		case strings.HasPrefix(up, "EHLO"), strings.HasPrefix(up, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(up, "MAIL FROM:"):
+			msg.from = addr(cmd)
+			reply("250 ok")
+		case strings.HasPrefix(up, "RCPT TO:"):
+			if f.rejectRcpt {
+				reply("550 no such user")
+				continue
+			}
+			msg.to = addr(cmd)
+			reply("250 ok")
+		case up == "DATA":
+			reply("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			msg.data = b.String()
+			f.got <- msg
+			reply("250 queued")
+		case up == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// addr extrae la dirección entre <> de MAIL FROM / RCPT TO.
+func addr(cmd string) string {
+	_, rest, _ := strings.Cut(cmd, "<")
+	a, _, _ := strings.Cut(rest, ">")
+	return a
+}
+
+func TestSMTPSend(t *testing.T) {
+	srv := newFakeSMTP(t)
+	ch := &SMTP{Addr: srv.ln.Addr().String(), From: "roma@example.test"}
+	subject, body, err := Render(EventInviteSent, map[string]string{
+		"actor_name": "Coach Ñandú", "name": "Ana", "invite_url": "http://x/invite/ABC", "expires_at": "2026-07-01",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := ch.Send(ctx, Message{Event: EventInviteSent, To: "ana@example.test", Subject: subject, Body: body}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := <-srv.got
+	if got.from != "roma@example.test" || got.to != "ana@example.test" {
+		t.Fatalf("envelope = %+v", got)
+	}
+	head, rawBody, _ := strings.Cut(got.data, "\r\n\r\n")
+	if !strings.Contains(head, "Subject: =?utf-8?q?") {
+		t.Fatalf("subject not encoded: %q", head)
+	}
+	decoded, err := io.ReadAll(quotedprintable.NewReader(strings.NewReader(rawBody)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(decoded), "http://x/invite/ABC") || !strings.Contains(string(decoded), "Hola Ana") {
+		t.Fatalf("body = %q", decoded)
+	}
+}
+
+func TestSMTPRejectedRecipient(t *testing.T) {
+	srv := newFakeSMTP(t)
+	srv.rejectRcpt = true
+	ch := &SMTP{Addr: srv.ln.Addr().String(), From: "roma@example.test"}
+	if err := ch.Send(context.Background(), Message{To: "nobody@example.test", Subject: "x", Body: "y"}); err == nil {
+		t.Fatal("expected error for rejected recipient")
+	}
+}
+
+func TestWebhookSignsAndFailsOnNon2xx(t *testing.T) {
+	status := http.StatusNoContent
+	var sig, event string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sig, event = r.Header.Get("X-Roma-Signature"), r.Header.Get("X-Roma-Event")
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	ch := &Webhook{URL: srv.URL, Secret: "s3cret"}
+	payload := []byte(`{"event":"checkin_submitted"}`)
+	if err := ch.Send(context.Background(), Message{Event: EventCheckinSubmitted, Payload: payload}); err != nil {
+		t.Fatal(err)
+	}
+	if sig != "sha256="+Sign("s3cret", payload) || event != EventCheckinSubmitted {
+		t.Fatalf("headers sig=%q event=%q", sig, event)
+	}
+
+	status = http.StatusBadGateway
+	if err := ch.Send(context.Background(), Message{Payload: payload}); err == nil {
+		t.Fatal("expected error on 502")
+	}
+}
+
+func TestRenderMissingFieldsAndUnknownEvent(t *testing.T) {
+	subject, body, err := Render(EventSessionCompleted, map[string]string{"disciple_name": "Ana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "Ana completó una sesión" || strings.Contains(body, "no value") {
+		t.Fatalf("subject=%q body=%q", subject, body)
+	}
+	if _, _, err := Render("nope", nil); err != ErrUnknownEvent {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{4, 4 * time.Minute},
+		{20, time.Hour},
+	} {
+		if got := Backoff(tc.attempt); got != tc.want {
+			t.Fatalf("Backoff(%d) = %v want %v", tc.attempt, got, tc.want)
+		}
+	}
+}
+
+func TestPrivateEventsSkipWebhook(t *testing.T) {
+	for _, ev := range []string{EventPasswordReset, EventEmailVerification, EventInviteSent} {
+		if !Private(ev) {
+			t.Fatalf("%s carries a one-recipient link and must be private", ev)
+		}
+	}
+	if Private(EventCheckinSubmitted) {
+		t.Fatal("checkin_submitted should reach the webhook")
+	}
+}
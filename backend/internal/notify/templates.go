@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"strings"
+	"text/template"
+)
+
+type tmpl struct{ subject, body *template.Template }
+
+func mustTmpl(subject, body string) tmpl {
+	return tmpl{
+		subject: template.Must(template.New("s").Option("missingkey=zero").Parse(subject)),
+		body:    template.Must(template.New("b").Option("missingkey=zero").Parse(body)),
+	}
+}
+
+var templates = map[string]tmpl{
+	EventInviteSent: mustTmpl(
+		`{{.actor_name}} te invitó a ROMA`,
+		`Hola{{with .name}} {{.}}{{end}},
+
+{{.actor_name}} te invitó a entrenar con ROMA.
+Acepta la invitación aquí: {{.invite_url}}
+
+El enlace vence el {{.expires_at}}.
+`),
+	EventAssignmentCreated: mustTmpl(
+		`Nuevo programa asignado`,
+		`Hola {{.recipient_name}},
+
+{{.actor_name}} te asignó un programa{{with .program_title}} ({{.}}){{end}} que empieza el {{.start_date}}.
+`),
+	EventSessionCompleted: mustTmpl(
+		`{{.disciple_name}} completó una sesión`,
+		`Hola {{.recipient_name}},
+
+{{.disciple_name}} cerró la sesión {{.session_id}}{{with .ended_at}} el {{.}}{{end}}.
+`),
+	EventCheckinSubmitted: mustTmpl(
+		`{{.disciple_name}} envió un check-in`,
+		`Hola {{.recipient_name}},
+
+{{.disciple_name}} registró un check-in del {{.checked_at}}. Está pendiente de revisión.
+`),
+	EventPasswordReset: mustTmpl(
+		`Restablecer tu contraseña de ROMA`,
+		`Hola {{.recipient_name}},
+
+Pediste restablecer tu contraseña. Usa este enlace (vence en {{.ttl}}):
+{{.reset_url}}
+
+Si no fuiste tú, ignora este mensaje.
+`),
+	EventEmailVerification: mustTmpl(
+		`Confirma tu email en ROMA`,
+		`Hola {{.recipient_name}},
+
+Confirma tu email con este enlace (vence en {{.ttl}}):
+{{.verify_url}}
+`),
+}
+
+// Render aplica la plantilla del evento; campos faltantes quedan vacíos
+// (con map[string]any text/template imprimiría "<no value>").
+func Render(event string, data map[string]string) (subject, body string, err error) {
+	t, ok := templates[event]
+	if !ok {
+		return "", "", ErrUnknownEvent
+	}
+	var s, b strings.Builder
+	if err := t.subject.Execute(&s, data); err != nil {
+		return "", "", err
+	}
+	if err := t.body.Execute(&b, data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(s.String()), b.String(), nil
+}
@@ -0,0 +1,114 @@
+// Package photo valida y normaliza fotos subidas: solo JPEG/PNG, límite de
+// tamaño y píxeles, re-codificación a JPEG (descarta EXIF, incluida la
+// ubicación) y miniaturas.
+package photo
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	_ "image/png" // registra el decoder
+	"io"
+	"net/http"
+)
+
+const (
+	MaxBytes   = 10 << 20
+	MaxPixels  = 40_000_000
+	ThumbSide  = 320
+	ContentJPG = "image/jpeg"
+)
+
+var (
+	ErrTooLarge    = errors.New("photo_too_large")
+	ErrUnsupported = errors.New("unsupported_photo_type")
+)
+
+type Processed struct {
+	Original []byte // JPEG re-codificado
+	Thumb    []byte
+	Width    int
+	Height   int
+}
+
+// Process lee hasta MaxBytes; más que eso es ErrTooLarge.
+func Process(r io.Reader) (*Processed, error) {
+	raw, err := io.ReadAll(io.LimitReader(r, MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > MaxBytes {
+		return nil, ErrTooLarge
+	}
+	switch http.DetectContentType(raw) {
+	case "image/jpeg", "image/png":
+	default:
+		return nil, ErrUnsupported
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	out := &Processed{Width: cfg.Width, Height: cfg.Height}
+	if out.Original, err = encode(img, 90); err != nil {
+		return nil, err
+	}
+	if out.Thumb, err = encode(Thumbnail(img, ThumbSide), 80); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func encode(img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	return buf.Bytes(), err
+}
+
+// Thumbnail reduce (nunca agranda) para que el lado mayor mida `side`,
+// promediando los píxeles de origen de cada píxel destino.
+func Thumbnail(src image.Image, side int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= side && h <= side {
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+		return dst
+	}
+	tw, th := side, h*side/w
+	if h > w {
+		tw, th = w*side/h, side
+	}
+	tw, th = max(tw, 1), max(th, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+max((y+1)*h/th, y*h/th+1)
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+max((x+1)*w/tw, x*w/tw+1)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,64 @@
+package photo
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func pngOf(w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestProcessPNG(t *testing.T) {
+	p, err := Process(bytes.NewReader(pngOf(800, 400)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Width != 800 || p.Height != 400 {
+		t.Fatalf("size = %dx%d", p.Width, p.Height)
+	}
+	thumb, err := jpeg.Decode(bytes.NewReader(p.Thumb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := thumb.Bounds(); b.Dx() != ThumbSide || b.Dy() != ThumbSide/2 {
+		t.Fatalf("thumb = %v", b)
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(p.Original)); err != nil {
+		t.Fatalf("original not jpeg: %v", err)
+	}
+}
+
+func TestThumbnailKeepsSmallImages(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 300))
+	if b := Thumbnail(img, ThumbSide).Bounds(); b.Dx() != 100 || b.Dy() != 300 {
+		t.Fatalf("bounds = %v", b)
+	}
+	if b := Thumbnail(img, 30).Bounds(); b.Dx() != 10 || b.Dy() != 30 {
+		t.Fatalf("portrait bounds = %v", b)
+	}
+}
+
+func TestProcessRejects(t *testing.T) {
+	if _, err := Process(strings.NewReader("GIF89a not really")); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("gif: %v", err)
+	}
+	big := append([]byte{0xFF, 0xD8, 0xFF}, make([]byte, MaxBytes)...)
+	if _, err := Process(bytes.NewReader(big)); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("big: %v", err)
+	}
+}
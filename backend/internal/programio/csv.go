@@ -0,0 +1,215 @@
+package programio
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Columnas del CSV (una fila por prescripción; un día sin prescripciones
+// va en una fila con exercise vacío). Al leer se busca por nombre de columna.
+var csvHeader = []string{
+	"week", "day", "day_title", "day_notes", "rest",
+	"exercise", "series", "reps", "rest_sec", "to_failure",
+	"tempo", "rir", "rpe", "method", "notes",
+}
+
+// EncodeCSV escribe el documento sin los metadatos del programa.
+func EncodeCSV(w io.Writer, d *Doc) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, wk := range d.Weeks {
+		for _, day := range wk.Days {
+			base := []string{
+				strconv.Itoa(wk.Week), strconv.Itoa(day.Day),
+				deref(day.Title), deref(day.Notes), boolStr(day.Rest),
+			}
+			if len(day.Prescriptions) == 0 {
+				if err := cw.Write(append(base, make([]string, len(csvHeader)-len(base))...)); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, p := range day.Prescriptions {
+				row := append(append([]string{}, base...),
+					p.Exercise, strconv.Itoa(p.Series), p.Reps, intStr(p.RestSec), boolStr(p.ToFailure),
+					deref(p.Tempo), intStr(p.RIR), floatStr(p.RPE), deref(p.Method), deref(p.Notes),
+				)
+				if err := cw.Write(row); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// DecodeCSV arma un documento a partir del CSV; meta trae título y demás
+// datos del programa que el CSV no lleva.
+func DecodeCSV(r io.Reader, meta Meta) (*Doc, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+	head, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("invalid_csv: %w", err)
+	}
+	col := map[string]int{}
+	for i, h := range head {
+		col[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	for _, req := range []string{"week", "day", "exercise", "series", "reps"} {
+		if _, ok := col[req]; !ok {
+			return nil, fmt.Errorf("invalid_csv: missing column %q", req)
+		}
+	}
+
+	type dayKey struct{ week, day int }
+	days := map[dayKey]*Day{}
+	var order []dayKey
+	line := 1
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("invalid_csv: line %d: %w", line, err)
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+		if strings.Join(rec, "") == "" {
+			continue
+		}
+		week, err1 := strconv.Atoi(get("week"))
+		day, err2 := strconv.Atoi(get("day"))
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid_csv: line %d: week/day must be numbers", line)
+		}
+		k := dayKey{week, day}
+		d := days[k]
+		if d == nil {
+			d = &Day{Day: day, Title: strPtr(get("day_title")), Notes: strPtr(get("day_notes")), Rest: parseBool(get("rest")), Prescriptions: []Prescription{}}
+			days[k] = d
+			order = append(order, k)
+		}
+		if get("exercise") == "" {
+			continue
+		}
+		p := Prescription{
+			Exercise:  get("exercise"),
+			Reps:      get("reps"),
+			ToFailure: parseBool(get("to_failure")),
+			Tempo:     strPtr(get("tempo")),
+			Method:    strPtr(get("method")),
+			Notes:     strPtr(get("notes")),
+		}
+		if p.Series, err = strconv.Atoi(get("series")); err != nil {
+			return nil, fmt.Errorf("invalid_csv: line %d: series must be a number", line)
+		}
+		if p.RestSec, err = parseIntPtr(get("rest_sec")); err != nil {
+			return nil, fmt.Errorf("invalid_csv: line %d: rest_sec must be a number", line)
+		}
+		if p.RIR, err = parseIntPtr(get("rir")); err != nil {
+			return nil, fmt.Errorf("invalid_csv: line %d: rir must be a number", line)
+		}
+		if p.RPE, err = parseFloatPtr(get("rpe")); err != nil {
+			return nil, fmt.Errorf("invalid_csv: line %d: rpe must be a number", line)
+		}
+		d.Prescriptions = append(d.Prescriptions, p)
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].week != order[j].week {
+			return order[i].week < order[j].week
+		}
+		return order[i].day < order[j].day
+	})
+	doc := New(meta)
+	for _, k := range order {
+		if n := len(doc.Weeks); n == 0 || doc.Weeks[n-1].Week != k.week {
+			doc.Weeks = append(doc.Weeks, Week{Week: k.week, Days: []Day{}})
+		}
+		wk := &doc.Weeks[len(doc.Weeks)-1]
+		wk.Days = append(wk.Days, *days[k])
+	}
+	return doc, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func strPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func intStr(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
+
+func floatStr(v *float32) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(float64(*v), 'f', -1, 32)
+}
+
+func boolStr(b bool) string {
+	if b {
+		return "true"
+	}
+	return ""
+}
+
+func parseBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "1", "true", "yes", "y", "x", "si", "sí":
+		return true
+	}
+	return false
+}
+
+func parseIntPtr(s string) (*int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func parseFloatPtr(s string) (*float32, error) {
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 32)
+	if err != nil {
+		return nil, err
+	}
+	f := float32(v)
+	return &f, nil
+}
@@ -0,0 +1,137 @@
+package programio
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Umbrales del matcher: por debajo de MinScore no se resuelve; si el segundo
+// candidato queda a menos de MinGap del primero, el nombre es ambiguo.
+const (
+	MinScore      = 0.85
+	MinGap        = 0.05
+	suggestScore  = 0.5
+	maxSuggestion = 3
+)
+
+type Candidate struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Scored struct {
+	Candidate
+	Score float64 `json:"score"`
+}
+
+// Matcher resuelve nombres de ejercicio contra el catálogo.
+type Matcher struct {
+	cands []Candidate
+	norm  []string
+	byID  map[string]Candidate
+}
+
+func NewMatcher(cands []Candidate) *Matcher {
+	m := &Matcher{cands: cands, norm: make([]string, len(cands)), byID: make(map[string]Candidate, len(cands))}
+	for i, c := range cands {
+		m.norm[i] = Normalize(c.Name)
+		m.byID[c.ID] = c
+	}
+	return m
+}
+
+func (m *Matcher) ByID(id string) (Candidate, bool) {
+	c, ok := m.byID[id]
+	return c, ok
+}
+
+// Match devuelve el mejor candidato si supera MinScore sin ambigüedad;
+// si no, ok=false y hasta 3 sugerencias ordenadas por score.
+func (m *Matcher) Match(name string) (best Scored, suggestions []Scored, ok bool) {
+	q := Normalize(name)
+	if q == "" {
+		return Scored{}, nil, false
+	}
+	scored := make([]Scored, 0, len(m.cands))
+	for i, c := range m.cands {
+		if s := similarity(q, m.norm[i]); s >= suggestScore {
+			scored = append(scored, Scored{Candidate: c, Score: s})
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].Score > scored[j].Score })
+	if len(scored) > maxSuggestion {
+		scored = scored[:maxSuggestion]
+	}
+	if len(scored) == 0 {
+		return Scored{}, nil, false
+	}
+	top := scored[0]
+	if top.Score == 1 {
+		return top, nil, true
+	}
+	if top.Score >= MinScore && (len(scored) == 1 || top.Score-scored[1].Score >= MinGap) {
+		return top, nil, true
+	}
+	return Scored{}, scored, false
+}
+
+var accents = strings.NewReplacer(
+	"á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n",
+	"à", "a", "è", "e", "ì", "i", "ò", "o", "ù", "u",
+)
+
+// Normalize: minúsculas, sin acentos, solo letras/dígitos separados por un espacio.
+func Normalize(s string) string {
+	s = accents.Replace(strings.ToLower(s))
+	f := strings.FieldsFunc(s, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	return strings.Join(f, " ")
+}
+
+// similarity en [0,1]: igualdad, mismo conjunto de palabras (orden distinto)
+// o distancia de edición relativa, lo que sea mayor.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ta, tb := sortedTokens(a), sortedTokens(b)
+	if ta == tb {
+		return 0.95
+	}
+	return max(ratio(a, b), ratio(ta, tb))
+}
+
+func sortedTokens(s string) string {
+	f := strings.Fields(s)
+	sort.Strings(f)
+	return strings.Join(f, " ")
+}
+
+func ratio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	n := max(len(ra), len(rb))
+	if n == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(n)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,135 @@
+// Package programio define el formato portable de programas (JSON versionado
+// y su variante CSV, una fila por prescripción). No toca la DB: los ejercicios
+// viajan por nombre y se resuelven contra el catálogo al importar (ver Matcher).
+package programio
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	Format  = "roma.program"
+	Version = 1
+)
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported_version")
+	ErrInvalidDoc         = errors.New("invalid_program")
+)
+
+type Doc struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	Program Meta   `json:"program"`
+	Weeks   []Week `json:"weeks"`
+}
+
+type Meta struct {
+	Title        string   `json:"title"`
+	Notes        *string  `json:"notes,omitempty"`
+	BlockRepeats int      `json:"block_repeats,omitempty"`
+	EndBehavior  string   `json:"end_behavior,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Level        *string  `json:"level,omitempty"`
+	Goal         *string  `json:"goal,omitempty"`
+}
+
+type Week struct {
+	Week int   `json:"week"`
+	Days []Day `json:"days"`
+}
+
+type Day struct {
+	Day           int            `json:"day"`
+	Title         *string        `json:"title,omitempty"`
+	Notes         *string        `json:"notes,omitempty"`
+	Rest          bool           `json:"rest,omitempty"`
+	Prescriptions []Prescription `json:"prescriptions"`
+}
+
+// Prescription: el orden en la lista es la posición.
+// ExerciseID es opcional y tiene prioridad sobre Exercise (nombre).
+type Prescription struct {
+	Exercise   string   `json:"exercise"`
+	ExerciseID *string  `json:"exercise_id,omitempty"`
+	Series     int      `json:"series"`
+	Reps       string   `json:"reps"`
+	RestSec    *int     `json:"rest_sec,omitempty"`
+	ToFailure  bool     `json:"to_failure,omitempty"`
+	Tempo      *string  `json:"tempo,omitempty"`
+	RIR        *int     `json:"rir,omitempty"`
+	RPE        *float32 `json:"rpe,omitempty"`
+	Method     *string  `json:"method,omitempty"` // methods.key
+	Notes      *string  `json:"notes,omitempty"`
+}
+
+func New(meta Meta) *Doc {
+	return &Doc{Format: Format, Version: Version, Program: meta, Weeks: []Week{}}
+}
+
+// DecodeJSON lee un documento; format vacío se acepta, versión futura no.
+func DecodeJSON(r io.Reader) (*Doc, error) {
+	var d Doc
+	if err := json.NewDecoder(r).Decode(&d); err != nil {
+		return nil, fmt.Errorf("invalid_json: %w", err)
+	}
+	if d.Format != "" && d.Format != Format {
+		return nil, fmt.Errorf("invalid_format: %q", d.Format)
+	}
+	if d.Version == 0 {
+		d.Version = Version
+	}
+	if d.Version > Version {
+		return nil, ErrUnsupportedVersion
+	}
+	d.Format = Format
+	return &d, nil
+}
+
+// Validate revisa estructura e índices; el error indica la ruta del campo.
+func (d *Doc) Validate() error {
+	if len(strings.TrimSpace(d.Program.Title)) < 2 {
+		return invalid("program.title: min 2 chars")
+	}
+	if d.Program.BlockRepeats < 0 {
+		return invalid("program.block_repeats: must be >= 1")
+	}
+	if len(d.Weeks) == 0 {
+		return invalid("weeks: at least one week")
+	}
+	seenWeek := map[int]bool{}
+	for wi, w := range d.Weeks {
+		if w.Week < 1 || seenWeek[w.Week] {
+			return invalid("weeks[%d].week: must be >= 1 and unique", wi)
+		}
+		seenWeek[w.Week] = true
+		seenDay := map[int]bool{}
+		for di, day := range w.Days {
+			if day.Day < 1 || seenDay[day.Day] {
+				return invalid("weeks[%d].days[%d].day: must be >= 1 and unique", wi, di)
+			}
+			seenDay[day.Day] = true
+			for pi, p := range day.Prescriptions {
+				path := fmt.Sprintf("weeks[%d].days[%d].prescriptions[%d]", wi, di, pi)
+				if strings.TrimSpace(p.Exercise) == "" && (p.ExerciseID == nil || *p.ExerciseID == "") {
+					return invalid("%s.exercise: required", path)
+				}
+				if p.Series < 1 {
+					return invalid("%s.series: must be >= 1", path)
+				}
+				if strings.TrimSpace(p.Reps) == "" {
+					return invalid("%s.reps: required", path)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: "+format, append([]any{ErrInvalidDoc}, args...)...)
+}
@@ -0,0 +1,114 @@
+package programio
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	rest, rpe, method := 90, float32(8.5), "fst7"
+	doc := New(Meta{Title: "Bloque A"})
+	doc.Weeks = []Week{
+		{Week: 1, Days: []Day{
+			{Day: 1, Prescriptions: []Prescription{
+				{Exercise: "Press banca", Series: 4, Reps: "6-8", RestSec: &rest, RPE: &rpe},
+				{Exercise: "Curl, barra", Series: 3, Reps: "10", ToFailure: true, Method: &method},
+			}},
+			{Day: 2, Rest: true, Prescriptions: []Prescription{}},
+		}},
+		{Week: 2, Days: []Day{
+			{Day: 1, Prescriptions: []Prescription{{Exercise: "Sentadilla", Series: 5, Reps: "5"}}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeCSV(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	got, err := DecodeCSV(&buf, Meta{Title: "Bloque A"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := got.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Weeks) != 2 || len(got.Weeks[0].Days) != 2 || !got.Weeks[0].Days[1].Rest {
+		t.Fatalf("weeks/days mismatch: %+v", got.Weeks)
+	}
+	p := got.Weeks[0].Days[0].Prescriptions
+	if len(p) != 2 || p[0].Exercise != "Press banca" || *p[0].RestSec != 90 || *p[0].RPE != 8.5 {
+		t.Fatalf("first prescription mismatch: %+v", p)
+	}
+	if p[1].Exercise != "Curl, barra" || !p[1].ToFailure || *p[1].Method != "fst7" {
+		t.Fatalf("second prescription mismatch: %+v", p[1])
+	}
+}
+
+func TestDecodeCSVColumnsByName(t *testing.T) {
+	in := "Exercise;Series\n" // separador incorrecto => faltan columnas
+	if _, err := DecodeCSV(strings.NewReader(in), Meta{Title: "x"}); err == nil {
+		t.Fatal("want missing column error")
+	}
+	in = "reps,series,exercise,day,week\n8,3,Remo,2,1\n10,3,Dominadas,1,1\n"
+	doc, err := DecodeCSV(strings.NewReader(in), Meta{Title: "Espalda"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	days := doc.Weeks[0].Days
+	if len(days) != 2 || days[0].Day != 1 || days[0].Prescriptions[0].Exercise != "Dominadas" {
+		t.Fatalf("days not sorted by index: %+v", days)
+	}
+}
+
+func TestDecodeJSONVersion(t *testing.T) {
+	if _, err := DecodeJSON(strings.NewReader(`{"format":"roma.program","version":2}`)); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("err=%v want unsupported version", err)
+	}
+	doc, err := DecodeJSON(strings.NewReader(`{"program":{"title":"A"},"weeks":[{"week":1,"days":[{"day":1,"prescriptions":[{"exercise":"Remo","series":0,"reps":"8"}]}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = doc.Validate()
+	if !errors.Is(err, ErrInvalidDoc) || !strings.Contains(err.Error(), "program.title") {
+		t.Fatalf("err=%v want title error", err)
+	}
+	doc.Program.Title = "Ab"
+	err = doc.Validate()
+	if !errors.Is(err, ErrInvalidDoc) || !strings.Contains(err.Error(), "weeks[0].days[0].prescriptions[0].series") {
+		t.Fatalf("err=%v want series error", err)
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	m := NewMatcher([]Candidate{
+		{ID: "1", Name: "Press banca"},
+		{ID: "2", Name: "Press inclinado con mancuernas"},
+		{ID: "3", Name: "Extensión de cuádriceps"},
+		{ID: "4", Name: "Curl bíceps barra"},
+		{ID: "5", Name: "Curl bíceps polea"},
+	})
+	cases := []struct {
+		name string
+		id   string
+		ok   bool
+	}{
+		{"press banca", "1", true},
+		{"PRESS-BANCA", "1", true},
+		{"extension de cuadriceps", "3", true},       // sin acentos
+		{"banca press", "1", true},                   // orden distinto
+		{"Press inclinado con mancuerna", "2", true}, // typo
+		{"curl biceps", "", false},                   // ambiguo barra/polea
+		{"peso muerto rumano", "", false},            // no existe
+	}
+	for _, tc := range cases {
+		best, _, ok := m.Match(tc.name)
+		if ok != tc.ok || (ok && best.ID != tc.id) {
+			t.Errorf("%q: ok=%v id=%q want ok=%v id=%q", tc.name, ok, best.ID, tc.ok, tc.id)
+		}
+	}
+	if _, sugg, ok := m.Match("curl biceps"); ok || len(sugg) < 2 {
+		t.Fatalf("ambiguous match should suggest both curls, got %+v", sugg)
+	}
+}
@@ -0,0 +1,410 @@
+// Package progression calcula el objetivo (carga/reps) de la próxima sesión
+// de una prescripción a partir de su regla y de las series registradas.
+// No toca la DB: recibe el historial ya cargado, de la sesión más antigua a la última.
+package progression
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/vicepalma/roma-system/backend/internal/strength"
+)
+
+type Kind string
+
+const (
+	KindDouble      Kind = "double"       // doble progresión: reps dentro del rango, luego carga
+	KindLinear      Kind = "linear"       // +kg fijo por sesión cumplida, deload tras estancarse
+	KindPercentWave Kind = "percent_wave" // ondas de %1RM
+	KindRPE         Kind = "rpe"          // autorregulación por RPE
+)
+
+var ErrInvalidKind = errors.New("invalid_progression_kind")
+
+type (
+	DoubleParams struct {
+		RepMin      int     `json:"rep_min"` // 0 = toma el rango de la prescripción
+		RepMax      int     `json:"rep_max"`
+		IncrementKg float64 `json:"increment_kg"`
+		RoundTo     float64 `json:"round_to"`
+	}
+
+	LinearParams struct {
+		IncrementKg float64 `json:"increment_kg"`
+		Stalls      int     `json:"stalls"`     // sesiones fallidas seguidas antes del deload
+		DeloadPct   float64 `json:"deload_pct"` // % que se quita en el deload
+		RoundTo     float64 `json:"round_to"`
+	}
+
+	Wave struct {
+		Pct  float64 `json:"pct"`
+		Reps string  `json:"reps"`
+	}
+
+	PercentWaveParams struct {
+		OneRM   float64 `json:"one_rm"` // 0 = estimado del historial
+		Waves   []Wave  `json:"waves"`
+		RoundTo float64 `json:"round_to"`
+	}
+
+	RPEParams struct {
+		TargetRPE float64 `json:"target_rpe"`
+		Reps      int     `json:"reps"` // 0 = mínimo del rango de la prescripción
+		RoundTo   float64 `json:"round_to"`
+	}
+)
+
+func defaultParams(kind Kind) (any, error) {
+	switch kind {
+	case KindDouble:
+		return &DoubleParams{IncrementKg: 2.5, RoundTo: 2.5}, nil
+	case KindLinear:
+		return &LinearParams{IncrementKg: 2.5, Stalls: 3, DeloadPct: 10, RoundTo: 2.5}, nil
+	case KindPercentWave:
+		return &PercentWaveParams{
+			Waves:   []Wave{{Pct: 70, Reps: "8"}, {Pct: 75, Reps: "6"}, {Pct: 80, Reps: "5"}, {Pct: 85, Reps: "3"}},
+			RoundTo: 2.5,
+		}, nil
+	case KindRPE:
+		return &RPEParams{TargetRPE: 8, RoundTo: 2.5}, nil
+	}
+	return nil, ErrInvalidKind
+}
+
+// ParseParams valida params contra el schema del kind (defaults aplicados,
+// campos desconocidos son error), igual que methods.params.
+func ParseParams(kind Kind, raw []byte) (any, error) {
+	p, err := defaultParams(kind)
+	if err != nil {
+		return nil, err
+	}
+	if t := bytes.TrimSpace(raw); len(t) > 0 && !bytes.Equal(t, []byte("null")) {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(p); err != nil {
+			return nil, fmt.Errorf("invalid_params: %w", err)
+		}
+	}
+	if err := validate(p); err != nil {
+		return nil, fmt.Errorf("invalid_params: %w", err)
+	}
+	return p, nil
+}
+
+func validate(p any) error {
+	switch v := p.(type) {
+	case *DoubleParams:
+		if v.RepMin < 0 || v.RepMax < v.RepMin {
+			return errors.New("rep_min must be >= 0 and <= rep_max")
+		}
+		if v.IncrementKg <= 0 || v.IncrementKg > 50 {
+			return errors.New("increment_kg must be >0 and <=50")
+		}
+		return validRound(v.RoundTo)
+	case *LinearParams:
+		if v.IncrementKg <= 0 || v.IncrementKg > 50 {
+			return errors.New("increment_kg must be >0 and <=50")
+		}
+		if v.Stalls < 1 || v.Stalls > 10 {
+			return errors.New("stalls must be 1..10")
+		}
+		if v.DeloadPct <= 0 || v.DeloadPct > 50 {
+			return errors.New("deload_pct must be >0 and <=50")
+		}
+		return validRound(v.RoundTo)
+	case *PercentWaveParams:
+		if v.OneRM < 0 {
+			return errors.New("one_rm must be >= 0")
+		}
+		if len(v.Waves) == 0 || len(v.Waves) > 12 {
+			return errors.New("waves must have 1..12 steps")
+		}
+		for i, w := range v.Waves {
+			if w.Pct <= 0 || w.Pct > 110 {
+				return fmt.Errorf("waves[%d].pct must be >0 and <=110", i)
+			}
+			if strings.TrimSpace(w.Reps) == "" {
+				return fmt.Errorf("waves[%d].reps is required", i)
+			}
+		}
+		return validRound(v.RoundTo)
+	case *RPEParams:
+		if v.TargetRPE < 5 || v.TargetRPE > 10 {
+			return errors.New("target_rpe must be 5..10")
+		}
+		if v.Reps < 0 || v.Reps > 30 {
+			return errors.New("reps must be 0..30")
+		}
+		return validRound(v.RoundTo)
+	}
+	return nil
+}
+
+func validRound(r float64) error {
+	if r <= 0 || r > 10 {
+		return errors.New("round_to must be >0 and <=10")
+	}
+	return nil
+}
+
+type Set struct {
+	Weight    *float64
+	Reps      int
+	RPE       *float32
+	ToFailure bool
+}
+
+// Session: series de una sesión cerrada para la prescripción.
+type Session struct {
+	Sets []Set
+}
+
+// History: últimas sesiones (antigua → reciente) y total de sesiones cerradas.
+type History struct {
+	Sessions []Session
+	Total    int
+}
+
+// Prescription: lo que pide el programa.
+type Prescription struct {
+	Series int
+	Reps   string
+	RPE    *float32
+}
+
+// Target: objetivo propuesto para la próxima sesión.
+type Target struct {
+	Rule   string   `json:"rule"`
+	Weight *float64 `json:"weight,omitempty"`
+	Reps   string   `json:"reps"`
+	RPE    *float64 `json:"rpe,omitempty"`
+	Reason string   `json:"reason"` // no_history | progress_load | progress_reps | hold | deload | wave | rpe
+	Source string   `json:"source"` // rule | override
+	Notes  *string  `json:"notes,omitempty"`
+}
+
+// Next aplica la regla; params debe venir de ParseParams.
+func Next(kind Kind, params any, pr Prescription, h History) Target {
+	t := Target{Rule: string(kind), Reps: strings.TrimSpace(pr.Reps), Source: "rule"}
+	switch p := params.(type) {
+	case *DoubleParams:
+		nextDouble(&t, p, pr, h)
+	case *LinearParams:
+		nextLinear(&t, p, pr, h)
+	case *PercentWaveParams:
+		nextWave(&t, p, h)
+	case *RPEParams:
+		nextRPE(&t, p, pr, h)
+	default:
+		t.Reason = "no_history"
+	}
+	return t
+}
+
+func nextDouble(t *Target, p *DoubleParams, pr Prescription, h History) {
+	lo, hi := RepRange(pr.Reps)
+	if p.RepMin > 0 {
+		lo, hi = p.RepMin, p.RepMax
+	}
+	top, sets, ok := lastTop(h)
+	if !ok || hi == 0 {
+		t.Reason = "no_history"
+		return
+	}
+	minReps := sets[0].Reps
+	for _, s := range sets {
+		minReps = min(minReps, s.Reps)
+	}
+	if len(sets) >= max(pr.Series, 1) && minReps >= hi {
+		t.Weight = ptr(round(top+p.IncrementKg, p.RoundTo))
+		t.Reps = strconv.Itoa(lo)
+		t.Reason = "progress_load"
+		return
+	}
+	t.Weight = ptr(top)
+	t.Reps = strconv.Itoa(min(max(minReps+1, lo), hi))
+	t.Reason = "progress_reps"
+}
+
+func nextLinear(t *Target, p *LinearParams, pr Prescription, h History) {
+	lo, _ := RepRange(pr.Reps)
+	top, _, ok := lastTop(h)
+	if !ok {
+		t.Reason = "no_history"
+		return
+	}
+	if lo > 0 {
+		t.Reps = strconv.Itoa(lo)
+	}
+	// racha de sesiones fallidas (desde la última hacia atrás)
+	stalls := 0
+	for i := len(h.Sessions) - 1; i >= 0; i-- {
+		w, sets, ok := topSets(h.Sessions[i])
+		if !ok {
+			continue
+		}
+		if completed(sets, pr.Series, lo) {
+			break
+		}
+		if w != top {
+			break // otra carga: la racha empieza en la carga actual
+		}
+		stalls++
+	}
+	switch {
+	case stalls == 0:
+		t.Weight = ptr(round(top+p.IncrementKg, p.RoundTo))
+		t.Reason = "progress_load"
+	case stalls >= p.Stalls:
+		t.Weight = ptr(round(top*(1-p.DeloadPct/100), p.RoundTo))
+		t.Reason = "deload"
+	default:
+		t.Weight = ptr(top)
+		t.Reason = "hold"
+	}
+}
+
+func nextWave(t *Target, p *PercentWaveParams, h History) {
+	step := p.Waves[h.Total%len(p.Waves)]
+	t.Reps = step.Reps
+	t.Reason = "wave"
+	oneRM := p.OneRM
+	if oneRM == 0 {
+		oneRM = bestE1RM(h)
+	}
+	if oneRM > 0 {
+		t.Weight = ptr(round(oneRM*step.Pct/100, p.RoundTo))
+	}
+}
+
+func nextRPE(t *Target, p *RPEParams, pr Prescription, h History) {
+	reps := p.Reps
+	if reps == 0 {
+		reps, _ = RepRange(pr.Reps)
+	}
+	if reps > 0 {
+		t.Reps = strconv.Itoa(reps)
+	}
+	t.RPE = ptr(p.TargetRPE)
+	t.Reason = "rpe"
+	// set más pesado de la última sesión con RPE registrado
+	for i := len(h.Sessions) - 1; i >= 0; i-- {
+		var best *Set
+		for j, s := range h.Sessions[i].Sets {
+			if s.Weight == nil || s.RPE == nil || s.Reps < 1 {
+				continue
+			}
+			if best == nil || *s.Weight > *best.Weight {
+				best = &h.Sessions[i].Sets[j]
+			}
+		}
+		if best == nil {
+			continue
+		}
+		e1rm := EpleyRPE(*best.Weight, best.Reps, float64(*best.RPE))
+		if reps > 0 {
+			t.Weight = ptr(round(e1rm/(1+(float64(reps)+10-p.TargetRPE)/30), p.RoundTo))
+		}
+		return
+	}
+	if top, _, ok := lastTop(h); ok {
+		t.Weight = ptr(top)
+		return
+	}
+	t.Reason = "no_history"
+}
+
+// Epley: 1RM estimado = w · (1 + reps/30).
+func Epley(weight float64, reps int) float64 {
+	return strength.E1RM(strength.Epley, weight, reps, nil)
+}
+
+// EpleyRPE suma las reps en reserva (10 - RPE) antes de estimar.
+func EpleyRPE(weight float64, reps int, rpe float64) float64 {
+	return strength.E1RM(strength.Epley, weight, reps, &rpe)
+}
+
+// RepRange interpreta "8-12", "10" o "8–10"; (0,0) si no es numérico (AMRAP).
+func RepRange(s string) (int, int) {
+	s = strings.ReplaceAll(strings.TrimSpace(s), "–", "-")
+	if a, b, ok := strings.Cut(s, "-"); ok {
+		lo, err1 := strconv.Atoi(strings.TrimSpace(a))
+		hi, err2 := strconv.Atoi(strings.TrimSpace(b))
+		if err1 != nil || err2 != nil || lo > hi {
+			return 0, 0
+		}
+		return lo, hi
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, 0
+	}
+	return n, n
+}
+
+// lastTop: carga más alta de la última sesión con carga y las series hechas con ella.
+func lastTop(h History) (float64, []Set, bool) {
+	for i := len(h.Sessions) - 1; i >= 0; i-- {
+		if w, sets, ok := topSets(h.Sessions[i]); ok {
+			return w, sets, true
+		}
+	}
+	return 0, nil, false
+}
+
+func topSets(s Session) (float64, []Set, bool) {
+	top, found := 0.0, false
+	for _, st := range s.Sets {
+		if st.Weight != nil && (!found || *st.Weight > top) {
+			top, found = *st.Weight, true
+		}
+	}
+	if !found {
+		return 0, nil, false
+	}
+	var out []Set
+	for _, st := range s.Sets {
+		if st.Weight != nil && *st.Weight == top {
+			out = append(out, st)
+		}
+	}
+	return top, out, true
+}
+
+func completed(sets []Set, series, reps int) bool {
+	if len(sets) < max(series, 1) {
+		return false
+	}
+	for _, s := range sets {
+		if s.Reps < reps {
+			return false
+		}
+	}
+	return true
+}
+
+func bestE1RM(h History) float64 {
+	best := 0.0
+	for _, s := range h.Sessions {
+		for _, st := range s.Sets {
+			if st.Weight != nil && st.Reps <= 12 {
+				best = math.Max(best, Epley(*st.Weight, st.Reps))
+			}
+		}
+	}
+	return best
+}
+
+func round(v, step float64) float64 {
+	if step <= 0 {
+		return v
+	}
+	return math.Round(v/step) * step
+}
+
+func ptr(v float64) *float64 { return &v }
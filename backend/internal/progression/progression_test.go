@@ -0,0 +1,100 @@
+package progression
+
+import (
+	"testing"
+)
+
+func sess(weight float64, reps ...int) Session {
+	s := Session{}
+	for _, r := range reps {
+		w := weight
+		s.Sets = append(s.Sets, Set{Weight: &w, Reps: r})
+	}
+	return s
+}
+
+func hist(ss ...Session) History { return History{Sessions: ss, Total: len(ss)} }
+
+func mustParams(t *testing.T, kind Kind, raw string) any {
+	t.Helper()
+	p, err := ParseParams(kind, []byte(raw))
+	if err != nil {
+		t.Fatalf("ParseParams(%s): %v", kind, err)
+	}
+	return p
+}
+
+func TestNextTargets(t *testing.T) {
+	pr := Prescription{Series: 3, Reps: "8-12"}
+	five := Prescription{Series: 3, Reps: "5"}
+	cases := []struct {
+		name   string
+		kind   Kind
+		params string
+		pr     Prescription
+		h      History
+		weight float64 // 0 = sin carga
+		reps   string
+		reason string
+	}{
+		{"double no history", KindDouble, "", pr, hist(), 0, "8-12", "no_history"},
+		{"double add rep", KindDouble, "", pr, hist(sess(60, 12, 10, 9)), 60, "10", "progress_reps"},
+		{"double add load", KindDouble, "", pr, hist(sess(60, 12, 12, 12)), 62.5, "8", "progress_load"},
+		{"double missing series", KindDouble, "", pr, hist(sess(60, 12, 12)), 60, "12", "progress_reps"},
+		{"double custom range", KindDouble, `{"rep_min":6,"rep_max":8,"increment_kg":5}`, pr, hist(sess(60, 8, 8, 8)), 65, "6", "progress_load"},
+		{"linear progress", KindLinear, "", five, hist(sess(100, 5, 5, 5)), 102.5, "5", "progress_load"},
+		{"linear hold", KindLinear, "", five, hist(sess(100, 5, 5, 5), sess(102.5, 5, 4, 3)), 102.5, "5", "hold"},
+		{"linear deload", KindLinear, `{"stalls":2}`, five, hist(sess(102.5, 5, 4, 3), sess(102.5, 5, 5, 4)), 92.5, "5", "deload"},
+		{"wave from 1rm", KindPercentWave, `{"one_rm":100}`, five, hist(sess(70, 8), sess(75, 6)), 80, "5", "wave"},
+		{"wave estimated 1rm", KindPercentWave, `{"waves":[{"pct":90,"reps":"2"}]}`, five, hist(sess(100, 3)), 100, "2", "wave"}, // e1RM 110 · 90%,
+		{"wave no data", KindPercentWave, "", five, hist(), 0, "8", "wave"},
+	}
+	for _, tc := range cases {
+		got := Next(tc.kind, mustParams(t, tc.kind, tc.params), tc.pr, tc.h)
+		var w float64
+		if got.Weight != nil {
+			w = *got.Weight
+		}
+		if w != tc.weight || got.Reps != tc.reps || got.Reason != tc.reason {
+			t.Errorf("%s: got weight=%v reps=%q reason=%q want %v %q %q", tc.name, w, got.Reps, got.Reason, tc.weight, tc.reps, tc.reason)
+		}
+	}
+}
+
+func TestNextRPE(t *testing.T) {
+	w, rpe := 100.0, float32(9)
+	h := hist(Session{Sets: []Set{{Weight: &w, Reps: 5, RPE: &rpe}}})
+	got := Next(KindRPE, mustParams(t, KindRPE, `{"target_rpe":7}`), Prescription{Series: 3, Reps: "5"}, h)
+	// e1RM = 100·(1+6/30) = 120 ; objetivo 5 reps @7 => 120/(1+8/30) ≈ 94.7 → 95
+	if got.Weight == nil || *got.Weight != 95 || got.RPE == nil || *got.RPE != 7 {
+		t.Fatalf("rpe target=%+v", got)
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	bad := []struct {
+		kind Kind
+		raw  string
+	}{
+		{"german", ""},
+		{KindDouble, `{"increment":2}`},
+		{KindLinear, `{"stalls":0}`},
+		{KindPercentWave, `{"waves":[]}`},
+		{KindRPE, `{"target_rpe":11}`},
+	}
+	for _, tc := range bad {
+		if _, err := ParseParams(tc.kind, []byte(tc.raw)); err == nil {
+			t.Errorf("%s %s: want error", tc.kind, tc.raw)
+		}
+	}
+}
+
+func TestRepRange(t *testing.T) {
+	cases := map[string][2]int{"8-12": {8, 12}, "10": {10, 10}, " 6 – 8 ": {6, 8}, "AMRAP": {0, 0}, "12-8": {0, 0}}
+	for in, want := range cases {
+		lo, hi := RepRange(in)
+		if lo != want[0] || hi != want[1] {
+			t.Errorf("RepRange(%q)=%d,%d want %v", in, lo, hi, want)
+		}
+	}
+}
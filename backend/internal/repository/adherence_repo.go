@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/adherence"
+	"gorm.io/gorm"
+)
+
+// AdherenceAssignment: plan y registros de un assignment activo en la ventana.
+type AdherenceAssignment struct {
+	AssignmentID string
+	DiscipleID   string
+	ProgramID    string
+	ProgramTitle string
+	StartDate    time.Time
+	EndDate      *time.Time
+	Input        adherence.Input
+}
+
+// AdherenceInputs carga, por cada assignment activo que se cruza con
+// [from, to] (fechas locales), el plan, las prescripciones y lo registrado.
+func (r *historyRepository) AdherenceInputs(ctx context.Context, discipleID, tz string, from, to time.Time) ([]AdherenceAssignment, error) {
+	return adherenceInputs(ctx, r.db, []string{discipleID}, tz, from, to)
+}
+
+func (r *historyRepository) AdherenceInputsFor(ctx context.Context, discipleIDs []string, tz string, from, to time.Time) ([]AdherenceAssignment, error) {
+	return adherenceInputs(ctx, r.db, discipleIDs, tz, from, to)
+}
+
+// adherenceInputs usa un número fijo de queries sin importar cuántos
+// discípulos/assignments haya (roster del coach).
+func adherenceInputs(ctx context.Context, db *gorm.DB, discipleIDs []string, tz string, from, to time.Time) ([]AdherenceAssignment, error) {
+	out := []AdherenceAssignment{}
+	if len(discipleIDs) == 0 {
+		return out, nil
+	}
+	db = db.WithContext(ctx)
+	fromS, toS := from.Format("2006-01-02"), to.Format("2006-01-02")
+
+	var asgs []struct {
+		ID           string
+		DiscipleID   string
+		ProgramID    string
+		ProgramTitle string
+		StartDate    time.Time
+		EndDate      *time.Time
+	}
+	if err := db.Raw(`
+		SELECT a.id, a.disciple_id, a.program_id, p.title AS program_title, a.start_date, a.end_date
+		FROM assignments a
+		JOIN programs p ON p.id = a.program_id
+		WHERE a.disciple_id IN ? AND a.is_active = true
+		  AND a.start_date <= ? AND (a.end_date IS NULL OR a.end_date >= ?)
+		ORDER BY a.disciple_id, a.start_date ASC, a.id ASC
+	`, discipleIDs, toS, fromS).Scan(&asgs).Error; err != nil {
+		return nil, err
+	}
+	if len(asgs) == 0 {
+		return out, nil
+	}
+	asgIDs := make([]string, 0, len(asgs))
+	programIDs := make([]string, 0, len(asgs))
+	for _, a := range asgs {
+		asgIDs = append(asgIDs, a.ID)
+		programIDs = append(programIDs, a.ProgramID)
+	}
+
+	plans, _, err := loadSchedulePlans(ctx, db, programIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var prescs []struct {
+		ProgramID string
+		ID        string
+		DayID     string
+		Exercise  string
+		Series    int
+		Reps      string
+		RPE       *float32 `gorm:"column:rpe"`
+	}
+	if err := db.Raw(`
+		SELECT w.program_id, pr.id, pr.day_id, e.name AS exercise, pr.series, pr.reps, pr.rpe
+		FROM prescriptions pr
+		JOIN program_days d ON d.id = pr.day_id
+		JOIN program_weeks w ON w.id = d.week_id
+		JOIN exercises e ON e.id = pr.exercise_id
+		WHERE w.program_id IN ?
+		ORDER BY w.program_id, w.week_index, d.day_index, pr.position, pr.id
+	`, programIDs).Scan(&prescs).Error; err != nil {
+		return nil, err
+	}
+	byProgram := map[string][]adherence.Prescription{}
+	for _, p := range prescs {
+		byProgram[p.ProgramID] = append(byProgram[p.ProgramID], adherence.Prescription{
+			ID: p.ID, DayID: p.DayID, Exercise: p.Exercise, Series: p.Series, Reps: p.Reps, RPE: f32ptr(p.RPE),
+		})
+	}
+
+	// Compute descarta lo que cae fuera de la vigencia de cada assignment
+	day := dateFloorTZ("s.performed_at", tz)
+	var sessions []struct {
+		AssignmentID string
+		adherence.Session
+	}
+	if err := db.Raw(`
+		SELECT s.assignment_id, s.id, s.day_id, TO_CHAR(`+day+`, 'YYYY-MM-DD') AS date
+		FROM session_logs s
+		WHERE s.assignment_id IN ? AND `+day+` BETWEEN ? AND ?
+		  AND (s.status = 'closed' OR EXISTS (SELECT 1 FROM set_logs sl WHERE sl.session_id = s.id))
+	`, asgIDs, fromS, toS).Scan(&sessions).Error; err != nil {
+		return nil, err
+	}
+	sessByAsg := map[string][]adherence.Session{}
+	for _, s := range sessions {
+		sessByAsg[s.AssignmentID] = append(sessByAsg[s.AssignmentID], s.Session)
+	}
+
+	var sets []struct {
+		AssignmentID   string
+		SessionID      string
+		PrescriptionID string
+		Reps           int
+		RPE            *float32 `gorm:"column:rpe"`
+	}
+	if err := db.Raw(`
+		SELECT s.assignment_id, sl.session_id, sl.prescription_id, sl.reps, sl.rpe
+		FROM set_logs sl
+		JOIN session_logs s ON s.id = sl.session_id
+		WHERE s.assignment_id IN ? AND `+day+` BETWEEN ? AND ?`+workingSets("sl", false)+`
+	`, asgIDs, fromS, toS).Scan(&sets).Error; err != nil {
+		return nil, err
+	}
+	setsByAsg := map[string][]adherence.Set{}
+	for _, st := range sets {
+		setsByAsg[st.AssignmentID] = append(setsByAsg[st.AssignmentID], adherence.Set{
+			SessionID: st.SessionID, PrescriptionID: st.PrescriptionID, Reps: st.Reps, RPE: f32ptr(st.RPE),
+		})
+	}
+
+	for _, a := range asgs {
+		in := adherence.Input{
+			Plan:          plans[a.ProgramID],
+			Start:         a.StartDate,
+			From:          from,
+			To:            to,
+			Prescriptions: byProgram[a.ProgramID],
+			Sessions:      sessByAsg[a.ID],
+			Sets:          setsByAsg[a.ID],
+		}
+		if a.StartDate.After(in.From) {
+			in.From = a.StartDate
+		}
+		if a.EndDate != nil && a.EndDate.Before(in.To) {
+			in.To = *a.EndDate
+		}
+		out = append(out, AdherenceAssignment{
+			AssignmentID: a.ID,
+			DiscipleID:   a.DiscipleID,
+			ProgramID:    a.ProgramID,
+			ProgramTitle: a.ProgramTitle,
+			StartDate:    a.StartDate,
+			EndDate:      a.EndDate,
+			Input:        in,
+		})
+	}
+	return out, nil
+}
+
+func f32ptr(v *float32) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
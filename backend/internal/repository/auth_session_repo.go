@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrRefreshInvalid = errors.New("invalid_token")
+	ErrRefreshReused  = errors.New("token_reused")
+)
+
+const (
+	RevokeLogout = "logout"
+	RevokeManual = "revoked"
+	RevokeReuse  = "reuse"
+)
+
+// AuthSession: un login (dispositivo). Agrupa la familia de refresh tokens.
+type AuthSession struct {
+	ID           string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID       string     `gorm:"type:uuid;not null" json:"user_id"`
+	UserAgent    *string    `json:"user_agent,omitempty"`
+	IP           *string    `gorm:"column:ip" json:"ip,omitempty"`
+	CreatedAt    time.Time  `gorm:"not null;default:now()" json:"created_at"`
+	LastUsedAt   time.Time  `gorm:"not null;default:now()" json:"last_used_at"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	RevokeReason *string    `json:"revoke_reason,omitempty"`
+}
+
+func (AuthSession) TableName() string { return "auth_sessions" }
+
+type RefreshToken struct {
+	TokenHash string    `gorm:"primaryKey"`
+	SessionID string    `gorm:"type:uuid;not null"`
+	CreatedAt time.Time `gorm:"not null;default:now()"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+func (RefreshToken) TableName() string { return "refresh_tokens" }
+
+type AuthSessionRepository interface {
+	// Create guarda la sesión y su primer refresh token.
+	Create(ctx context.Context, s *AuthSession, tokenHash string) error
+	// Rotate canjea oldHash por newHash. Un token ya rotado revoca toda la
+	// sesión (ErrRefreshReused); desconocido, vencido o revocado es ErrRefreshInvalid.
+	Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time, userAgent, ip *string) (*AuthSession, error)
+	FindByToken(ctx context.Context, tokenHash string) (*AuthSession, error)
+	ListActive(ctx context.Context, userID string) ([]AuthSession, error)
+	// Revoke devuelve gorm.ErrRecordNotFound si la sesión no es del usuario o ya estaba revocada.
+	Revoke(ctx context.Context, userID, sessionID, reason string) error
+	RevokeAllExcept(ctx context.Context, userID, keepID, reason string) (int64, error)
+	// Purge borra sesiones vencidas o revocadas antes de `before` (y sus tokens).
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+type authSessionRepository struct{ db *gorm.DB }
+
+func NewAuthSessionRepository(db *gorm.DB) AuthSessionRepository {
+	return &authSessionRepository{db: db}
+}
+
+func (r *authSessionRepository) Create(ctx context.Context, s *AuthSession, tokenHash string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(s).Error; err != nil {
+			return err
+		}
+		return tx.Create(&RefreshToken{TokenHash: tokenHash, SessionID: s.ID, ExpiresAt: s.ExpiresAt}).Error
+	})
+}
+
+func (r *authSessionRepository) Rotate(ctx context.Context, oldHash, newHash string, expiresAt time.Time, userAgent, ip *string) (*AuthSession, error) {
+	var sess AuthSession
+	reused := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rt RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", oldHash).First(&rt).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshInvalid
+			}
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&sess, "id = ?", rt.SessionID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if sess.RevokedAt != nil || !now.Before(sess.ExpiresAt) || !now.Before(rt.ExpiresAt) {
+			return ErrRefreshInvalid
+		}
+		if rt.UsedAt != nil {
+			// reuso de un token ya rotado: alguien más lo tiene, cae toda la familia.
+			// Se confirma la revocación y el error se devuelve fuera de la tx.
+			reused = true
+			return tx.Model(&AuthSession{}).Where("id = ?", sess.ID).
+				Updates(map[string]any{"revoked_at": now, "revoke_reason": RevokeReuse}).Error
+		}
+
+		if err := tx.Model(&RefreshToken{}).Where("token_hash = ?", oldHash).
+			Update("used_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&RefreshToken{TokenHash: newHash, SessionID: sess.ID, ExpiresAt: expiresAt}).Error; err != nil {
+			return err
+		}
+		sess.LastUsedAt, sess.ExpiresAt = now, expiresAt
+		patch := map[string]any{"last_used_at": now, "expires_at": expiresAt}
+		if userAgent != nil {
+			sess.UserAgent, patch["user_agent"] = userAgent, *userAgent
+		}
+		if ip != nil {
+			sess.IP, patch["ip"] = ip, *ip
+		}
+		return tx.Model(&AuthSession{}).Where("id = ?", sess.ID).Updates(patch).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshReused
+	}
+	return &sess, nil
+}
+
+func (r *authSessionRepository) FindByToken(ctx context.Context, tokenHash string) (*AuthSession, error) {
+	var s AuthSession
+	err := r.db.WithContext(ctx).
+		Joins("JOIN refresh_tokens rt ON rt.session_id = auth_sessions.id").
+		Where("rt.token_hash = ?", tokenHash).
+		First(&s).Error
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *authSessionRepository) ListActive(ctx context.Context, userID string) ([]AuthSession, error) {
+	var out []AuthSession
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > now()", userID).
+		Order("last_used_at DESC").
+		Find(&out).Error
+	return out, err
+}
+
+func (r *authSessionRepository) Revoke(ctx context.Context, userID, sessionID, reason string) error {
+	res := r.db.WithContext(ctx).Model(&AuthSession{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).
+		Updates(map[string]any{"revoked_at": time.Now(), "revoke_reason": reason})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *authSessionRepository) RevokeAllExcept(ctx context.Context, userID, keepID, reason string) (int64, error) {
+	q := r.db.WithContext(ctx).Model(&AuthSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID)
+	if keepID != "" {
+		q = q.Where("id <> ?", keepID)
+	}
+	res := q.Updates(map[string]any{"revoked_at": time.Now(), "revoke_reason": reason})
+	return res.RowsAffected, res.Error
+}
+
+func (r *authSessionRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("expires_at < ? OR revoked_at < ?", before, before).
+		Delete(&AuthSession{})
+	return res.RowsAffected, res.Error
+}
@@ -2,27 +2,73 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"time"
 
+	"github.com/lib/pq"
+	"github.com/vicepalma/roma-system/backend/internal/bodymetrics"
 	"github.com/vicepalma/roma-system/backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// CheckinRequirement: métricas que el coach pide al discípulo.
+type CheckinRequirement struct {
+	DiscipleID string         `gorm:"type:uuid;primaryKey" json:"disciple_id"`
+	Metrics    pq.StringArray `gorm:"type:text[];not null;default:'{}'" json:"metrics"`
+	UpdatedBy  *string        `gorm:"type:uuid" json:"updated_by,omitempty"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+func (CheckinRequirement) TableName() string { return "checkin_requirements" }
+
+type CheckinFilter struct {
+	Status   *string // pending | reviewed
+	From, To *time.Time
+}
+
+// InboxCheckin: check-in con datos del discípulo para el inbox del coach.
+type InboxCheckin struct {
+	domain.Checkin
+	DiscipleName  string `json:"disciple_name"`
+	DiscipleEmail string `json:"disciple_email"`
+}
+
 type CheckinRepository interface {
+	WithTx(tx *gorm.DB) CheckinRepository
 	Create(ctx context.Context, checkin *domain.Checkin) error
-	ListByDisciple(ctx context.Context, discipleID string, limit, offset int) ([]domain.Checkin, int64, error)
+	ListByDisciple(ctx context.Context, discipleID string, f CheckinFilter, limit, offset int) ([]domain.Checkin, int64, error)
 	FindByID(ctx context.Context, id string) (*domain.Checkin, error)
+	// Update guarda fecha, notas y métricas; resetReview vuelve el check-in a pending.
+	Update(ctx context.Context, c *domain.Checkin, resetReview bool) error
+	Delete(ctx context.Context, id string) error
+	SetReview(ctx context.Context, id, coachID, status string, comment *string) (*domain.Checkin, error)
+	// Inbox: check-ins de todos los discípulos con link aceptado del coach.
+	Inbox(ctx context.Context, coachID string, f CheckinFilter, limit, offset int) ([]InboxCheckin, int64, error)
+
+	GetRequirement(ctx context.Context, discipleID string) (*CheckinRequirement, error)
+	SaveRequirement(ctx context.Context, req *CheckinRequirement) error
+	// MetricSeries: valores informados por métrica (claves de bodymetrics) en [from, to].
+	MetricSeries(ctx context.Context, discipleID string, metrics []string, from, to *time.Time) (map[string][]bodymetrics.Point, error)
+
+	// AddPhoto agrega al final si el check-in tiene menos de maxPhotos; false si no.
+	AddPhoto(ctx context.Context, checkinID string, p domain.CheckinPhoto, maxPhotos int) (bool, error)
+	// RemovePhoto devuelve la foto quitada (nil si no estaba).
+	RemovePhoto(ctx context.Context, checkinID, photoID string) (*domain.CheckinPhoto, error)
 }
 
 type checkinRepository struct{ db *gorm.DB }
 
-func NewCheckinRepository(db *gorm.DB) CheckinRepository { return &checkinRepository{db: db} }
+func NewCheckinRepository(db *gorm.DB) CheckinRepository          { return &checkinRepository{db: db} }
+func (r *checkinRepository) WithTx(tx *gorm.DB) CheckinRepository { return &checkinRepository{db: tx} }
 
 func (r *checkinRepository) Create(ctx context.Context, checkin *domain.Checkin) error {
 	return r.db.WithContext(ctx).Create(checkin).Error
 }
 
-func (r *checkinRepository) ListByDisciple(ctx context.Context, discipleID string, limit, offset int) ([]domain.Checkin, int64, error) {
-	q := r.db.WithContext(ctx).Model(&domain.Checkin{}).Where("disciple_id = ?", discipleID)
+func (r *checkinRepository) ListByDisciple(ctx context.Context, discipleID string, f CheckinFilter, limit, offset int) ([]domain.Checkin, int64, error) {
+	q := applyCheckinFilter(r.db.WithContext(ctx).Model(&domain.Checkin{}).Where("disciple_id = ?", discipleID), "", f)
 	var total int64
 	if err := q.Count(&total).Error; err != nil {
 		return nil, 0, err
@@ -45,3 +91,174 @@ func (r *checkinRepository) FindByID(ctx context.Context, id string) (*domain.Ch
 	}
 	return &out, nil
 }
+
+func applyCheckinFilter(q *gorm.DB, alias string, f CheckinFilter) *gorm.DB {
+	if f.Status != nil {
+		q = q.Where(alias+"review_status = ?", *f.Status)
+	}
+	if f.From != nil {
+		q = q.Where(alias+"checked_at >= ?", f.From.Format("2006-01-02"))
+	}
+	if f.To != nil {
+		q = q.Where(alias+"checked_at <= ?", f.To.Format("2006-01-02"))
+	}
+	return q
+}
+
+func (r *checkinRepository) Update(ctx context.Context, c *domain.Checkin, resetReview bool) error {
+	cols := []string{
+		"checked_at", "notes", "weight_kg", "waist_cm", "chest_cm", "arm_cm", "thigh_cm",
+		"body_fat_pct", "resting_hr", "sleep_hours", "energy", "stress", "soreness", "updated_at",
+	}
+	c.UpdatedAt = time.Now()
+	if resetReview {
+		// vuelve a pending sin rastro de la revisión anterior (como SetReview)
+		c.ReviewStatus = domain.CheckinPending
+		c.ReviewedBy, c.ReviewedAt, c.CoachComment = nil, nil, nil
+		cols = append(cols, "review_status", "reviewed_by", "reviewed_at", "coach_comment")
+	}
+	return r.db.WithContext(ctx).Model(c).Select(cols).Updates(c).Error
+}
+
+func (r *checkinRepository) Delete(ctx context.Context, id string) error {
+	res := r.db.WithContext(ctx).Delete(&domain.Checkin{}, "id = ?", id)
+	if res.Error == nil && res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return res.Error
+}
+
+func (r *checkinRepository) SetReview(ctx context.Context, id, coachID, status string, comment *string) (*domain.Checkin, error) {
+	patch := map[string]any{"review_status": status, "coach_comment": comment}
+	if status == domain.CheckinReviewed {
+		patch["reviewed_by"], patch["reviewed_at"] = coachID, time.Now()
+	} else {
+		patch["reviewed_by"], patch["reviewed_at"] = nil, nil
+	}
+	res := r.db.WithContext(ctx).Model(&domain.Checkin{}).Where("id = ?", id).Updates(patch)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.FindByID(ctx, id)
+}
+
+func (r *checkinRepository) Inbox(ctx context.Context, coachID string, f CheckinFilter, limit, offset int) ([]InboxCheckin, int64, error) {
+	q := r.db.WithContext(ctx).Table("checkins AS c").
+		Joins("JOIN coach_links cl ON cl.disciple_id = c.disciple_id AND cl.coach_id = ? AND cl.status = 'accepted'", coachID).
+		Joins("JOIN users u ON u.id = c.disciple_id").
+		Where("c.disciple_id <> ?", coachID)
+	q = applyCheckinFilter(q, "c.", f)
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	rows := []InboxCheckin{}
+	// los más viejos primero: es una cola de trabajo
+	err := q.Select("c.*, u.name AS disciple_name, u.email AS disciple_email").
+		Order("c.checked_at ASC, c.created_at ASC").
+		Limit(limit).Offset(offset).
+		Find(&rows).Error
+	return rows, total, err
+}
+
+// GetRequirement devuelve una lista vacía si el coach no configuró nada.
+func (r *checkinRepository) GetRequirement(ctx context.Context, discipleID string) (*CheckinRequirement, error) {
+	var out CheckinRequirement
+	err := r.db.WithContext(ctx).First(&out, "disciple_id = ?", discipleID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &CheckinRequirement{DiscipleID: discipleID, Metrics: pq.StringArray{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (r *checkinRepository) SaveRequirement(ctx context.Context, req *CheckinRequirement) error {
+	if req.Metrics == nil {
+		req.Metrics = pq.StringArray{}
+	}
+	return r.db.WithContext(ctx).Raw(`
+		INSERT INTO checkin_requirements (disciple_id, metrics, updated_by, updated_at)
+		VALUES (?, ?, ?, now())
+		ON CONFLICT (disciple_id) DO UPDATE
+		SET metrics = EXCLUDED.metrics, updated_by = EXCLUDED.updated_by, updated_at = now()
+		RETURNING updated_at
+	`, req.DiscipleID, req.Metrics, req.UpdatedBy).Row().Scan(&req.UpdatedAt)
+}
+
+func (r *checkinRepository) MetricSeries(ctx context.Context, discipleID string, metrics []string, from, to *time.Time) (map[string][]bodymetrics.Point, error) {
+	q := r.db.WithContext(ctx).Model(&domain.Checkin{}).Where("disciple_id = ?", discipleID)
+	if from != nil {
+		q = q.Where("checked_at >= ?", from.Format("2006-01-02"))
+	}
+	if to != nil {
+		q = q.Where("checked_at <= ?", to.Format("2006-01-02"))
+	}
+	var rows []domain.Checkin
+	if err := q.Order("checked_at ASC").Order("created_at ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string][]bodymetrics.Point, len(metrics))
+	for _, c := range rows {
+		values := c.Metrics()
+		date := c.CheckedAt.Format("2006-01-02")
+		for _, m := range metrics {
+			if v, ok := values[m]; ok {
+				out[m] = append(out[m], bodymetrics.Point{Date: date, Value: v})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (r *checkinRepository) AddPhoto(ctx context.Context, checkinID string, p domain.CheckinPhoto, maxPhotos int) (bool, error) {
+	p.URL, p.ThumbURL = "", ""
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return false, err
+	}
+	// condición y append en el mismo UPDATE: dos subidas simultáneas no pasan el tope
+	res := r.db.WithContext(ctx).Exec(`
+		UPDATE checkins SET attachments = attachments || jsonb_build_array(?::jsonb)
+		WHERE id = ? AND jsonb_array_length(attachments) < ?
+	`, string(raw), checkinID, maxPhotos)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *checkinRepository) RemovePhoto(ctx context.Context, checkinID, photoID string) (*domain.CheckinPhoto, error) {
+	var removed *domain.CheckinPhoto
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var c domain.Checkin
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "attachments").
+			First(&c, "id = ?", checkinID).Error; err != nil {
+			return err
+		}
+		keep := make([]domain.CheckinPhoto, 0, len(c.Photos))
+		for i := range c.Photos {
+			if c.Photos[i].ID == photoID {
+				removed = &c.Photos[i]
+				continue
+			}
+			keep = append(keep, c.Photos[i])
+		}
+		if removed == nil {
+			return nil
+		}
+		raw, err := json.Marshal(keep)
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE checkins SET attachments = ?::jsonb WHERE id = ?`, string(raw), checkinID).Error
+	})
+	return removed, err
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/schedule"
 	"gorm.io/gorm"
 )
 
@@ -49,18 +50,13 @@ type AssignmentRow struct {
 	AssignedBy     string    `gorm:"type:uuid;not null"`
 	StartDate      time.Time `gorm:"type:date;not null"`
 	EndDate        *time.Time
-	IsActive       bool `gorm:"not null;default:false"`
+	IsActive       bool   `gorm:"not null;default:false"`
+	ScheduleMode   string `gorm:"not null;default:'calendar'"`
 	CreatedAt      time.Time
 }
 
-type ProgramDayLite struct {
-	ID       string `gorm:"type:uuid;primaryKey"`
-	WeekID   string `gorm:"type:uuid;index"`
-	DayIndex int
-	Notes    *string
-}
-
 type CoachRepository interface {
+	WithTx(tx *gorm.DB) CoachRepository
 	CreateLink(ctx context.Context, coachID, discipleID string, autoAccept bool) (*domain.CoachLink, error)
 	UpdateStatus(ctx context.Context, id, newStatus string, actorID string) (*domain.CoachLink, error)
 	ListLinksForUser(ctx context.Context, userID string) (incoming, outgoing []domain.CoachLink, err error)
@@ -73,13 +69,16 @@ type CoachRepository interface {
 
 	GetAssignmentByID(ctx context.Context, id string) (*AssignmentRow, error)
 	UpdateAssignment(ctx context.Context, id string, patch map[string]any) error
-	ListProgramDaysByProgramWeek(ctx context.Context, programID string, weekIndex int) ([]ProgramDayLite, error)
+	ProgramSchedule(ctx context.Context, programID string) (schedule.Plan, map[string]*string, error)
 	GetActiveAssignment(ctx context.Context, discipleID string) (*domain.Assignment, error)
+
+	Roster(ctx context.Context, coachID string, f RosterFilter) ([]RosterRow, error)
 }
 
 type coachRepository struct{ db *gorm.DB }
 
-func NewCoachRepository(db *gorm.DB) CoachRepository { return &coachRepository{db: db} }
+func NewCoachRepository(db *gorm.DB) CoachRepository          { return &coachRepository{db: db} }
+func (r *coachRepository) WithTx(tx *gorm.DB) CoachRepository { return &coachRepository{db: tx} }
 
 func (r *coachRepository) CreateLink(ctx context.Context, coachID, discipleID string, autoAccept bool) (*domain.CoachLink, error) {
 	status := "pending"
@@ -251,31 +250,15 @@ func (r *coachRepository) UpdateAssignment(ctx context.Context, id string, patch
 		Updates(patch).Error
 }
 
-// ListProgramDaysByProgramWeek (week_index=1 para el MVP)
-func (r *coachRepository) ListProgramDaysByProgramWeek(ctx context.Context, programID string, weekIndex int) ([]ProgramDayLite, error) {
-	type weekRow struct {
-		ID string
-	}
-	var w weekRow
-	if err := r.db.WithContext(ctx).
-		Raw(`SELECT id FROM program_weeks WHERE program_id = ? AND week_index = ? LIMIT 1`, programID, weekIndex).
-		Scan(&w).Error; err != nil {
-		return nil, err
-	}
-	var days []ProgramDayLite
-	if err := r.db.WithContext(ctx).
-		Raw(`SELECT id, week_id, day_index, notes
-		     FROM program_days WHERE week_id = ? ORDER BY day_index ASC, id ASC`, w.ID).
-		Scan(&days).Error; err != nil {
-		return nil, err
-	}
-	return days, nil
+// ProgramSchedule: plan completo (todas las semanas) + notas por day_id
+func (r *coachRepository) ProgramSchedule(ctx context.Context, programID string) (schedule.Plan, map[string]*string, error) {
+	return loadSchedulePlan(ctx, r.db, programID)
 }
 
 func (r *coachRepository) GetActiveAssignment(ctx context.Context, discipleID string) (*domain.Assignment, error) {
 	var a domain.Assignment
 	q := `
-SELECT id, program_id, program_version, disciple_id, assigned_by, start_date, end_date, is_active, created_at
+SELECT id, program_id, program_version, disciple_id, assigned_by, start_date, end_date, is_active, schedule_mode, created_at
 FROM assignments
 WHERE disciple_id = ?
   AND is_active = true
@@ -4,9 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/progression"
+	"github.com/vicepalma/roma-system/backend/internal/schedule"
 	"gorm.io/gorm"
 )
 
@@ -45,10 +49,13 @@ type DailyMuscleVolume struct {
 }
 
 type MeTodayDay struct {
-	ID       string
-	WeekID   string
-	DayIndex int
-	Notes    sql.NullString
+	ID        string
+	WeekID    string
+	WeekIndex int
+	DayIndex  int
+	Notes     sql.NullString
+	Rest      bool   // día sin prescripciones
+	Mode      string // calendar | next_undone (modo con el que se resolvió)
 }
 
 type MeTodayPrescription struct {
@@ -63,6 +70,7 @@ type MeTodayPrescription struct {
 	ExerciseName  string
 	PrimaryMuscle string
 	Equipment     sql.NullString
+	Target        *progression.Target // objetivo de la próxima sesión (regla u override del coach)
 }
 
 var ErrNoDay = errors.New("no_day")
@@ -71,6 +79,8 @@ type (
 	HistorySessionFilter struct {
 		Status    *string
 		ProgramID *string
+		// AllSetTypes: sets y volumen incluyen calentamiento y drops
+		AllSetTypes bool
 	}
 
 	// Para /history?group=session
@@ -86,6 +96,9 @@ type (
 		PerformedAt    time.Time  `json:"performed_at"`
 		Status         string     `json:"status"`
 		EndedAt        *time.Time `json:"ended_at,omitempty"`
+		LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+		AutoClosed     bool       `json:"auto_closed"`
+		DurationSec    int64      `json:"duration_sec"` // ver domain.SessionDuration
 		Sets           int        `json:"sets"`
 		ExercisesCount int        `json:"exercises_count"`
 		Volume         float64    `json:"volume"`
@@ -115,24 +128,36 @@ type HistoryRepository interface {
 	ListRecentSessions(ctx context.Context, discipleID string, since time.Time) ([]domain.SessionLog, error)
 	ListSetsInSessions(ctx context.Context, discipleID string, since time.Time) ([]domain.SetLog, error)
 	BestSetsByExercise(ctx context.Context, discipleID string) ([]PRRow, error)
+	// series con peso por ejercicio (fecha local), para analytics de fuerza
+	StrengthSets(ctx context.Context, discipleID, tz string, exerciseID *string, from, to *time.Time) ([]StrengthSetRow, error)
+	// series con horas por sesión (fecha local), para cumplimiento de descansos.
+	// exerciseID elige las sesiones donde se hizo el ejercicio, pero devuelve
+	// todas sus series: el descanso depende de la serie anterior, sea cual sea.
+	TimedSets(ctx context.Context, discipleID, tz string, exerciseID *string, from, to *time.Time) ([]TimedSetRow, error)
 
-	DailyVolumeByExercise(ctx context.Context, discipleID string, sinceDate string, tz string) ([]DailyExerciseVolume, error)
-	DailyVolumeByMuscle(ctx context.Context, discipleID string, sinceDate string, tz string) ([]DailyMuscleVolume, error)
+	// allSetTypes=false cuenta solo series efectivas (sin calentamiento ni drops)
+	DailyVolumeByExercise(ctx context.Context, discipleID string, sinceDate string, tz string, allSetTypes bool) ([]DailyExerciseVolume, error)
+	DailyVolumeByMuscle(ctx context.Context, discipleID string, sinceDate string, tz string, allSetTypes bool) ([]DailyMuscleVolume, error)
 
 	ListRelevantExercisesForUser(ctx context.Context, discipleID string) ([]ExerciseCatalogItem, error)
 
-	ResolveToday(ctx context.Context, discipleID string, tz string) (assignmentID string, day *MeTodayDay, prescs []MeTodayPrescription, err error)
+	// mode vacío => usa assignments.schedule_mode
+	ResolveToday(ctx context.Context, discipleID string, tz string, mode string) (assignmentID string, day *MeTodayDay, prescs []MeTodayPrescription, err error)
 
 	LatestSessionForAssignmentDay(ctx context.Context, assignmentID, dayID string) (*CurrentSessionInfo, error)
 	ActiveAssignmentForToday(ctx context.Context, discipleID, tz string) (string, error)
 
 	// history (group=session|day)
 	GetSessionsHistory(ctx context.Context, discipleID, tz string, from, to *time.Time, filter HistorySessionFilter, limit, offset int) ([]HistorySessionRow, int64, error)
-	GetDaysAggregate(ctx context.Context, discipleID, tz string, from, to *time.Time, limit, offset int) ([]HistoryDayAgg, int64, error)
+	GetDaysAggregate(ctx context.Context, discipleID, tz string, from, to *time.Time, allSetTypes bool, limit, offset int) ([]HistoryDayAgg, int64, error)
 
 	// /disciples/:id/sessions
 	ListDiscipleSessions(ctx context.Context, discipleID, tz string, from, to *time.Time, filter HistorySessionFilter, limit, offset int) ([]DiscipleSessionRow, int64, error)
 
+	// plan vs registrado por assignment activo (ver adherence_repo.go)
+	AdherenceInputs(ctx context.Context, discipleID, tz string, from, to time.Time) ([]AdherenceAssignment, error)
+	AdherenceInputsFor(ctx context.Context, discipleIDs []string, tz string, from, to time.Time) ([]AdherenceAssignment, error)
+
 	// /disciples/:id/days
 	ListPlanVsDone(ctx context.Context, discipleID, tz string, from, to *time.Time, limit, offset int) ([]PlanVsDoneRow, int64, error)
 }
@@ -187,14 +212,14 @@ func (r *historyRepository) BestSetsByExercise(ctx context.Context, discipleID s
 		JOIN prescriptions p ON p.id = set_logs.prescription_id
 		WHERE s.disciple_id = ?
 		  AND set_logs.weight IS NOT NULL
-		  AND set_logs.reps BETWEEN 1 AND 36
+		  AND set_logs.reps BETWEEN 1 AND 36`+workingSets("set_logs", false)+`
 		GROUP BY p.exercise_id
 		ORDER BY estimated_1rm DESC NULLS LAST, max_weight DESC NULLS LAST, max_reps DESC
 	`, discipleID).Scan(&rows).Error
 	return rows, err
 }
 
-func (r *historyRepository) DailyVolumeByExercise(ctx context.Context, discipleID string, sinceDate string, tz string) ([]DailyExerciseVolume, error) {
+func (r *historyRepository) DailyVolumeByExercise(ctx context.Context, discipleID string, sinceDate string, tz string, allSetTypes bool) ([]DailyExerciseVolume, error) {
 	rows := []DailyExerciseVolume{}
 	err := r.db.WithContext(ctx).Raw(`
 		SELECT 
@@ -209,14 +234,14 @@ func (r *historyRepository) DailyVolumeByExercise(ctx context.Context, discipleI
 		JOIN prescriptions p ON p.id = set_logs.prescription_id
 		JOIN exercises e     ON e.id = p.exercise_id
 		WHERE s.disciple_id = ?
-		  AND (s.performed_at AT TIME ZONE ? )::date >= ?::date
+		  AND (s.performed_at AT TIME ZONE ? )::date >= ?::date`+workingSets("set_logs", allSetTypes)+`
 		GROUP BY 1,2,3
 		ORDER BY 1 ASC, 3 ASC
 	`, tz, discipleID, tz, sinceDate).Scan(&rows).Error
 	return rows, err
 }
 
-func (r *historyRepository) DailyVolumeByMuscle(ctx context.Context, discipleID string, sinceDate string, tz string) ([]DailyMuscleVolume, error) {
+func (r *historyRepository) DailyVolumeByMuscle(ctx context.Context, discipleID string, sinceDate string, tz string, allSetTypes bool) ([]DailyMuscleVolume, error) {
 	rows := []DailyMuscleVolume{}
 	err := r.db.WithContext(ctx).Raw(`
 		SELECT 
@@ -230,7 +255,7 @@ func (r *historyRepository) DailyVolumeByMuscle(ctx context.Context, discipleID
 		JOIN prescriptions p  ON p.id = set_logs.prescription_id
 		JOIN exercises e      ON e.id = p.exercise_id
 		WHERE s.disciple_id = ?
-		  AND (s.performed_at AT TIME ZONE ? )::date >= ?::date
+		  AND (s.performed_at AT TIME ZONE ? )::date >= ?::date`+workingSets("set_logs", allSetTypes)+`
 		GROUP BY 1,2
 		ORDER BY 1 ASC, 2 ASC
 	`, tz, discipleID, tz, sinceDate).Scan(&rows).Error
@@ -253,10 +278,10 @@ func (r *historyRepository) ListRelevantExercisesForUser(ctx context.Context, di
 	return rows, err
 }
 
-func (r *historyRepository) ResolveToday(ctx context.Context, discipleID string, tz string) (string, *MeTodayDay, []MeTodayPrescription, error) {
+func (r *historyRepository) ResolveToday(ctx context.Context, discipleID string, tz string, mode string) (string, *MeTodayDay, []MeTodayPrescription, error) {
 	// 1) assignment activo más reciente a fecha de HOY en TZ
 	const qAssign = `
-SELECT a.id, a.program_id
+SELECT a.id, a.program_id, a.start_date, a.schedule_mode
 FROM assignments a
 WHERE a.disciple_id = $1
   AND a.is_active = true
@@ -265,31 +290,56 @@ WHERE a.disciple_id = $1
 ORDER BY a.created_at DESC
 LIMIT 1;
 `
-	var assignID, programID string
-	if err := r.db.WithContext(ctx).Raw(qAssign, discipleID, tz).Row().Scan(&assignID, &programID); err != nil {
+	var assignID, programID, asgMode string
+	var startDate time.Time
+	if err := r.db.WithContext(ctx).Raw(qAssign, discipleID, tz).Row().Scan(&assignID, &programID, &startDate, &asgMode); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", nil, nil, ErrNoDay
 		}
 		return "", nil, nil, err
 	}
 
-	// 2) (MVP) Week 1 / Day 1 del programa
-	const qDay = `
-SELECT d.id, d.week_id, d.day_index, d.notes
-FROM program_days d
-JOIN program_weeks w ON w.id = d.week_id
-WHERE w.program_id = $1 AND w.week_index = 1 AND d.day_index = 1
-ORDER BY d.id ASC
-LIMIT 1;
-`
-	var day MeTodayDay
-	if err := r.db.WithContext(ctx).Raw(qDay, programID).Row().Scan(&day.ID, &day.WeekID, &day.DayIndex, &day.Notes); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", nil, nil, ErrNoDay
-		}
+	m, ok := schedule.ParseMode(mode)
+	if !ok {
+		m, _ = schedule.ParseMode(asgMode)
+	}
+	if m == "" {
+		m = schedule.ModeCalendar
+	}
+
+	// 2) Plan completo (todas las semanas) y día que toca según el modo
+	plan, notes, err := loadSchedulePlan(ctx, r.db, programID)
+	if err != nil {
 		return "", nil, nil, err
 	}
 
+	var picked schedule.Day
+	switch m {
+	case schedule.ModeNextUndone:
+		done, err := r.completedDayCounts(ctx, assignID)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		picked, ok = schedule.NextUndone(plan, done)
+	default:
+		var slot schedule.Slot
+		slot, ok = schedule.OnDate(plan, startDate, time.Now().In(loadTZ(tz)))
+		picked = slot.Day
+	}
+	if !ok {
+		return "", nil, nil, ErrNoDay
+	}
+
+	day := MeTodayDay{
+		ID:        picked.ID,
+		WeekID:    picked.WeekID,
+		WeekIndex: picked.WeekIndex,
+		DayIndex:  picked.DayIndex,
+		Notes:     nullString(notes[picked.ID]),
+		Rest:      picked.Rest,
+		Mode:      string(m),
+	}
+
 	// 3) Prescripciones + datos de ejercicio
 	const qPresc = `
 SELECT p.id, p.day_id, p.exercise_id, p.series, p.reps, p.rest_sec, p.to_failure, p.position,
@@ -299,7 +349,7 @@ JOIN exercises e ON e.id = p.exercise_id
 WHERE p.day_id = $1
 ORDER BY p.position ASC, p.id ASC;
 `
-	rows, err := r.db.Raw(qPresc, day.ID).Rows()
+	rows, err := r.db.WithContext(ctx).Raw(qPresc, day.ID).Rows()
 	if err != nil {
 		return assignID, &day, nil, err
 	}
@@ -320,9 +370,45 @@ ORDER BY p.position ASC, p.id ASC;
 		return assignID, &day, nil, err
 	}
 
+	// 4) Objetivos de progresión
+	ids := make([]string, len(out))
+	for i := range out {
+		ids[i] = out[i].ID
+	}
+	targets, err := progressionTargets(ctx, r.db, discipleID, assignID, ids)
+	if err != nil {
+		return assignID, &day, nil, err
+	}
+	for i := range out {
+		if t, ok := targets[out[i].ID]; ok {
+			out[i].Target = &t
+		}
+	}
+
 	return assignID, &day, out, nil
 }
 
+// completedDayCounts: sesiones cerradas del assignment por day_id.
+func (r *historyRepository) completedDayCounts(ctx context.Context, assignmentID string) (map[string]int, error) {
+	var rows []struct {
+		DayID string
+		N     int
+	}
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT day_id, COUNT(*) AS n
+		FROM session_logs
+		WHERE assignment_id = ? AND status = 'closed'
+		GROUP BY day_id
+	`, assignmentID).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make(map[string]int, len(rows))
+	for _, r := range rows {
+		out[r.DayID] = r.N
+	}
+	return out, nil
+}
+
 func (r *historyRepository) LatestSessionForAssignmentDay(ctx context.Context, assignmentID, dayID string) (*CurrentSessionInfo, error) {
 	const q = `
 		SELECT s.id,
@@ -370,11 +456,99 @@ LIMIT 1;`
 }
 
 // ========== helpers ==========
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func loadTZ(tz string) *time.Location {
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// workingSets: condición " AND <alias>.set_type IN (...)" que deja solo las
+// series efectivas (domain.WorkingSetTypes); con all no filtra.
+func workingSets(alias string, all bool) string {
+	if all {
+		return ""
+	}
+	return " AND " + alias + ".set_type IN ('" + strings.Join(domain.WorkingSetTypes, "','") + "')"
+}
+
 func dateFloorTZ(col, tz string) string {
 	// devuelve: (DATE (col AT TIME ZONE 'tz'))
 	return "DATE((" + col + ") AT TIME ZONE '" + tz + "')"
 }
 
+type StrengthSetRow struct {
+	ExerciseID   string
+	ExerciseName string
+	Date         string // YYYY-MM-DD en la TZ pedida
+	Weight       float64
+	Reps         int
+	RPE          *float32 `gorm:"column:rpe"`
+}
+
+func (r *historyRepository) StrengthSets(ctx context.Context, discipleID, tz string, exerciseID *string, from, to *time.Time) ([]StrengthSetRow, error) {
+	day := dateFloorTZ("s.performed_at", tz)
+	where := "s.disciple_id = ? AND sl.weight > 0 AND sl.reps > 0" + workingSets("sl", false)
+	args := []any{discipleID}
+	if exerciseID != nil {
+		where += " AND p.exercise_id = ?"
+		args = append(args, *exerciseID)
+	}
+	if from != nil {
+		where += " AND " + day + " >= ?"
+		args = append(args, from.Format("2006-01-02"))
+	}
+	if to != nil {
+		where += " AND " + day + " <= ?"
+		args = append(args, to.Format("2006-01-02"))
+	}
+	rows := []StrengthSetRow{}
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT p.exercise_id, e.name AS exercise_name,
+		       TO_CHAR(`+day+`, 'YYYY-MM-DD') AS date,
+		       sl.weight::float AS weight, sl.reps, sl.rpe
+		FROM set_logs sl
+		JOIN session_logs s ON s.id = sl.session_id
+		JOIN prescriptions p ON p.id = sl.prescription_id
+		JOIN exercises e ON e.id = p.exercise_id
+		WHERE `+where+`
+		ORDER BY e.name ASC, p.exercise_id, s.performed_at ASC, sl.set_index ASC
+	`, args...).Scan(&rows).Error
+	return rows, err
+}
+
+func (r *historyRepository) TimedSets(ctx context.Context, discipleID, tz string, exerciseID *string, from, to *time.Time) ([]TimedSetRow, error) {
+	day := dateFloorTZ("s.performed_at", tz)
+	where := "s.disciple_id = ? AND (sl.started_at IS NOT NULL OR sl.completed_at IS NOT NULL)"
+	args := []any{discipleID}
+	if exerciseID != nil {
+		where += ` AND sl.session_id IN (
+			SELECT sx.session_id FROM set_logs sx
+			JOIN prescriptions px ON px.id = sx.prescription_id
+			WHERE px.exercise_id = ?)`
+		args = append(args, *exerciseID)
+	}
+	if from != nil {
+		where += " AND " + day + " >= ?"
+		args = append(args, from.Format("2006-01-02"))
+	}
+	if to != nil {
+		where += " AND " + day + " <= ?"
+		args = append(args, to.Format("2006-01-02"))
+	}
+	rows := []TimedSetRow{}
+	err := r.db.WithContext(ctx).Raw(fmt.Sprintf(timedSetsSQL, where), args...).Scan(&rows).Error
+	return rows, err
+}
+
 // ========== /history?group=session ==========
 func (r *historyRepository) GetSessionsHistory(ctx context.Context, discipleID, tz string, from, to *time.Time, filter HistorySessionFilter, limit, offset int) ([]HistorySessionRow, int64, error) {
 	where := "s.disciple_id = ?"
@@ -420,6 +594,9 @@ SELECT
   s.performed_at,
   s.status,
   s.ended_at,
+  s.last_activity_at,
+  s.auto_closed,
+  GREATEST(0, EXTRACT(EPOCH FROM COALESCE(s.ended_at, s.last_activity_at, s.performed_at) - s.performed_at))::bigint AS duration_sec,
   COALESCE(COUNT(sl.id),0)                         AS sets,
   COALESCE(COUNT(DISTINCT pr.exercise_id),0)        AS exercises_count,
   COALESCE(SUM(COALESCE(sl.weight,0) * sl.reps),0) AS volume
@@ -428,7 +605,7 @@ JOIN assignments a ON a.id = s.assignment_id
 JOIN programs prog ON prog.id = a.program_id
 JOIN program_days pd ON pd.id = s.day_id
 JOIN program_weeks pw ON pw.id = pd.week_id
-LEFT JOIN set_logs sl ON sl.session_id = s.id
+LEFT JOIN set_logs sl ON sl.session_id = s.id` + workingSets("sl", filter.AllSetTypes) + `
 LEFT JOIN prescriptions pr ON pr.id = sl.prescription_id
 WHERE ` + where + `
 GROUP BY s.id, a.program_id, prog.title, pw.week_index, pd.day_index, pd.title
@@ -444,7 +621,7 @@ LIMIT ? OFFSET ?`
 }
 
 // ========== /history?group=day ==========
-func (r *historyRepository) GetDaysAggregate(ctx context.Context, discipleID, tz string, from, to *time.Time, limit, offset int) ([]HistoryDayAgg, int64, error) {
+func (r *historyRepository) GetDaysAggregate(ctx context.Context, discipleID, tz string, from, to *time.Time, allSetTypes bool, limit, offset int) ([]HistoryDayAgg, int64, error) {
 	dayExpr := dateFloorTZ("s.performed_at", tz)
 
 	where := "s.disciple_id = ?"
@@ -471,7 +648,7 @@ SELECT
   COALESCE(COUNT(sl.id),0)     AS sets,
   COALESCE(SUM(COALESCE(sl.weight,0) * sl.reps),0) AS volume
 FROM session_logs s
-LEFT JOIN set_logs sl ON sl.session_id = s.id
+LEFT JOIN set_logs sl ON sl.session_id = s.id` + workingSets("sl", allSetTypes) + `
 WHERE ` + where + `
 GROUP BY day_date
 ORDER BY day_date DESC
@@ -495,7 +672,7 @@ func (r *historyRepository) ListDiscipleSessions(ctx context.Context, discipleID
 // Aproximación: por cada día con sesiones (performed_at), calculamos:
 //   - planned_sets: SUM de series de prescriptions del day_id de esas sesiones
 //     (si hay varias sesiones mismo day_id y misma fecha, planned no se duplica por sesión)
-//   - done_sets: COUNT de set_logs de esas sesiones ese día
+//   - done_sets: COUNT de set_logs efectivos (sin calentamiento ni drops) de esas sesiones ese día
 func (r *historyRepository) ListPlanVsDone(ctx context.Context, discipleID, tz string, from, to *time.Time, limit, offset int) ([]PlanVsDoneRow, int64, error) {
 	dayExpr := dateFloorTZ("s.performed_at", tz)
 
@@ -531,7 +708,7 @@ planned AS (
 done AS (
   SELECT b.day_date, b.day_id, COALESCE(COUNT(sl.id),0) AS done_sets
   FROM base b
-  LEFT JOIN set_logs sl ON sl.session_id = b.session_id
+  LEFT JOIN set_logs sl ON sl.session_id = b.session_id` + workingSets("sl", false) + `
   GROUP BY b.day_date, b.day_id
 )
 SELECT
@@ -2,36 +2,62 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
+	"github.com/vicepalma/roma-system/backend/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	InvitePending  = "pending"
+	InviteAccepted = "accepted"
+	InviteExpired  = "expired"
+	InviteRevoked  = "revoked"
+)
+
+var (
+	ErrInviteInvalid       = errors.New("invalid_code")
+	ErrInviteNotPending    = errors.New("invite_not_pending")
+	ErrInviteExpired       = errors.New("invite_expired")
+	ErrInviteEmailMismatch = errors.New("invite_email_mismatch")
+	ErrEmailInUse          = errors.New("email_in_use")
 )
 
 type Invitation struct {
-	ID         string `gorm:"type:uuid;primaryKey"`
-	Code       string `gorm:"uniqueIndex"`
-	CoachID    string `gorm:"type:uuid;not null"`
-	Email      string `gorm:"not null"`
-	Name       *string
-	Status     string    `gorm:"not null;default:pending"`
-	ExpiresAt  time.Time `gorm:"not null"`
-	AcceptedBy *string
-	AcceptedAt *time.Time
-	CreatedAt  time.Time `gorm:"not null;default:now()"`
+	ID        string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Code      string     `gorm:"uniqueIndex" json:"code"`
+	CoachID   string     `gorm:"type:uuid;not null" json:"coach_id"`
+	Email     string     `gorm:"not null" json:"email"`
+	Name      *string    `json:"name,omitempty"`
+	Status    string     `gorm:"not null;default:pending" json:"status"` // pending|accepted|expired|revoked
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedBy    *string    `gorm:"type:uuid" json:"used_by,omitempty"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `gorm:"not null;default:now()" json:"created_at"`
 }
 
 func (Invitation) TableName() string { return "invite_codes" }
 
 type InviteRepository interface {
+	WithTx(tx *gorm.DB) InviteRepository
 	Create(ctx context.Context, inv *Invitation) error
 	FindByCode(ctx context.Context, code string) (*Invitation, error)
+	ListByCoach(ctx context.Context, coachID string, status *string, limit, offset int) ([]Invitation, int64, error)
 	MarkAccepted(ctx context.Context, id string, userID string, at time.Time) error
 	MarkRevoked(ctx context.Context, id string) error
+	ExpireStale(ctx context.Context, now time.Time) (int64, error)
+	// Accept valida el código y crea el vínculo coach-discípulo en una
+	// transacción. Si user.ID está vacío, también crea la cuenta.
+	Accept(ctx context.Context, code string, user *domain.User) (*Invitation, *domain.CoachLink, error)
 }
 
 type inviteRepository struct{ db *gorm.DB }
 
-func NewInviteRepository(db *gorm.DB) InviteRepository { return &inviteRepository{db: db} }
+func NewInviteRepository(db *gorm.DB) InviteRepository          { return &inviteRepository{db: db} }
+func (r *inviteRepository) WithTx(tx *gorm.DB) InviteRepository { return &inviteRepository{db: tx} }
 
 func (r *inviteRepository) Create(ctx context.Context, inv *Invitation) error {
 	return r.db.WithContext(ctx).Create(inv).Error
@@ -45,20 +71,133 @@ func (r *inviteRepository) FindByCode(ctx context.Context, code string) (*Invita
 	return &inv, nil
 }
 
+// ListByCoach: las pendientes ya vencidas se informan como expired aunque el
+// barrido aún no haya pasado.
+func (r *inviteRepository) ListByCoach(ctx context.Context, coachID string, status *string, limit, offset int) ([]Invitation, int64, error) {
+	base := `
+FROM (
+  SELECT id, code, coach_id, email, name,
+         CASE WHEN status = 'pending' AND expires_at <= now() THEN 'expired' ELSE status END AS status,
+         expires_at, used_by, used_at, created_at
+  FROM invite_codes
+  WHERE coach_id = ?
+) i
+`
+	args := []any{coachID}
+	if status != nil && *status != "" {
+		base += ` WHERE i.status = ?`
+		args = append(args, *status)
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Raw(`SELECT COUNT(*) `+base, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var out []Invitation
+	if err := r.db.WithContext(ctx).
+		Raw(`SELECT i.* `+base+` ORDER BY i.created_at DESC LIMIT ? OFFSET ?`, append(args, limit, offset)...).
+		Scan(&out).Error; err != nil {
+		return nil, 0, err
+	}
+	return out, total, nil
+}
+
 func (r *inviteRepository) MarkAccepted(ctx context.Context, id string, userID string, at time.Time) error {
 	return r.db.WithContext(ctx).
 		Model(&Invitation{}).
-		Where("id = ? AND status = 'pending'", id).
+		Where("id = ? AND status = 'pending' AND used_at IS NULL", id).
 		Updates(map[string]any{
-			"status":      "accepted",
-			"accepted_by": userID,
-			"accepted_at": at,
+			"status":  InviteAccepted,
+			"used_by": userID,
+			"used_at": at,
 		}).Error
 }
 
+// MarkRevoked solo afecta pendientes; si no hay fila devuelve ErrInviteNotPending.
 func (r *inviteRepository) MarkRevoked(ctx context.Context, id string) error {
-	return r.db.WithContext(ctx).
+	res := r.db.WithContext(ctx).
 		Model(&Invitation{}).
 		Where("id = ? AND status = 'pending'", id).
-		Update("status", "revoked").Error
+		Update("status", InviteRevoked)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInviteNotPending
+	}
+	return nil
+}
+
+func (r *inviteRepository) ExpireStale(ctx context.Context, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Model(&Invitation{}).
+		Where("status = 'pending' AND expires_at <= ?", now).
+		Update("status", InviteExpired)
+	return res.RowsAffected, res.Error
+}
+
+func (r *inviteRepository) Accept(ctx context.Context, code string, user *domain.User) (*Invitation, *domain.CoachLink, error) {
+	var inv Invitation
+	var link domain.CoachLink
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// FOR UPDATE: dos aceptaciones simultáneas del mismo código se serializan
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("code = ?", code).First(&inv).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInviteInvalid
+			}
+			return err
+		}
+		now := time.Now()
+		if inv.Status != InvitePending {
+			return ErrInviteNotPending
+		}
+		if !now.Before(inv.ExpiresAt) {
+			return ErrInviteExpired
+		}
+
+		if user.ID == "" {
+			if !strings.EqualFold(strings.TrimSpace(user.Email), inv.Email) {
+				return ErrInviteEmailMismatch
+			}
+			var taken int64
+			if err := tx.Model(&domain.User{}).Where("email = ?", inv.Email).Count(&taken).Error; err != nil {
+				return err
+			}
+			if taken > 0 {
+				return ErrEmailInUse
+			}
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.First(user, "id = ?", user.ID).Error; err != nil {
+				return err
+			}
+			if !strings.EqualFold(user.Email, inv.Email) {
+				return ErrInviteEmailMismatch
+			}
+		}
+
+		if err := tx.Raw(`
+			INSERT INTO coach_links (coach_id, disciple_id, status)
+			VALUES (?, ?, 'accepted')
+			ON CONFLICT (coach_id, disciple_id)
+			DO UPDATE SET status = 'accepted', updated_at = now()
+			RETURNING *`, inv.CoachID, user.ID).Scan(&link).Error; err != nil {
+			return err
+		}
+
+		inv.Status, inv.UsedBy, inv.UsedAt = InviteAccepted, &user.ID, &now
+		return tx.Model(&Invitation{}).Where("id = ?", inv.ID).Updates(map[string]any{
+			"status":  InviteAccepted,
+			"used_by": user.ID,
+			"used_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &inv, &link, nil
 }
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// JSONB: columna jsonb cruda; se serializa tal cual en la respuesta.
+type JSONB []byte
+
+func (j JSONB) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return "{}", nil
+	}
+	return string(j), nil
+}
+
+func (j *JSONB) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSONB(v)
+	default:
+		return errors.New("jsonb: unsupported type")
+	}
+	return nil
+}
+
+func (j JSONB) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("{}"), nil
+	}
+	return j, nil
+}
+
+func (j *JSONB) UnmarshalJSON(b []byte) error {
+	*j = append((*j)[:0], b...)
+	return nil
+}
+
+type Method struct {
+	ID        string  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Key       string  `gorm:"not null;uniqueIndex" json:"key"`
+	Name      string  `gorm:"not null" json:"name"`
+	Kind      string  `gorm:"not null;default:'straight'" json:"kind"`
+	Params    JSONB   `gorm:"type:jsonb;not null" json:"params"`
+	CreatedBy *string `gorm:"type:uuid" json:"created_by,omitempty"`
+}
+
+func (Method) TableName() string { return "methods" }
+
+type MethodFilter struct {
+	Query  string
+	Kind   string
+	Limit  int
+	Offset int
+}
+
+// Datos mínimos de la prescripción para expandir su método
+type PrescriptionMethodRow struct {
+	ID        string
+	Series    int
+	Reps      string
+	RestSec   *int
+	ToFailure bool
+	MethodID  *string
+}
+
+type MethodRepository interface {
+	Search(ctx context.Context, f MethodFilter) ([]Method, int64, error)
+	Create(ctx context.Context, m *Method) error
+	Get(ctx context.Context, id string) (*Method, error)
+	Update(ctx context.Context, id string, patch map[string]any) (*Method, error)
+	Delete(ctx context.Context, id string) error
+	GetPrescription(ctx context.Context, prescriptionID string) (*PrescriptionMethodRow, error)
+}
+
+type methodRepository struct{ db *gorm.DB }
+
+func NewMethodRepository(db *gorm.DB) MethodRepository { return &methodRepository{db: db} }
+
+func (r *methodRepository) Search(ctx context.Context, f MethodFilter) ([]Method, int64, error) {
+	q := r.db.WithContext(ctx).Model(&Method{})
+	if s := strings.TrimSpace(f.Query); s != "" {
+		ilike := "%" + strings.ToLower(s) + "%"
+		q = q.Where("lower(key) LIKE ? OR lower(name) LIKE ?", ilike, ilike)
+	}
+	if s := strings.TrimSpace(f.Kind); s != "" {
+		q = q.Where("kind = ?", strings.ToLower(s))
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	if f.Offset > 0 {
+		q = q.Offset(f.Offset)
+	}
+	var items []Method
+	if err := q.Order("lower(name) ASC, id ASC").Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+func (r *methodRepository) Create(ctx context.Context, m *Method) error {
+	return r.db.WithContext(ctx).Create(m).Error
+}
+
+func (r *methodRepository) Get(ctx context.Context, id string) (*Method, error) {
+	var m Method
+	if err := r.db.WithContext(ctx).First(&m, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r *methodRepository) Update(ctx context.Context, id string, patch map[string]any) (*Method, error) {
+	if err := r.db.WithContext(ctx).Model(&Method{}).Where("id = ?", id).Updates(patch).Error; err != nil {
+		return nil, err
+	}
+	return r.Get(ctx, id)
+}
+
+// Delete: prescriptions.method_id queda en NULL (ON DELETE SET NULL)
+func (r *methodRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&Method{}, "id = ?", id).Error
+}
+
+func (r *methodRepository) GetPrescription(ctx context.Context, prescriptionID string) (*PrescriptionMethodRow, error) {
+	var row PrescriptionMethodRow
+	tx := r.db.WithContext(ctx).Raw(`
+		SELECT id, series, reps, rest_sec, to_failure, method_id
+		FROM prescriptions
+		WHERE id = ?
+	`, prescriptionID).Scan(&row)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &row, nil
+}
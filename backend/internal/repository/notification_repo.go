@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"
+)
+
+type OutboxMessage struct {
+	ID            string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Channel       string     `gorm:"not null" json:"channel"`
+	Event         string     `gorm:"not null" json:"event"`
+	Recipient     *string    `json:"recipient,omitempty"`
+	Subject       string     `gorm:"not null" json:"subject"`
+	Body          string     `gorm:"not null" json:"body"`
+	Payload       *string    `gorm:"type:jsonb" json:"payload,omitempty"`
+	Status        string     `gorm:"not null;default:pending" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;default:now()" json:"next_attempt_at"`
+	LastError     *string    `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `gorm:"not null;default:now()" json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+func (OutboxMessage) TableName() string { return "notification_outbox" }
+
+// Recipient: destinatario resuelto desde users.
+type Recipient struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type NotificationRepository interface {
+	WithTx(tx *gorm.DB) NotificationRepository
+	Enqueue(ctx context.Context, msgs []OutboxMessage) error
+	// ClaimDue toma hasta limit pendientes vencidas, suma un intento y corre
+	// next_attempt_at en lease: otra instancia no las repite mientras tanto.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error)
+	MarkSent(ctx context.Context, id string, at time.Time) error
+	// MarkFailed con next nil deja el mensaje en failed (sin más reintentos).
+	MarkFailed(ctx context.Context, id string, errMsg string, next *time.Time) error
+
+	Recipients(ctx context.Context, userIDs []string) ([]Recipient, error)
+	CoachesOf(ctx context.Context, discipleID string) ([]Recipient, error)
+}
+
+type notificationRepository struct{ db *gorm.DB }
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) WithTx(tx *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: tx}
+}
+
+func (r *notificationRepository) Enqueue(ctx context.Context, msgs []OutboxMessage) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&msgs).Error
+}
+
+func (r *notificationRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]OutboxMessage, error) {
+	var out []OutboxMessage
+	err := r.db.WithContext(ctx).Raw(`
+UPDATE notification_outbox o
+SET attempts = o.attempts + 1,
+    next_attempt_at = now() + make_interval(secs => ?)
+WHERE o.id IN (
+  SELECT id FROM notification_outbox
+  WHERE status = 'pending' AND next_attempt_at <= now()
+  ORDER BY next_attempt_at
+  LIMIT ?
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING o.*`, lease.Seconds(), limit).Scan(&out).Error
+	return out, err
+}
+
+func (r *notificationRepository) MarkSent(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&OutboxMessage{}).Where("id = ?", id).
+		Updates(map[string]any{"status": OutboxSent, "sent_at": at, "last_error": nil}).Error
+}
+
+func (r *notificationRepository) MarkFailed(ctx context.Context, id string, errMsg string, next *time.Time) error {
+	patch := map[string]any{"last_error": errMsg}
+	if next != nil {
+		patch["next_attempt_at"] = *next
+	} else {
+		patch["status"] = OutboxFailed
+	}
+	return r.db.WithContext(ctx).Model(&OutboxMessage{}).Where("id = ?", id).Updates(patch).Error
+}
+
+func (r *notificationRepository) Recipients(ctx context.Context, userIDs []string) ([]Recipient, error) {
+	var out []Recipient
+	if len(userIDs) == 0 {
+		return out, nil
+	}
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT id, name, email FROM users WHERE id IN ?`, userIDs).
+		Scan(&out).Error
+	return out, err
+}
+
+func (r *notificationRepository) CoachesOf(ctx context.Context, discipleID string) ([]Recipient, error) {
+	var out []Recipient
+	err := r.db.WithContext(ctx).Raw(`
+SELECT u.id, u.name, u.email
+FROM coach_links cl
+JOIN users u ON u.id = cl.coach_id
+WHERE cl.disciple_id = ? AND cl.status = 'accepted' AND cl.coach_id <> cl.disciple_id`, discipleID).
+		Scan(&out).Error
+	return out, err
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ProgramTree: programa completo (semanas → días → prescripciones) para
+// export/import. En import ExerciseID/MethodID ya vienen resueltos.
+type ProgramTree struct {
+	Program ProgramRow
+	Weeks   []WeekTree
+}
+
+type WeekTree struct {
+	WeekIndex int
+	Days      []DayTree
+}
+
+type DayTree struct {
+	DayIndex      int
+	Title         *string
+	Notes         *string
+	IsRest        bool
+	Prescriptions []PrescriptionTree
+}
+
+type PrescriptionTree struct {
+	ExerciseID   string
+	ExerciseName string
+	Series       int
+	Reps         string
+	RestSec      *int
+	ToFailure    bool
+	Tempo        *string
+	RIR          *int
+	RPE          *float32
+	MethodID     *string
+	MethodKey    *string
+	Notes        *string
+}
+
+func (r *programRepository) ExportTree(ctx context.Context, programID string) (*ProgramTree, error) {
+	db := r.db.WithContext(ctx)
+	var t ProgramTree
+	// ProgramRow no tiene TableName: sin Table gorm consultaría "program_rows"
+	if err := db.Table("programs").First(&t.Program, "id = ?", programID).Error; err != nil {
+		return nil, err
+	}
+
+	type dayRow struct {
+		DayID     string
+		WeekIndex int
+		DayIndex  int
+		Title     *string
+		Notes     *string
+		IsRest    bool
+	}
+	var days []dayRow
+	if err := db.Raw(`
+		SELECT d.id AS day_id, w.week_index, d.day_index, d.title, d.notes, d.is_rest
+		FROM program_weeks w
+		LEFT JOIN program_days d ON d.week_id = w.id
+		WHERE w.program_id = ?
+		ORDER BY w.week_index, d.day_index, d.id
+	`, programID).Scan(&days).Error; err != nil {
+		return nil, err
+	}
+
+	type prRow struct {
+		DayID string
+		PrescriptionTree
+	}
+	var prs []prRow
+	if err := db.Raw(`
+		SELECT pr.day_id, pr.exercise_id, e.name AS exercise_name, pr.series, pr.reps, pr.rest_sec,
+		       pr.to_failure, pr.tempo, pr.rir, pr.rpe, pr.method_id, m.key AS method_key, pr.notes
+		FROM prescriptions pr
+		JOIN program_days d  ON d.id = pr.day_id
+		JOIN program_weeks w ON w.id = d.week_id
+		JOIN exercises e     ON e.id = pr.exercise_id
+		LEFT JOIN methods m  ON m.id = pr.method_id
+		WHERE w.program_id = ?
+		ORDER BY pr.position, pr.id
+	`, programID).Scan(&prs).Error; err != nil {
+		return nil, err
+	}
+	byDay := map[string][]PrescriptionTree{}
+	for _, p := range prs {
+		byDay[p.DayID] = append(byDay[p.DayID], p.PrescriptionTree)
+	}
+
+	for _, d := range days {
+		if n := len(t.Weeks); n == 0 || t.Weeks[n-1].WeekIndex != d.WeekIndex {
+			t.Weeks = append(t.Weeks, WeekTree{WeekIndex: d.WeekIndex})
+		}
+		if d.DayID == "" {
+			continue // semana sin días
+		}
+		wk := &t.Weeks[len(t.Weeks)-1]
+		wk.Days = append(wk.Days, DayTree{
+			DayIndex:      d.DayIndex,
+			Title:         d.Title,
+			Notes:         d.Notes,
+			IsRest:        d.IsRest,
+			Prescriptions: byDay[d.DayID],
+		})
+	}
+	return &t, nil
+}
+
+// ImportTree crea programa + semanas + días + prescripciones en una transacción.
+func (r *programRepository) ImportTree(ctx context.Context, t *ProgramTree) (*ProgramRow, error) {
+	if t == nil {
+		return nil, errors.New("empty_tree")
+	}
+	out := t.Program
+	out.Tags = tagsOrEmpty(out.Tags)
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(`
+			INSERT INTO programs (owner_id, title, notes, visibility, kind, version, block_repeats, end_behavior, tags, level, goal)
+			VALUES (?, ?, ?, 'private', ?, 1, ?, ?, ?, ?, ?)
+			RETURNING `+programRowCols,
+			out.OwnerID, out.Title, out.Notes, out.Kind, out.BlockRepeats, out.EndBehavior, out.Tags, out.Level, out.Goal).
+			Scan(&out).Error; err != nil {
+			return err
+		}
+		for _, w := range t.Weeks {
+			var weekID string
+			if err := tx.Raw(`INSERT INTO program_weeks (program_id, week_index) VALUES (?, ?) RETURNING id`,
+				out.ID, w.WeekIndex).Row().Scan(&weekID); err != nil {
+				return err
+			}
+			for _, d := range w.Days {
+				var dayID string
+				if err := tx.Raw(`
+					INSERT INTO program_days (week_id, day_index, title, notes, is_rest)
+					VALUES (?, ?, ?, ?, ?)
+					RETURNING id
+				`, weekID, d.DayIndex, d.Title, d.Notes, d.IsRest).Row().Scan(&dayID); err != nil {
+					return err
+				}
+				for i, p := range d.Prescriptions {
+					if err := tx.Exec(`
+						INSERT INTO prescriptions
+						(day_id, exercise_id, series, reps, rest_sec, to_failure, tempo, rir, rpe, method_id, notes, position)
+						VALUES (?,?,?,?,?,?,?,?,?,?,?,?)
+					`, dayID, p.ExerciseID, p.Series, p.Reps, p.RestSec, p.ToFailure, p.Tempo, p.RIR, p.RPE, p.MethodID, p.Notes, i+1).Error; err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -5,20 +5,28 @@ import (
 	"errors"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/vicepalma/roma-system/backend/internal/domain"
 	"gorm.io/gorm"
 )
 
 type Program struct {
-	ID         string `gorm:"type:uuid;primaryKey"`
-	OwnerID    string `gorm:"type:uuid;not null"`
-	Title      string `gorm:"not null"`
-	Notes      *string
-	Visibility string `gorm:"not null;default:'private'"`
-	Kind       string `gorm:"not null;default:'coach_program'"`
-	Version    int    `gorm:"not null;default:1"`
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	ID              string `gorm:"type:uuid;primaryKey"`
+	OwnerID         string `gorm:"type:uuid;not null"`
+	Title           string `gorm:"not null"`
+	Notes           *string
+	Visibility      string         `gorm:"not null;default:'private'"`
+	Kind            string         `gorm:"not null;default:'coach_program'"`
+	Version         int            `gorm:"not null;default:1"`
+	BlockRepeats    int            `gorm:"not null;default:1"`
+	EndBehavior     string         `gorm:"not null;default:'loop'"`
+	Tags            pq.StringArray `gorm:"type:text[];not null;default:'{}'"`
+	Level           *string
+	Goal            *string
+	SourceProgramID *string `gorm:"type:uuid"`
+	SourceVersion   *int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 type ProgramVersion struct {
@@ -42,6 +50,7 @@ type ProgramDay struct {
 	DayIndex int     `gorm:"not null" json:"day_index"`
 	Title    *string `gorm:"type:text" json:"title,omitempty"`
 	Notes    *string `gorm:"type:text" json:"notes,omitempty"`
+	IsRest   bool    `gorm:"not null;default:false" json:"is_rest"`
 }
 
 type Prescription struct {
@@ -87,15 +96,22 @@ type ProgramFilter struct {
 }
 
 type ProgramRow struct {
-	ID         string `gorm:"type:uuid;primaryKey"`
-	OwnerID    string `gorm:"type:uuid;not null"`
-	Title      string `gorm:"not null"`
-	Notes      *string
-	Visibility string `gorm:"not null;default:private"`
-	Kind       string `gorm:"not null;default:coach_program"`
-	Version    int    `gorm:"not null;default:1"`
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
+	ID              string `gorm:"type:uuid;primaryKey"`
+	OwnerID         string `gorm:"type:uuid;not null"`
+	Title           string `gorm:"not null"`
+	Notes           *string
+	Visibility      string         `gorm:"not null;default:private"`
+	Kind            string         `gorm:"not null;default:coach_program"`
+	Version         int            `gorm:"not null;default:1"`
+	BlockRepeats    int            `gorm:"not null;default:1"`
+	EndBehavior     string         `gorm:"not null;default:loop"`
+	Tags            pq.StringArray `gorm:"type:text[];not null;default:'{}'"`
+	Level           *string
+	Goal            *string
+	SourceProgramID *string `gorm:"type:uuid"`
+	SourceVersion   *int
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 type ProgramRepository interface {
@@ -140,6 +156,14 @@ type ProgramRepository interface {
 	UpdateProgram(ctx context.Context, id string, patch map[string]any) error
 	DeleteProgram(ctx context.Context, id string) error
 	CreateNextVersionClone(ctx context.Context, programID string) (*ProgramRow, error)
+
+	// templates
+	SearchTemplates(ctx context.Context, f TemplateFilter) ([]ProgramTemplateRow, int64, error)
+	CloneTemplate(ctx context.Context, templateID, ownerID, kind string, title *string) (*ProgramRow, error)
+
+	// import/export
+	ExportTree(ctx context.Context, programID string) (*ProgramTree, error)
+	ImportTree(ctx context.Context, t *ProgramTree) (*ProgramRow, error)
 }
 
 type programRepository struct{ db *gorm.DB }
@@ -497,7 +521,9 @@ func (r *programRepository) CreateNextVersionClone(ctx context.Context, programI
 
 	// 1) programa base
 	var base ProgramRow
-	if err := tx.Raw(`SELECT id, owner_id, title, notes, visibility, version FROM programs WHERE id = ?`, programID).
+	if err := tx.Raw(`SELECT id, owner_id, title, notes, visibility, kind, version, block_repeats, end_behavior,
+	                         tags, level, goal, source_program_id, source_version
+	                  FROM programs WHERE id = ?`, programID).
 		Scan(&base).Error; err != nil {
 		tx.Rollback()
 		return nil, err
@@ -510,25 +536,51 @@ func (r *programRepository) CreateNextVersionClone(ctx context.Context, programI
 	// 2) crea nuevo programa version+1
 	var newProg ProgramRow
 	err := tx.Raw(`
-		INSERT INTO programs (owner_id, title, notes, visibility, version)
-		VALUES (?, ?, ?, ?, ?)
-		RETURNING id, owner_id, title, notes, visibility, version, created_at, updated_at
-	`, base.OwnerID, base.Title, base.Notes, base.Visibility, base.Version+1).Scan(&newProg).Error
+		INSERT INTO programs (owner_id, title, notes, visibility, kind, version, block_repeats, end_behavior,
+		                      tags, level, goal, source_program_id, source_version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING `+programRowCols+`
+	`, base.OwnerID, base.Title, base.Notes, base.Visibility, base.Kind, base.Version+1, base.BlockRepeats, base.EndBehavior,
+		tagsOrEmpty(base.Tags), base.Level, base.Goal, base.SourceProgramID, base.SourceVersion).Scan(&newProg).Error
 	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
-	// 3) mapear semanas
+	// 3) semanas, días y prescripciones
+	if err := cloneProgramContent(tx, base.ID, newProg.ID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	return &newProg, nil
+}
+
+const programRowCols = `id, owner_id, title, notes, visibility, kind, version, block_repeats, end_behavior,
+	tags, level, goal, source_program_id, source_version, created_at, updated_at`
+
+func tagsOrEmpty(t pq.StringArray) pq.StringArray {
+	if t == nil {
+		return pq.StringArray{}
+	}
+	return t
+}
+
+// cloneProgramContent copia semanas + días + prescripciones de fromID a toID
+// dentro de la transacción del llamador.
+func cloneProgramContent(tx *gorm.DB, fromID, toID string) error {
+	// semanas
 	type wkmap struct {
 		OldID, NewID string
 		WeekIndex    int
 	}
 	var wks []wkmap
-	if err := tx.Raw(`SELECT id AS old_id, week_index FROM program_weeks WHERE program_id = ? ORDER BY week_index, id`, base.ID).
+	if err := tx.Raw(`SELECT id AS old_id, week_index FROM program_weeks WHERE program_id = ? ORDER BY week_index, id`, fromID).
 		Scan(&wks).Error; err != nil {
-		tx.Rollback()
-		return nil, err
+		return err
 	}
 	for i := range wks {
 		var id string
@@ -536,27 +588,27 @@ func (r *programRepository) CreateNextVersionClone(ctx context.Context, programI
 			INSERT INTO program_weeks (program_id, week_index)
 			VALUES (?, ?)
 			RETURNING id
-		`, newProg.ID, wks[i].WeekIndex).Row().Scan(&id); err != nil {
-			tx.Rollback()
-			return nil, err
+		`, toID, wks[i].WeekIndex).Row().Scan(&id); err != nil {
+			return err
 		}
 		wks[i].NewID = id
 	}
 
-	// 4) mapear días
+	// días
 	type dymap struct {
 		OldID, NewID, OldWeekID, NewWeekID string
 		DayIndex                           int
+		Title                              *string
 		Notes                              *string
+		IsRest                             bool
 	}
 	var days []dymap
-	if err := tx.Raw(`SELECT d.id AS old_id, d.week_id AS old_week_id, d.day_index, d.notes
+	if err := tx.Raw(`SELECT d.id AS old_id, d.week_id AS old_week_id, d.day_index, d.title, d.notes, d.is_rest
 	                   FROM program_days d
 	                   JOIN program_weeks w ON w.id = d.week_id
 	                   WHERE w.program_id = ?
-	                   ORDER BY d.day_index, d.id`, base.ID).Scan(&days).Error; err != nil {
-		tx.Rollback()
-		return nil, err
+	                   ORDER BY d.day_index, d.id`, fromID).Scan(&days).Error; err != nil {
+		return err
 	}
 	// asignar week nuevo
 	wkIndexByOld := map[string]string{}
@@ -567,17 +619,16 @@ func (r *programRepository) CreateNextVersionClone(ctx context.Context, programI
 		days[i].NewWeekID = wkIndexByOld[days[i].OldWeekID]
 		var id string
 		if err := tx.Raw(`
-			INSERT INTO program_days (week_id, day_index, notes)
-			VALUES (?, ?, ?)
+			INSERT INTO program_days (week_id, day_index, title, notes, is_rest)
+			VALUES (?, ?, ?, ?, ?)
 			RETURNING id
-		`, days[i].NewWeekID, days[i].DayIndex, days[i].Notes).Row().Scan(&id); err != nil {
-			tx.Rollback()
-			return nil, err
+		`, days[i].NewWeekID, days[i].DayIndex, days[i].Title, days[i].Notes, days[i].IsRest).Row().Scan(&id); err != nil {
+			return err
 		}
 		days[i].NewID = id
 	}
 
-	// 5) clonar prescripciones
+	// prescripciones
 	dayMap := map[string]string{}
 	for _, d := range days {
 		dayMap[d.OldID] = d.NewID
@@ -603,9 +654,8 @@ func (r *programRepository) CreateNextVersionClone(ctx context.Context, programI
 	                   FROM prescriptions
 	                   WHERE day_id IN (SELECT d.id FROM program_days d
 	                                    JOIN program_weeks w ON w.id=d.week_id
-	                                    WHERE w.program_id = ?)`, base.ID).Scan(&presc).Error; err != nil {
-		tx.Rollback()
-		return nil, err
+	                                    WHERE w.program_id = ?)`, fromID).Scan(&presc).Error; err != nil {
+		return err
 	}
 	for _, p := range presc {
 		newDay := dayMap[p.DayID]
@@ -617,13 +667,8 @@ func (r *programRepository) CreateNextVersionClone(ctx context.Context, programI
 			(day_id, exercise_id, series, reps, rest_sec, to_failure, tempo, rir, rpe, method_id, notes, position)
 			VALUES (?,?,?,?,?,?,?,?,?,?,?,?)
 		`, newDay, p.ExerciseID, p.Series, p.Reps, p.RestSec, p.ToFailure, p.Tempo, p.Rir, p.Rpe, p.MethodID, p.Notes, p.Position).Error; err != nil {
-			tx.Rollback()
-			return nil, err
+			return err
 		}
 	}
-
-	if err := tx.Commit().Error; err != nil {
-		return nil, err
-	}
-	return &newProg, nil
+	return nil
 }
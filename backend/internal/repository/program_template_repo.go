@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Plantilla = programa de coach publicado (visibility=public).
+const templateWhere = `p.visibility = 'public' AND p.kind = 'coach_program'`
+
+var ErrTemplateNotFound = errors.New("template_not_found")
+
+type TemplateFilter struct {
+	Query       string
+	Tags        []string // todas deben estar presentes
+	Level       string
+	Goal        string
+	DaysPerWeek int // días de entrenamiento (no descanso) de la semana más cargada
+	Limit       int
+	Offset      int
+}
+
+type ProgramTemplateRow struct {
+	ID          string         `json:"id"`
+	OwnerID     string         `json:"owner_id"`
+	OwnerName   string         `json:"owner_name"`
+	Title       string         `json:"title"`
+	Notes       *string        `json:"notes,omitempty"`
+	Tags        pq.StringArray `gorm:"type:text[]" json:"tags"`
+	Level       *string        `json:"level,omitempty"`
+	Goal        *string        `json:"goal,omitempty"`
+	Version     int            `json:"version"`
+	Weeks       int            `json:"weeks"`
+	DaysPerWeek int            `json:"days_per_week"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+func (r *programRepository) SearchTemplates(ctx context.Context, f TemplateFilter) ([]ProgramTemplateRow, int64, error) {
+	where := []string{templateWhere}
+	args := []any{}
+	if s := strings.TrimSpace(f.Query); s != "" {
+		where = append(where, `(LOWER(p.title) LIKE ? OR LOWER(COALESCE(p.notes, '')) LIKE ?)`)
+		like := "%" + strings.ToLower(s) + "%"
+		args = append(args, like, like)
+	}
+	if len(f.Tags) > 0 {
+		where = append(where, `p.tags @> ?`)
+		args = append(args, pq.StringArray(f.Tags))
+	}
+	if s := strings.TrimSpace(f.Level); s != "" {
+		where = append(where, `p.level = ?`)
+		args = append(args, strings.ToLower(s))
+	}
+	if s := strings.TrimSpace(f.Goal); s != "" {
+		where = append(where, `LOWER(p.goal) = ?`)
+		args = append(args, strings.ToLower(s))
+	}
+	if f.DaysPerWeek > 0 {
+		where = append(where, `COALESCE(st.days_per_week, 0) = ?`)
+		args = append(args, f.DaysPerWeek)
+	}
+
+	from := `
+		FROM programs p
+		JOIN users u ON u.id = p.owner_id
+		LEFT JOIN (
+			SELECT w.program_id,
+			       COUNT(*)                    AS weeks,
+			       COALESCE(MAX(wd.trained), 0) AS days_per_week
+			FROM program_weeks w
+			LEFT JOIN (
+				SELECT week_id, COUNT(*) FILTER (WHERE NOT is_rest) AS trained
+				FROM program_days
+				GROUP BY week_id
+			) wd ON wd.week_id = w.id
+			GROUP BY w.program_id
+		) st ON st.program_id = p.id
+		WHERE ` + strings.Join(where, " AND ")
+
+	db := r.db.WithContext(ctx)
+	var total int64
+	if err := db.Raw(`SELECT COUNT(*) `+from, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	limit, offset := f.Limit, f.Offset
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	var rows []ProgramTemplateRow
+	err := db.Raw(`
+		SELECT p.id, p.owner_id, u.name AS owner_name, p.title, p.notes, p.tags, p.level, p.goal, p.version,
+		       COALESCE(st.weeks, 0) AS weeks, COALESCE(st.days_per_week, 0) AS days_per_week, p.updated_at
+		`+from+`
+		ORDER BY p.updated_at DESC, p.id
+		LIMIT ? OFFSET ?
+	`, append(args, limit, offset)...).Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
+
+// CloneTemplate copia la plantilla completa como programa privado de ownerID
+// (versión 1) y guarda la procedencia en source_program_id/source_version.
+func (r *programRepository) CloneTemplate(ctx context.Context, templateID, ownerID, kind string, title *string) (*ProgramRow, error) {
+	var out ProgramRow
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var src ProgramRow
+		if err := tx.Raw(`
+			SELECT p.id, p.title, p.notes, p.version, p.block_repeats, p.end_behavior, p.tags, p.level, p.goal
+			FROM programs p
+			WHERE p.id = ? AND `+templateWhere, templateID).Scan(&src).Error; err != nil {
+			return err
+		}
+		if src.ID == "" {
+			return ErrTemplateNotFound
+		}
+		name := src.Title
+		if title != nil && strings.TrimSpace(*title) != "" {
+			name = strings.TrimSpace(*title)
+		}
+		if err := tx.Raw(`
+			INSERT INTO programs (owner_id, title, notes, visibility, kind, version, block_repeats, end_behavior,
+			                      tags, level, goal, source_program_id, source_version)
+			VALUES (?, ?, ?, 'private', ?, 1, ?, ?, ?, ?, ?, ?, ?)
+			RETURNING `+programRowCols,
+			ownerID, name, src.Notes, kind, src.BlockRepeats, src.EndBehavior,
+			tagsOrEmpty(src.Tags), src.Level, src.Goal, src.ID, src.Version).Scan(&out).Error; err != nil {
+			return err
+		}
+		return cloneProgramContent(tx, src.ID, out.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
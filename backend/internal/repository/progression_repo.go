@@ -0,0 +1,295 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/progression"
+	"gorm.io/gorm"
+)
+
+// Sesiones cerradas que se leen por prescripción para calcular el objetivo.
+const progressionHistorySessions = 12
+
+// ProgressionRule: regla de una prescripción o, por defecto, de un método.
+type ProgressionRule struct {
+	ID             string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	PrescriptionID *string   `gorm:"type:uuid" json:"prescription_id,omitempty"`
+	MethodID       *string   `gorm:"type:uuid" json:"method_id,omitempty"`
+	Kind           string    `gorm:"not null" json:"kind"`
+	Params         JSONB     `gorm:"type:jsonb;not null" json:"params"`
+	CreatedBy      *string   `gorm:"type:uuid" json:"created_by,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (ProgressionRule) TableName() string { return "progression_rules" }
+
+// ProgressionOverride: objetivo fijado por el coach; vale hasta que se cierra
+// una sesión con series de esa prescripción.
+type ProgressionOverride struct {
+	ID             string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AssignmentID   string    `gorm:"type:uuid;not null" json:"assignment_id"`
+	PrescriptionID string    `gorm:"type:uuid;not null" json:"prescription_id"`
+	Weight         *float64  `json:"weight,omitempty"`
+	Reps           *string   `json:"reps,omitempty"`
+	RPE            *float32  `gorm:"column:rpe" json:"rpe,omitempty"`
+	Notes          *string   `json:"notes,omitempty"`
+	CreatedBy      string    `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (ProgressionOverride) TableName() string { return "progression_overrides" }
+
+type ProgressionRepository interface {
+	// scope: "prescription" | "method"
+	GetRule(ctx context.Context, scope, id string) (*ProgressionRule, error)
+	SaveRule(ctx context.Context, r *ProgressionRule) error
+	DeleteRule(ctx context.Context, scope, id string) error
+
+	SaveOverride(ctx context.Context, o *ProgressionOverride) error
+	DeleteOverride(ctx context.Context, assignmentID, prescriptionID string) error
+	PrescriptionInAssignment(ctx context.Context, assignmentID, prescriptionID string) (bool, error)
+
+	// objetivos para la próxima sesión del assignment (override > regla)
+	Targets(ctx context.Context, assignmentID string, prescriptionIDs []string) (map[string]progression.Target, error)
+}
+
+type progressionRepository struct{ db *gorm.DB }
+
+func NewProgressionRepository(db *gorm.DB) ProgressionRepository {
+	return &progressionRepository{db: db}
+}
+
+func ruleColumn(scope string) string {
+	if scope == "method" {
+		return "method_id"
+	}
+	return "prescription_id"
+}
+
+func (r *progressionRepository) GetRule(ctx context.Context, scope, id string) (*ProgressionRule, error) {
+	var rule ProgressionRule
+	if err := r.db.WithContext(ctx).First(&rule, ruleColumn(scope)+" = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// SaveRule reemplaza la regla existente del mismo destino.
+func (r *progressionRepository) SaveRule(ctx context.Context, rule *ProgressionRule) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		scope, id := "prescription", rule.PrescriptionID
+		if rule.MethodID != nil {
+			scope, id = "method", rule.MethodID
+		}
+		if err := tx.Where(ruleColumn(scope)+" = ?", *id).Delete(&ProgressionRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(rule).Error
+	})
+}
+
+func (r *progressionRepository) DeleteRule(ctx context.Context, scope, id string) error {
+	return r.db.WithContext(ctx).Where(ruleColumn(scope)+" = ?", id).Delete(&ProgressionRule{}).Error
+}
+
+func (r *progressionRepository) SaveOverride(ctx context.Context, o *ProgressionOverride) error {
+	return r.db.WithContext(ctx).Raw(`
+		INSERT INTO progression_overrides (assignment_id, prescription_id, weight, reps, rpe, notes, created_by)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (assignment_id, prescription_id) DO UPDATE
+		SET weight = EXCLUDED.weight, reps = EXCLUDED.reps, rpe = EXCLUDED.rpe, notes = EXCLUDED.notes,
+		    created_by = EXCLUDED.created_by, updated_at = now()
+		RETURNING id, assignment_id, prescription_id, weight, reps, rpe, notes, created_by, created_at, updated_at
+	`, o.AssignmentID, o.PrescriptionID, o.Weight, o.Reps, o.RPE, o.Notes, o.CreatedBy).Scan(o).Error
+}
+
+func (r *progressionRepository) DeleteOverride(ctx context.Context, assignmentID, prescriptionID string) error {
+	return r.db.WithContext(ctx).
+		Where("assignment_id = ? AND prescription_id = ?", assignmentID, prescriptionID).
+		Delete(&ProgressionOverride{}).Error
+}
+
+func (r *progressionRepository) PrescriptionInAssignment(ctx context.Context, assignmentID, prescriptionID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("prescriptions AS pr").
+		Joins("JOIN program_days d ON d.id = pr.day_id").
+		Joins("JOIN program_weeks w ON w.id = d.week_id").
+		Joins("JOIN assignments a ON a.program_id = w.program_id").
+		Where("pr.id = ? AND a.id = ?", prescriptionID, assignmentID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *progressionRepository) Targets(ctx context.Context, assignmentID string, prescriptionIDs []string) (map[string]progression.Target, error) {
+	var discipleID string
+	if err := r.db.WithContext(ctx).Table("assignments").Select("disciple_id").
+		Where("id = ?", assignmentID).Scan(&discipleID).Error; err != nil {
+		return nil, err
+	}
+	if discipleID == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return progressionTargets(ctx, r.db, discipleID, assignmentID, prescriptionIDs)
+}
+
+// progressionTargets arma el objetivo de cada prescripción con regla u
+// override vigente; las que no tienen ninguno no aparecen en el mapa.
+// Lo usa también ResolveToday.
+func progressionTargets(ctx context.Context, db *gorm.DB, discipleID, assignmentID string, ids []string) (map[string]progression.Target, error) {
+	out := map[string]progression.Target{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	db = db.WithContext(ctx)
+
+	// regla de la prescripción o, si no hay, la de su método
+	var rules []struct {
+		PrescriptionRef string
+		Series          int
+		Reps            string
+		RPE             *float32 `gorm:"column:rpe"`
+		Kind            *string
+		Params          JSONB
+	}
+	if err := db.Raw(`
+		SELECT DISTINCT ON (p.id)
+		       p.id AS prescription_ref, p.series, p.reps, p.rpe, r.kind, r.params
+		FROM prescriptions p
+		LEFT JOIN progression_rules r
+		       ON r.prescription_id = p.id OR (r.method_id IS NOT NULL AND r.method_id = p.method_id)
+		WHERE p.id IN ?
+		ORDER BY p.id, (r.prescription_id IS NOT NULL) DESC
+	`, ids).Scan(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	var overrides []ProgressionOverride
+	if err := db.Raw(`
+		SELECT o.*
+		FROM progression_overrides o
+		WHERE o.assignment_id = ? AND o.prescription_id IN ?
+		  AND NOT EXISTS (
+		        SELECT 1
+		        FROM set_logs st
+		        JOIN session_logs sl ON sl.id = st.session_id
+		        WHERE sl.assignment_id = o.assignment_id
+		          AND st.prescription_id = o.prescription_id
+		          AND sl.status = 'closed'
+		          AND sl.ended_at > o.updated_at
+		  )
+	`, assignmentID, ids).Scan(&overrides).Error; err != nil {
+		return nil, err
+	}
+	byPresc := make(map[string]ProgressionOverride, len(overrides))
+	for _, o := range overrides {
+		byPresc[o.PrescriptionID] = o
+	}
+
+	withRule := make([]string, 0, len(rules))
+	for _, r := range rules {
+		if r.Kind != nil {
+			withRule = append(withRule, r.PrescriptionRef)
+		}
+	}
+	hist, err := progressionHistory(db, discipleID, withRule)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rules {
+		var t progression.Target
+		if r.Kind != nil {
+			kind := progression.Kind(*r.Kind)
+			// una regla guardada con params inválidos no debe tumbar /me/today:
+			// se ignora (queda solo el override, si hay)
+			if params, err := progression.ParseParams(kind, r.Params); err != nil {
+				log.Printf("[Progression] regla inválida de la prescripción %s: %v", r.PrescriptionRef, err)
+			} else {
+				t = progression.Next(kind, params, progression.Prescription{Series: r.Series, Reps: r.Reps, RPE: r.RPE}, hist[r.PrescriptionRef])
+			}
+		}
+		if o, ok := byPresc[r.PrescriptionRef]; ok {
+			t = applyOverride(t, o, r.Reps)
+		}
+		if t.Source != "" {
+			out[r.PrescriptionRef] = t
+		}
+	}
+	return out, nil
+}
+
+func applyOverride(t progression.Target, o ProgressionOverride, reps string) progression.Target {
+	if t.Reps == "" {
+		t.Reps = reps
+	}
+	if o.Weight != nil {
+		t.Weight = o.Weight
+	}
+	if o.Reps != nil {
+		t.Reps = *o.Reps
+	}
+	if o.RPE != nil {
+		v := float64(*o.RPE)
+		t.RPE = &v
+	}
+	t.Notes = o.Notes
+	t.Reason = "coach_override"
+	t.Source = "override"
+	return t
+}
+
+// progressionHistory: últimas sesiones cerradas del discípulo por prescripción.
+func progressionHistory(db *gorm.DB, discipleID string, ids []string) (map[string]progression.History, error) {
+	out := map[string]progression.History{}
+	if len(ids) == 0 {
+		return out, nil
+	}
+	var rows []struct {
+		PrescriptionID string
+		SessionID      string
+		Weight         *float64
+		Reps           int
+		RPE            *float32 `gorm:"column:rpe"`
+		ToFailure      bool
+		Total          int
+	}
+	if err := db.Raw(`
+		WITH s AS (
+		  SELECT st.prescription_id, st.session_id, sl.performed_at, st.set_index,
+		         st.weight, st.reps, st.rpe, st.to_failure,
+		         DENSE_RANK() OVER (PARTITION BY st.prescription_id ORDER BY sl.performed_at DESC, sl.id DESC) AS rk
+		  FROM set_logs st
+		  JOIN session_logs sl ON sl.id = st.session_id
+		  WHERE sl.disciple_id = ? AND sl.status = 'closed' AND st.prescription_id IN ?`+workingSets("st", false)+`
+		)
+		SELECT s.prescription_id, s.session_id, s.weight, s.reps, s.rpe, s.to_failure,
+		       MAX(s.rk) OVER (PARTITION BY s.prescription_id) AS total
+		FROM s
+		ORDER BY s.prescription_id, s.performed_at, s.session_id, s.set_index
+	`, discipleID, ids).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	lastSession := map[string]string{}
+	for _, r := range rows {
+		h := out[r.PrescriptionID]
+		h.Total = r.Total
+		if lastSession[r.PrescriptionID] != r.SessionID {
+			lastSession[r.PrescriptionID] = r.SessionID
+			h.Sessions = append(h.Sessions, progression.Session{})
+		}
+		cur := &h.Sessions[len(h.Sessions)-1]
+		cur.Sets = append(cur.Sets, progression.Set{Weight: r.Weight, Reps: r.Reps, RPE: r.RPE, ToFailure: r.ToFailure})
+		out[r.PrescriptionID] = h
+	}
+	for id, h := range out {
+		if n := len(h.Sessions); n > progressionHistorySessions {
+			h.Sessions = h.Sessions[n-progressionHistorySessions:]
+			out[id] = h
+		}
+	}
+	return out, nil
+}
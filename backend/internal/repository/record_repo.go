@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/strength"
+	"gorm.io/gorm"
+)
+
+type PersonalRecord struct {
+	ID           string    `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	DiscipleID   string    `gorm:"type:uuid;not null" json:"disciple_id"`
+	ExerciseID   string    `gorm:"type:uuid;not null" json:"exercise_id"`
+	ExerciseName string    `gorm:"->;-:migration" json:"exercise_name,omitempty"`
+	SessionID    string    `gorm:"type:uuid;not null" json:"session_id"`
+	SetLogID     string    `gorm:"type:uuid;not null" json:"set_log_id"`
+	Kind         string    `gorm:"not null" json:"kind"` // weight | reps | e1rm | volume
+	Value        float64   `json:"value"`
+	Previous     *float64  `json:"previous,omitempty"`
+	Weight       *float64  `json:"weight,omitempty"`
+	Reps         *int      `json:"reps,omitempty"`
+	AchievedAt   time.Time `json:"achieved_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (PersonalRecord) TableName() string { return "personal_records" }
+
+type RecordFilter struct {
+	ExerciseID *string
+	Kind       *string
+	Limit      int
+	Offset     int
+}
+
+type RecordRepository interface {
+	// DetectForSet compara la serie (ya guardada) con el historial del
+	// discípulo en ese ejercicio y persiste los PR que encuentre.
+	DetectForSet(ctx context.Context, setID string) ([]PersonalRecord, error)
+	Feed(ctx context.Context, discipleID string, f RecordFilter) ([]PersonalRecord, int64, error)
+}
+
+type recordRepository struct{ db *gorm.DB }
+
+func NewRecordRepository(db *gorm.DB) RecordRepository { return &recordRepository{db: db} }
+
+// Fórmula con la que se comparan los e1RM al detectar PRs.
+const recordFormula = strength.Epley
+
+func (r *recordRepository) DetectForSet(ctx context.Context, setID string) ([]PersonalRecord, error) {
+	out := []PersonalRecord{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var cur struct {
+			DiscipleID string
+			ExerciseID string
+			SessionID  string
+			SetType    string
+			Weight     *float64
+			Reps       int
+			RPE        *float32 `gorm:"column:rpe"`
+		}
+		if err := tx.Raw(`
+			SELECT s.disciple_id, p.exercise_id, sl.session_id, sl.set_type, sl.weight::float AS weight, sl.reps, sl.rpe
+			FROM set_logs sl
+			JOIN session_logs s ON s.id = sl.session_id
+			JOIN prescriptions p ON p.id = sl.prescription_id
+			WHERE sl.id = ?
+		`, setID).Scan(&cur).Error; err != nil {
+			return err
+		}
+		if cur.ExerciseID == "" {
+			return gorm.ErrRecordNotFound
+		}
+		// calentamiento y drops no marcan PR ni cuentan como marca previa
+		if !domain.IsWorkingSet(cur.SetType) {
+			return nil
+		}
+		set := strength.Set{Reps: cur.Reps}
+		if cur.Weight != nil {
+			set.Weight = *cur.Weight
+		}
+		if cur.RPE != nil {
+			v := float64(*cur.RPE)
+			set.RPE = &v
+		}
+
+		// serialize por discípulo+ejercicio: dos series simultáneas no deben
+		// ver cada una la marca anterior y registrar ambos PR
+		if err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext(?))`, cur.DiscipleID+":"+cur.ExerciseID).Error; err != nil {
+			return err
+		}
+
+		var b strength.Bests
+		var agg struct {
+			N          int
+			Weight     float64
+			RepsAtLoad int
+		}
+		if err := tx.Raw(`
+			SELECT COUNT(*) AS n,
+			       COALESCE(MAX(sl.weight), 0)::float AS weight,
+			       COALESCE(MAX(sl.reps) FILTER (WHERE COALESCE(sl.weight, 0) >= ?), 0) AS reps_at_load
+			FROM set_logs sl
+			JOIN session_logs s ON s.id = sl.session_id
+			JOIN prescriptions p ON p.id = sl.prescription_id
+			WHERE s.disciple_id = ? AND p.exercise_id = ? AND sl.id <> ?`+workingSets("sl", false)+`
+		`, set.Weight, cur.DiscipleID, cur.ExerciseID, setID).Scan(&agg).Error; err != nil {
+			return err
+		}
+		b.Sets, b.Weight, b.RepsAtLoad = agg.N, agg.Weight, agg.RepsAtLoad
+		if b.Sets == 0 {
+			return nil
+		}
+
+		var prev []struct {
+			Weight float64
+			Reps   int
+			RPE    *float32 `gorm:"column:rpe"`
+		}
+		if err := tx.Raw(`
+			SELECT sl.weight::float AS weight, sl.reps, sl.rpe
+			FROM set_logs sl
+			JOIN session_logs s ON s.id = sl.session_id
+			JOIN prescriptions p ON p.id = sl.prescription_id
+			WHERE s.disciple_id = ? AND p.exercise_id = ? AND sl.id <> ? AND sl.weight > 0 AND sl.reps > 0`+workingSets("sl", false)+`
+		`, cur.DiscipleID, cur.ExerciseID, setID).Scan(&prev).Error; err != nil {
+			return err
+		}
+		for _, p := range prev {
+			var rpe *float64
+			if p.RPE != nil {
+				v := float64(*p.RPE)
+				rpe = &v
+			}
+			if e := strength.E1RM(recordFormula, p.Weight, p.Reps, rpe); e > b.E1RM {
+				b.E1RM = e
+			}
+		}
+
+		var vol struct {
+			Best    float64
+			Current float64
+		}
+		if err := tx.Raw(`
+			WITH v AS (
+			  SELECT sl.session_id, SUM(COALESCE(sl.weight, 0) * sl.reps)::float AS volume
+			  FROM set_logs sl
+			  JOIN session_logs s ON s.id = sl.session_id
+			  JOIN prescriptions p ON p.id = sl.prescription_id
+			  WHERE s.disciple_id = ? AND p.exercise_id = ?`+workingSets("sl", false)+`
+			  GROUP BY sl.session_id
+			)
+			SELECT COALESCE(MAX(volume) FILTER (WHERE session_id <> ?), 0) AS best,
+			       COALESCE(MAX(volume) FILTER (WHERE session_id = ?), 0)  AS current
+			FROM v
+		`, cur.DiscipleID, cur.ExerciseID, cur.SessionID, cur.SessionID).Scan(&vol).Error; err != nil {
+			return err
+		}
+		b.SessionVolume = vol.Best
+
+		for _, rec := range strength.DetectRecords(recordFormula, b, set, vol.Current) {
+			pr := PersonalRecord{
+				DiscipleID: cur.DiscipleID,
+				ExerciseID: cur.ExerciseID,
+				SessionID:  cur.SessionID,
+				SetLogID:   setID,
+				Kind:       string(rec.Kind),
+				Value:      rec.Value,
+				Previous:   &rec.Previous,
+				Weight:     cur.Weight,
+				Reps:       &cur.Reps,
+			}
+			q := `
+				INSERT INTO personal_records (disciple_id, exercise_id, session_id, set_log_id, kind, value, previous, weight, reps)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+			if rec.Kind == strength.RecordVolume {
+				q += `
+				ON CONFLICT (session_id, exercise_id) WHERE kind = 'volume' DO UPDATE
+				SET value = EXCLUDED.value, set_log_id = EXCLUDED.set_log_id,
+				    weight = EXCLUDED.weight, reps = EXCLUDED.reps, achieved_at = now()`
+			}
+			q += `
+				RETURNING id, previous, achieved_at, created_at`
+			if err := tx.Raw(q, pr.DiscipleID, pr.ExerciseID, pr.SessionID, pr.SetLogID, pr.Kind, pr.Value, pr.Previous, pr.Weight, pr.Reps).
+				Row().Scan(&pr.ID, &pr.Previous, &pr.AchievedAt, &pr.CreatedAt); err != nil {
+				return err
+			}
+			out = append(out, pr)
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *recordRepository) Feed(ctx context.Context, discipleID string, f RecordFilter) ([]PersonalRecord, int64, error) {
+	q := r.db.WithContext(ctx).Table("personal_records AS pr").
+		Joins("JOIN exercises e ON e.id = pr.exercise_id").
+		Where("pr.disciple_id = ?", discipleID)
+	if f.ExerciseID != nil {
+		q = q.Where("pr.exercise_id = ?", *f.ExerciseID)
+	}
+	if f.Kind != nil {
+		q = q.Where("pr.kind = ?", *f.Kind)
+	}
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if f.Limit <= 0 || f.Limit > 200 {
+		f.Limit = 50
+	}
+	rows := []PersonalRecord{}
+	err := q.Select("pr.*, e.name AS exercise_name").
+		Order("pr.achieved_at DESC, pr.id DESC").
+		Limit(f.Limit).Offset(f.Offset).
+		Scan(&rows).Error
+	return rows, total, err
+}
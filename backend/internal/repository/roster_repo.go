@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// RosterRow: un discípulo del coach con sus agregados (una sola query).
+type RosterRow struct {
+	DiscipleID      string     `json:"disciple_id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	LastSessionAt   *time.Time `json:"last_session_at,omitempty"`
+	OpenSession     bool       `json:"open_session"`
+	LastWeightKg    *float64   `json:"last_weight_kg,omitempty"`
+	LastCheckinAt   *time.Time `json:"last_checkin_at,omitempty"`
+	WeightTrendKgWk *float64   `json:"weight_trend_kg_week,omitempty"` // pendiente de los check-ins recientes
+	NewPRs          int        `gorm:"column:new_prs" json:"new_prs"`
+}
+
+type RosterFilter struct {
+	Query        *string // nombre o email
+	InactiveDays *int    // sin sesiones hace más de N días (o nunca)
+	OpenSession  *bool
+	PRDays       int // ventana para new_prs
+	TrendDays    int // ventana de check-ins para la tendencia de peso
+}
+
+// Roster agrega por discípulo (links aceptados) en una query con subconsultas
+// LATERAL; el orden y la paginación los resuelve el servicio.
+func (r *coachRepository) Roster(ctx context.Context, coachID string, f RosterFilter) ([]RosterRow, error) {
+	if f.PRDays <= 0 {
+		f.PRDays = 7
+	}
+	if f.TrendDays <= 0 {
+		f.TrendDays = 28
+	}
+	q := `
+		SELECT u.id AS disciple_id, u.name, u.email,
+		       ls.last_session_at,
+		       COALESCE(ls.open_session, false) AS open_session,
+		       lc.weight_kg::float AS last_weight_kg,
+		       lc.checked_at::timestamptz AS last_checkin_at,
+		       wt.slope AS weight_trend_kg_wk,
+		       COALESCE(pr.n, 0) AS new_prs
+		FROM coach_links cl
+		JOIN users u ON u.id = cl.disciple_id
+		LEFT JOIN LATERAL (
+		  SELECT MAX(s.performed_at) AS last_session_at,
+		         bool_or(s.status = 'open') AS open_session
+		  FROM session_logs s
+		  WHERE s.disciple_id = u.id
+		) ls ON true
+		LEFT JOIN LATERAL (
+		  SELECT c.weight_kg, c.checked_at
+		  FROM checkins c
+		  WHERE c.disciple_id = u.id AND c.weight_kg IS NOT NULL
+		  ORDER BY c.checked_at DESC, c.created_at DESC
+		  LIMIT 1
+		) lc ON true
+		LEFT JOIN LATERAL (
+		  SELECT regr_slope(c.weight_kg::float, (c.checked_at - DATE '2000-01-01')::float / 7) AS slope
+		  FROM checkins c
+		  WHERE c.disciple_id = u.id AND c.weight_kg IS NOT NULL
+		    AND c.checked_at >= CURRENT_DATE - ?::int
+		) wt ON true
+		LEFT JOIN LATERAL (
+		  SELECT COUNT(*) AS n
+		  FROM personal_records p
+		  WHERE p.disciple_id = u.id AND p.achieved_at >= now() - make_interval(days => ?)
+		) pr ON true
+		WHERE cl.coach_id = ? AND cl.status = 'accepted'`
+	args := []any{f.TrendDays, f.PRDays, coachID}
+
+	if f.Query != nil && strings.TrimSpace(*f.Query) != "" {
+		like := "%" + strings.ToLower(strings.TrimSpace(*f.Query)) + "%"
+		q += ` AND (LOWER(u.name) LIKE ? OR LOWER(u.email) LIKE ?)`
+		args = append(args, like, like)
+	}
+	if f.InactiveDays != nil {
+		q += ` AND (ls.last_session_at IS NULL OR ls.last_session_at < now() - make_interval(days => ?))`
+		args = append(args, *f.InactiveDays)
+	}
+	if f.OpenSession != nil {
+		q += ` AND COALESCE(ls.open_session, false) = ?`
+		args = append(args, *f.OpenSession)
+	}
+	q += ` ORDER BY u.name ASC, u.id ASC`
+
+	rows := []RosterRow{}
+	err := r.db.WithContext(ctx).Raw(q, args...).Scan(&rows).Error
+	return rows, err
+}
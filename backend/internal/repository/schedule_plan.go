@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/vicepalma/roma-system/backend/internal/schedule"
+	"gorm.io/gorm"
+)
+
+// loadSchedulePlan: días de todas las semanas del programa en orden del plan
+// (week_index, day_index) + block_repeats/end_behavior. Devuelve notas por day_id.
+func loadSchedulePlan(ctx context.Context, db *gorm.DB, programID string) (schedule.Plan, map[string]*string, error) {
+	plans, notes, err := loadSchedulePlans(ctx, db, []string{programID})
+	if err != nil {
+		return schedule.Plan{}, nil, err
+	}
+	return plans[programID], notes, nil
+}
+
+// loadSchedulePlans: igual que loadSchedulePlan para varios programas en dos queries.
+func loadSchedulePlans(ctx context.Context, db *gorm.DB, programIDs []string) (map[string]schedule.Plan, map[string]*string, error) {
+	plans := make(map[string]schedule.Plan, len(programIDs))
+	notes := map[string]*string{}
+	if len(programIDs) == 0 {
+		return plans, notes, nil
+	}
+
+	var cfgs []struct {
+		ID           string
+		BlockRepeats int
+		EndBehavior  string
+	}
+	if err := db.WithContext(ctx).Raw(`
+		SELECT id, block_repeats, end_behavior FROM programs WHERE id IN ?
+	`, programIDs).Scan(&cfgs).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, cfg := range cfgs {
+		end, ok := schedule.ParseEndBehavior(cfg.EndBehavior)
+		if !ok {
+			end = schedule.EndLoop
+		}
+		plans[cfg.ID] = schedule.Plan{Days: []schedule.Day{}, Repeats: cfg.BlockRepeats, End: end}
+	}
+
+	var rows []struct {
+		ProgramID     string
+		ID            string
+		WeekID        string
+		WeekIndex     int
+		DayIndex      int
+		Notes         *string
+		IsRest        bool
+		Prescriptions int
+	}
+	if err := db.WithContext(ctx).Raw(`
+		SELECT w.program_id, d.id, d.week_id, w.week_index, d.day_index, d.notes, d.is_rest, COUNT(p.id) AS prescriptions
+		FROM program_days d
+		JOIN program_weeks w ON w.id = d.week_id
+		LEFT JOIN prescriptions p ON p.day_id = d.id
+		WHERE w.program_id IN ?
+		GROUP BY w.program_id, d.id, d.week_id, w.week_index, d.day_index, d.notes, d.is_rest
+		ORDER BY w.program_id, w.week_index ASC, d.day_index ASC, d.id ASC
+	`, programIDs).Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+
+	for _, r := range rows {
+		plan := plans[r.ProgramID]
+		plan.Days = append(plan.Days, schedule.Day{
+			ID:        r.ID,
+			WeekID:    r.WeekID,
+			WeekIndex: r.WeekIndex,
+			DayIndex:  r.DayIndex,
+			Rest:      r.IsRest || r.Prescriptions == 0,
+		})
+		plans[r.ProgramID] = plan
+		notes[r.ID] = r.Notes
+	}
+	return plans, notes, nil
+}
@@ -3,12 +3,21 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/vicepalma/roma-system/backend/internal/domain"
 	"gorm.io/gorm"
 )
 
+// ErrOpenSessionExists: el discípulo ya tiene una sesión abierta (ux_session_logs_one_open).
+var ErrOpenSessionExists = errors.New("open_session_exists")
+
+// ErrInvalidParentSet: el padre de un drop no existe o es de otra sesión o
+// prescripción, o el cambio dejaría drops colgando de otra serie.
+var ErrInvalidParentSet = errors.New("invalid_parent_set")
+
 type SessionMeta struct {
 	ID           string     `json:"id"`
 	AssignmentID string     `json:"assignment_id"`
@@ -18,6 +27,9 @@ type SessionMeta struct {
 	Status       string     `json:"status"`
 	EndedAt      *time.Time `json:"ended_at,omitempty"`
 	Notes        *string    `json:"notes,omitempty"`
+
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+	AutoClosed     bool       `json:"auto_closed"`
 }
 
 type SessionRepository interface {
@@ -30,12 +42,18 @@ type SessionRepository interface {
 
 	GetSessionByID(ctx context.Context, id string) (*domain.SessionLog, error)
 	ListSessionSets(ctx context.Context, sessionID string, prescriptionID *string, limit, offset int) ([]domain.SetLog, int64, error)
+	// series con horas y rest_sec prescrito, para la línea de tiempo
+	ListTimedSets(ctx context.Context, sessionID string) ([]TimedSetRow, error)
 
 	GetSessionMeta(ctx context.Context, id string) (*SessionMeta, error)
 	UpdateSession(ctx context.Context, id string, patch map[string]any) error
 	UpdateSet(ctx context.Context, setID string, patch map[string]any) error
 	DeleteSet(ctx context.Context, setID string) error
 	GetLatestOpenByDisciple(ctx context.Context, discipleID string) (*domain.SessionLog, error)
+
+	// CloseIdle cierra las sesiones abiertas sin actividad desde `before`
+	// (ended_at = última actividad). sessionID != "" limita a esa sesión.
+	CloseIdle(ctx context.Context, before time.Time, sessionID string) (int64, error)
 }
 
 type sessionRepository struct{ db *gorm.DB }
@@ -43,7 +61,49 @@ type sessionRepository struct{ db *gorm.DB }
 func NewSessionRepository(db *gorm.DB) SessionRepository { return &sessionRepository{db: db} }
 
 func (r *sessionRepository) CreateSession(ctx context.Context, s *domain.SessionLog) error {
-	return r.db.WithContext(ctx).Create(s).Error
+	err := r.db.WithContext(ctx).Create(s).Error
+	if isOpenSessionConflict(err) {
+		return ErrOpenSessionExists
+	}
+	return err
+}
+
+func isOpenSessionConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == "ux_session_logs_one_open"
+}
+
+// isDropParentViolation: set_type=drop sin parent_set_id (ck_set_logs_drop_parent).
+func isDropParentViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23514" && pgErr.ConstraintName == "ck_set_logs_drop_parent"
+}
+
+// closeOpenSessions cierra (como auto_closed) las sesiones abiertas que
+// cumplen where; ended_at queda en la última actividad, no en "ahora".
+func closeOpenSessions(tx *gorm.DB, where string, args ...any) (int64, error) {
+	res := tx.Exec(`
+		UPDATE session_logs
+		SET status = 'closed', auto_closed = true, updated_at = now(),
+		    ended_at = GREATEST(performed_at, COALESCE(last_activity_at, performed_at))
+		WHERE status = 'open' AND `+where, args...)
+	return res.RowsAffected, res.Error
+}
+
+// touchSession registra actividad (serie o cardio) en la sesión.
+func touchSession(tx *gorm.DB, sessionID string, at time.Time) error {
+	return tx.Exec(`UPDATE session_logs SET last_activity_at = GREATEST(last_activity_at, ?) WHERE id = ?`, at, sessionID).Error
+}
+
+func (r *sessionRepository) CloseIdle(ctx context.Context, before time.Time, sessionID string) (int64, error) {
+	// inactiva desde que el servidor la vio por última vez (una sesión recién
+	// sincronizada no se cierra aunque su actividad sea de hace horas)
+	where, args := "GREATEST(created_at, last_activity_at) < ?", []any{before}
+	if sessionID != "" {
+		where += " AND id = ?"
+		args = append(args, sessionID)
+	}
+	return closeOpenSessions(r.db.WithContext(ctx), where, args...)
 }
 
 func (r *sessionRepository) GetSession(ctx context.Context, id, discipleID string) (*domain.SessionLog, error) {
@@ -68,6 +128,10 @@ func (r *sessionRepository) ListSets(ctx context.Context, sessionID string) ([]d
 			s.reps,
 			s.rpe,
 			s.to_failure,
+			s.set_type,
+			s.parent_set_id,
+			s.started_at,
+			s.completed_at,
 			p.day_id,
 			p.exercise_id,
 			COALESCE(e.name, '') AS exercise_name
@@ -80,25 +144,127 @@ func (r *sessionRepository) ListSets(ctx context.Context, sessionID string) ([]d
 	return rows, err
 }
 
+// lockSession serializa las escrituras de series de una sesión (API en vivo
+// y /sessions/sync). Es un lock por id: sirve aunque la sesión aún no exista.
+func lockSession(tx *gorm.DB, sessionID string) error {
+	return tx.Exec(`SELECT pg_advisory_xact_lock(hashtext(?))`, "session:"+sessionID).Error
+}
+
+// deferSetIndexes: correr índices choca fila a fila con la unicidad de
+// (session_id, prescription_id, set_index); se verifica al commit.
+func deferSetIndexes(tx *gorm.DB) error {
+	return tx.Exec(`SET CONSTRAINTS ux_set_logs_session_prescription_index DEFERRED`).Error
+}
+
+// shiftSets suma delta a los índices desde `from` de una prescripción en la
+// sesión, sin tocar exceptID (la serie que se está moviendo).
+func shiftSets(tx *gorm.DB, sessionID, prescriptionID string, from, delta int, exceptID string) error {
+	q := tx.Table("set_logs").Where("session_id = ? AND prescription_id = ? AND set_index >= ?", sessionID, prescriptionID, from)
+	if exceptID != "" {
+		q = q.Where("id <> ?", exceptID)
+	}
+	return q.Update("set_index", gorm.Expr("set_index + ?", delta)).Error
+}
+
+func lastSetIndex(tx *gorm.DB, sessionID, prescriptionID, exceptID string) (int, error) {
+	q := tx.Table("set_logs").Select("COALESCE(MAX(set_index), 0)").
+		Where("session_id = ? AND prescription_id = ?", sessionID, prescriptionID)
+	if exceptID != "" {
+		q = q.Where("id <> ?", exceptID)
+	}
+	var last int
+	err := q.Scan(&last).Error
+	return last, err
+}
+
+// dropRoot valida el padre de un drop (misma sesión y prescripción) y
+// devuelve la serie raíz de la cadena: un drop de un drop cuelga del mismo padre.
+func dropRoot(tx *gorm.DB, parentID, sessionID, prescriptionID string) (string, error) {
+	var p []struct {
+		SessionID      string
+		PrescriptionID string
+		ParentSetID    *string
+	}
+	if err := tx.Raw(`SELECT session_id, prescription_id, parent_set_id FROM set_logs WHERE id = ?`, parentID).Scan(&p).Error; err != nil {
+		return "", err
+	}
+	if len(p) == 0 || p[0].SessionID != sessionID || p[0].PrescriptionID != prescriptionID {
+		return "", ErrInvalidParentSet
+	}
+	if p[0].ParentSetID != nil {
+		return *p[0].ParentSetID, nil
+	}
+	return parentID, nil
+}
+
+// AddSet asigna set_index en el servidor: 0 o un índice más allá del final
+// agrega al final de la prescripción; un índice existente inserta ahí y
+// corre los siguientes. Un drop sin índice va justo después de su cadena.
 func (r *sessionRepository) AddSet(ctx context.Context, set *domain.SetLog) error {
-	return r.db.WithContext(ctx).Create(set).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockSession(tx, set.SessionID); err != nil {
+			return err
+		}
+		if set.ParentSetID != nil {
+			root, err := dropRoot(tx, *set.ParentSetID, set.SessionID, set.PrescriptionID)
+			if err != nil {
+				return err
+			}
+			set.ParentSetID = &root
+			if set.SetIndex < 1 {
+				var chainLast int
+				if err := tx.Raw(`SELECT COALESCE(MAX(set_index), 0) FROM set_logs WHERE id = ? OR parent_set_id = ?`, root, root).Scan(&chainLast).Error; err != nil {
+					return err
+				}
+				set.SetIndex = chainLast + 1
+			}
+		}
+		last, err := lastSetIndex(tx, set.SessionID, set.PrescriptionID, "")
+		if err != nil {
+			return err
+		}
+		if set.SetIndex < 1 || set.SetIndex > last {
+			set.SetIndex = last + 1
+		} else {
+			if err := deferSetIndexes(tx); err != nil {
+				return err
+			}
+			if err := shiftSets(tx, set.SessionID, set.PrescriptionID, set.SetIndex, 1, ""); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(set).Error; err != nil {
+			return err
+		}
+		at := time.Now()
+		if set.CompletedAt != nil {
+			at = *set.CompletedAt
+		}
+		return touchSession(tx, set.SessionID, at)
+	})
 }
 
 /* -------- Cardio (session) -------- */
 type CardioSegment struct {
-	ID          string  `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
-	SessionID   string  `gorm:"type:uuid;not null;index" json:"session_id"`
-	Modality    string  `gorm:"type:text;not null" json:"modality"`
-	Minutes     int     `gorm:"not null" json:"minutes"`
-	TargetHRMin *int    `json:"target_hr_min,omitempty"`
-	TargetHRMax *int    `json:"target_hr_max,omitempty"`
-	Notes       *string `json:"notes,omitempty"`
+	ID              string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SessionID       string     `gorm:"type:uuid;not null;index" json:"session_id"`
+	Modality        string     `gorm:"type:text;not null" json:"modality"`
+	Minutes         int        `gorm:"not null" json:"minutes"`
+	TargetHRMin     *int       `json:"target_hr_min,omitempty"`
+	TargetHRMax     *int       `json:"target_hr_max,omitempty"`
+	Notes           *string    `json:"notes,omitempty"`
+	ClientUpdatedAt *time.Time `json:"client_updated_at,omitempty"`
 }
 
 func (CardioSegment) TableName() string { return "cardio_segments" }
 
 func (r *sessionRepository) AddCardio(ctx context.Context, seg *CardioSegment) error {
-	return r.db.WithContext(ctx).Create(seg).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(seg).Error; err != nil {
+			return err
+		}
+		return touchSession(tx, seg.SessionID, time.Now())
+	})
 }
 func (r *sessionRepository) ListCardio(ctx context.Context, sessionID string) ([]CardioSegment, error) {
 	var rows []CardioSegment
@@ -138,22 +304,180 @@ func (r *sessionRepository) ListSessionSets(ctx context.Context, sessionID strin
 	return items, total, nil
 }
 
+// TimedSetRow: serie con sus horas y el rest_sec de su prescripción (ver internal/timing).
+type TimedSetRow struct {
+	SessionID      string
+	SetID          string
+	PrescriptionID string
+	ExerciseID     string
+	ExerciseName   string
+	SetIndex       int
+	SetType        string
+	StartedAt      *time.Time
+	CompletedAt    *time.Time
+	RestSec        *int
+}
+
+// timedSetsSQL: compartido con historyRepository.TimedSets; ordena por
+// sesión y set_index (timing.Timeline reordena por hora).
+const timedSetsSQL = `
+	SELECT sl.session_id, sl.id AS set_id, sl.prescription_id, p.exercise_id,
+	       COALESCE(e.name, '') AS exercise_name, sl.set_index, sl.set_type,
+	       sl.started_at, sl.completed_at, p.rest_sec
+	FROM set_logs sl
+	JOIN session_logs s ON s.id = sl.session_id
+	JOIN prescriptions p ON p.id = sl.prescription_id
+	LEFT JOIN exercises e ON e.id = p.exercise_id
+	WHERE %s
+	ORDER BY s.performed_at ASC, sl.session_id, sl.set_index ASC, sl.id`
+
+func (r *sessionRepository) ListTimedSets(ctx context.Context, sessionID string) ([]TimedSetRow, error) {
+	rows := []TimedSetRow{}
+	err := r.db.WithContext(ctx).Raw(fmt.Sprintf(timedSetsSQL, "sl.session_id = ?"), sessionID).Scan(&rows).Error
+	return rows, err
+}
+
+type setPosition struct {
+	SessionID      string
+	PrescriptionID string
+	SetIndex       int
+	ParentSetID    *string
+}
+
+// lockSetPosition bloquea la sesión de la serie y relee su posición (una
+// inserción concurrente pudo correrla). nil si la serie no existe.
+func lockSetPosition(tx *gorm.DB, setID string) (*setPosition, error) {
+	var sessionID string
+	if err := tx.Raw(`SELECT session_id FROM set_logs WHERE id = ?`, setID).Scan(&sessionID).Error; err != nil || sessionID == "" {
+		return nil, err
+	}
+	if err := lockSession(tx, sessionID); err != nil {
+		return nil, err
+	}
+	var pos []setPosition
+	if err := tx.Raw(`SELECT session_id, prescription_id, set_index, parent_set_id FROM set_logs WHERE id = ?`, setID).Scan(&pos).Error; err != nil || len(pos) == 0 {
+		return nil, err
+	}
+	return &pos[0], nil
+}
+
+// checkDropChain valida el padre con que queda la serie (patch
+// parent_set_id: string o nil) y que sus propios drops no queden colgando de
+// un drop o de otra prescripción.
+func checkDropChain(tx *gorm.DB, setID string, cur *setPosition, presc string, patch map[string]any) error {
+	parent := ""
+	if cur.ParentSetID != nil {
+		parent = *cur.ParentSetID
+	}
+	if v, ok := patch["parent_set_id"]; ok {
+		parent, _ = v.(string)
+	}
+	if parent != "" {
+		root, err := dropRoot(tx, parent, cur.SessionID, presc)
+		if err != nil {
+			return err
+		}
+		if root == setID {
+			return ErrInvalidParentSet
+		}
+		patch["parent_set_id"] = root
+	}
+	if parent == "" && presc == cur.PrescriptionID {
+		return nil
+	}
+	var drops int64
+	if err := tx.Table("set_logs").Where("parent_set_id = ?", setID).Count(&drops).Error; err != nil {
+		return err
+	}
+	if drops > 0 {
+		return ErrInvalidParentSet
+	}
+	return nil
+}
+
+// UpdateSet: cambiar set_index o prescription_id mueve la serie (cierra el
+// hueco que deja e inserta en la nueva posición, acotada al final).
 func (r *sessionRepository) UpdateSet(ctx context.Context, setID string, patch map[string]any) error {
-	return r.db.WithContext(ctx).Table("set_logs").Where("id = ?", setID).Updates(patch).Error
+	_, moveIndex := patch["set_index"]
+	_, movePresc := patch["prescription_id"]
+	_, reparent := patch["parent_set_id"]
+	if !moveIndex && !movePresc && !reparent {
+		err := r.db.WithContext(ctx).Table("set_logs").Where("id = ?", setID).Updates(patch).Error
+		if isDropParentViolation(err) {
+			return ErrInvalidParentSet
+		}
+		return err
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cur, err := lockSetPosition(tx, setID)
+		if err != nil || cur == nil {
+			return err
+		}
+		presc, _ := patch["prescription_id"].(string)
+		if presc == "" {
+			presc = cur.PrescriptionID
+		}
+		if err := checkDropChain(tx, setID, cur, presc, patch); err != nil {
+			return err
+		}
+		if !moveIndex && !movePresc {
+			return tx.Table("set_logs").Where("id = ?", setID).Updates(patch).Error
+		}
+		if err := deferSetIndexes(tx); err != nil {
+			return err
+		}
+		if err := shiftSets(tx, cur.SessionID, cur.PrescriptionID, cur.SetIndex+1, -1, setID); err != nil {
+			return err
+		}
+		last, err := lastSetIndex(tx, cur.SessionID, presc, setID)
+		if err != nil {
+			return err
+		}
+		target := last + 1
+		if idx, ok := patch["set_index"].(int); ok && idx >= 1 && idx <= last {
+			target = idx
+		} else if !ok && presc == cur.PrescriptionID && cur.SetIndex <= last {
+			target = cur.SetIndex
+		}
+		if err := shiftSets(tx, cur.SessionID, presc, target, 1, setID); err != nil {
+			return err
+		}
+		patch["set_index"] = target
+		return tx.Table("set_logs").Where("id = ?", setID).Updates(patch).Error
+	})
 }
 
+// DeleteSet borra la serie (y sus drops, ON DELETE CASCADE) y renumera la
+// prescripción sin huecos.
 func (r *sessionRepository) DeleteSet(ctx context.Context, setID string) error {
-	return r.db.WithContext(ctx).Exec(`DELETE FROM set_logs WHERE id = ?`, setID).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cur, err := lockSetPosition(tx, setID)
+		if err != nil || cur == nil {
+			return err
+		}
+		if err := tx.Exec(`DELETE FROM set_logs WHERE id = ?`, setID).Error; err != nil {
+			return err
+		}
+		if err := deferSetIndexes(tx); err != nil {
+			return err
+		}
+		return renumberSets(tx, cur.SessionID)
+	})
 }
 
 func (r *sessionRepository) UpdateSession(ctx context.Context, id string, patch map[string]any) error {
-	return r.db.WithContext(ctx).Table("session_logs").Where("id = ?", id).Updates(patch).Error
+	err := r.db.WithContext(ctx).Table("session_logs").Where("id = ?", id).Updates(patch).Error
+	if isOpenSessionConflict(err) {
+		return ErrOpenSessionExists
+	}
+	return err
 }
 
 func (r *sessionRepository) GetSessionMeta(ctx context.Context, id string) (*SessionMeta, error) {
 	var out SessionMeta
 	err := r.db.WithContext(ctx).
-		Raw(`SELECT id, assignment_id, disciple_id, day_id, performed_at, status, ended_at, notes
+		Raw(`SELECT id, assignment_id, disciple_id, day_id, performed_at, status, ended_at, notes,
+		            last_activity_at, auto_closed
 		     FROM session_logs WHERE id = ?`, id).
 		Scan(&out).Error
 	if err != nil {
@@ -168,7 +492,8 @@ func (r *sessionRepository) GetSessionMeta(ctx context.Context, id string) (*Ses
 func (r *sessionRepository) GetLatestOpenByDisciple(ctx context.Context, discipleID string) (*domain.SessionLog, error) {
 	var s domain.SessionLog
 	q := `
-	SELECT id, assignment_id, day_id, disciple_id, performed_at, notes, created_at, updated_at, status, ended_at
+	SELECT id, assignment_id, day_id, disciple_id, performed_at, notes, created_at, updated_at, status, ended_at,
+	       client_updated_at, last_activity_at, auto_closed
 	FROM session_logs
 	WHERE disciple_id = ?
 	  AND status = 'open'
@@ -0,0 +1,430 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/sessionsync"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSyncKeyTaken: otra petición ya aplicó un lote con esa Idempotency-Key.
+var ErrSyncKeyTaken = errors.New("idempotency_key_taken")
+
+// SyncRequest: respuesta guardada de un lote aplicado, por Idempotency-Key.
+type SyncRequest struct {
+	DiscipleID  string    `gorm:"type:uuid;primaryKey"`
+	IdemKey     string    `gorm:"primaryKey"`
+	RequestHash string    `gorm:"not null"`
+	SessionID   string    `gorm:"type:uuid;not null"`
+	Response    JSONB     `gorm:"type:jsonb;not null"`
+	CreatedAt   time.Time `gorm:"not null;default:now()"`
+}
+
+func (SyncRequest) TableName() string { return "session_sync_requests" }
+
+type syncTombstone struct {
+	ID        string `gorm:"type:uuid;primaryKey"`
+	SessionID string `gorm:"type:uuid"`
+	Kind      string
+	DeletedAt time.Time
+}
+
+func (syncTombstone) TableName() string { return "session_sync_tombstones" }
+
+type SessionSyncRepository interface {
+	// Apply serializa los lotes de una misma sesión, carga su estado, deja que
+	// merge decida y escribe el plan en una sola transacción. Si merge falla
+	// no se escribe nada. req (si no es nil) se guarda después de merge, que
+	// puede completar su Response; si la llave ya existe devuelve ErrSyncKeyTaken.
+	Apply(ctx context.Context, discipleID string, b *sessionsync.Batch,
+		merge func(sessionsync.State) (*sessionsync.Plan, error), req *SyncRequest) error
+	FindRequest(ctx context.Context, discipleID, key string) (*SyncRequest, error)
+	SaveResponse(ctx context.Context, discipleID, key string, response []byte) error
+	PurgeRequests(ctx context.Context, before time.Time) (int64, error)
+}
+
+type sessionSyncRepository struct{ db *gorm.DB }
+
+func NewSessionSyncRepository(db *gorm.DB) SessionSyncRepository {
+	return &sessionSyncRepository{db: db}
+}
+
+func (r *sessionSyncRepository) Apply(ctx context.Context, discipleID string, b *sessionsync.Batch,
+	merge func(sessionsync.State) (*sessionsync.Plan, error), req *SyncRequest) error {
+	sessionID := b.Session.ID
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockSession(tx, sessionID); err != nil {
+			return err
+		}
+		st, err := r.loadState(tx, discipleID, b)
+		if err != nil {
+			return err
+		}
+		plan, err := merge(st)
+		if err != nil {
+			return err
+		}
+		activity := planActivity(plan)
+		autoClosed, err := keepOneOpen(tx, discipleID, plan, activity)
+		if err != nil {
+			return err
+		}
+		if err := applyPlan(tx, discipleID, sessionID, plan); err != nil {
+			if isOpenSessionConflict(err) {
+				return ErrOpenSessionExists
+			}
+			return err
+		}
+		if autoClosed {
+			if err := tx.Table("session_logs").Where("id = ?", sessionID).Update("auto_closed", true).Error; err != nil {
+				return err
+			}
+		}
+		if activity != nil {
+			if err := touchSession(tx, sessionID, *activity); err != nil {
+				return err
+			}
+		}
+		if req == nil {
+			return nil
+		}
+		req.SessionID = sessionID
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(req)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrSyncKeyTaken
+		}
+		return nil
+	})
+}
+
+// planActivity: reloj de la última serie o cardio escrita por el lote.
+func planActivity(p *sessionsync.Plan) *time.Time {
+	var last *time.Time
+	see := func(t time.Time) {
+		if last == nil || t.After(*last) {
+			last = &t
+		}
+	}
+	for _, x := range append(p.InsertSets, p.UpdateSets...) {
+		if x.CompletedAt != nil {
+			see(*x.CompletedAt)
+		} else {
+			see(x.UpdatedAt)
+		}
+	}
+	for _, x := range append(p.InsertCardio, p.UpdateCardio...) {
+		see(x.UpdatedAt)
+	}
+	return last
+}
+
+// keepOneOpen: una sesión nueva y abierta no convive con otra abierta del
+// discípulo. Queda abierta la que empezó después; la otra se cierra en su
+// última actividad. Devuelve true si la que se cierra es la del lote.
+func keepOneOpen(tx *gorm.DB, discipleID string, p *sessionsync.Plan, activity *time.Time) (bool, error) {
+	if !p.CreateSession || p.Session.Status != sessionsync.StatusOpen {
+		return false, nil
+	}
+	var newer int64
+	if err := tx.Table("session_logs").
+		Where("disciple_id = ? AND status = 'open' AND performed_at > ?", discipleID, p.Session.StartedAt).
+		Count(&newer).Error; err != nil {
+		return false, err
+	}
+	if newer == 0 {
+		_, err := closeOpenSessions(tx, "disciple_id = ?", discipleID)
+		return false, err
+	}
+	end := p.Session.StartedAt
+	if activity != nil && activity.After(end) {
+		end = *activity
+	}
+	p.Session.Status, p.Session.EndedAt = sessionsync.StatusClosed, &end
+	return true, nil
+}
+
+func (r *sessionSyncRepository) loadState(tx *gorm.DB, discipleID string, b *sessionsync.Batch) (sessionsync.State, error) {
+	st := sessionsync.State{
+		Prescriptions: map[string]bool{},
+		Sets:          map[string]sessionsync.Set{},
+		Cardio:        map[string]sessionsync.Cardio{},
+		ForeignIDs:    map[string]bool{},
+		Tombstones:    map[string]time.Time{},
+	}
+	sessionID := b.Session.ID
+
+	var sess []struct {
+		DiscipleID   string
+		AssignmentID string
+		DayID        string
+		PerformedAt  time.Time
+		Notes        *string
+		Status       string
+		EndedAt      *time.Time
+		UpdatedAt    time.Time
+	}
+	if err := tx.Raw(`
+		SELECT disciple_id, assignment_id, day_id, performed_at, notes, status, ended_at,
+		       COALESCE(client_updated_at, updated_at) AS updated_at
+		FROM session_logs WHERE id = ?
+	`, sessionID).Scan(&sess).Error; err != nil {
+		return st, err
+	}
+	if len(sess) > 0 {
+		s := sess[0]
+		if s.DiscipleID != discipleID {
+			st.Foreign = true
+			return st, nil
+		}
+		st.Session = &sessionsync.Session{
+			ID: sessionID, AssignmentID: s.AssignmentID, DayID: s.DayID, StartedAt: s.PerformedAt,
+			Notes: s.Notes, Status: s.Status, EndedAt: s.EndedAt, UpdatedAt: s.UpdatedAt,
+		}
+	} else {
+		var n int64
+		if err := tx.Table("assignments").
+			Where("id = ? AND disciple_id = ? AND is_active = true", b.Session.AssignmentID, discipleID).
+			Count(&n).Error; err != nil {
+			return st, err
+		}
+		st.AssignmentActive = n > 0
+		if err := tx.Table("assignments AS a").
+			Joins("JOIN program_weeks w ON w.program_id = a.program_id").
+			Joins("JOIN program_days d ON d.week_id = w.id").
+			Where("a.id = ? AND d.id = ?", b.Session.AssignmentID, b.Session.DayID).
+			Count(&n).Error; err != nil {
+			return st, err
+		}
+		st.DayInAssignment = n > 0
+	}
+
+	var presc []string
+	if err := tx.Table("prescriptions").Where("day_id = ?", b.Session.DayID).Pluck("id", &presc).Error; err != nil {
+		return st, err
+	}
+	for _, id := range presc {
+		st.Prescriptions[id] = true
+	}
+
+	setIDs := make([]string, 0, len(b.Sets))
+	for _, x := range b.Sets {
+		setIDs = append(setIDs, x.ID)
+	}
+	cardioIDs := make([]string, 0, len(b.Cardio))
+	for _, x := range b.Cardio {
+		cardioIDs = append(cardioIDs, x.ID)
+	}
+
+	if len(setIDs) > 0 {
+		var rows []domain.SetLog
+		if err := tx.Where("id IN ?", setIDs).Find(&rows).Error; err != nil {
+			return st, err
+		}
+		for _, x := range rows {
+			if x.SessionID != sessionID {
+				st.ForeignIDs[x.ID] = true
+				continue
+			}
+			st.Sets[x.ID] = sessionsync.Set{
+				ID: x.ID, PrescriptionID: x.PrescriptionID, SetIndex: x.SetIndex, Weight: x.Weight,
+				Reps: x.Reps, RPE: x.RPE, ToFailure: x.ToFailure, SetType: x.SetType, ParentSetID: x.ParentSetID,
+				UpdatedAt: deref(x.ClientUpdatedAt),
+			}
+		}
+	}
+	if len(cardioIDs) > 0 {
+		var rows []CardioSegment
+		if err := tx.Where("id IN ?", cardioIDs).Find(&rows).Error; err != nil {
+			return st, err
+		}
+		for _, x := range rows {
+			if x.SessionID != sessionID {
+				st.ForeignIDs[x.ID] = true
+				continue
+			}
+			st.Cardio[x.ID] = sessionsync.Cardio{
+				ID: x.ID, Modality: x.Modality, Minutes: x.Minutes, HRMin: x.TargetHRMin,
+				HRMax: x.TargetHRMax, Notes: x.Notes, UpdatedAt: deref(x.ClientUpdatedAt),
+			}
+		}
+	}
+	if ids := append(setIDs, cardioIDs...); len(ids) > 0 {
+		var rows []syncTombstone
+		if err := tx.Where("id IN ?", ids).Find(&rows).Error; err != nil {
+			return st, err
+		}
+		for _, x := range rows {
+			if x.SessionID != sessionID {
+				st.ForeignIDs[x.ID] = true
+				continue
+			}
+			st.Tombstones[x.ID] = x.DeletedAt
+		}
+	}
+	return st, nil
+}
+
+// deref: una fila escrita por la API en vivo no tiene reloj de dispositivo;
+// cualquier edición sincronizada le gana.
+func deref(t *time.Time) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return *t
+}
+
+func applyPlan(tx *gorm.DB, discipleID, sessionID string, p *sessionsync.Plan) error {
+	// los set_index del dispositivo pueden chocar con los del servidor: se
+	// escriben tal cual y se renumeran al final (renumberSets)
+	if err := deferSetIndexes(tx); err != nil {
+		return err
+	}
+	if s := p.Session; s != nil {
+		updatedAt := s.UpdatedAt
+		if p.CreateSession {
+			row := &domain.SessionLog{
+				ID: s.ID, AssignmentID: s.AssignmentID, DayID: s.DayID, DiscipleID: discipleID,
+				PerformedAt: s.StartedAt, Notes: s.Notes, Status: s.Status, EndedAt: s.EndedAt,
+				ClientUpdatedAt: &updatedAt,
+			}
+			if err := tx.Create(row).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Table("session_logs").Where("id = ?", sessionID).Updates(map[string]any{
+			"performed_at":      s.StartedAt,
+			"notes":             s.Notes,
+			"status":            s.Status,
+			"ended_at":          s.EndedAt,
+			"client_updated_at": updatedAt,
+			"updated_at":        time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(p.InsertSets) > 0 {
+		rows := make([]domain.SetLog, 0, len(p.InsertSets))
+		for _, x := range p.InsertSets {
+			rows = append(rows, domain.SetLog{
+				ID: x.ID, SessionID: sessionID, PrescriptionID: x.PrescriptionID, SetIndex: x.SetIndex,
+				Weight: x.Weight, Reps: x.Reps, RPE: x.RPE, ToFailure: x.ToFailure,
+				SetType: x.SetType, ParentSetID: x.ParentSetID,
+				StartedAt: x.StartedAt, CompletedAt: x.CompletedAt, ClientUpdatedAt: &x.UpdatedAt,
+			})
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return err
+		}
+	}
+	for _, x := range p.UpdateSets {
+		if err := tx.Table("set_logs").Where("id = ? AND session_id = ?", x.ID, sessionID).Updates(map[string]any{
+			"prescription_id":   x.PrescriptionID,
+			"set_index":         x.SetIndex,
+			"weight":            x.Weight,
+			"reps":              x.Reps,
+			"rpe":               x.RPE,
+			"to_failure":        x.ToFailure,
+			"set_type":          x.SetType,
+			"parent_set_id":     x.ParentSetID,
+			"started_at":        x.StartedAt,
+			"completed_at":      x.CompletedAt,
+			"client_updated_at": x.UpdatedAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(p.InsertCardio) > 0 {
+		rows := make([]CardioSegment, 0, len(p.InsertCardio))
+		for _, x := range p.InsertCardio {
+			rows = append(rows, CardioSegment{
+				ID: x.ID, SessionID: sessionID, Modality: x.Modality, Minutes: x.Minutes,
+				TargetHRMin: x.HRMin, TargetHRMax: x.HRMax, Notes: x.Notes, ClientUpdatedAt: &x.UpdatedAt,
+			})
+		}
+		if err := tx.Create(&rows).Error; err != nil {
+			return err
+		}
+	}
+	for _, x := range p.UpdateCardio {
+		if err := tx.Table("cardio_segments").Where("id = ? AND session_id = ?", x.ID, sessionID).Updates(map[string]any{
+			"modality":          x.Modality,
+			"minutes":           x.Minutes,
+			"target_hr_min":     x.HRMin,
+			"target_hr_max":     x.HRMax,
+			"notes":             x.Notes,
+			"client_updated_at": x.UpdatedAt,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	var tombs []syncTombstone
+	var delSets, delCardio []string
+	for _, x := range p.DeleteSets {
+		delSets = append(delSets, x.ID)
+		tombs = append(tombs, syncTombstone{ID: x.ID, SessionID: sessionID, Kind: "set", DeletedAt: x.UpdatedAt})
+	}
+	for _, x := range p.DeleteCardio {
+		delCardio = append(delCardio, x.ID)
+		tombs = append(tombs, syncTombstone{ID: x.ID, SessionID: sessionID, Kind: "cardio", DeletedAt: x.UpdatedAt})
+	}
+	if len(delSets) > 0 {
+		if err := tx.Exec(`DELETE FROM set_logs WHERE id IN ? AND session_id = ?`, delSets, sessionID).Error; err != nil {
+			return err
+		}
+	}
+	if len(delCardio) > 0 {
+		if err := tx.Exec(`DELETE FROM cardio_segments WHERE id IN ? AND session_id = ?`, delCardio, sessionID).Error; err != nil {
+			return err
+		}
+	}
+	if len(tombs) > 0 {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tombs).Error; err != nil {
+			return err
+		}
+	}
+	if len(p.InsertSets)+len(p.UpdateSets)+len(p.DeleteSets) > 0 {
+		return renumberSets(tx, sessionID)
+	}
+	return nil
+}
+
+// renumberSets deja 1..n sin huecos por prescripción, respetando el orden
+// actual (a igual índice, el id desempata).
+func renumberSets(tx *gorm.DB, sessionID string) error {
+	return tx.Exec(`
+		UPDATE set_logs s SET set_index = r.rn
+		FROM (
+			SELECT id, row_number() OVER (PARTITION BY prescription_id ORDER BY set_index, id) AS rn
+			FROM set_logs WHERE session_id = ?
+		) r
+		WHERE s.id = r.id AND s.set_index <> r.rn
+	`, sessionID).Error
+}
+
+func (r *sessionSyncRepository) FindRequest(ctx context.Context, discipleID, key string) (*SyncRequest, error) {
+	var req SyncRequest
+	if err := r.db.WithContext(ctx).First(&req, "disciple_id = ? AND idem_key = ?", discipleID, key).Error; err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (r *sessionSyncRepository) SaveResponse(ctx context.Context, discipleID, key string, response []byte) error {
+	return r.db.WithContext(ctx).Model(&SyncRequest{}).
+		Where("disciple_id = ? AND idem_key = ?", discipleID, key).
+		Update("response", JSONB(response)).Error
+}
+
+func (r *sessionSyncRepository) PurgeRequests(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&SyncRequest{})
+	return res.RowsAffected, res.Error
+}
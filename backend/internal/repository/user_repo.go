@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/vicepalma/roma-system/backend/internal/domain"
 	"gorm.io/gorm"
@@ -11,6 +12,11 @@ type UserRepository interface {
 	Create(ctx context.Context, u *domain.User) error
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
 	FindByID(ctx context.Context, id string) (*domain.User, error)
+	UpdatePassword(ctx context.Context, id, hash string) error
+	// ReplacePassword cambia el hash solo si sigue siendo oldHash; false si otro
+	// cambio llegó antes (un token de reset no se usa dos veces).
+	ReplacePassword(ctx context.Context, id, oldHash, newHash string) (bool, error)
+	MarkEmailVerified(ctx context.Context, id string, at time.Time) error
 }
 
 type userRepository struct{ db *gorm.DB }
@@ -36,3 +42,21 @@ func (r *userRepository) FindByID(ctx context.Context, id string) (*domain.User,
 	}
 	return &u, nil
 }
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id, hash string) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).
+		Updates(map[string]any{"password_hash": hash, "updated_at": time.Now()}).Error
+}
+
+func (r *userRepository) ReplacePassword(ctx context.Context, id, oldHash, newHash string) (bool, error) {
+	tx := r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ? AND password_hash = ?", id, oldHash).
+		Updates(map[string]any{"password_hash": newHash, "updated_at": time.Now()})
+	return tx.RowsAffected > 0, tx.Error
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ? AND email_verified_at IS NULL", id).
+		Update("email_verified_at", at).Error
+}
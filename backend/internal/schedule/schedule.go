@@ -0,0 +1,166 @@
+// Package schedule resuelve qué día de programa toca para un assignment.
+// No toca la DB: recibe los días del programa ya ordenados y devuelve posiciones.
+package schedule
+
+import (
+	"strings"
+	"time"
+)
+
+type Mode string
+
+const (
+	// ModeCalendar: el día depende solo de la fecha (start_date + offset).
+	ModeCalendar Mode = "calendar"
+	// ModeNextUndone: el día es el siguiente del plan que aún no se completó.
+	ModeNextUndone Mode = "next_undone"
+)
+
+// ParseMode normaliza el modo; ok=false si no es válido.
+func ParseMode(s string) (Mode, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "calendar":
+		return ModeCalendar, true
+	case "next_undone", "next":
+		return ModeNextUndone, true
+	}
+	return "", false
+}
+
+// EndBehavior: qué pasa cuando se terminan las pasadas del bloque.
+type EndBehavior string
+
+const (
+	EndStop EndBehavior = "stop" // no hay más días
+	EndLoop EndBehavior = "loop" // vuelve a la semana 1
+	EndHold EndBehavior = "hold" // repite la última semana
+)
+
+func ParseEndBehavior(s string) (EndBehavior, bool) {
+	switch EndBehavior(strings.ToLower(strings.TrimSpace(s))) {
+	case EndStop:
+		return EndStop, true
+	case EndLoop:
+		return EndLoop, true
+	case EndHold:
+		return EndHold, true
+	}
+	return "", false
+}
+
+// Day es un día del programa en el orden del plan (week_index, day_index).
+// Rest=true para días de descanso explícitos o sin prescripciones.
+type Day struct {
+	ID        string
+	WeekID    string
+	WeekIndex int
+	DayIndex  int
+	Rest      bool
+}
+
+// Plan: días de todas las semanas en orden + configuración del programa.
+type Plan struct {
+	Days    []Day
+	Repeats int // pasadas completas del bloque antes de aplicar End (min 1)
+	End     EndBehavior
+}
+
+// Slot es la posición resuelta de una fecha dentro del plan.
+type Slot struct {
+	Day   Day
+	Cycle int  // pasada del bloque (0-based)
+	Hold  bool // true si cae en la repetición de la última semana
+}
+
+func (p Plan) repeats() int {
+	if p.Repeats < 1 {
+		return 1
+	}
+	return p.Repeats
+}
+
+func (p Plan) lastWeek() []Day {
+	if len(p.Days) == 0 {
+		return nil
+	}
+	last := p.Days[len(p.Days)-1].WeekIndex
+	i := len(p.Days)
+	for i > 0 && p.Days[i-1].WeekIndex == last {
+		i--
+	}
+	return p.Days[i:]
+}
+
+// At devuelve el slot para el día `offset` desde el inicio (0 = start_date),
+// un día de programa por día de calendario.
+func (p Plan) At(offset int) (Slot, bool) {
+	n := len(p.Days)
+	if n == 0 || offset < 0 {
+		return Slot{}, false
+	}
+	total := n * p.repeats()
+	if offset < total {
+		return Slot{Day: p.Days[offset%n], Cycle: offset / n}, true
+	}
+	switch p.End {
+	case EndStop:
+		return Slot{}, false
+	case EndHold:
+		week := p.lastWeek()
+		return Slot{Day: week[(offset-total)%len(week)], Cycle: p.repeats() - 1, Hold: true}, true
+	default:
+		return Slot{Day: p.Days[offset%n], Cycle: offset / n}, true
+	}
+}
+
+// DaysBetween cuenta días de calendario entre dos fechas (ignora la hora).
+func DaysBetween(from, to time.Time) int {
+	f := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	t := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(t.Sub(f).Hours() / 24)
+}
+
+// OnDate devuelve el slot que corresponde a `date` para un plan iniciado en `start`.
+func OnDate(p Plan, start, date time.Time) (Slot, bool) {
+	return p.At(DaysBetween(start, date))
+}
+
+// NextUndone devuelve el primer día (no rest) con menos completados que el
+// resto de la pasada actual. done = sesiones cerradas por day_id.
+func NextUndone(p Plan, done map[string]int) (Day, bool) {
+	cycle, ok := minDone(p.Days, done)
+	if !ok {
+		return Day{}, false
+	}
+	days := p.Days
+	if cycle >= p.repeats() {
+		switch p.End {
+		case EndStop:
+			return Day{}, false
+		case EndHold:
+			days = p.lastWeek()
+			if cycle, ok = minDone(days, done); !ok {
+				return Day{}, false
+			}
+		}
+	}
+	for _, d := range days {
+		if !d.Rest && done[d.ID] == cycle {
+			return d, true
+		}
+	}
+	return Day{}, false
+}
+
+func minDone(days []Day, done map[string]int) (int, bool) {
+	cycle := -1
+	for _, d := range days {
+		if d.Rest {
+			continue
+		}
+		if n := done[d.ID]; cycle < 0 || n < cycle {
+			cycle = n
+		}
+	}
+	return cycle, cycle >= 0
+}
@@ -0,0 +1,71 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func testPlan(end EndBehavior, repeats int) Plan {
+	return Plan{
+		Days: []Day{
+			{ID: "w1d1", WeekIndex: 1, DayIndex: 1},
+			{ID: "w1d2", WeekIndex: 1, DayIndex: 2, Rest: true},
+			{ID: "w2d1", WeekIndex: 2, DayIndex: 1},
+			{ID: "w2d2", WeekIndex: 2, DayIndex: 2},
+		},
+		Repeats: repeats,
+		End:     end,
+	}
+}
+
+func TestOnDateWalksAllWeeks(t *testing.T) {
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name   string
+		plan   Plan
+		offset int
+		want   string
+		ok     bool
+	}{
+		{"before start", testPlan(EndLoop, 1), -1, "", false},
+		{"first day", testPlan(EndLoop, 1), 0, "w1d1", true},
+		{"rest day", testPlan(EndLoop, 1), 1, "w1d2", true},
+		{"second week", testPlan(EndLoop, 1), 3, "w2d2", true},
+		{"loop", testPlan(EndLoop, 1), 4, "w1d1", true},
+		{"stop", testPlan(EndStop, 1), 4, "", false},
+		{"repeat then stop", testPlan(EndStop, 2), 6, "w2d1", true},
+		{"hold last week", testPlan(EndHold, 1), 4, "w2d1", true},
+		{"hold cycles last week", testPlan(EndHold, 1), 7, "w2d2", true},
+	}
+	for _, tc := range cases {
+		got, ok := OnDate(tc.plan, start, start.AddDate(0, 0, tc.offset))
+		if ok != tc.ok || got.Day.ID != tc.want {
+			t.Fatalf("%s: got=%q ok=%v want=%q ok=%v", tc.name, got.Day.ID, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestNextUndone(t *testing.T) {
+	cases := []struct {
+		name string
+		plan Plan
+		done map[string]int
+		want string
+		ok   bool
+	}{
+		{"fresh", testPlan(EndLoop, 1), map[string]int{}, "w1d1", true},
+		{"skips rest", testPlan(EndLoop, 1), map[string]int{"w1d1": 1}, "w2d1", true},
+		{"loops", testPlan(EndLoop, 1), map[string]int{"w1d1": 1, "w2d1": 1, "w2d2": 1}, "w1d1", true},
+		{"stops", testPlan(EndStop, 1), map[string]int{"w1d1": 1, "w2d1": 1, "w2d2": 1}, "", false},
+		{"holds", testPlan(EndHold, 1), map[string]int{"w1d1": 1, "w2d1": 2, "w2d2": 1}, "w2d2", true},
+	}
+	for _, tc := range cases {
+		got, ok := NextUndone(tc.plan, tc.done)
+		if ok != tc.ok || got.ID != tc.want {
+			t.Fatalf("%s: got=%q ok=%v want=%q ok=%v", tc.name, got.ID, ok, tc.want, tc.ok)
+		}
+	}
+	if _, ok := NextUndone(Plan{Days: []Day{{ID: "r", Rest: true}}}, nil); ok {
+		t.Fatal("only rest days should not resolve a day")
+	}
+}
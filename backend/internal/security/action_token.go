@@ -0,0 +1,52 @@
+package security
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ActionClaims: token de acción de cuenta (reset de password, verificación
+// de email). Binding ata el token a un estado del usuario (huella del hash
+// de password, email) para que deje de valer cuando ese estado cambia.
+type ActionClaims struct {
+	Binding string `json:"bnd"`
+	jwt.RegisteredClaims
+}
+
+// ActionTokens firma como SignInvite (llave activa o HS256) con audience = propósito.
+type ActionTokens struct{}
+
+func (ActionTokens) Sign(purpose, userID, binding string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := ActionClaims{
+		Binding: binding,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			Audience:  jwt.ClaimStrings{purpose},
+		},
+	}
+	return signJWT(claims, purpose)
+}
+
+func (ActionTokens) Parse(purpose, token string) (userID, binding string, err error) {
+	var claims ActionClaims
+	tok, err := jwt.ParseWithClaims(
+		token,
+		&claims,
+		verifyKey,
+		jwt.WithValidMethods(jwtMethods),
+		jwt.WithAudience(purpose),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return "", "", err
+	}
+	if !tok.Valid || claims.Subject == "" {
+		return "", "", errors.New("invalid action token")
+	}
+	return claims.Subject, claims.Binding, nil
+}
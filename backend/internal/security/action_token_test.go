@@ -0,0 +1,30 @@
+package security
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActionTokenPurposeAndExpiry(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	var at ActionTokens
+	tok, err := at.Sign("password_reset", "user-1", "abc", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, bnd, err := at.Parse("password_reset", tok)
+	if err != nil || uid != "user-1" || bnd != "abc" {
+		t.Fatalf("parse=%q,%q,%v", uid, bnd, err)
+	}
+	if _, _, err := at.Parse("email_verify", tok); err == nil {
+		t.Fatal("token accepted for another purpose")
+	}
+	if _, err := ParseInvite(tok); err == nil {
+		t.Fatal("action token accepted as invite")
+	}
+
+	expired, _ := at.Sign("password_reset", "user-1", "abc", -time.Minute)
+	if _, _, err := at.Parse("password_reset", expired); err == nil {
+		t.Fatal("expired token accepted")
+	}
+}
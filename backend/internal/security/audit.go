@@ -0,0 +1,117 @@
+package security
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DBAudit guarda decisiones en authz_audit (migración 0022): todas las
+// denegaciones y las escrituras permitidas; las lecturas permitidas no, por
+// volumen. Record no bloquea: si el buffer se llena la decisión se descarta
+// (Dropped) en vez de frenar la request.
+type DBAudit struct {
+	db      *gorm.DB
+	ch      chan Decision
+	dropped atomic.Int64
+}
+
+const auditBatch = 200
+
+type auditRow struct {
+	At         time.Time
+	ActorID    string
+	Resource   string
+	ResourceID string
+	Action     string
+	Allowed    bool
+	Rule       string
+	Reason     string
+	Route      string
+}
+
+func (auditRow) TableName() string { return "authz_audit" }
+
+func NewDBAudit(db *gorm.DB, size int) *DBAudit {
+	if size <= 0 {
+		size = 1024
+	}
+	return &DBAudit{db: db, ch: make(chan Decision, size)}
+}
+
+func (a *DBAudit) Record(d Decision) {
+	if d.Allowed && d.Action == ActRead {
+		return
+	}
+	if !d.Allowed {
+		log.Printf("[Authz] deny actor=%s %s:%s %s route=%q reason=%s", d.ActorID, d.Resource, d.ResourceID, d.Action, d.Route, d.Reason)
+	}
+	select {
+	case a.ch <- d:
+	default:
+		a.dropped.Add(1)
+	}
+}
+
+// Dropped: decisiones descartadas por buffer lleno.
+func (a *DBAudit) Dropped() int64 { return a.dropped.Load() }
+
+// Run escribe en lotes hasta que ctx termine (y vacía lo pendiente al salir).
+func (a *DBAudit) Run(ctx context.Context) {
+	tick := time.NewTicker(time.Second)
+	defer tick.Stop()
+	batch := make([]auditRow, 0, auditBatch)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := a.db.WithContext(context.Background()).CreateInBatches(batch, auditBatch).Error; err != nil {
+			log.Printf("[Authz] audit insert: %v", err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case d := <-a.ch:
+					batch = append(batch, toAuditRow(d))
+				default:
+					flush()
+					return
+				}
+			}
+		case d := <-a.ch:
+			batch = append(batch, toAuditRow(d))
+			if len(batch) >= auditBatch {
+				flush()
+			}
+		case <-tick.C:
+			flush()
+		}
+	}
+}
+
+// Purge borra lo más antiguo que retention.
+func (a *DBAudit) Purge(ctx context.Context, retention time.Duration) (int64, error) {
+	res := a.db.WithContext(ctx).Where("at < ?", time.Now().Add(-retention)).Delete(&auditRow{})
+	return res.RowsAffected, res.Error
+}
+
+func toAuditRow(d Decision) auditRow {
+	return auditRow{
+		At:         d.At,
+		ActorID:    d.ActorID,
+		Resource:   string(d.Resource),
+		ResourceID: d.ResourceID,
+		Action:     string(d.Action),
+		Allowed:    d.Allowed,
+		Rule:       d.Rule,
+		Reason:     d.Reason,
+		Route:      d.Route,
+	}
+}
@@ -10,7 +10,10 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-const CtxUserID = "user_id"
+const (
+	CtxUserID    = "user_id"
+	CtxSessionID = "session_id"
+)
 
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -51,8 +54,10 @@ func AuthRequired() gin.HandlerFunc {
 			}
 		}
 
-		// Exigir typ=access si está presente
-		if typ, _ := claims["typ"].(string); typ != "" && typ != "access" {
+		// Solo access tokens: NewAccessToken siempre pone typ=access y
+		// ParseAndValidate ya exigió aud=roma-api (invitación y tokens de
+		// cuenta llevan su propósito como aud)
+		if typ, _ := claims["typ"].(string); typ != "access" {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
 			return
 		}
@@ -63,8 +68,26 @@ func AuthRequired() gin.HandlerFunc {
 			return
 		}
 
+		// sesión revocada (logout, cambio o reset de password): el access
+		// emitido bajo ella deja de valer antes de vencer
+		sid, _ := claims["sid"].(string)
+		if db := sessionDB.Load(); db != nil && sid != "" {
+			active, err := SessionActive(db.WithContext(c.Request.Context()), sid)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+				return
+			}
+			if !active {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+				return
+			}
+		}
+
 		// Listo: deja el user en el contexto para guards/handlers
 		c.Set(CtxUserID, sub)
+		if sid != "" {
+			c.Set(CtxSessionID, sid)
+		}
 		c.Next()
 	}
 }
@@ -0,0 +1,220 @@
+package security
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+// AuthzCache: caché en proceso del rol de cada usuario y del conjunto de
+// discípulos vinculados (aceptados) de cada coach, para que RequireRole y las
+// reglas del Authorizer no repitan esas consultas en cada request; también
+// si la sesión de refresh de un access token sigue activa. Las entradas
+// viven TTL; además Listen escucha NOTIFY roma_authz (migraciones 0021 y
+// 0028) y borra al instante lo que cambió en users.role, coach_links o
+// auth_sessions.
+// Sin UseAuthzCache las funciones consultan la DB como siempre.
+type AuthzCache struct {
+	ttl time.Duration
+
+	mu    sync.RWMutex
+	roles map[string]cachedRole
+	links map[string]cachedLinks
+	sess  map[string]cachedSession
+	// gen sube con cada invalidación: una lectura que empezó antes no se guarda
+	gen uint64
+}
+
+type cachedRole struct {
+	role string
+	exp  time.Time
+}
+
+type cachedLinks struct {
+	disciples map[string]struct{}
+	exp       time.Time
+}
+
+type cachedSession struct {
+	active bool
+	exp    time.Time
+}
+
+const AuthzChannel = "roma_authz"
+
+func NewAuthzCache(ttl time.Duration) *AuthzCache {
+	return &AuthzCache{
+		ttl:   ttl,
+		roles: map[string]cachedRole{},
+		links: map[string]cachedLinks{},
+		sess:  map[string]cachedSession{},
+	}
+}
+
+var authz atomic.Pointer[AuthzCache]
+
+// UseAuthzCache activa la caché para los guards; nil la desactiva.
+func UseAuthzCache(c *AuthzCache) { authz.Store(c) }
+
+func (a *AuthzCache) role(db *gorm.DB, userID string) (string, error) {
+	now := time.Now()
+	a.mu.RLock()
+	e, ok := a.roles[userID]
+	gen := a.gen
+	a.mu.RUnlock()
+	if ok && now.Before(e.exp) {
+		return e.role, nil
+	}
+	role, err := roleFromDB(db, userID)
+	if err != nil {
+		return "", err
+	}
+	a.mu.Lock()
+	if a.gen == gen {
+		a.roles[userID] = cachedRole{role: role, exp: now.Add(a.ttl)}
+	}
+	a.mu.Unlock()
+	return role, nil
+}
+
+func (a *AuthzCache) isCoachOf(db *gorm.DB, coachID, discipleID string) (bool, error) {
+	now := time.Now()
+	a.mu.RLock()
+	e, ok := a.links[coachID]
+	gen := a.gen
+	a.mu.RUnlock()
+	if !ok || !now.Before(e.exp) {
+		var ids []string
+		err := db.Table("coach_links").
+			Where("coach_id = ? AND status = 'accepted'", coachID).
+			Pluck("disciple_id", &ids).Error
+		if err != nil {
+			return false, err
+		}
+		e = cachedLinks{disciples: make(map[string]struct{}, len(ids)), exp: now.Add(a.ttl)}
+		for _, id := range ids {
+			e.disciples[id] = struct{}{}
+		}
+		a.mu.Lock()
+		if a.gen == gen {
+			a.links[coachID] = e
+		}
+		a.mu.Unlock()
+	}
+	_, linked := e.disciples[discipleID]
+	return linked, nil
+}
+
+func (a *AuthzCache) sessionActive(db *gorm.DB, sid string) (bool, error) {
+	now := time.Now()
+	a.mu.RLock()
+	e, ok := a.sess[sid]
+	gen := a.gen
+	a.mu.RUnlock()
+	if ok && now.Before(e.exp) {
+		return e.active, nil
+	}
+	active, err := sessionActiveFromDB(db, sid)
+	if err != nil {
+		return false, err
+	}
+	a.mu.Lock()
+	if a.gen == gen {
+		a.sess[sid] = cachedSession{active: active, exp: now.Add(a.ttl)}
+	}
+	a.mu.Unlock()
+	return active, nil
+}
+
+func (a *AuthzCache) InvalidateUser(userID string) {
+	a.mu.Lock()
+	delete(a.roles, userID)
+	a.gen++
+	a.mu.Unlock()
+}
+
+func (a *AuthzCache) InvalidateLinks(coachID string) {
+	a.mu.Lock()
+	delete(a.links, coachID)
+	a.gen++
+	a.mu.Unlock()
+}
+
+func (a *AuthzCache) InvalidateSession(sid string) {
+	a.mu.Lock()
+	delete(a.sess, sid)
+	a.gen++
+	a.mu.Unlock()
+}
+
+func (a *AuthzCache) Flush() {
+	a.mu.Lock()
+	a.roles = map[string]cachedRole{}
+	a.links = map[string]cachedLinks{}
+	a.sess = map[string]cachedSession{}
+	a.gen++
+	a.mu.Unlock()
+}
+
+// handleNotify aplica un payload 'user:<id>' | 'links:<coach_id>' |
+// 'session:<id>'; otro valor vacía todo.
+func (a *AuthzCache) handleNotify(payload string) {
+	kind, id, _ := strings.Cut(payload, ":")
+	switch {
+	case kind == "user" && id != "":
+		a.InvalidateUser(id)
+	case kind == "links" && id != "":
+		a.InvalidateLinks(id)
+	case kind == "session" && id != "":
+		a.InvalidateSession(id)
+	default:
+		a.Flush()
+	}
+}
+
+// Listen mantiene una conexión LISTEN roma_authz hasta que ctx termine. Si la
+// conexión cae se vacía la caché (pudo perderse un aviso) y se reintenta.
+func (a *AuthzCache) Listen(ctx context.Context, dsn string) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		err := a.listenOnce(ctx, dsn, func() { backoff = time.Second })
+		if ctx.Err() != nil {
+			return
+		}
+		a.Flush()
+		log.Printf("[AuthzCache] listen: %v (reintento en %s)", err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+	}
+}
+
+func (a *AuthzCache) listenOnce(ctx context.Context, dsn string, connected func()) error {
+	conn, err := pgx.Connect(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, "LISTEN "+AuthzChannel); err != nil {
+		return err
+	}
+	// lo cacheado antes de escuchar pudo cambiar sin aviso
+	a.Flush()
+	connected()
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		a.handleNotify(n.Payload)
+	}
+}
@@ -0,0 +1,173 @@
+package security
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	disciple1 = "11111111-1111-4111-8111-111111111111"
+	disciple2 = "22222222-2222-4222-8222-222222222222"
+)
+
+// countingDriver responde las consultas de los guards con datos fijos
+// (coach-1 es coach y tiene a disciple1) y cuenta los round-trips.
+type countingDriver struct{ queries atomic.Int64 }
+
+func (d *countingDriver) Connect(context.Context) (driver.Conn, error) { return countingConn{d}, nil }
+func (d *countingDriver) Driver() driver.Driver                        { return nil }
+
+type countingConn struct{ d *countingDriver }
+
+func (c countingConn) Prepare(q string) (driver.Stmt, error) { return countingStmt{c.d, q}, nil }
+func (countingConn) Close() error                            { return nil }
+func (countingConn) Begin() (driver.Tx, error)               { return nil, driver.ErrSkip }
+
+type countingStmt struct {
+	d *countingDriver
+	q string
+}
+
+func (countingStmt) Close() error                               { return nil }
+func (countingStmt) NumInput() int                              { return -1 }
+func (countingStmt) Exec([]driver.Value) (driver.Result, error) { return nil, driver.ErrSkip }
+func (s countingStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.d.queries.Add(1)
+	switch {
+	case strings.Contains(s.q, "SELECT role"):
+		return &fixedRows{cols: []string{"role"}, vals: []driver.Value{"coach"}}, nil
+	case strings.Contains(s.q, "count(*)"):
+		return &fixedRows{cols: []string{"count"}, vals: []driver.Value{int64(1)}}, nil
+	default: // disciple_id de coach_links
+		return &fixedRows{cols: []string{"disciple_id"}, vals: []driver.Value{disciple1}}, nil
+	}
+}
+
+type fixedRows struct {
+	cols []string
+	vals []driver.Value
+}
+
+func (r *fixedRows) Columns() []string { return r.cols }
+func (r *fixedRows) Close() error      { return nil }
+func (r *fixedRows) Next(dest []driver.Value) error {
+	if len(r.vals) == 0 {
+		return io.EOF
+	}
+	dest[0], r.vals = r.vals[0], r.vals[1:]
+	return nil
+}
+
+func countingGorm(tb testing.TB) (*gorm.DB, *countingDriver) {
+	tb.Helper()
+	drv := &countingDriver{}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(drv)}), &gorm.Config{})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db, drv
+}
+
+// coachRoute: la cadena típica de una ruta de coach sobre un discípulo.
+func coachRoute(tb testing.TB, db *gorm.DB) *gin.Engine {
+	UseAuthorizer(NewAuthorizer(NewSQLPolicyStore(db), nil))
+	tb.Cleanup(func() { UseAuthorizer(nil) })
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/disciples/:id",
+		func(c *gin.Context) { c.Set(CtxUserID, "coach-1") },
+		RequireRole(db, "coach"),
+		Authorize(RoutePolicies{"GET /disciples/:id": {Resource: ResDisciple, Action: ActRead, Param: "id"}}),
+		func(c *gin.Context) { c.Status(http.StatusNoContent) },
+	)
+	return r
+}
+
+func serveCoachRoute(tb testing.TB, r http.Handler, discipleID string, want int) {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/disciples/"+discipleID, nil))
+	if w.Code != want {
+		tb.Fatalf("status=%d want %d", w.Code, want)
+	}
+}
+
+func TestAuthzCacheCutsQueriesAndInvalidates(t *testing.T) {
+	db, drv := countingGorm(t)
+	r := coachRoute(t, db)
+
+	for i := 0; i < 5; i++ {
+		serveCoachRoute(t, r, disciple1, http.StatusNoContent)
+	}
+	if n := drv.queries.Load(); n != 10 {
+		t.Fatalf("without cache queries=%d want 10", n)
+	}
+
+	cache := NewAuthzCache(time.Minute)
+	UseAuthzCache(cache)
+	t.Cleanup(func() { UseAuthzCache(nil) })
+	drv.queries.Store(0)
+	for i := 0; i < 5; i++ {
+		serveCoachRoute(t, r, disciple1, http.StatusNoContent)
+	}
+	serveCoachRoute(t, r, disciple2, http.StatusForbidden)
+	if n := drv.queries.Load(); n != 2 {
+		t.Fatalf("with cache queries=%d want 2", n)
+	}
+
+	cache.handleNotify("links:coach-1")
+	serveCoachRoute(t, r, disciple1, http.StatusNoContent)
+	cache.handleNotify("user:coach-1")
+	serveCoachRoute(t, r, disciple1, http.StatusNoContent)
+	if n := drv.queries.Load(); n != 4 {
+		t.Fatalf("after invalidation queries=%d want 4", n)
+	}
+
+	// sesión de refresh: cacheada hasta el aviso de revocación
+	drv.queries.Store(0)
+	for i := 0; i < 3; i++ {
+		if ok, err := SessionActive(db, "sess-1"); err != nil || !ok {
+			t.Fatalf("session active=%v err=%v", ok, err)
+		}
+	}
+	cache.handleNotify("session:sess-1")
+	if _, err := SessionActive(db, "sess-1"); err != nil {
+		t.Fatal(err)
+	}
+	if n := drv.queries.Load(); n != 2 {
+		t.Fatalf("session queries=%d want 2", n)
+	}
+}
+
+func BenchmarkCoachRouteGuards(b *testing.B) {
+	for _, tc := range []struct {
+		name  string
+		cache *AuthzCache
+	}{
+		{"db", nil},
+		{"cached", NewAuthzCache(time.Minute)},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			db, drv := countingGorm(b)
+			UseAuthzCache(tc.cache)
+			defer UseAuthzCache(nil)
+			r := coachRoute(b, db)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				serveCoachRoute(b, r, disciple1, http.StatusNoContent)
+			}
+			b.ReportMetric(float64(drv.queries.Load())/float64(b.N), "queries/op")
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+var ErrBlobURLInvalid = errors.New("invalid_or_expired_url")
+
+// Prefijo público de los archivos firmados (fuera de /api: el navegador los
+// pide sin Authorization, p. ej. desde un <img>).
+const BlobURLPrefix = "/files/"
+
+// Vida de una URL firmada (BLOB_URL_TTL_MIN, por defecto 15 min).
+func BlobURLTTL() time.Duration {
+	return time.Duration(mustEnvInt("BLOB_URL_TTL_MIN", 15)) * time.Minute
+}
+
+func blobSig(secret, key string, exp int64) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte("blob:" + key + ":" + strconv.FormatInt(exp, 10)))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// SignBlobURL devuelve la ruta relativa con exp y firma HMAC (JWT_SECRET).
+func SignBlobURL(key string, ttl time.Duration) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_SECRET missing")
+	}
+	exp := time.Now().Add(ttl).Unix()
+	q := url.Values{}
+	q.Set("exp", strconv.FormatInt(exp, 10))
+	q.Set("sig", blobSig(secret, key, exp))
+	return BlobURLPrefix + key + "?" + q.Encode(), nil
+}
+
+// VerifyBlobURL valida firma y vencimiento de los parámetros de la URL.
+func VerifyBlobURL(key, expRaw, sig string) error {
+	secret := os.Getenv("JWT_SECRET")
+	exp, err := strconv.ParseInt(expRaw, 10, 64)
+	if secret == "" || err != nil || time.Now().Unix() > exp {
+		return ErrBlobURLInvalid
+	}
+	if !hmac.Equal([]byte(sig), []byte(blobSig(secret, key, exp))) {
+		return ErrBlobURLInvalid
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package security
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedBlobURL(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	raw, err := SignBlobURL("checkins/d/c/p.jpg", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := strings.TrimPrefix(u.Path, BlobURLPrefix)
+	exp, sig := u.Query().Get("exp"), u.Query().Get("sig")
+	if err := VerifyBlobURL(key, exp, sig); err != nil {
+		t.Fatalf("valid url rejected: %v", err)
+	}
+	if err := VerifyBlobURL("checkins/d/c/other.jpg", exp, sig); err == nil {
+		t.Fatal("signature reused for another key")
+	}
+	if err := VerifyBlobURL(key, "1", sig); err == nil {
+		t.Fatal("expired/tampered exp accepted")
+	}
+
+	expired, _ := SignBlobURL("k.jpg", -time.Minute)
+	u, _ = url.Parse(expired)
+	if err := VerifyBlobURL("k.jpg", u.Query().Get("exp"), u.Query().Get("sig")); err == nil {
+		t.Fatal("expired url accepted")
+	}
+}
@@ -17,3 +17,10 @@ func UserID(c *gin.Context) string {
 	}
 	return ""
 }
+
+// SessionID: sesión de refresh (claim sid) del access token; "" si no trae.
+func SessionID(c *gin.Context) string {
+	s, _ := c.Get(CtxSessionID)
+	id, _ := s.(string)
+	return id
+}
@@ -3,7 +3,6 @@ package security
 
 import (
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -17,10 +16,6 @@ type InviteClaims struct {
 
 // Emite un JWT de invitación (audience=invite, typ=invite)
 func SignInvite(coachID, discipleEmail string, ttl time.Duration) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "", errors.New("JWT_SECRET missing")
-	}
 	now := time.Now()
 	claims := InviteClaims{
 		CoachID:       coachID,
@@ -32,25 +27,17 @@ func SignInvite(coachID, discipleEmail string, ttl time.Duration) (string, error
 			Audience:  jwt.ClaimStrings{"invite"},
 		},
 	}
-	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tok.Header["typ"] = "invite"
-	return tok.SignedString([]byte(secret))
+	return signJWT(claims, "invite")
 }
 
 // Valida y parsea el código de invitación
 func ParseInvite(code string) (*InviteClaims, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return nil, errors.New("JWT_SECRET missing")
-	}
 	var claims InviteClaims
 	tok, err := jwt.ParseWithClaims(
 		code,
 		&claims,
-		func(t *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		},
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		verifyKey,
+		jwt.WithValidMethods(jwtMethods),
 		jwt.WithAudience("invite"), // <- valida aud automáticamente en v5
 	)
 	if err != nil {
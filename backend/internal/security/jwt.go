@@ -22,45 +22,50 @@ func mustEnvInt(key string, def int) int {
 	return def
 }
 
-func GenerateTokens(userID string) (TokenPair, error) {
-	secret := os.Getenv("JWT_SECRET")
-	accessTTL := time.Duration(mustEnvInt("ACCESS_TTL_MIN", 15)) * time.Minute
-	refreshTTL := time.Duration(mustEnvInt("REFRESH_TTL_H", 168)) * time.Hour
+// AccessTTL: vida del access token (ACCESS_TTL_MIN, por defecto 15 min).
+func AccessTTL() time.Duration {
+	return time.Duration(mustEnvInt("ACCESS_TTL_MIN", 15)) * time.Minute
+}
 
-	now := time.Now()
+// RefreshTTL: vida de una sesión sin uso (REFRESH_TTL_H, por defecto 168 h).
+func RefreshTTL() time.Duration {
+	return time.Duration(mustEnvInt("REFRESH_TTL_H", 168)) * time.Hour
+}
 
-	access := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+// Emisor y audiencia de los access tokens: la misma llave firma invitaciones
+// y tokens de cuenta (aud = propósito), así que quien verifique con el JWKS
+// debe exigir estos dos para aceptar solo access tokens.
+const (
+	AccessIssuer   = "roma"
+	AccessAudience = "roma-api"
+)
+
+// NewAccessToken firma un access token (llave activa, o HS256). sid identifica la sesión de
+// refresh que lo emitió (vacío si no hay sesión). Con UseSessionCheck, revocar
+// la sesión invalida también el access ya emitido.
+func NewAccessToken(userID, sid string) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": AccessIssuer,
+		"aud": AccessAudience,
 		"sub": userID,
 		"typ": "access",
 		"iat": now.Unix(),
-		"exp": now.Add(accessTTL).Unix(),
-	})
-	refresh := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": userID,
-		"typ": "refresh",
-		"iat": now.Unix(),
-		"exp": now.Add(refreshTTL).Unix(),
-	})
-
-	accessStr, err := access.SignedString([]byte(secret))
-	if err != nil {
-		return TokenPair{}, err
+		"exp": now.Add(AccessTTL()).Unix(),
 	}
-	refreshStr, err := refresh.SignedString([]byte(secret))
-	if err != nil {
-		return TokenPair{}, err
+	if sid != "" {
+		claims["sid"] = sid
 	}
-	return TokenPair{AccessToken: accessStr, RefreshToken: refreshStr}, nil
+	return signJWT(claims, "")
 }
 
+// ParseAndValidate valida un access token (firma, iss y aud).
 func ParseAndValidate(tokenStr string) (*jwt.Token, jwt.MapClaims, error) {
-	secret := []byte(os.Getenv("JWT_SECRET"))
-	tok, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return secret, nil
-	})
+	tok, err := jwt.Parse(tokenStr, verifyKey,
+		jwt.WithValidMethods(jwtMethods),
+		jwt.WithIssuer(AccessIssuer),
+		jwt.WithAudience(AccessAudience),
+	)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,292 @@
+package security
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Llaves asimétricas para firmar JWT (RS256 / EdDSA) con kid en el header.
+//
+// JWT_KEYS_DIR contiene un PEM por llave; el nombre del archivo es el kid:
+//
+//	2026-10-rsa.pem        privada (PKCS#8 o PKCS#1): firma y verifica
+//	2026-04-rsa.pub.pem    solo pública: verifica tokens emitidos antes
+//
+// Firma la llave JWT_ACTIVE_KID o, si no se indica, la privada con el kid
+// mayor (nombres con fecha). Rotar = agregar la nueva, Reload, y retirar la
+// vieja cuando venzan sus tokens. Sin JWT_KEYS_DIR todo sigue en HS256 con
+// JWT_SECRET.
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+const (
+	pemPrivSuffix = ".pem"
+	pemPubSuffix  = ".pub.pem"
+	minRSABits    = 2048
+)
+
+type signingKey struct {
+	kid    string
+	method jwt.SigningMethod
+	priv   crypto.Signer // nil: solo verificación
+	pub    crypto.PublicKey
+}
+
+type keySet struct {
+	byKid  map[string]*signingKey
+	signer *signingKey
+}
+
+type KeyManager struct {
+	dir       string
+	activeKid string
+	// AcceptHS256: sigue aceptando tokens HS256 (sin kid) firmados con
+	// JWT_SECRET, para la transición desde el esquema anterior.
+	AcceptHS256 bool
+
+	set atomic.Pointer[keySet]
+}
+
+func NewKeyManager(dir, activeKid string) (*KeyManager, error) {
+	m := &KeyManager{dir: dir, activeKid: activeKid}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Reload relee el directorio. Si falla, se mantiene el juego de llaves anterior.
+func (m *KeyManager) Reload() error {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return err
+	}
+	set := &keySet{byKid: map[string]*signingKey{}}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, pemPrivSuffix) {
+			continue
+		}
+		raw, err := os.ReadFile(filepath.Join(m.dir, name))
+		if err != nil {
+			return err
+		}
+		var k *signingKey
+		if kid, ok := strings.CutSuffix(name, pemPubSuffix); ok {
+			k, err = parsePublicPEM(kid, raw)
+		} else {
+			k, err = parsePrivatePEM(strings.TrimSuffix(name, pemPrivSuffix), raw)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		// si existen privada y pública del mismo kid, gana la privada
+		if prev, ok := set.byKid[k.kid]; ok && prev.priv != nil {
+			continue
+		}
+		set.byKid[k.kid] = k
+	}
+
+	if m.activeKid != "" {
+		k := set.byKid[m.activeKid]
+		if k == nil || k.priv == nil {
+			return fmt.Errorf("active key %q: %w", m.activeKid, ErrUnknownKey)
+		}
+		set.signer = k
+	} else {
+		kids := make([]string, 0, len(set.byKid))
+		for kid, k := range set.byKid {
+			if k.priv != nil {
+				kids = append(kids, kid)
+			}
+		}
+		if len(kids) == 0 {
+			return errors.New("no private key in " + m.dir)
+		}
+		sort.Strings(kids)
+		set.signer = set.byKid[kids[len(kids)-1]]
+	}
+	m.set.Store(set)
+	return nil
+}
+
+// ActiveKid: kid con el que se firman los tokens nuevos.
+func (m *KeyManager) ActiveKid() string { return m.set.Load().signer.kid }
+
+func (m *KeyManager) sign(claims jwt.Claims, typ string) (string, error) {
+	k := m.set.Load().signer
+	tok := jwt.NewWithClaims(k.method, claims)
+	tok.Header["kid"] = k.kid
+	if typ != "" {
+		tok.Header["typ"] = typ
+	}
+	return tok.SignedString(k.priv)
+}
+
+func (m *KeyManager) verifyKey(kid string, method jwt.SigningMethod) (any, error) {
+	k := m.set.Load().byKid[kid]
+	if k == nil {
+		return nil, ErrUnknownKey
+	}
+	if k.method.Alg() != method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return k.pub, nil
+}
+
+func parsePrivatePEM(kid string, raw []byte) (*signingKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("invalid pem")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported pem type %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < minRSABits {
+			return nil, fmt.Errorf("rsa key too short (%d bits)", k.N.BitLen())
+		}
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, priv: k, pub: &k.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, priv: k, pub: k.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+func parsePublicPEM(kid string, raw []byte) (*signingKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("invalid public pem")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, pub: k}, nil
+	case ed25519.PublicKey:
+		return &signingKey{kid: kid, method: jwt.SigningMethodEdDSA, pub: k}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// JWK: clave pública en formato RFC 7517 (RSA y OKP/Ed25519).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS publica todas las llaves cargadas (activa y retiradas), ordenadas por kid.
+func (m *KeyManager) JWKS() JWKSet {
+	set := m.set.Load()
+	out := JWKSet{Keys: make([]JWK, 0, len(set.byKid))}
+	for _, k := range set.byKid {
+		j := JWK{Kid: k.kid, Use: "sig", Alg: k.method.Alg()}
+		switch pub := k.pub.(type) {
+		case *rsa.PublicKey:
+			j.Kty = "RSA"
+			j.N = b64url(pub.N.Bytes())
+			j.E = b64url(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			j.Kty, j.Crv = "OKP", "Ed25519"
+			j.X = b64url(pub)
+		}
+		out.Keys = append(out.Keys, j)
+	}
+	sort.Slice(out.Keys, func(i, j int) bool { return out.Keys[i].Kid < out.Keys[j].Kid })
+	return out
+}
+
+func b64url(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+// ---- llaves del proceso ----
+
+var keys atomic.Pointer[KeyManager]
+
+// UseKeys activa el KeyManager para todos los JWT (access, invitaciones,
+// tokens de cuenta). nil vuelve a HS256 con JWT_SECRET.
+func UseKeys(m *KeyManager) { keys.Store(m) }
+
+// CurrentKeys devuelve el KeyManager en uso (nil en modo HS256).
+func CurrentKeys() *KeyManager { return keys.Load() }
+
+var jwtMethods = []string{
+	jwt.SigningMethodHS256.Alg(),
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
+func signJWT(claims jwt.Claims, typ string) (string, error) {
+	if m := keys.Load(); m != nil {
+		return m.sign(claims, typ)
+	}
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_SECRET missing")
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if typ != "" {
+		tok.Header["typ"] = typ
+	}
+	return tok.SignedString([]byte(secret))
+}
+
+// verifyKey es el jwt.Keyfunc común: por kid si hay KeyManager, HS256 si no
+// (o si el KeyManager lo sigue aceptando).
+func verifyKey(t *jwt.Token) (any, error) {
+	m := keys.Load()
+	if m != nil {
+		if kid, _ := t.Header["kid"].(string); kid != "" {
+			return m.verifyKey(kid, t.Method)
+		}
+		if !m.AcceptHS256 {
+			return nil, ErrUnknownKey
+		}
+	}
+	if t.Method != jwt.SigningMethodHS256 {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("JWT_SECRET missing")
+	}
+	return []byte(secret), nil
+}
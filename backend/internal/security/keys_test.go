@@ -0,0 +1,135 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writeKeyPEM(t *testing.T, dir, name, typ string, der []byte) {
+	t.Helper()
+	raw := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, name), raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func kidOf(t *testing.T, tok string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(tok, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid
+}
+
+func TestKeyManagerRotation(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKeyPEM(t, dir, "2026-01-rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	km, err := NewKeyManager(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	UseKeys(km)
+	t.Cleanup(func() { UseKeys(nil) })
+
+	hsToken, err := func() (string, error) {
+		UseKeys(nil)
+		defer UseKeys(km)
+		return NewAccessToken("user-1", "")
+	}()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := NewAccessToken("user-1", "sess-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := kidOf(t, oldToken); kid != "2026-01-rsa" {
+		t.Fatalf("kid=%q", kid)
+	}
+	if _, _, err := ParseAndValidate(hsToken); err == nil {
+		t.Fatal("hs256 token accepted without AcceptHS256")
+	}
+
+	// rotación: entra una Ed25519 más nueva, la RSA sigue verificando
+	_, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(edPriv)
+	writeKeyPEM(t, dir, "2026-07-ed.pem", "PRIVATE KEY", der)
+	if err := km.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := NewAccessToken("user-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kid := kidOf(t, newToken); kid != "2026-07-ed" {
+		t.Fatalf("kid after rotation=%q", kid)
+	}
+	for _, tok := range []string{oldToken, newToken} {
+		if _, claims, err := ParseAndValidate(tok); err != nil || claims["sub"] != "user-1" {
+			t.Fatalf("verify: %v", err)
+		}
+	}
+	code, err := SignInvite("coach-1", "d@example.test", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseInvite(code); err != nil {
+		t.Fatalf("invite: %v", err)
+	}
+	// misma llave, pero sin iss/aud de access token
+	if _, _, err := ParseAndValidate(code); err == nil {
+		t.Fatal("invite accepted as access token")
+	}
+	if _, claims, _ := ParseAndValidate(newToken); claims["iss"] != AccessIssuer || claims["aud"] != AccessAudience {
+		t.Fatalf("access claims=%v", claims)
+	}
+
+	jwks := km.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Kty != "RSA" || jwks.Keys[0].E != "AQAB" ||
+		jwks.Keys[1].Kty != "OKP" || jwks.Keys[1].Alg != "EdDSA" {
+		t.Fatalf("jwks=%+v", jwks)
+	}
+
+	// retiro: la RSA queda solo pública y luego se borra
+	pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	writeKeyPEM(t, dir, "2026-01-rsa.pub.pem", "PUBLIC KEY", pubDER)
+	_ = os.Remove(filepath.Join(dir, "2026-01-rsa.pem"))
+	if err := km.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParseAndValidate(oldToken); err != nil {
+		t.Fatalf("retired key no longer verifies: %v", err)
+	}
+	_ = os.Remove(filepath.Join(dir, "2026-01-rsa.pub.pem"))
+	if err := km.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ParseAndValidate(oldToken); err == nil {
+		t.Fatal("token of removed key accepted")
+	}
+
+	km.AcceptHS256 = true
+	if _, _, err := ParseAndValidate(hsToken); err != nil {
+		t.Fatalf("legacy hs256: %v", err)
+	}
+	if _, err := NewKeyManager(dir, "missing"); err == nil {
+		t.Fatal("unknown active kid accepted")
+	}
+}
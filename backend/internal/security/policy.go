@@ -0,0 +1,349 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Autorización por políticas: cada (recurso, acción) tiene una regla
+// declarada en `policies`. Los recursos hijos se resuelven a su dueño real
+// (week/day/prescription → programa; assignment/session/set/checkin →
+// discípulo) y se evalúan con las mismas reglas. Un único Authorizer decide
+// y deja cada decisión en el AuditSink.
+
+type Resource string
+
+const (
+	ResProgram      Resource = "program"
+	ResWeek         Resource = "week"
+	ResDay          Resource = "day"
+	ResPrescription Resource = "prescription"
+	ResDisciple     Resource = "disciple"
+	ResAssignment   Resource = "assignment"
+	ResSession      Resource = "session"
+	ResSet          Resource = "set"
+	ResCheckin      Resource = "checkin"
+)
+
+type Action string
+
+const (
+	ActRead    Action = "read"
+	ActMutate  Action = "mutate"
+	ActExecute Action = "execute" // usar el recurso: asignar/versionar un programa, entrenar una sesión, revisar un check-in
+)
+
+// Motivos de denegación (Decision.Reason).
+const (
+	ReasonAnonymous = "anonymous"
+	ReasonNotFound  = "not_found"
+	ReasonNoPolicy  = "no_policy"
+	ReasonNoMatch   = "no_rule_matched"
+)
+
+// Target: hechos del recurso ya resuelto.
+type Target struct {
+	ProgramID         string
+	ProgramOwner      string
+	ProgramKind       string // coach_program | self_training
+	ProgramVisibility string // private | public
+	DiscipleID        string
+}
+
+// PolicyStore trae los hechos que necesitan las reglas (SQLPolicyStore en producción).
+type PolicyStore interface {
+	Role(ctx context.Context, userID string) (string, error)
+	IsCoachOf(ctx context.Context, coachID, discipleID string) (bool, error)
+	// Target devuelve nil si el recurso no existe.
+	Target(ctx context.Context, res Resource, id string) (*Target, error)
+	// ProgramAssigned: el programa está asignado al actor o a un discípulo suyo.
+	ProgramAssigned(ctx context.Context, programID, actorID string) (bool, error)
+}
+
+type Decision struct {
+	At         time.Time `json:"at"`
+	ActorID    string    `json:"actor_id"`
+	Resource   Resource  `json:"resource"`
+	ResourceID string    `json:"resource_id"`
+	Action     Action    `json:"action"`
+	Allowed    bool      `json:"allowed"`
+	Rule       string    `json:"rule,omitempty"`   // regla que concedió el acceso
+	Reason     string    `json:"reason,omitempty"` // motivo de la denegación
+	Route      string    `json:"route,omitempty"`
+}
+
+type AuditSink interface {
+	Record(d Decision)
+}
+
+// AuditFunc adapta una función a AuditSink.
+type AuditFunc func(Decision)
+
+func (f AuditFunc) Record(d Decision) { f(d) }
+
+// ---- reglas ----
+
+type evalCtx struct {
+	ctx     context.Context
+	store   PolicyStore
+	actorID string
+	target  *Target
+	matched string
+	role    *string
+}
+
+func (e *evalCtx) actorRole() (string, error) {
+	if e.role == nil {
+		r, err := e.store.Role(e.ctx, e.actorID)
+		if err != nil {
+			return "", err
+		}
+		e.role = &r
+	}
+	return *e.role, nil
+}
+
+// Rule: predicado con nombre (el nombre queda en la auditoría).
+type Rule struct {
+	Name string
+	eval func(e *evalCtx) (bool, error)
+}
+
+func rule(name string, f func(e *evalCtx) (bool, error)) Rule { return Rule{Name: name, eval: f} }
+
+func anyOf(rules ...Rule) Rule {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.Name
+	}
+	return Rule{Name: strings.Join(names, "|"), eval: func(e *evalCtx) (bool, error) {
+		for _, r := range rules {
+			ok, err := r.eval(e)
+			if err != nil || ok {
+				if ok && e.matched == "" {
+					e.matched = r.Name
+				}
+				return ok, err
+			}
+		}
+		return false, nil
+	}}
+}
+
+func allOf(rules ...Rule) Rule {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.Name
+	}
+	name := strings.Join(names, "&")
+	return Rule{Name: name, eval: func(e *evalCtx) (bool, error) {
+		for _, r := range rules {
+			ok, err := r.eval(e)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	}}
+}
+
+var (
+	ruleProgramOwner = rule("program_owner", func(e *evalCtx) (bool, error) {
+		return e.target.ProgramOwner == e.actorID, nil
+	})
+	// coach edita sus coach_program; discípulo, sus self_training
+	ruleProgramEditable = rule("program_editable", func(e *evalCtx) (bool, error) {
+		if e.target.ProgramOwner != e.actorID {
+			return false, nil
+		}
+		role, err := e.actorRole()
+		if err != nil {
+			return false, err
+		}
+		return (role == "coach" && e.target.ProgramKind == "coach_program") ||
+			(role == "disciple" && e.target.ProgramKind == "self_training"), nil
+	})
+	rulePublicTemplate = rule("public_template", func(e *evalCtx) (bool, error) {
+		return e.target.ProgramKind == "coach_program" && e.target.ProgramVisibility == "public", nil
+	})
+	ruleNotSelfTraining = rule("not_self_training", func(e *evalCtx) (bool, error) {
+		return e.target.ProgramKind != "self_training", nil
+	})
+	ruleProgramAssigned = rule("program_assigned", func(e *evalCtx) (bool, error) {
+		return e.store.ProgramAssigned(e.ctx, e.target.ProgramID, e.actorID)
+	})
+	ruleSelf = rule("self", func(e *evalCtx) (bool, error) {
+		return e.target.DiscipleID == e.actorID, nil
+	})
+	ruleCoachOf = rule("coach_of", func(e *evalCtx) (bool, error) {
+		return e.store.IsCoachOf(e.ctx, e.actorID, e.target.DiscipleID)
+	})
+	// coach vinculado que no es el propio discípulo (revisar check-ins)
+	ruleCoachOfOther = rule("coach_of_other", func(e *evalCtx) (bool, error) {
+		if e.target.DiscipleID == e.actorID {
+			return false, nil
+		}
+		return e.store.IsCoachOf(e.ctx, e.actorID, e.target.DiscipleID)
+	})
+)
+
+var (
+	programRead   = anyOf(ruleProgramOwner, rulePublicTemplate, allOf(ruleNotSelfTraining, ruleProgramAssigned))
+	programMutate = ruleProgramEditable
+	discipleRead  = anyOf(ruleSelf, ruleCoachOf)
+)
+
+var policies = map[Resource]map[Action]Rule{
+	ResProgram:      {ActRead: programRead, ActMutate: programMutate, ActExecute: ruleProgramOwner},
+	ResWeek:         {ActRead: programRead, ActMutate: programMutate},
+	ResDay:          {ActRead: programRead, ActMutate: programMutate},
+	ResPrescription: {ActRead: programRead, ActMutate: programMutate},
+	// datos del discípulo que gestiona su coach (requisitos de check-in, overrides)
+	ResDisciple:   {ActRead: discipleRead, ActMutate: discipleRead},
+	ResAssignment: {ActRead: discipleRead, ActMutate: discipleRead, ActExecute: ruleSelf},
+	ResSession:    {ActRead: discipleRead, ActMutate: ruleSelf, ActExecute: ruleSelf},
+	ResSet:        {ActRead: discipleRead, ActMutate: ruleSelf},
+	ResCheckin:    {ActRead: discipleRead, ActMutate: ruleSelf, ActExecute: ruleCoachOfOther},
+}
+
+// ---- authorizer ----
+
+type Authorizer struct {
+	store PolicyStore
+	audit AuditSink
+}
+
+// NewAuthorizer: audit puede ser nil.
+func NewAuthorizer(store PolicyStore, audit AuditSink) *Authorizer {
+	return &Authorizer{store: store, audit: audit}
+}
+
+// Check evalúa la política de (res, act) sobre el recurso id.
+func (a *Authorizer) Check(ctx context.Context, actorID string, res Resource, id string, act Action) (Decision, error) {
+	return a.check(ctx, actorID, res, id, act, "")
+}
+
+func (a *Authorizer) check(ctx context.Context, actorID string, res Resource, id string, act Action, route string) (Decision, error) {
+	d := Decision{At: time.Now(), ActorID: actorID, Resource: res, ResourceID: id, Action: act, Route: route}
+	err := a.decide(ctx, &d)
+	if err != nil {
+		return d, err
+	}
+	if a.audit != nil {
+		a.audit.Record(d)
+	}
+	return d, nil
+}
+
+func (a *Authorizer) decide(ctx context.Context, d *Decision) error {
+	if d.ActorID == "" {
+		d.Reason = ReasonAnonymous
+		return nil
+	}
+	r, ok := policies[d.Resource][d.Action]
+	if !ok {
+		d.Reason = ReasonNoPolicy
+		return nil
+	}
+	if d.ResourceID == "" {
+		d.Reason = ReasonNotFound
+		return nil
+	}
+	t, err := a.store.Target(ctx, d.Resource, d.ResourceID)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		d.Reason = ReasonNotFound
+		return nil
+	}
+	e := &evalCtx{ctx: ctx, store: a.store, actorID: d.ActorID, target: t}
+	allowed, err := r.eval(e)
+	if err != nil {
+		return err
+	}
+	d.Allowed = allowed
+	if allowed {
+		d.Rule = e.matched
+		if d.Rule == "" {
+			d.Rule = r.Name
+		}
+	} else {
+		d.Reason = ReasonNoMatch
+	}
+	return nil
+}
+
+var authorizer atomic.Pointer[Authorizer]
+
+// UseAuthorizer fija el Authorizer de Authorize y Can.
+func UseAuthorizer(a *Authorizer) { authorizer.Store(a) }
+
+var errNoAuthorizer = errors.New("authorizer not configured")
+
+// Can: chequeo dentro de un handler (ids que llegan en el body o en la query).
+func Can(c *gin.Context, res Resource, id string, act Action) (bool, error) {
+	a := authorizer.Load()
+	if a == nil {
+		return false, errNoAuthorizer
+	}
+	d, err := a.check(c.Request.Context(), UserID(c), res, id, act, c.Request.Method+" "+c.FullPath())
+	return d.Allowed, err
+}
+
+// RoutePolicy: política de recurso de una ruta. Param es el path param con
+// el id; Missing, el status si el recurso no existe (por defecto 403, igual
+// que un recurso ajeno). El valor cero = solo autenticación.
+type RoutePolicy struct {
+	Resource Resource
+	Action   Action
+	Param    string
+	Missing  int
+}
+
+// RoutePolicies: "METHOD /ruta/completa/:param" → política.
+type RoutePolicies map[string]RoutePolicy
+
+// Authorize aplica la política declarada para la ruta. Una ruta sin entrada
+// se rechaza (falla cerrado); el valor cero es la excepción explícita.
+func Authorize(routes RoutePolicies) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		p, ok := routes[route]
+		if !ok {
+			log.Printf("[Authz] ruta sin política: %q", route)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+			return
+		}
+		if p.Resource == "" {
+			c.Next()
+			return
+		}
+		a := authorizer.Load()
+		if a == nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+			return
+		}
+		d, err := a.check(c.Request.Context(), UserID(c), p.Resource, c.Param(p.Param), p.Action, route)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+			return
+		}
+		if !d.Allowed {
+			if d.Reason == ReasonNotFound && p.Missing != 0 {
+				c.AbortWithStatusJSON(p.Missing, gin.H{"error": "not_found"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,88 @@
+package security
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SQLPolicyStore resuelve cada recurso con una sola consulta hasta su
+// programa o discípulo. Rol y vínculos pasan por RoleOf/IsCoachOf (y su caché).
+type SQLPolicyStore struct {
+	db *gorm.DB
+}
+
+func NewSQLPolicyStore(db *gorm.DB) *SQLPolicyStore {
+	return &SQLPolicyStore{db: db}
+}
+
+func (s *SQLPolicyStore) Role(ctx context.Context, userID string) (string, error) {
+	return RoleOf(s.db.WithContext(ctx), userID)
+}
+
+func (s *SQLPolicyStore) IsCoachOf(ctx context.Context, coachID, discipleID string) (bool, error) {
+	return IsCoachOf(s.db.WithContext(ctx), coachID, discipleID)
+}
+
+const programCols = "p.id AS program_id, p.owner_id AS program_owner, p.kind AS program_kind, p.visibility AS program_visibility"
+
+func (s *SQLPolicyStore) Target(ctx context.Context, res Resource, id string) (*Target, error) {
+	// ids mal formados no existen (y no llegan a Postgres como error de cast)
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, nil
+	}
+	db := s.db.WithContext(ctx)
+	var q *gorm.DB
+	switch res {
+	case ResProgram:
+		q = db.Table("programs AS p").Select(programCols).Where("p.id = ?", id)
+	case ResWeek:
+		q = db.Table("program_weeks AS w").Select(programCols).
+			Joins("JOIN programs p ON p.id = w.program_id").
+			Where("w.id = ?", id)
+	case ResDay:
+		q = db.Table("program_days AS d").Select(programCols).
+			Joins("JOIN program_weeks w ON w.id = d.week_id").
+			Joins("JOIN programs p ON p.id = w.program_id").
+			Where("d.id = ?", id)
+	case ResPrescription:
+		q = db.Table("prescriptions AS pr").Select(programCols).
+			Joins("JOIN program_days d ON d.id = pr.day_id").
+			Joins("JOIN program_weeks w ON w.id = d.week_id").
+			Joins("JOIN programs p ON p.id = w.program_id").
+			Where("pr.id = ?", id)
+	case ResDisciple:
+		// sin consulta: un usuario inexistente no es uno mismo ni está vinculado
+		return &Target{DiscipleID: id}, nil
+	case ResAssignment:
+		q = db.Table("assignments").Select("disciple_id").Where("id = ?", id)
+	case ResSession:
+		q = db.Table("session_logs").Select("disciple_id").Where("id = ?", id)
+	case ResSet:
+		q = db.Table("set_logs AS st").Select("s.disciple_id").
+			Joins("JOIN session_logs s ON s.id = st.session_id").
+			Where("st.id = ?", id)
+	case ResCheckin:
+		q = db.Table("checkins").Select("disciple_id").Where("id = ?", id)
+	default:
+		return nil, nil
+	}
+	var t Target
+	if err := q.Scan(&t).Error; err != nil {
+		return nil, err
+	}
+	if t.ProgramID == "" && t.DiscipleID == "" {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+func (s *SQLPolicyStore) ProgramAssigned(ctx context.Context, programID, actorID string) (bool, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Table("assignments AS a").
+		Joins("LEFT JOIN coach_links cl ON cl.disciple_id = a.disciple_id AND cl.coach_id = ? AND cl.status = 'accepted'", actorID).
+		Where("a.program_id = ? AND (a.disciple_id = ? OR cl.id IS NOT NULL)", programID, actorID).
+		Count(&count).Error
+	return count > 0, err
+}
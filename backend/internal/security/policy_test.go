@@ -0,0 +1,181 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+// memStore: PolicyStore en memoria para los tests de reglas.
+type memStore struct {
+	roles    map[string]string
+	links    map[string]bool // coach|disciple
+	targets  map[string]*Target
+	assigned map[string]bool // program|actor
+}
+
+func (m memStore) Role(_ context.Context, id string) (string, error) { return m.roles[id], nil }
+func (m memStore) IsCoachOf(_ context.Context, coach, disciple string) (bool, error) {
+	return m.links[coach+"|"+disciple], nil
+}
+func (m memStore) Target(_ context.Context, _ Resource, id string) (*Target, error) {
+	return m.targets[id], nil
+}
+func (m memStore) ProgramAssigned(_ context.Context, program, actor string) (bool, error) {
+	return m.assigned[program+"|"+actor], nil
+}
+
+func testStore() memStore {
+	return memStore{
+		roles: map[string]string{"coach-1": "coach", "coach-2": "coach", "disciple-1": "disciple", "disciple-2": "disciple"},
+		links: map[string]bool{"coach-1|disciple-1": true},
+		targets: map[string]*Target{
+			"coach-prog":    {ProgramID: "coach-prog", ProgramOwner: "coach-1", ProgramKind: "coach_program", ProgramVisibility: "private"},
+			"template":      {ProgramID: "template", ProgramOwner: "coach-2", ProgramKind: "coach_program", ProgramVisibility: "public"},
+			"self-prog":     {ProgramID: "self-prog", ProgramOwner: "disciple-1", ProgramKind: "self_training", ProgramVisibility: "private"},
+			"assigned-self": {ProgramID: "assigned-self", ProgramOwner: "disciple-1", ProgramKind: "self_training", ProgramVisibility: "private"},
+			"d1-thing":      {DiscipleID: "disciple-1"},
+		},
+		assigned: map[string]bool{
+			"coach-prog|disciple-1": true, "assigned-self|coach-1": true,
+		},
+	}
+}
+
+func TestAuthorizerPolicies(t *testing.T) {
+	a := NewAuthorizer(testStore(), nil)
+	cases := []struct {
+		name  string
+		actor string
+		res   Resource
+		id    string
+		act   Action
+		allow bool
+		rule  string
+		why   string
+	}{
+		{"owner reads program", "coach-1", ResProgram, "coach-prog", ActRead, true, "program_owner", ""},
+		{"assigned disciple reads program", "disciple-1", ResDay, "coach-prog", ActRead, true, "not_self_training&program_assigned", ""},
+		{"outsider cannot read program", "disciple-2", ResWeek, "coach-prog", ActRead, false, "", ReasonNoMatch},
+		{"public template readable", "disciple-2", ResPrescription, "template", ActRead, true, "public_template", ""},
+		{"template not mutable by others", "coach-1", ResProgram, "template", ActMutate, false, "", ReasonNoMatch},
+		{"coach mutates own coach_program", "coach-1", ResPrescription, "coach-prog", ActMutate, true, "program_editable", ""},
+		{"disciple cannot mutate assigned program", "disciple-1", ResDay, "coach-prog", ActMutate, false, "", ReasonNoMatch},
+		{"disciple mutates own self_training", "disciple-1", ResProgram, "self-prog", ActMutate, true, "program_editable", ""},
+		{"coach cannot mutate disciple self_training", "coach-1", ResProgram, "self-prog", ActMutate, false, "", ReasonNoMatch},
+		{"self_training private even if assigned", "coach-1", ResProgram, "assigned-self", ActRead, false, "", ReasonNoMatch},
+		{"only owner executes program", "disciple-1", ResProgram, "coach-prog", ActExecute, false, "", ReasonNoMatch},
+		{"disciple reads own session", "disciple-1", ResSession, "d1-thing", ActRead, true, "self", ""},
+		{"linked coach reads session", "coach-1", ResSession, "d1-thing", ActRead, true, "coach_of", ""},
+		{"linked coach cannot log sets", "coach-1", ResSession, "d1-thing", ActExecute, false, "", ReasonNoMatch},
+		{"foreign coach reads nothing", "coach-2", ResSet, "d1-thing", ActRead, false, "", ReasonNoMatch},
+		{"linked coach patches assignment", "coach-1", ResAssignment, "d1-thing", ActMutate, true, "coach_of", ""},
+		{"only disciple starts sessions", "coach-1", ResAssignment, "d1-thing", ActExecute, false, "", ReasonNoMatch},
+		{"coach reviews checkin", "coach-1", ResCheckin, "d1-thing", ActExecute, true, "coach_of_other", ""},
+		{"disciple cannot review own checkin", "disciple-1", ResCheckin, "d1-thing", ActExecute, false, "", ReasonNoMatch},
+		{"linked coach manages disciple", "coach-1", ResDisciple, "d1-thing", ActMutate, true, "coach_of", ""},
+		{"missing resource", "coach-1", ResSession, "nope", ActRead, false, "", ReasonNotFound},
+		{"undeclared action", "coach-1", ResSet, "d1-thing", ActExecute, false, "", ReasonNoPolicy},
+		{"anonymous", "", ResProgram, "template", ActRead, false, "", ReasonAnonymous},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := a.Check(context.Background(), tc.actor, tc.res, tc.id, tc.act)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Allowed != tc.allow || d.Rule != tc.rule || d.Reason != tc.why {
+				t.Fatalf("decision=%+v want allow=%v rule=%q reason=%q", d, tc.allow, tc.rule, tc.why)
+			}
+		})
+	}
+}
+
+func TestAuthorizeMiddlewareAudits(t *testing.T) {
+	var got []Decision
+	UseAuthorizer(NewAuthorizer(testStore(), AuditFunc(func(d Decision) { got = append(got, d) })))
+	t.Cleanup(func() { UseAuthorizer(nil) })
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	routes := RoutePolicies{
+		"GET /checkins/:id": {Resource: ResCheckin, Action: ActRead, Param: "id", Missing: http.StatusNotFound},
+		"GET /sessions/:id": {Resource: ResSession, Action: ActRead, Param: "id"},
+		"GET /open/:id":     {},
+	}
+	for _, p := range []string{"/checkins/:id", "/sessions/:id", "/open/:id", "/unlisted/:id"} {
+		r.GET(p, func(c *gin.Context) { c.Set(CtxUserID, "coach-2") }, Authorize(routes),
+			func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+
+	for _, tc := range []struct {
+		path string
+		want int
+	}{
+		{"/checkins/nope", http.StatusNotFound},
+		{"/sessions/nope", http.StatusForbidden},
+		{"/sessions/d1-thing", http.StatusForbidden},
+		{"/open/anything", http.StatusNoContent},
+		{"/unlisted/anything", http.StatusInternalServerError}, // sin política: falla cerrado
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if w.Code != tc.want {
+			t.Fatalf("%s status=%d want %d", tc.path, w.Code, tc.want)
+		}
+	}
+	if len(got) != 3 {
+		t.Fatalf("audited %d decisions, want 3", len(got))
+	}
+	if d := got[2]; d.Route != "GET /sessions/:id" || d.ActorID != "coach-2" || d.ResourceID != "d1-thing" || d.Allowed {
+		t.Fatalf("audit=%+v", d)
+	}
+}
+
+func TestSQLPolicyStoreTargets(t *testing.T) {
+	db, mock, cleanup := mockGorm(t)
+	defer cleanup()
+	s := NewSQLPolicyStore(db)
+	ctx := context.Background()
+	const dayID = "33333333-3333-4333-8333-333333333333"
+	const setID = "44444444-4444-4444-8444-444444444444"
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT p.id AS program_id, p.owner_id AS program_owner, p.kind AS program_kind, p.visibility AS program_visibility FROM program_days AS d JOIN program_weeks w ON w.id = d.week_id JOIN programs p ON p.id = w.program_id WHERE d.id = $1`)).
+		WithArgs(dayID).
+		WillReturnRows(sqlmock.NewRows([]string{"program_id", "program_owner", "program_kind", "program_visibility"}).
+			AddRow("program-1", "coach-1", "coach_program", "private"))
+	tg, err := s.Target(ctx, ResDay, dayID)
+	if err != nil || tg == nil || tg.ProgramID != "program-1" || tg.ProgramOwner != "coach-1" || tg.ProgramKind != "coach_program" {
+		t.Fatalf("day target=%+v err=%v", tg, err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT s.disciple_id FROM set_logs AS st JOIN session_logs s ON s.id = st.session_id WHERE st.id = $1`)).
+		WithArgs(setID).
+		WillReturnRows(sqlmock.NewRows([]string{"disciple_id"}))
+	tg, err = s.Target(ctx, ResSet, setID)
+	if err != nil || tg != nil {
+		t.Fatalf("missing set target=%+v err=%v", tg, err)
+	}
+
+	// ids que no son UUID no llegan a la DB
+	if tg, err := s.Target(ctx, ResSession, "not-a-uuid"); err != nil || tg != nil {
+		t.Fatalf("invalid id target=%+v err=%v", tg, err)
+	}
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM assignments AS a LEFT JOIN coach_links cl`).
+		WithArgs("coach-1", "program-1", "coach-1").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	ok, err := s.ProgramAssigned(ctx, "program-1", "coach-1")
+	if err != nil || !ok {
+		t.Fatalf("program assigned ok=%v err=%v", ok, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
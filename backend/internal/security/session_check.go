@@ -0,0 +1,28 @@
+package security
+
+import (
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+var sessionDB atomic.Pointer[gorm.DB]
+
+// UseSessionCheck hace que AuthRequired rechace un access token cuya sesión
+// de refresh (claim sid) fue revocada, p. ej. tras cambiar o resetear la
+// password. nil lo desactiva: el access vence solo, en AccessTTL.
+func UseSessionCheck(db *gorm.DB) { sessionDB.Store(db) }
+
+// SessionActive: la sesión existe y no está revocada (con caché si hay AuthzCache).
+func SessionActive(db *gorm.DB, sid string) (bool, error) {
+	if a := authz.Load(); a != nil {
+		return a.sessionActive(db, sid)
+	}
+	return sessionActiveFromDB(db, sid)
+}
+
+func sessionActiveFromDB(db *gorm.DB, sid string) (bool, error) {
+	var n int64
+	err := db.Table("auth_sessions").Where("id = ? AND revoked_at IS NULL", sid).Count(&n).Error
+	return n > 0, err
+}
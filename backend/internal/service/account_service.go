@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/notify"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeEmailVerify   = "email_verify"
+)
+
+var (
+	PasswordResetTTL = time.Hour
+	EmailVerifyTTL   = 48 * time.Hour
+)
+
+var (
+	ErrActionTokenInvalid = errors.New("invalid_token")
+	// token bien firmado pero ya consumido (la password o el email cambiaron)
+	ErrActionTokenUsed = errors.New("token_used")
+	ErrAlreadyVerified = errors.New("email_already_verified")
+	ErrUserNotFound    = errors.New("user_not_found")
+)
+
+// ActionTokens firma/valida tokens de acción de cuenta (security.ActionTokens).
+type ActionTokens interface {
+	Sign(purpose, userID, binding string, ttl time.Duration) (string, error)
+	Parse(purpose, token string) (userID, binding string, err error)
+}
+
+// AccountService: reset de password y verificación de email. Los tokens no
+// se guardan: quedan atados a la huella del hash de password / al email, así
+// que usar uno cambia ese estado y lo invalida (uso único).
+type AccountService interface {
+	RequestPasswordReset(ctx context.Context, email string) error
+	// ResetPassword recibe el hash nuevo ya calculado; revoca todas las sesiones.
+	ResetPassword(ctx context.Context, token, newHash string) error
+	// ChangePassword: el llamador ya validó la password actual; conserva keepSessionID.
+	ChangePassword(ctx context.Context, userID, newHash, keepSessionID string) error
+	SendVerification(ctx context.Context, userID string) error
+	VerifyEmail(ctx context.Context, token string) error
+}
+
+type accountService struct {
+	users    repository.UserRepository
+	sessions AuthSessionService
+	tokens   ActionTokens
+	notify   Notifier
+	baseURL  string // front: <base>/reset-password?token=... (opcional)
+}
+
+func NewAccountService(users repository.UserRepository, sessions AuthSessionService, tokens ActionTokens, notifier Notifier, baseURL string) AccountService {
+	return &accountService{
+		users:    users,
+		sessions: sessions,
+		tokens:   tokens,
+		notify:   notifier,
+		baseURL:  strings.TrimRight(baseURL, "/"),
+	}
+}
+
+// passwordBinding: huella corta del hash actual (no expone el hash en el token).
+func passwordBinding(hash string) string {
+	sum := sha256.Sum256([]byte(hash))
+	return hex.EncodeToString(sum[:8])
+}
+
+func (s *accountService) link(path, token string) string {
+	if s.baseURL == "" {
+		return token
+	}
+	return s.baseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+func (s *accountService) RequestPasswordReset(ctx context.Context, email string) error {
+	u, err := s.users.FindByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	if err != nil {
+		// email desconocido: misma respuesta para no revelar cuentas
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	tok, err := s.tokens.Sign(PurposePasswordReset, u.ID, passwordBinding(u.PasswordHash), PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+	emit(ctx, s.notify, NotifyEvent{
+		Kind:    notify.EventPasswordReset,
+		ActorID: u.ID,
+		Data:    map[string]string{"reset_url": s.link("/reset-password", tok), "ttl": PasswordResetTTL.String()},
+	})
+	return nil
+}
+
+func (s *accountService) ResetPassword(ctx context.Context, token, newHash string) error {
+	userID, binding, err := s.tokens.Parse(PurposePasswordReset, token)
+	if err != nil {
+		return ErrActionTokenInvalid
+	}
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrActionTokenInvalid
+		}
+		return err
+	}
+	if binding != passwordBinding(u.PasswordHash) {
+		return ErrActionTokenUsed
+	}
+	// condicional sobre el hash verificado: dos resets simultáneos con el
+	// mismo token no pueden ganar ambos
+	ok, err := s.users.ReplacePassword(ctx, u.ID, u.PasswordHash, newHash)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrActionTokenUsed
+	}
+	_, err = s.sessions.RevokeOthers(ctx, u.ID, "")
+	return err
+}
+
+func (s *accountService) ChangePassword(ctx context.Context, userID, newHash, keepSessionID string) error {
+	if err := s.users.UpdatePassword(ctx, userID, newHash); err != nil {
+		return err
+	}
+	_, err := s.sessions.RevokeOthers(ctx, userID, keepSessionID)
+	return err
+}
+
+func (s *accountService) SendVerification(ctx context.Context, userID string) error {
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if u.EmailVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+	tok, err := s.tokens.Sign(PurposeEmailVerify, u.ID, strings.ToLower(u.Email), EmailVerifyTTL)
+	if err != nil {
+		return err
+	}
+	emit(ctx, s.notify, NotifyEvent{
+		Kind:    notify.EventEmailVerification,
+		ActorID: u.ID,
+		Data:    map[string]string{"verify_url": s.link("/verify-email", tok), "ttl": EmailVerifyTTL.String()},
+	})
+	return nil
+}
+
+func (s *accountService) VerifyEmail(ctx context.Context, token string) error {
+	userID, binding, err := s.tokens.Parse(PurposeEmailVerify, token)
+	if err != nil {
+		return ErrActionTokenInvalid
+	}
+	u, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrActionTokenInvalid
+		}
+		return err
+	}
+	if binding != strings.ToLower(u.Email) || u.EmailVerifiedAt != nil {
+		return ErrActionTokenUsed
+	}
+	return s.users.MarkEmailVerified(ctx, u.ID, time.Now())
+}
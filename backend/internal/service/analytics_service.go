@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/strength"
+	"github.com/vicepalma/roma-system/backend/internal/timing"
+)
+
+var ErrInvalidFormula = errors.New("invalid_formula")
+
+// AnalyticsService: curvas de fuerza (e1RM) por ejercicio a partir de set_logs
+// y cumplimiento de descansos por prescripción.
+type AnalyticsService interface {
+	Strength(ctx context.Context, discipleID string, q StrengthQuery) (*StrengthReport, error)
+	Rest(ctx context.Context, discipleID string, q RestQuery) (*RestReport, error)
+}
+
+type StrengthQuery struct {
+	ExerciseID *string
+	From, To   *time.Time
+	Formula    string // epley (default) | brzycki
+	TZ         string
+}
+
+type StrengthReport struct {
+	Formula   strength.Formula   `json:"formula"`
+	Exercises []ExerciseStrength `json:"exercises"`
+}
+
+type ExerciseStrength struct {
+	ExerciseID   string            `json:"exercise_id"`
+	ExerciseName string            `json:"exercise_name"`
+	Sets         int               `json:"sets"`
+	Best         *strength.Point   `json:"best,omitempty"`   // mayor e1RM del rango
+	Latest       *strength.Point   `json:"latest,omitempty"` // último día con series
+	Trend        *strength.Trend   `json:"trend,omitempty"`  // nil con menos de dos días
+	RepMaxes     []strength.RepMax `json:"rep_maxes"`
+	Series       []strength.Point  `json:"series"`
+}
+
+type RestQuery struct {
+	ExerciseID *string
+	From, To   *time.Time
+	TZ         string
+}
+
+type RestReport struct {
+	Sessions      int              `json:"sessions"` // sesiones con series cronometradas
+	Prescriptions []RestCompliance `json:"prescriptions"`
+}
+
+// RestCompliance: descanso real contra rest_sec de una prescripción.
+type RestCompliance struct {
+	timing.Compliance
+	ExerciseID   string `json:"exercise_id"`
+	ExerciseName string `json:"exercise_name"`
+}
+
+// TimelineItem: una serie de la línea de tiempo con su ejercicio.
+type TimelineItem struct {
+	timing.Entry
+	ExerciseID   string `json:"exercise_id"`
+	ExerciseName string `json:"exercise_name"`
+}
+
+type analyticsService struct{ repo repository.HistoryRepository }
+
+func NewAnalyticsService(r repository.HistoryRepository) AnalyticsService {
+	return &analyticsService{repo: r}
+}
+
+func (s *analyticsService) Strength(ctx context.Context, discipleID string, q StrengthQuery) (*StrengthReport, error) {
+	f, ok := strength.ParseFormula(q.Formula)
+	if !ok {
+		return nil, ErrInvalidFormula
+	}
+	rows, err := s.repo.StrengthSets(ctx, discipleID, normTZ(q.TZ).String(), q.ExerciseID, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &StrengthReport{Formula: f, Exercises: []ExerciseStrength{}}
+	// rows viene agrupado por ejercicio
+	for i := 0; i < len(rows); {
+		j := i
+		sets := []strength.Set{}
+		for ; j < len(rows) && rows[j].ExerciseID == rows[i].ExerciseID; j++ {
+			st := strength.Set{Date: rows[j].Date, Weight: rows[j].Weight, Reps: rows[j].Reps}
+			if rows[j].RPE != nil {
+				v := float64(*rows[j].RPE)
+				st.RPE = &v
+			}
+			sets = append(sets, st)
+		}
+		ex := ExerciseStrength{
+			ExerciseID:   rows[i].ExerciseID,
+			ExerciseName: rows[i].ExerciseName,
+			Sets:         len(sets),
+			RepMaxes:     strength.RepMaxes(sets),
+			Series:       strength.Series(f, sets),
+		}
+		if n := len(ex.Series); n > 0 {
+			latest := ex.Series[n-1]
+			ex.Latest = &latest
+			best := ex.Series[0]
+			for _, p := range ex.Series[1:] {
+				if p.E1RM > best.E1RM {
+					best = p
+				}
+			}
+			ex.Best = &best
+		}
+		if t, ok := strength.Slope(ex.Series); ok {
+			ex.Trend = &t
+		}
+		out.Exercises = append(out.Exercises, ex)
+		i = j
+	}
+	return out, nil
+}
+
+func (s *analyticsService) Rest(ctx context.Context, discipleID string, q RestQuery) (*RestReport, error) {
+	rows, err := s.repo.TimedSets(ctx, discipleID, normTZ(q.TZ).String(), q.ExerciseID, q.From, q.To)
+	if err != nil {
+		return nil, err
+	}
+	_, rest, sessions := timeline(rows, q.ExerciseID)
+	return &RestReport{Sessions: sessions, Prescriptions: rest}, nil
+}
+
+// timeline arma la línea de tiempo de cada sesión (rows viene agrupado por
+// sesión) y el cumplimiento de descansos del conjunto. exerciseID filtra el
+// resultado después de armar la línea completa: en un superset A1, B1, A2 el
+// descanso previo a A2 se mide desde B1, no desde A1.
+func timeline(rows []repository.TimedSetRow, exerciseID *string) ([]TimelineItem, []RestCompliance, int) {
+	exercise := map[string]repository.TimedSetRow{} // por prescripción
+	entries := []timing.Entry{}
+	sessions := 0
+	for i := 0; i < len(rows); {
+		j := i
+		sets := []timing.Set{}
+		for ; j < len(rows) && rows[j].SessionID == rows[i].SessionID; j++ {
+			r := rows[j]
+			exercise[r.PrescriptionID] = r
+			sets = append(sets, timing.Set{
+				ID: r.SetID, PrescriptionID: r.PrescriptionID, SetIndex: r.SetIndex, SetType: r.SetType,
+				StartedAt: r.StartedAt, CompletedAt: r.CompletedAt, RestTargetSec: r.RestSec,
+			})
+		}
+		entries = append(entries, timing.Timeline(sets)...)
+		sessions++
+		i = j
+	}
+
+	keep := func(prescriptionID string) bool {
+		return exerciseID == nil || exercise[prescriptionID].ExerciseID == *exerciseID
+	}
+	items := make([]TimelineItem, 0, len(entries))
+	for _, e := range entries {
+		if !keep(e.PrescriptionID) {
+			continue
+		}
+		ex := exercise[e.PrescriptionID]
+		items = append(items, TimelineItem{Entry: e, ExerciseID: ex.ExerciseID, ExerciseName: ex.ExerciseName})
+	}
+	rest := []RestCompliance{}
+	for _, c := range timing.Summarize(entries) {
+		if !keep(c.PrescriptionID) {
+			continue
+		}
+		ex := exercise[c.PrescriptionID]
+		rest = append(rest, RestCompliance{Compliance: c, ExerciseID: ex.ExerciseID, ExerciseName: ex.ExerciseName})
+	}
+	return items, rest, sessions
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+)
+
+func TestTimelineFiltersAfterSuperset(t *testing.T) {
+	t0 := time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+	at := func(sec int) *time.Time {
+		v := t0.Add(time.Duration(sec) * time.Second)
+		return &v
+	}
+	rest := 30
+	set := func(id, pr, ex string, idx, start int) repository.TimedSetRow {
+		row := repository.TimedSetRow{SessionID: "s1", SetID: id, PrescriptionID: pr, ExerciseID: ex,
+			SetIndex: idx, StartedAt: at(start), CompletedAt: at(start + 40)}
+		if pr == "pa" {
+			row.RestSec = &rest
+		}
+		return row
+	}
+	// superset A1, B1, A2 y luego A3 tras un descanso normal
+	rows := []repository.TimedSetRow{
+		set("a1", "pa", "ex-a", 1, 0),
+		set("a2", "pa", "ex-a", 2, 130),
+		set("a3", "pa", "ex-a", 3, 200),
+		set("b1", "pb", "ex-b", 1, 60),
+	}
+	exA := "ex-a"
+	items, comp, sessions := timeline(rows, &exA)
+	if sessions != 1 || len(items) != 3 {
+		t.Fatalf("sessions=%d items=%+v", sessions, items)
+	}
+	// A2 descansa desde B1 (30 s), no desde A1
+	if a2 := items[1]; a2.ID != "a2" || *a2.RestSec != 30 || a2.SamePrescription {
+		t.Fatalf("a2=%+v", a2)
+	}
+	// sólo A2→A3 cuenta para el cumplimiento de A
+	if len(comp) != 1 || comp[0].ExerciseID != "ex-a" || comp[0].Intervals != 1 || comp[0].AvgSec != 30 {
+		t.Fatalf("compliance=%+v", comp)
+	}
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefreshInvalid  = repository.ErrRefreshInvalid
+	ErrRefreshReused   = repository.ErrRefreshReused
+	ErrSessionNotFound = errors.New("session_not_found")
+)
+
+// RefreshTTL: vida de una sesión sin uso; cada refresh la extiende.
+// main la ajusta con security.RefreshTTL() (REFRESH_TTL_H).
+var RefreshTTL = 168 * time.Hour
+
+// ClientMeta: datos del dispositivo para listar sesiones.
+type ClientMeta struct {
+	UserAgent string
+	IP        string
+}
+
+// IssuedSession: refresh opaco (solo se guarda su hash) y la sesión que lo emitió.
+// El access token lo firma la capa http con SessionID como claim sid.
+type IssuedSession struct {
+	SessionID    string
+	UserID       string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+type AuthSessionService interface {
+	Start(ctx context.Context, userID string, meta ClientMeta) (*IssuedSession, error)
+	Refresh(ctx context.Context, refreshToken string, meta ClientMeta) (*IssuedSession, error)
+	// Logout revoca la sesión del refresh dado; un token desconocido no es error.
+	Logout(ctx context.Context, refreshToken string) error
+	List(ctx context.Context, userID string) ([]repository.AuthSession, error)
+	Revoke(ctx context.Context, userID, sessionID string) error
+	RevokeOthers(ctx context.Context, userID, keepID string) (int64, error)
+	Purge(ctx context.Context) (int64, error)
+}
+
+type authSessionService struct {
+	repo repository.AuthSessionRepository
+}
+
+func NewAuthSessionService(repo repository.AuthSessionRepository) AuthSessionService {
+	return &authSessionService{repo: repo}
+}
+
+func newRefreshToken() (raw, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw = base64.RawURLEncoding.EncodeToString(b)
+	return raw, hashRefreshToken(raw), nil
+}
+
+func hashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func optMeta(v string, max int) *string {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil
+	}
+	if len(v) > max {
+		v = v[:max]
+	}
+	return &v
+}
+
+func (s *authSessionService) Start(ctx context.Context, userID string, meta ClientMeta) (*IssuedSession, error) {
+	if userID == "" {
+		return nil, errors.New("user_id required")
+	}
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	sess := &repository.AuthSession{
+		UserID:    userID,
+		UserAgent: optMeta(meta.UserAgent, 256),
+		IP:        optMeta(meta.IP, 64),
+		ExpiresAt: time.Now().Add(RefreshTTL),
+	}
+	if err := s.repo.Create(ctx, sess, hash); err != nil {
+		return nil, err
+	}
+	return &IssuedSession{SessionID: sess.ID, UserID: userID, RefreshToken: raw, ExpiresAt: sess.ExpiresAt}, nil
+}
+
+func (s *authSessionService) Refresh(ctx context.Context, refreshToken string, meta ClientMeta) (*IssuedSession, error) {
+	if strings.TrimSpace(refreshToken) == "" {
+		return nil, ErrRefreshInvalid
+	}
+	raw, hash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	exp := time.Now().Add(RefreshTTL)
+	sess, err := s.repo.Rotate(ctx, hashRefreshToken(refreshToken), hash, exp,
+		optMeta(meta.UserAgent, 256), optMeta(meta.IP, 64))
+	if err != nil {
+		if errors.Is(err, ErrRefreshReused) {
+			log.Printf("[Refresh] reuso de refresh token detectado; sesión revocada")
+		}
+		return nil, err
+	}
+	return &IssuedSession{SessionID: sess.ID, UserID: sess.UserID, RefreshToken: raw, ExpiresAt: exp}, nil
+}
+
+func (s *authSessionService) Logout(ctx context.Context, refreshToken string) error {
+	sess, err := s.repo.FindByToken(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := s.repo.Revoke(ctx, sess.UserID, sess.ID, repository.RevokeLogout); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *authSessionService) List(ctx context.Context, userID string) ([]repository.AuthSession, error) {
+	return s.repo.ListActive(ctx, userID)
+}
+
+func (s *authSessionService) Revoke(ctx context.Context, userID, sessionID string) error {
+	if err := s.repo.Revoke(ctx, userID, sessionID, repository.RevokeManual); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *authSessionService) RevokeOthers(ctx context.Context, userID, keepID string) (int64, error) {
+	return s.repo.RevokeAllExcept(ctx, userID, keepID, repository.RevokeManual)
+}
+
+// Purge: las sesiones se guardan una semana tras vencer/revocarse (auditoría
+// de reuso) y luego se borran junto con sus tokens.
+func (s *authSessionService) Purge(ctx context.Context) (int64, error) {
+	n, err := s.repo.Purge(ctx, time.Now().Add(-7*24*time.Hour))
+	if err != nil {
+		log.Printf("[PurgeSessions] error: %v", err)
+		return 0, err
+	}
+	return n, nil
+}
@@ -1,51 +1,406 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/vicepalma/roma-system/backend/internal/blobstore"
+	"github.com/vicepalma/roma-system/backend/internal/bodymetrics"
 	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/notify"
+	"github.com/vicepalma/roma-system/backend/internal/photo"
 	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"gorm.io/gorm"
 )
 
-var ErrInvalidCheckin = errors.New("invalid_checkin")
+var (
+	ErrInvalidCheckin  = errors.New("invalid_checkin")
+	ErrInvalidMetric   = errors.New("invalid_metric")
+	ErrMissingMetrics  = errors.New("missing_required_metrics")
+	ErrNotCheckinOwner = errors.New("not_checkin_owner")
+	ErrPhotoLimit      = errors.New("photo_limit_reached")
+	ErrPhotoNotFound   = errors.New("photo_not_found")
+	ErrPhotosDisabled  = errors.New("photo_storage_disabled")
+	ErrEditWindow      = errors.New("edit_window_closed")
+	ErrInvalidReview   = errors.New("invalid_review_status")
+)
+
+// CheckinEditWindow: plazo desde la creación para que el discípulo edite o
+// borre (incluye fotos). Configurable con CHECKIN_EDIT_WINDOW_H.
+var CheckinEditWindow = 72 * time.Hour
+
+// Fotos por check-in.
+const MaxCheckinPhotos = 6
+
+// CreateCheckin: campos opcionales salvo la fecha; las métricas que exija el
+// coach pasan a ser obligatorias. En Update, nil / fecha cero = sin cambio.
+type CreateCheckin struct {
+	CheckedAt  time.Time
+	WeightKG   *float64
+	Notes      *string
+	WaistCM    *float64
+	ChestCM    *float64
+	ArmCM      *float64
+	ThighCM    *float64
+	BodyFatPct *float64
+	RestingHR  *int
+	SleepHours *float64
+	Energy     *int
+	Stress     *int
+	Soreness   *int
+}
+
+type CheckinTrends struct {
+	Window  int                          `json:"window_days"`
+	Metrics map[string]bodymetrics.Trend `json:"metrics"`
+}
 
 type CheckinService interface {
-	Create(ctx context.Context, discipleID string, checkedAt time.Time, weightKG *float64, notes *string) (*domain.Checkin, error)
-	List(ctx context.Context, discipleID string, limit, offset int) ([]domain.Checkin, int64, error)
+	Create(ctx context.Context, discipleID string, in CreateCheckin) (*domain.Checkin, error)
+	List(ctx context.Context, discipleID string, f repository.CheckinFilter, limit, offset int) ([]domain.Checkin, int64, error)
 	Get(ctx context.Context, id string) (*domain.Checkin, error)
+	Update(ctx context.Context, userID, id string, in CreateCheckin) (*domain.Checkin, error)
+	Delete(ctx context.Context, userID, id string) error
+
+	// Review: el acceso del coach al discípulo se valida en el handler.
+	Review(ctx context.Context, coachID, id, status string, comment *string) (*domain.Checkin, error)
+	Inbox(ctx context.Context, coachID string, f repository.CheckinFilter, limit, offset int) ([]repository.InboxCheckin, int64, error)
+
+	Requirements(ctx context.Context, discipleID string) (*repository.CheckinRequirement, error)
+	SetRequirements(ctx context.Context, coachID, discipleID string, metrics []string) (*repository.CheckinRequirement, error)
+	Trends(ctx context.Context, discipleID string, metrics []string, window int, from, to *time.Time) (*CheckinTrends, error)
+
+	// Fotos: solo el discípulo dueño del check-in sube o borra.
+	AddPhoto(ctx context.Context, userID, checkinID string, r io.Reader) (*domain.CheckinPhoto, error)
+	DeletePhoto(ctx context.Context, userID, checkinID, photoID string) error
+	OpenBlob(ctx context.Context, key string) (io.ReadCloser, error)
 }
 
-type checkinService struct{ repo repository.CheckinRepository }
+type checkinService struct {
+	db     *gorm.DB
+	repo   repository.CheckinRepository
+	blobs  blobstore.Store
+	notify Notifier
+}
 
-func NewCheckinService(repo repository.CheckinRepository) CheckinService {
-	return &checkinService{repo: repo}
+// NewCheckinService: con blobs nil las fotos quedan deshabilitadas; con
+// notifier nil no se avisa al coach.
+func NewCheckinService(repo repository.CheckinRepository, db *gorm.DB, blobs blobstore.Store, notifier Notifier) CheckinService {
+	return &checkinService{db: db, repo: repo, blobs: blobs, notify: notifier}
 }
 
-func (s *checkinService) Create(ctx context.Context, discipleID string, checkedAt time.Time, weightKG *float64, notes *string) (*domain.Checkin, error) {
-	if discipleID == "" || checkedAt.IsZero() {
-		return nil, ErrInvalidCheckin
-	}
-	if weightKG != nil && *weightKG <= 0 {
+func (s *checkinService) Create(ctx context.Context, discipleID string, in CreateCheckin) (*domain.Checkin, error) {
+	if discipleID == "" || in.CheckedAt.IsZero() {
 		return nil, ErrInvalidCheckin
 	}
-	checkin := &domain.Checkin{
-		DiscipleID: discipleID,
-		CheckedAt:  checkedAt,
-		WeightKG:   weightKG,
-		Notes:      notes,
+	checkin := &domain.Checkin{DiscipleID: discipleID}
+	applyCheckin(checkin, in)
+	if err := s.validate(ctx, checkin); err != nil {
+		return nil, err
 	}
-	if err := s.repo.Create(ctx, checkin); err != nil {
+	// el check-in y el aviso al coach en la misma transacción (outbox)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.repo.WithTx(tx).Create(ctx, checkin); err != nil {
+			return err
+		}
+		return emitTx(ctx, tx, s.notify, NotifyEvent{
+			Kind:       notify.EventCheckinSubmitted,
+			ActorID:    discipleID,
+			DiscipleID: discipleID,
+			Data: map[string]string{
+				"checkin_id": checkin.ID,
+				"checked_at": checkin.CheckedAt.Format("2006-01-02"),
+			},
+		})
+	})
+	if err != nil {
 		return nil, err
 	}
+	checkin.Photos = []domain.CheckinPhoto{}
 	return checkin, nil
 }
 
-func (s *checkinService) List(ctx context.Context, discipleID string, limit, offset int) ([]domain.Checkin, int64, error) {
-	return s.repo.ListByDisciple(ctx, discipleID, limit, offset)
+// applyCheckin copia lo informado (no nil / fecha no cero) sobre el check-in.
+func applyCheckin(c *domain.Checkin, in CreateCheckin) {
+	if !in.CheckedAt.IsZero() {
+		c.CheckedAt = in.CheckedAt
+	}
+	set := func(dst **float64, v *float64) {
+		if v != nil {
+			*dst = v
+		}
+	}
+	seti := func(dst **int, v *int) {
+		if v != nil {
+			*dst = v
+		}
+	}
+	if in.Notes != nil {
+		c.Notes = in.Notes
+	}
+	set(&c.WeightKG, in.WeightKG)
+	set(&c.WaistCM, in.WaistCM)
+	set(&c.ChestCM, in.ChestCM)
+	set(&c.ArmCM, in.ArmCM)
+	set(&c.ThighCM, in.ThighCM)
+	set(&c.BodyFatPct, in.BodyFatPct)
+	seti(&c.RestingHR, in.RestingHR)
+	set(&c.SleepHours, in.SleepHours)
+	seti(&c.Energy, in.Energy)
+	seti(&c.Stress, in.Stress)
+	seti(&c.Soreness, in.Soreness)
+}
+
+// validate: rangos de cada métrica y las que exige el coach.
+func (s *checkinService) validate(ctx context.Context, c *domain.Checkin) error {
+	values := c.Metrics()
+	for k, v := range values {
+		if d, _ := bodymetrics.Lookup(k); !d.Valid(v) {
+			if k == bodymetrics.WeightKG {
+				return ErrInvalidCheckin
+			}
+			return fmt.Errorf("%w: %s", ErrInvalidMetric, k)
+		}
+	}
+
+	req, err := s.repo.GetRequirement(ctx, c.DiscipleID)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, k := range req.Metrics {
+		if _, ok := values[k]; !ok {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrMissingMetrics, strings.Join(missing, ","))
+	}
+	return nil
+}
+
+func (s *checkinService) List(ctx context.Context, discipleID string, f repository.CheckinFilter, limit, offset int) ([]domain.Checkin, int64, error) {
+	return s.repo.ListByDisciple(ctx, discipleID, f, limit, offset)
 }
 
 func (s *checkinService) Get(ctx context.Context, id string) (*domain.Checkin, error) {
 	return s.repo.FindByID(ctx, id)
 }
+
+// owned: check-in del usuario y todavía dentro del plazo de edición.
+func (s *checkinService) owned(ctx context.Context, userID, id string) (*domain.Checkin, error) {
+	c, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.DiscipleID != userID {
+		return nil, ErrNotCheckinOwner
+	}
+	if time.Since(c.CreatedAt) > CheckinEditWindow {
+		return nil, ErrEditWindow
+	}
+	return c, nil
+}
+
+// Update: si el coach ya lo había revisado vuelve a pending para que lo vea otra vez.
+func (s *checkinService) Update(ctx context.Context, userID, id string, in CreateCheckin) (*domain.Checkin, error) {
+	c, err := s.owned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	applyCheckin(c, in)
+	if err := s.validate(ctx, c); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, c, c.ReviewStatus == domain.CheckinReviewed); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (s *checkinService) Delete(ctx context.Context, userID, id string) error {
+	c, err := s.owned(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if s.blobs != nil {
+		for _, p := range c.Photos {
+			s.deleteBlobs(p)
+		}
+	}
+	return nil
+}
+
+func (s *checkinService) Review(ctx context.Context, coachID, id, status string, comment *string) (*domain.Checkin, error) {
+	if status == "" {
+		status = domain.CheckinReviewed
+	}
+	if status != domain.CheckinReviewed && status != domain.CheckinPending {
+		return nil, ErrInvalidReview
+	}
+	return s.repo.SetReview(ctx, id, coachID, status, comment)
+}
+
+func (s *checkinService) Inbox(ctx context.Context, coachID string, f repository.CheckinFilter, limit, offset int) ([]repository.InboxCheckin, int64, error) {
+	return s.repo.Inbox(ctx, coachID, f, limit, offset)
+}
+
+func (s *checkinService) Requirements(ctx context.Context, discipleID string) (*repository.CheckinRequirement, error) {
+	return s.repo.GetRequirement(ctx, discipleID)
+}
+
+func (s *checkinService) SetRequirements(ctx context.Context, coachID, discipleID string, metrics []string) (*repository.CheckinRequirement, error) {
+	keys, err := metricKeys(metrics)
+	if err != nil {
+		return nil, err
+	}
+	req := &repository.CheckinRequirement{DiscipleID: discipleID, Metrics: pq.StringArray(keys), UpdatedBy: &coachID}
+	if err := s.repo.SaveRequirement(ctx, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Trends: media móvil de `window` días por métrica. Sin métricas pedidas
+// devuelve todas las que tengan datos.
+func (s *checkinService) Trends(ctx context.Context, discipleID string, metrics []string, window int, from, to *time.Time) (*CheckinTrends, error) {
+	if window <= 0 {
+		window = 28
+	}
+	if window > 365 {
+		window = 365
+	}
+	keys, err := metricKeys(metrics)
+	if err != nil {
+		return nil, err
+	}
+	explicit := len(keys) > 0
+	if !explicit {
+		for _, d := range bodymetrics.All {
+			keys = append(keys, d.Key)
+		}
+	}
+	series, err := s.repo.MetricSeries(ctx, discipleID, keys, from, to)
+	if err != nil {
+		return nil, err
+	}
+	out := &CheckinTrends{Window: window, Metrics: map[string]bodymetrics.Trend{}}
+	for _, k := range keys {
+		if len(series[k]) == 0 && !explicit {
+			continue
+		}
+		out.Metrics[k] = bodymetrics.MovingAverage(series[k], window)
+	}
+	return out, nil
+}
+
+// metricKeys valida y deduplica claves de métricas, en el orden del catálogo.
+func metricKeys(metrics []string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, m := range metrics {
+		m = strings.ToLower(strings.TrimSpace(m))
+		if m == "" {
+			continue
+		}
+		if _, ok := bodymetrics.Lookup(m); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMetric, m)
+		}
+		seen[m] = true
+	}
+	out := make([]string, 0, len(seen))
+	for _, d := range bodymetrics.All {
+		if seen[d.Key] {
+			out = append(out, d.Key)
+		}
+	}
+	return out, nil
+}
+
+func (s *checkinService) ownCheckin(ctx context.Context, userID, checkinID string) (*domain.Checkin, error) {
+	if s.blobs == nil {
+		return nil, ErrPhotosDisabled
+	}
+	return s.owned(ctx, userID, checkinID)
+}
+
+func (s *checkinService) AddPhoto(ctx context.Context, userID, checkinID string, r io.Reader) (*domain.CheckinPhoto, error) {
+	c, err := s.ownCheckin(ctx, userID, checkinID)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.Photos) >= MaxCheckinPhotos {
+		return nil, ErrPhotoLimit
+	}
+	img, err := photo.Process(r)
+	if err != nil {
+		return nil, err
+	}
+
+	id := uuid.NewString()
+	base := "checkins/" + c.DiscipleID + "/" + c.ID + "/" + id
+	p := domain.CheckinPhoto{
+		ID:          id,
+		Key:         base + ".jpg",
+		ThumbKey:    base + "_thumb.jpg",
+		ContentType: photo.ContentJPG,
+		Width:       img.Width,
+		Height:      img.Height,
+		UploadedAt:  time.Now().UTC(),
+	}
+	if p.Size, err = s.blobs.Put(ctx, p.Key, bytes.NewReader(img.Original)); err != nil {
+		return nil, err
+	}
+	if _, err = s.blobs.Put(ctx, p.ThumbKey, bytes.NewReader(img.Thumb)); err == nil {
+		var ok bool
+		if ok, err = s.repo.AddPhoto(ctx, c.ID, p, MaxCheckinPhotos); err == nil && !ok {
+			err = ErrPhotoLimit
+		}
+	}
+	if err != nil {
+		s.deleteBlobs(p)
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *checkinService) DeletePhoto(ctx context.Context, userID, checkinID, photoID string) error {
+	if _, err := s.ownCheckin(ctx, userID, checkinID); err != nil {
+		return err
+	}
+	p, err := s.repo.RemovePhoto(ctx, checkinID, photoID)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return ErrPhotoNotFound
+	}
+	s.deleteBlobs(*p)
+	return nil
+}
+
+// deleteBlobs es best-effort: un archivo huérfano no debe romper la operación.
+func (s *checkinService) deleteBlobs(p domain.CheckinPhoto) {
+	for _, k := range []string{p.Key, p.ThumbKey} {
+		if err := s.blobs.Delete(context.Background(), k); err != nil {
+			log.Printf("[DeletePhoto] blob %s: %v", k, err)
+		}
+	}
+}
+
+func (s *checkinService) OpenBlob(ctx context.Context, key string) (io.ReadCloser, error) {
+	if s.blobs == nil {
+		return nil, ErrPhotosDisabled
+	}
+	return s.blobs.Open(ctx, key)
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/adherence"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+)
+
+var ErrInvalidRosterSort = errors.New("invalid_sort")
+
+type RosterQuery struct {
+	Query        *string
+	InactiveDays *int
+	OpenSession  *bool
+	PRDays       int
+	TZ           string
+	Sort         string // name | last_session | inactive | adherence | weight_trend | prs
+	Order        string // asc | desc
+	Limit        int
+	Offset       int
+}
+
+type RosterItem struct {
+	repository.RosterRow
+	DaysSinceSession *int               `json:"days_since_session,omitempty"`
+	WeekAdherence    *adherence.Summary `json:"week_adherence,omitempty"` // nil si no hay plan esta semana
+}
+
+// Roster: panel del coach. Los agregados salen de una query y la adherencia
+// semanal de un lote para todos los discípulos (sin N+1).
+func (s *coachService) Roster(ctx context.Context, coachID string, q RosterQuery) ([]RosterItem, int, error) {
+	less, ok := rosterSorts[strings.ToLower(q.Sort)]
+	if q.Sort == "" {
+		less = rosterSorts["name"]
+	} else if !ok {
+		return nil, 0, ErrInvalidRosterSort
+	}
+
+	rows, err := s.repo.Roster(ctx, coachID, repository.RosterFilter{
+		Query:        q.Query,
+		InactiveDays: q.InactiveDays,
+		OpenSession:  q.OpenSession,
+		PRDays:       q.PRDays,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	ids := make([]string, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.DiscipleID)
+	}
+	week, err := s.hist.WeekAdherence(ctx, ids, q.TZ)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	now := time.Now()
+	items := make([]RosterItem, 0, len(rows))
+	for _, r := range rows {
+		it := RosterItem{RosterRow: r}
+		if r.LastSessionAt != nil {
+			d := int(now.Sub(*r.LastSessionAt).Hours() / 24)
+			it.DaysSinceSession = &d
+		}
+		if r.WeightTrendKgWk != nil {
+			v := math.Round(*r.WeightTrendKgWk*100) / 100
+			it.WeightTrendKgWk = &v
+		}
+		if a, ok := week[r.DiscipleID]; ok {
+			it.WeekAdherence = &a
+		}
+		items = append(items, it)
+	}
+
+	desc := strings.EqualFold(q.Order, "desc")
+	sort.SliceStable(items, func(i, j int) bool {
+		if desc {
+			return less(items[j], items[i])
+		}
+		return less(items[i], items[j])
+	})
+
+	total := len(items)
+	if q.Limit <= 0 || q.Limit > 200 {
+		q.Limit = 50
+	}
+	if q.Offset < 0 || q.Offset > total {
+		q.Offset = total
+	}
+	end := min(q.Offset+q.Limit, total)
+	return items[q.Offset:end], total, nil
+}
+
+// rosterSorts: los valores nulos (sin sesiones, sin plan, sin check-ins)
+// quedan al final en orden ascendente.
+var rosterSorts = map[string]func(a, b RosterItem) bool{
+	"name": func(a, b RosterItem) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	},
+	"last_session": func(a, b RosterItem) bool {
+		return lessTime(a.LastSessionAt, b.LastSessionAt)
+	},
+	"inactive": func(a, b RosterItem) bool {
+		// más días sin entrenar primero; nunca entrenó = máximo
+		return lessTime(b.LastSessionAt, a.LastSessionAt)
+	},
+	"adherence": func(a, b RosterItem) bool {
+		return lessFloat(adherenceRate(a.WeekAdherence), adherenceRate(b.WeekAdherence))
+	},
+	"weight_trend": func(a, b RosterItem) bool {
+		return lessFloat(a.WeightTrendKgWk, b.WeightTrendKgWk)
+	},
+	"prs": func(a, b RosterItem) bool {
+		return a.NewPRs < b.NewPRs
+	},
+}
+
+func adherenceRate(s *adherence.Summary) *float64 {
+	if s == nil {
+		return nil
+	}
+	return &s.Rate
+}
+
+func lessTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a != nil && b == nil
+	}
+	return a.Before(*b)
+}
+
+func lessFloat(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a != nil && b == nil
+	}
+	return *a < *b
+}
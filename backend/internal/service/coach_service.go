@@ -7,15 +7,21 @@ import (
 	"time"
 
 	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/notify"
 	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/schedule"
 	"gorm.io/gorm"
 )
 
 type CalendarDay struct {
-	Date  time.Time `json:"date"`
-	DayID string    `json:"day_id"`
-	Index int       `json:"day_index"`
-	Notes *string   `json:"notes,omitempty"`
+	Date      time.Time `json:"date"`
+	DayID     string    `json:"day_id"`
+	WeekIndex int       `json:"week_index"`
+	Index     int       `json:"day_index"`
+	Notes     *string   `json:"notes,omitempty"`
+	Rest      bool      `json:"rest"`
+	Cycle     int       `json:"cycle"`          // pasada del bloque (0-based)
+	Hold      bool      `json:"hold,omitempty"` // repetición de la última semana
 }
 
 var ErrAssignmentNotFound = errors.New("assignment_not_for_disciple")
@@ -33,10 +39,12 @@ type CoachService interface {
 
 	ListAssignments(ctx context.Context, coachID string, discipleID *string, limit, offset int) ([]repository.AssignmentListRow, int64, error)
 
-	UpdateAssignment(ctx context.Context, id string, endDate *time.Time, isActive *bool) (*repository.AssignmentRow, error)
+	UpdateAssignment(ctx context.Context, id string, endDate *time.Time, isActive *bool, scheduleMode *string) (*repository.AssignmentRow, error)
 	AssignmentCalendar(ctx context.Context, id string, from, to time.Time) ([]CalendarDay, error)
 	ActivateAssignment(ctx context.Context, discipleID, assignmentID string) error
 	GetActiveAssignment(ctx context.Context, discipleID string) (*domain.Assignment, error)
+
+	Roster(ctx context.Context, coachID string, q RosterQuery) ([]RosterItem, int, error)
 }
 
 type coachService struct {
@@ -44,6 +52,7 @@ type coachService struct {
 	repo   repository.CoachRepository
 	hist   HistoryService
 	assign repository.AssignmentRepository
+	notify Notifier // opcional
 }
 
 type CoachOverview struct {
@@ -54,14 +63,16 @@ type CoachOverview struct {
 }
 
 type AdherenceResponse struct {
-	DaysRequested int     `json:"days"`
-	DaysWithSets  int     `json:"days_with_sets"`
-	Rate          float64 `json:"rate"`
+	DaysRequested int              `json:"days"`
+	DaysWithSets  int              `json:"days_with_sets"`
+	Rate          float64          `json:"rate"` // contra el plan; días con sesión / días si no hay plan
+	Plan          *AdherenceReport `json:"plan,omitempty"`
 }
 
 func NewCoachService(r repository.CoachRepository, hist HistoryService, opts ...any) CoachService {
 	var db *gorm.DB
 	var ar repository.AssignmentRepository
+	var n Notifier
 	for _, o := range opts {
 		if v, ok := o.(*gorm.DB); ok {
 			db = v
@@ -69,8 +80,11 @@ func NewCoachService(r repository.CoachRepository, hist HistoryService, opts ...
 		if v, ok := o.(repository.AssignmentRepository); ok {
 			ar = v
 		}
+		if v, ok := o.(Notifier); ok {
+			n = v
+		}
 	}
-	return &coachService{db: db, repo: r, hist: hist, assign: ar}
+	return &coachService{db: db, repo: r, hist: hist, assign: ar, notify: n}
 }
 
 func (s *coachService) CreateLink(ctx context.Context, coachID, discipleID string, autoAccept bool) (*domain.CoachLink, error) {
@@ -149,7 +163,28 @@ func (s *coachService) AssignProgram(ctx context.Context, coachID, discipleID, p
 	if startDate.IsZero() {
 		startDate = time.Now()
 	}
-	return s.repo.CreateAssignment(ctx, coachID, discipleID, programID, startDate)
+	// la asignación y su aviso en la misma transacción (outbox)
+	var a *repository.AssignmentMinimal
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if a, err = s.repo.WithTx(tx).CreateAssignment(ctx, coachID, discipleID, programID, startDate); err != nil {
+			return err
+		}
+		return emitTx(ctx, tx, s.notify, NotifyEvent{
+			Kind:       notify.EventAssignmentCreated,
+			ActorID:    coachID,
+			DiscipleID: discipleID,
+			Data: map[string]string{
+				"assignment_id": a.ID,
+				"program_id":    programID,
+				"start_date":    startDate.Format("2006-01-02"),
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
 }
 
 func (s *coachService) GetOverview(ctx context.Context, coachID, discipleID string, days int, metric, tz string) (*CoachOverview, error) {
@@ -161,8 +196,8 @@ func (s *coachService) GetOverview(ctx context.Context, coachID, discipleID stri
 		return nil, errors.New("forbidden: not a coach of disciple")
 	}
 
-	me, err := s.hist.GetMeTodayFor(ctx, discipleID, tz)
-	if err != nil && !errors.Is(err, ErrNoDay) {
+	me, err := s.hist.GetMeTodayFor(ctx, discipleID, tz, "")
+	if err != nil && !errors.Is(err, ErrNoDay) && !errors.Is(err, repository.ErrNoDay) {
 		return nil, err
 	}
 	// pivot y adherence deben poder devolver vacío sin error
@@ -175,6 +210,10 @@ func (s *coachService) GetOverview(ctx context.Context, coachID, discipleID stri
 		return nil, err
 	}
 
+	rate := float64(ad.DaysWithSets) / float64(max(1, days))
+	if ad.Plan != nil && ad.Plan.ScheduledDays > 0 {
+		rate = ad.Plan.Rate
+	}
 	return &CoachOverview{
 		DiscipleID: discipleID,
 		MeToday:    me,
@@ -182,7 +221,8 @@ func (s *coachService) GetOverview(ctx context.Context, coachID, discipleID stri
 		Adherence: &AdherenceResponse{
 			DaysRequested: days,
 			DaysWithSets:  ad.DaysWithSets,
-			Rate:          float64(ad.DaysWithSets) / float64(max(1, days)),
+			Rate:          rate,
+			Plan:          ad.Plan,
 		},
 	}, nil
 }
@@ -214,7 +254,7 @@ func max(a, b int) int {
 	return b
 }
 
-func (s *coachService) UpdateAssignment(ctx context.Context, id string, endDate *time.Time, isActive *bool) (*repository.AssignmentRow, error) {
+func (s *coachService) UpdateAssignment(ctx context.Context, id string, endDate *time.Time, isActive *bool, scheduleMode *string) (*repository.AssignmentRow, error) {
 	if id == "" {
 		return nil, errors.New("id required")
 	}
@@ -225,6 +265,13 @@ func (s *coachService) UpdateAssignment(ctx context.Context, id string, endDate
 	if isActive != nil {
 		patch["is_active"] = *isActive
 	}
+	if scheduleMode != nil {
+		m, ok := schedule.ParseMode(*scheduleMode)
+		if !ok {
+			return nil, errors.New("invalid_schedule_mode")
+		}
+		patch["schedule_mode"] = string(m)
+	}
 	if len(patch) == 0 {
 		return nil, errors.New("nothing to update")
 	}
@@ -234,7 +281,8 @@ func (s *coachService) UpdateAssignment(ctx context.Context, id string, endDate
 	return s.repo.GetAssignmentByID(ctx, id)
 }
 
-// MVP: asigna los días del programa (week 1) en ciclo sobre el rango [from..to]
+// AssignmentCalendar recorre todas las semanas del programa desde start_date,
+// aplicando block_repeats y end_behavior (stop|loop|hold) del programa.
 func (s *coachService) AssignmentCalendar(ctx context.Context, id string, from, to time.Time) ([]CalendarDay, error) {
 	if to.Before(from) {
 		from, to = to, from
@@ -255,35 +303,27 @@ func (s *coachService) AssignmentCalendar(ctx context.Context, id string, from,
 		return []CalendarDay{}, nil
 	}
 
-	days, err := s.repo.ListProgramDaysByProgramWeek(ctx, asg.ProgramID, 1)
+	plan, notes, err := s.repo.ProgramSchedule(ctx, asg.ProgramID)
 	if err != nil {
 		return nil, err
 	}
-	if len(days) == 0 {
-		return []CalendarDay{}, nil
-	}
 
-	// Construimos calendario cíclico
 	out := make([]CalendarDay, 0, 32)
-	// punto de arranque: desplazamiento desde start al "from"
-	diff := int(from.Sub(start).Hours() / 24) // días
-	idx := diff % len(days)
-	if idx < 0 {
-		idx += len(days)
-	}
-	cur := from
-
-	for !cur.After(to) {
-		d := days[idx]
+	for cur := from; !cur.After(to); cur = cur.AddDate(0, 0, 1) {
+		slot, ok := schedule.OnDate(plan, start, cur)
+		if !ok {
+			break // plan vacío o terminado (end_behavior=stop)
+		}
 		out = append(out, CalendarDay{
-			Date:  cur,
-			DayID: d.ID,
-			Index: d.DayIndex,
-			Notes: d.Notes,
+			Date:      cur,
+			DayID:     slot.Day.ID,
+			WeekIndex: slot.Day.WeekIndex,
+			Index:     slot.Day.DayIndex,
+			Notes:     notes[slot.Day.ID],
+			Rest:      slot.Day.Rest,
+			Cycle:     slot.Cycle,
+			Hold:      slot.Hold,
 		})
-		// siguiente día
-		cur = cur.AddDate(0, 0, 1)
-		idx = (idx + 1) % len(days)
 	}
 	return out, nil
 }
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/vicepalma/roma-system/backend/internal/adherence"
 	"github.com/vicepalma/roma-system/backend/internal/domain"
 	"github.com/vicepalma/roma-system/backend/internal/repository"
 )
@@ -65,15 +66,18 @@ type HistoryService interface {
 	GetHistory(ctx context.Context, discipleID string, days int) (*HistoryResponse, error)
 	GetPRs(ctx context.Context, discipleID string) ([]repository.PRRow, error)
 
-	GetDailyByExercise(ctx context.Context, discipleID string, days int, includeCatalog bool, tz string) ([]repository.DailyExerciseVolume, []repository.ExerciseCatalogItem, error)
-	GetDailyByMuscle(ctx context.Context, discipleID string, days int, tz string) ([]repository.DailyMuscleVolume, error)
+	// allSetTypes=false (por defecto) cuenta solo series efectivas
+	GetDailyByExercise(ctx context.Context, discipleID string, days int, includeCatalog bool, tz string, allSetTypes bool) ([]repository.DailyExerciseVolume, []repository.ExerciseCatalogItem, error)
+	GetDailyByMuscle(ctx context.Context, discipleID string, days int, tz string, allSetTypes bool) ([]repository.DailyMuscleVolume, error)
 
-	GetPivotByExercise(ctx context.Context, discipleID string, days int, includeCatalog bool, metric string, tz string) (*PivotResponse, error)
-	GetPivotByMuscle(ctx context.Context, discipleID string, days int, metric string, tz string) (*PivotResponse, error)
+	GetPivotByExercise(ctx context.Context, discipleID string, days int, includeCatalog bool, metric string, tz string, allSetTypes bool) (*PivotResponse, error)
+	GetPivotByMuscle(ctx context.Context, discipleID string, days int, metric string, tz string, allSetTypes bool) (*PivotResponse, error)
 
-	GetMeTodayFor(ctx context.Context, discipleID string, tz string) (*MeTodayResponse, error)
+	GetMeTodayFor(ctx context.Context, discipleID string, tz string, mode string) (*MeTodayResponse, error)
 	GetPivotByExerciseFor(ctx context.Context, discipleID string, days int, metric, tz string, includeCatalog bool) (*PivotResponse, error)
 	GetAdherence(ctx context.Context, discipleID string, days int, tz string) (Adherence, error)
+	GetPlanAdherence(ctx context.Context, discipleID string, days int, tz string) (*AdherenceReport, error)
+	WeekAdherence(ctx context.Context, discipleIDs []string, tz string) (map[string]adherence.Summary, error)
 
 	History(ctx context.Context, discipleID, tz, group string, from, to *time.Time, filter repository.HistorySessionFilter, limit, offset int) (any, int64, error)
 	ListSessions(ctx context.Context, discipleID, tz string, from, to *time.Time, filter repository.HistorySessionFilter, limit, offset int) (any, int64, error)
@@ -116,11 +120,11 @@ func (s *historyService) GetPRs(ctx context.Context, discipleID string) ([]repos
 }
 
 // summaries crudos (para pivot y summary)
-func (s *historyService) GetDailyByExercise(ctx context.Context, discipleID string, days int, includeCatalog bool, tz string) ([]repository.DailyExerciseVolume, []repository.ExerciseCatalogItem, error) {
+func (s *historyService) GetDailyByExercise(ctx context.Context, discipleID string, days int, includeCatalog bool, tz string, allSetTypes bool) ([]repository.DailyExerciseVolume, []repository.ExerciseCatalogItem, error) {
 	loc := normTZ(tz)
 	sinceDate := sinceLocalDate(days, loc)
 
-	rows, err := s.repo.DailyVolumeByExercise(ctx, discipleID, sinceDate, loc.String())
+	rows, err := s.repo.DailyVolumeByExercise(ctx, discipleID, sinceDate, loc.String(), allSetTypes)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -137,11 +141,11 @@ func (s *historyService) GetDailyByExercise(ctx context.Context, discipleID stri
 	return out, catalog, nil
 }
 
-func (s *historyService) GetDailyByMuscle(ctx context.Context, discipleID string, days int, tz string) ([]repository.DailyMuscleVolume, error) {
+func (s *historyService) GetDailyByMuscle(ctx context.Context, discipleID string, days int, tz string, allSetTypes bool) ([]repository.DailyMuscleVolume, error) {
 	loc := normTZ(tz)
 	sinceDate := sinceLocalDate(days, loc)
 
-	rows, err := s.repo.DailyVolumeByMuscle(ctx, discipleID, sinceDate, loc.String())
+	rows, err := s.repo.DailyVolumeByMuscle(ctx, discipleID, sinceDate, loc.String(), allSetTypes)
 	if err != nil {
 		return nil, err
 	}
@@ -249,11 +253,11 @@ func fillMuscleGaps(in []repository.DailyMuscleVolume, start, end time.Time) []r
 	return out
 }
 
-func (s *historyService) GetPivotByExercise(ctx context.Context, discipleID string, days int, includeCatalog bool, metric string, tz string) (*PivotResponse, error) {
+func (s *historyService) GetPivotByExercise(ctx context.Context, discipleID string, days int, includeCatalog bool, metric string, tz string, allSetTypes bool) (*PivotResponse, error) {
 	metric = normMetric(metric)
 	loc := normTZ(tz)
 
-	rows, catalog, err := s.GetDailyByExercise(ctx, discipleID, days, includeCatalog, tz)
+	rows, catalog, err := s.GetDailyByExercise(ctx, discipleID, days, includeCatalog, tz, allSetTypes)
 	if err != nil {
 		return nil, err
 	}
@@ -301,11 +305,11 @@ func (s *historyService) GetPivotByExercise(ctx context.Context, discipleID stri
 	return resp, nil
 }
 
-func (s *historyService) GetPivotByMuscle(ctx context.Context, discipleID string, days int, metric string, tz string) (*PivotResponse, error) {
+func (s *historyService) GetPivotByMuscle(ctx context.Context, discipleID string, days int, metric string, tz string, allSetTypes bool) (*PivotResponse, error) {
 	metric = normMetric(metric)
 	loc := normTZ(tz)
 
-	rows, err := s.GetDailyByMuscle(ctx, discipleID, days, tz)
+	rows, err := s.GetDailyByMuscle(ctx, discipleID, days, tz, allSetTypes)
 	if err != nil {
 		return nil, err
 	}
@@ -347,8 +351,9 @@ func (s *historyService) GetPivotByMuscle(ctx context.Context, discipleID string
 	}, nil
 }
 
-func (s *historyService) GetMeTodayFor(ctx context.Context, discipleID string, tz string) (*MeTodayResponse, error) {
-	assignID, day, presc, err := s.repo.ResolveToday(ctx, discipleID, tz)
+// GetMeTodayFor: mode = calendar | next_undone; vacío usa el modo del assignment.
+func (s *historyService) GetMeTodayFor(ctx context.Context, discipleID string, tz string, mode string) (*MeTodayResponse, error) {
+	assignID, day, presc, err := s.repo.ResolveToday(ctx, discipleID, tz, mode)
 	if err != nil {
 		// Propagamos ErrNoDay para que el handler devuelva 200 vacío
 		log.Printf("[GetMeTodayFor] ResolveToday err disciple=%s tz=%s -> %v", discipleID, tz, err)
@@ -383,10 +388,26 @@ func (s *historyService) GetPivotByExerciseFor(
 	metric, tz string,
 	includeCatalog bool,
 ) (*PivotResponse, error) {
-	return s.GetPivotByExercise(ctx, discipleID, days, includeCatalog, metric, tz)
+	return s.GetPivotByExercise(ctx, discipleID, days, includeCatalog, metric, tz, false)
 }
 
-type Adherence struct{ DaysWithSets int }
+type Adherence struct {
+	DaysWithSets int
+	Plan         *AdherenceReport // contra el calendario de los assignments activos
+}
+
+type AdherenceReport struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	adherence.Report
+	Assignments []AssignmentAdherence `json:"assignments"`
+}
+
+type AssignmentAdherence struct {
+	AssignmentID string `json:"assignment_id"`
+	ProgramTitle string `json:"program_title"`
+	adherence.Report
+}
 
 func (s *historyService) GetAdherence(ctx context.Context, discipleID string, days int, tz string) (Adherence, error) {
 	loc := normTZ(tz)
@@ -405,12 +426,66 @@ func (s *historyService) GetAdherence(ctx context.Context, discipleID string, da
 		k := t.Format("2006-01-02")
 		seen[k] = struct{}{}
 	}
-	return Adherence{DaysWithSets: len(seen)}, nil
+
+	// el plan es un extra: si falla, el overview sigue con DaysWithSets
+	plan, err := s.GetPlanAdherence(ctx, discipleID, days, tz)
+	if err != nil {
+		log.Printf("[GetAdherence] GetPlanAdherence err disciple=%s -> %v", discipleID, err)
+		plan = nil
+	}
+	return Adherence{DaysWithSets: len(seen), Plan: plan}, nil
+}
+
+// GetPlanAdherence: últimos `days` días locales hasta hoy, semanal y por assignment.
+func (s *historyService) GetPlanAdherence(ctx context.Context, discipleID string, days int, tz string) (*AdherenceReport, error) {
+	loc := normTZ(tz)
+	now := time.Now().In(loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -(clampDays(days) - 1))
+
+	inputs, err := s.repo.AdherenceInputs(ctx, discipleID, loc.String(), from, to)
+	if err != nil {
+		return nil, err
+	}
+	out := &AdherenceReport{From: from.Format("2006-01-02"), To: to.Format("2006-01-02"), Assignments: []AssignmentAdherence{}}
+	reports := make([]adherence.Report, 0, len(inputs))
+	for _, in := range inputs {
+		r := adherence.Compute(in.Input)
+		reports = append(reports, r)
+		out.Assignments = append(out.Assignments, AssignmentAdherence{AssignmentID: in.AssignmentID, ProgramTitle: in.ProgramTitle, Report: r})
+	}
+	out.Report = adherence.Merge(reports...)
+	return out, nil
+}
+
+// WeekAdherence: semana local en curso (lunes a hoy) para varios discípulos,
+// con un número fijo de queries. Sin plan en la semana no hay entrada.
+func (s *historyService) WeekAdherence(ctx context.Context, discipleIDs []string, tz string) (map[string]adherence.Summary, error) {
+	loc := normTZ(tz)
+	now := time.Now().In(loc)
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -((int(to.Weekday()) + 6) % 7)) // lunes
+
+	inputs, err := s.repo.AdherenceInputsFor(ctx, discipleIDs, loc.String(), from, to)
+	if err != nil {
+		return nil, err
+	}
+	reports := map[string][]adherence.Report{}
+	for _, in := range inputs {
+		reports[in.DiscipleID] = append(reports[in.DiscipleID], adherence.Compute(in.Input))
+	}
+	out := make(map[string]adherence.Summary, len(reports))
+	for id, rs := range reports {
+		if m := adherence.Merge(rs...); m.ScheduledDays > 0 {
+			out[id] = m.Summary
+		}
+	}
+	return out, nil
 }
 
 func (s *historyService) History(ctx context.Context, discipleID, tz, group string, from, to *time.Time, filter repository.HistorySessionFilter, limit, offset int) (any, int64, error) {
 	if group == "day" {
-		return s.repo.GetDaysAggregate(ctx, discipleID, tz, from, to, limit, offset)
+		return s.repo.GetDaysAggregate(ctx, discipleID, tz, from, to, filter.AllSetTypes, limit, offset)
 	}
 	// por defecto: session
 	return s.repo.GetSessionsHistory(ctx, discipleID, tz, from, to, filter, limit, offset)
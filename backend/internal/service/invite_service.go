@@ -5,10 +5,12 @@ import (
 	"crypto/rand"
 	"encoding/base32"
 	"errors"
+	"log"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/notify"
 	"github.com/vicepalma/roma-system/backend/internal/repository"
 	"gorm.io/gorm"
 )
@@ -16,12 +18,30 @@ import (
 type InviteService interface {
 	CreateInvite(ctx context.Context, coachID, email, name string, ttlHours int) (*InviteDTO, error)
 	AcceptInvite(ctx context.Context, code string, discipleID string) (*AcceptResult, error)
+
+	ListInvites(ctx context.Context, coachID string, status *string, limit, offset int) ([]repository.Invitation, int64, error)
+	RevokeInvite(ctx context.Context, coachID, code string) error
+	ResendInvite(ctx context.Context, coachID, code string, ttlHours int) (*InviteDTO, error)
+	ExpireStale(ctx context.Context) (int64, error)
+	// SignupWithInvite crea la cuenta (u.PasswordHash ya calculado) y el
+	// vínculo con el coach en una sola transacción.
+	SignupWithInvite(ctx context.Context, code string, u *domain.User) (*AcceptResult, error)
 }
 
-type InviteRepository interface {
-	Create(ctx context.Context, inv *Invitation) error
-	FindByCode(ctx context.Context, code string) (*Invitation, error)
-	MarkAccepted(ctx context.Context, id string, usedBy string, usedAt time.Time) error
+var (
+	ErrInviteInvalid       = repository.ErrInviteInvalid
+	ErrInviteNotPending    = repository.ErrInviteNotPending
+	ErrInviteExpired       = repository.ErrInviteExpired
+	ErrInviteEmailMismatch = repository.ErrInviteEmailMismatch
+	ErrInviteStatus        = errors.New("invalid_status")
+	ErrEmailInUse          = repository.ErrEmailInUse
+)
+
+var inviteStatuses = map[string]bool{
+	repository.InvitePending:  true,
+	repository.InviteAccepted: true,
+	repository.InviteExpired:  true,
+	repository.InviteRevoked:  true,
 }
 
 type InviteDTO struct {
@@ -38,28 +58,16 @@ type AcceptResult struct {
 }
 
 type inviteService struct {
+	db      *gorm.DB
 	inv     repository.InviteRepository
 	coach   CoachService
 	baseURL string // ej: http://localhost:5173/invite/  (opcional)
+	notify  Notifier
 }
 
-type Invitation struct {
-	ID        string     `gorm:"type:uuid;primaryKey"`
-	Code      string     `gorm:"uniqueIndex;not null"`
-	CoachID   string     `gorm:"type:uuid;not null"`
-	Email     string     `gorm:"not null"`
-	Name      *string    `gorm:""`
-	Status    string     `gorm:"not null;default:'pending'"` // pending|accepted|expired|cancelled
-	ExpiresAt time.Time  `gorm:"not null"`
-	UsedBy    *string    `gorm:"type:uuid"`
-	UsedAt    *time.Time `gorm:""`
-	CreatedAt time.Time  `gorm:"autoCreateTime"`
-}
-
-type inviteRepository struct{ db *gorm.DB }
-
-func NewInviteService(inv repository.InviteRepository, coach CoachService, baseURL string) InviteService {
-	return &inviteService{inv: inv, coach: coach, baseURL: strings.TrimRight(baseURL, "/")}
+// NewInviteService: con notifier nil no se envía el email de invitación.
+func NewInviteService(inv repository.InviteRepository, db *gorm.DB, coach CoachService, baseURL string, notifier Notifier) InviteService {
+	return &inviteService{db: db, inv: inv, coach: coach, baseURL: strings.TrimRight(baseURL, "/"), notify: notifier}
 }
 
 func randCode(nBytes int) (string, error) {
@@ -96,13 +104,29 @@ func (s *inviteService) CreateInvite(ctx context.Context, coachID, email, name s
 		Status:    "pending",
 		ExpiresAt: time.Now().Add(time.Duration(ttlHours) * time.Hour),
 	}
-	if err := s.inv.Create(ctx, inv); err != nil {
-		return nil, err
-	}
 	url := code
 	if s.baseURL != "" {
 		url = s.baseURL + "/" + code
 	}
+	// la invitación y su email en la misma transacción (outbox)
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.inv.WithTx(tx).Create(ctx, inv); err != nil {
+			return err
+		}
+		return emitTx(ctx, tx, s.notify, NotifyEvent{
+			Kind:    notify.EventInviteSent,
+			ActorID: coachID,
+			Email:   email,
+			Data: map[string]string{
+				"name":       strings.TrimSpace(name),
+				"invite_url": url,
+				"expires_at": inv.ExpiresAt.Format("02/01/2006 15:04"),
+			},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
 	return &InviteDTO{
 		Code:      code,
 		InviteURL: url,
@@ -116,51 +140,97 @@ func (s *inviteService) AcceptInvite(ctx context.Context, code string, discipleI
 	if code == "" || discipleID == "" {
 		return nil, errors.New("code and disciple_id required")
 	}
-	inv, err := s.inv.FindByCode(ctx, code)
+	// El email del usuario autenticado debe coincidir con el invitado
+	_, link, err := s.inv.Accept(ctx, code, &domain.User{ID: discipleID})
 	if err != nil {
-		return nil, errors.New("invalid_code")
-	}
-	if inv.Status != "pending" {
-		return nil, errors.New("invite_not_pending")
-	}
-	if time.Now().After(inv.ExpiresAt) {
-		return nil, errors.New("invite_expired")
+		return nil, err
 	}
+	return &AcceptResult{LinkID: link.ID, Status: repository.InviteAccepted}, nil
+}
 
-	// Crea/acepta link coach-disciple
-	link, err := s.coach.CreateLink(ctx, inv.CoachID, discipleID, true /*autoAccept*/)
+func (s *inviteService) SignupWithInvite(ctx context.Context, code string, u *domain.User) (*AcceptResult, error) {
+	if code == "" || u == nil || u.Email == "" {
+		return nil, errors.New("code and email required")
+	}
+	u.ID = ""
+	u.Email = strings.ToLower(strings.TrimSpace(u.Email))
+	u.Role = "disciple"
+	// el código llegó a ese email: cuenta verificada de entrada
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+	_, link, err := s.inv.Accept(ctx, code, u)
 	if err != nil {
 		return nil, err
 	}
+	return &AcceptResult{LinkID: link.ID, Status: repository.InviteAccepted}, nil
+}
 
-	if err := s.inv.MarkAccepted(ctx, inv.ID, discipleID, time.Now()); err != nil {
+func (s *inviteService) ListInvites(ctx context.Context, coachID string, status *string, limit, offset int) ([]repository.Invitation, int64, error) {
+	if status != nil && !inviteStatuses[*status] {
+		return nil, 0, ErrInviteStatus
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.inv.ListByCoach(ctx, coachID, status, limit, offset)
+}
+
+// ownInvite: una invitación de otro coach se reporta como inexistente.
+func (s *inviteService) ownInvite(ctx context.Context, coachID, code string) (*repository.Invitation, error) {
+	inv, err := s.inv.FindByCode(ctx, code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInviteInvalid
+		}
 		return nil, err
 	}
-	return &AcceptResult{LinkID: link.ID, Status: "accepted"}, nil
+	if inv.CoachID != coachID {
+		return nil, ErrInviteInvalid
+	}
+	return inv, nil
 }
 
-func (r *inviteRepository) Create(ctx context.Context, inv *Invitation) error {
-	if inv.ID == "" {
-		inv.ID = uuid.NewString()
+func (s *inviteService) RevokeInvite(ctx context.Context, coachID, code string) error {
+	inv, err := s.ownInvite(ctx, coachID, code)
+	if err != nil {
+		return err
 	}
-	return r.db.WithContext(ctx).Create(inv).Error
+	return s.inv.MarkRevoked(ctx, inv.ID)
 }
 
-func (r *inviteRepository) FindByCode(ctx context.Context, code string) (*Invitation, error) {
-	var i Invitation
-	if err := r.db.WithContext(ctx).Where("code = ?", code).First(&i).Error; err != nil {
+// ResendInvite revoca el código anterior (si seguía pendiente) y emite uno
+// nuevo para el mismo email. Aceptadas no se reenvían.
+func (s *inviteService) ResendInvite(ctx context.Context, coachID, code string, ttlHours int) (*InviteDTO, error) {
+	inv, err := s.ownInvite(ctx, coachID, code)
+	if err != nil {
 		return nil, err
 	}
-	return &i, nil
+	if inv.Status == repository.InviteAccepted {
+		return nil, ErrInviteNotPending
+	}
+	if inv.Status == repository.InvitePending {
+		if err := s.inv.MarkRevoked(ctx, inv.ID); err != nil && !errors.Is(err, ErrInviteNotPending) {
+			return nil, err
+		}
+	}
+	name := ""
+	if inv.Name != nil {
+		name = *inv.Name
+	}
+	return s.CreateInvite(ctx, coachID, inv.Email, name, ttlHours)
 }
 
-func (r *inviteRepository) MarkAccepted(ctx context.Context, id string, usedBy string, usedAt time.Time) error {
-	return r.db.WithContext(ctx).
-		Model(&Invitation{}).
-		Where("id = ? AND status = 'pending' AND used_at IS NULL", id).
-		Updates(map[string]any{
-			"status":  "accepted",
-			"used_by": usedBy,
-			"used_at": usedAt,
-		}).Error
+func (s *inviteService) ExpireStale(ctx context.Context) (int64, error) {
+	n, err := s.inv.ExpireStale(ctx, time.Now())
+	if err != nil {
+		log.Printf("[ExpireStale] error: %v", err)
+		return 0, err
+	}
+	if n > 0 {
+		log.Printf("[ExpireStale] %d invitaciones vencidas", n)
+	}
+	return n, nil
 }
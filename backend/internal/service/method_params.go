@@ -0,0 +1,242 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Tipos de método soportados (methods.kind).
+const (
+	MethodStraight  = "straight"
+	MethodFST7      = "fst7"
+	MethodDropSet   = "drop_set"
+	MethodRestPause = "rest_pause"
+	MethodSuperset  = "superset"
+	MethodCluster   = "cluster"
+)
+
+var ErrInvalidMethodKind = errors.New("invalid_method_kind")
+
+// Schemas tipados de methods.params. Campos ausentes toman el default.
+type (
+	StraightParams struct{}
+
+	FST7Params struct {
+		Series     int    `json:"series"`   // 7
+		RestSec    int    `json:"rest_sec"` // 30-45
+		ToFailure  bool   `json:"to_failure"`
+		TargetReps string `json:"target_reps,omitempty"`
+	}
+
+	DropSetParams struct {
+		Drops       int     `json:"drops"`        // bajadas tras el set principal
+		DropPercent float64 `json:"drop_percent"` // % de carga que se quita en cada bajada
+		RestSec     int     `json:"rest_sec"`     // descanso entre bajadas (normalmente 0)
+		ToFailure   bool    `json:"to_failure"`   // cada bajada al fallo
+		TargetReps  string  `json:"target_reps,omitempty"`
+	}
+
+	RestPauseParams struct {
+		MiniSets   int    `json:"mini_sets"` // mini-series tras el set de activación
+		PauseSec   int    `json:"pause_sec"` // 10-20s
+		TargetReps string `json:"target_reps,omitempty"`
+	}
+
+	SupersetParams struct {
+		Exercises int `json:"exercises"` // prescripciones consecutivas que forman el bloque
+		RestSec   int `json:"rest_sec"`  // descanso al terminar la vuelta
+	}
+
+	ClusterParams struct {
+		Clusters       int `json:"clusters"`         // bloques por serie
+		RepsPerCluster int `json:"reps_per_cluster"` // reps por bloque
+		IntraRestSec   int `json:"intra_rest_sec"`   // descanso entre bloques
+	}
+)
+
+func defaultMethodParams(kind string) (any, error) {
+	switch kind {
+	case MethodStraight:
+		return &StraightParams{}, nil
+	case MethodFST7:
+		return &FST7Params{Series: 7, RestSec: 30, ToFailure: true}, nil
+	case MethodDropSet:
+		return &DropSetParams{Drops: 2, DropPercent: 20, ToFailure: true}, nil
+	case MethodRestPause:
+		return &RestPauseParams{MiniSets: 2, PauseSec: 15}, nil
+	case MethodSuperset:
+		return &SupersetParams{Exercises: 2, RestSec: 90}, nil
+	case MethodCluster:
+		return &ClusterParams{Clusters: 4, RepsPerCluster: 2, IntraRestSec: 20}, nil
+	}
+	return nil, ErrInvalidMethodKind
+}
+
+// ParseMethodParams valida params contra el schema del kind y devuelve el
+// struct tipado con defaults aplicados. Campos desconocidos son error.
+func ParseMethodParams(kind string, raw []byte) (any, error) {
+	p, err := defaultMethodParams(kind)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(raw)) > 0 && !bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(p); err != nil {
+			return nil, fmt.Errorf("invalid_params: %w", err)
+		}
+	}
+	if err := validateMethodParams(p); err != nil {
+		return nil, fmt.Errorf("invalid_params: %w", err)
+	}
+	return p, nil
+}
+
+func validateMethodParams(p any) error {
+	switch v := p.(type) {
+	case *FST7Params:
+		if v.Series < 1 || v.Series > 12 {
+			return errors.New("series must be 1..12")
+		}
+		if v.RestSec < 0 || v.RestSec > 300 {
+			return errors.New("rest_sec must be 0..300")
+		}
+	case *DropSetParams:
+		if v.Drops < 1 || v.Drops > 5 {
+			return errors.New("drops must be 1..5")
+		}
+		if v.DropPercent <= 0 || v.DropPercent > 50 {
+			return errors.New("drop_percent must be >0 and <=50")
+		}
+		if v.RestSec < 0 || v.RestSec > 60 {
+			return errors.New("rest_sec must be 0..60")
+		}
+	case *RestPauseParams:
+		if v.MiniSets < 1 || v.MiniSets > 6 {
+			return errors.New("mini_sets must be 1..6")
+		}
+		if v.PauseSec < 5 || v.PauseSec > 60 {
+			return errors.New("pause_sec must be 5..60")
+		}
+	case *SupersetParams:
+		if v.Exercises < 2 || v.Exercises > 4 {
+			return errors.New("exercises must be 2..4")
+		}
+		if v.RestSec < 0 || v.RestSec > 300 {
+			return errors.New("rest_sec must be 0..300")
+		}
+	case *ClusterParams:
+		if v.Clusters < 2 || v.Clusters > 10 {
+			return errors.New("clusters must be 2..10")
+		}
+		if v.RepsPerCluster < 1 || v.RepsPerCluster > 10 {
+			return errors.New("reps_per_cluster must be 1..10")
+		}
+		if v.IntraRestSec < 5 || v.IntraRestSec > 60 {
+			return errors.New("intra_rest_sec must be 5..60")
+		}
+	}
+	return nil
+}
+
+// ExpectedSet es una serie esperada tras expandir prescripción + método.
+type ExpectedSet struct {
+	Index     int      `json:"index"` // 1-based, orden de ejecución
+	Series    int      `json:"series"`
+	Kind      string   `json:"kind"` // working | drop | mini | cluster | superset
+	Reps      string   `json:"reps"`
+	RestSec   *int     `json:"rest_sec,omitempty"`
+	LoadPct   *float64 `json:"load_pct,omitempty"` // % de la carga del set principal
+	ToFailure bool     `json:"to_failure"`
+}
+
+// PrescriptionSpec: lo mínimo de la prescripción para expandir.
+type PrescriptionSpec struct {
+	Series    int
+	Reps      string
+	RestSec   *int
+	ToFailure bool
+}
+
+// ExpandPrescription arma la estructura de series esperada según el método.
+// Sin método (p == nil o straight) son `series` series iguales.
+func ExpandPrescription(pr PrescriptionSpec, params any) []ExpectedSet {
+	series := pr.Series
+	if series < 1 {
+		series = 1
+	}
+	reps := strings.TrimSpace(pr.Reps)
+	out := make([]ExpectedSet, 0, series)
+	add := func(s ExpectedSet) {
+		s.Index = len(out) + 1
+		out = append(out, s)
+	}
+
+	switch p := params.(type) {
+	case *FST7Params:
+		if reps == "" {
+			reps = p.TargetReps
+		}
+		for i := 1; i <= p.Series; i++ {
+			add(ExpectedSet{Series: i, Kind: "working", Reps: reps, RestSec: intPtr(p.RestSec), ToFailure: p.ToFailure})
+		}
+	case *DropSetParams:
+		for i := 1; i <= series; i++ {
+			add(ExpectedSet{Series: i, Kind: "working", Reps: reps, RestSec: intPtr(p.RestSec), LoadPct: floatPtr(100), ToFailure: pr.ToFailure || p.ToFailure})
+			load := 100.0
+			for d := 1; d <= p.Drops; d++ {
+				load = math.Round(load*(100-p.DropPercent)) / 100
+				rest := intPtr(p.RestSec)
+				if d == p.Drops {
+					rest = pr.RestSec
+				}
+				add(ExpectedSet{Series: i, Kind: "drop", Reps: orDefault(p.TargetReps, "AMRAP"), RestSec: rest, LoadPct: floatPtr(load), ToFailure: p.ToFailure})
+			}
+		}
+	case *RestPauseParams:
+		for i := 1; i <= series; i++ {
+			add(ExpectedSet{Series: i, Kind: "working", Reps: reps, RestSec: intPtr(p.PauseSec), ToFailure: true})
+			for m := 1; m <= p.MiniSets; m++ {
+				rest := intPtr(p.PauseSec)
+				if m == p.MiniSets {
+					rest = pr.RestSec
+				}
+				add(ExpectedSet{Series: i, Kind: "mini", Reps: orDefault(p.TargetReps, "AMRAP"), RestSec: rest, ToFailure: true})
+			}
+		}
+	case *ClusterParams:
+		for i := 1; i <= series; i++ {
+			for k := 1; k <= p.Clusters; k++ {
+				rest := intPtr(p.IntraRestSec)
+				if k == p.Clusters {
+					rest = pr.RestSec
+				}
+				add(ExpectedSet{Series: i, Kind: "cluster", Reps: fmt.Sprint(p.RepsPerCluster), RestSec: rest})
+			}
+		}
+	case *SupersetParams:
+		// sin descanso entre ejercicios; el descanso va al cerrar la vuelta
+		for i := 1; i <= series; i++ {
+			add(ExpectedSet{Series: i, Kind: "superset", Reps: reps, RestSec: intPtr(p.RestSec), ToFailure: pr.ToFailure})
+		}
+	default:
+		for i := 1; i <= series; i++ {
+			add(ExpectedSet{Series: i, Kind: "working", Reps: reps, RestSec: pr.RestSec, ToFailure: pr.ToFailure})
+		}
+	}
+	return out
+}
+
+func intPtr(v int) *int           { return &v }
+func floatPtr(v float64) *float64 { return &v }
+
+func orDefault(s, def string) string {
+	if strings.TrimSpace(s) == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+)
+
+type MethodService interface {
+	List(ctx context.Context, f repository.MethodFilter) ([]repository.Method, int64, error)
+	Create(ctx context.Context, createdBy string, in CreateMethod) (*repository.Method, error)
+	Get(ctx context.Context, id string) (*repository.Method, error)
+	// Update/Delete: solo el creador; los métodos sembrados son de solo lectura
+	Update(ctx context.Context, userID, id string, in UpdateMethod) (*repository.Method, error)
+	Delete(ctx context.Context, userID, id string) error
+
+	ExpandPrescription(ctx context.Context, prescriptionID string) (*ExpandedPrescription, error)
+}
+
+type CreateMethod struct {
+	Key    string          `json:"key" binding:"required"`
+	Name   string          `json:"name" binding:"required"`
+	Kind   string          `json:"kind" binding:"required"`
+	Params json.RawMessage `json:"params"`
+}
+
+type UpdateMethod struct {
+	Name   *string         `json:"name"`
+	Kind   *string         `json:"kind"`
+	Params json.RawMessage `json:"params"`
+}
+
+type ExpandedPrescription struct {
+	PrescriptionID string             `json:"prescription_id"`
+	Method         *repository.Method `json:"method,omitempty"`
+	Sets           []ExpectedSet      `json:"sets"`
+}
+
+var methodKeyRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,39}$`)
+
+var (
+	ErrInvalidMethodKey = errors.New("invalid_method_key")
+	ErrMethodReadOnly   = errors.New("method_read_only")
+	ErrNotMethodOwner   = errors.New("not_method_owner")
+)
+
+// methodWritable: los métodos sembrados (created_by NULL) no se editan.
+func methodWritable(m *repository.Method, userID string) error {
+	if m.CreatedBy == nil {
+		return ErrMethodReadOnly
+	}
+	if *m.CreatedBy != userID {
+		return ErrNotMethodOwner
+	}
+	return nil
+}
+
+type methodService struct{ repo repository.MethodRepository }
+
+func NewMethodService(r repository.MethodRepository) MethodService {
+	return &methodService{repo: r}
+}
+
+func (s *methodService) List(ctx context.Context, f repository.MethodFilter) ([]repository.Method, int64, error) {
+	if f.Limit < 0 {
+		f.Limit = 0
+	}
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+	return s.repo.Search(ctx, f)
+}
+
+func (s *methodService) Create(ctx context.Context, createdBy string, in CreateMethod) (*repository.Method, error) {
+	key := strings.ToLower(strings.TrimSpace(in.Key))
+	if !methodKeyRe.MatchString(key) {
+		return nil, ErrInvalidMethodKey
+	}
+	name := strings.TrimSpace(in.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	kind := strings.ToLower(strings.TrimSpace(in.Kind))
+	params, err := normalizeMethodParams(kind, in.Params)
+	if err != nil {
+		return nil, err
+	}
+	m := &repository.Method{Key: key, Name: name, Kind: kind, Params: params}
+	if createdBy != "" {
+		m.CreatedBy = &createdBy
+	}
+	if err := s.repo.Create(ctx, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (s *methodService) Get(ctx context.Context, id string) (*repository.Method, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Update: si cambia kind o params, se re-valida params contra el schema final.
+func (s *methodService) Update(ctx context.Context, userID, id string, in UpdateMethod) (*repository.Method, error) {
+	cur, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := methodWritable(cur, userID); err != nil {
+		return nil, err
+	}
+	patch := map[string]any{}
+	if in.Name != nil {
+		name := strings.TrimSpace(*in.Name)
+		if name == "" {
+			return nil, errors.New("name is required")
+		}
+		patch["name"] = name
+	}
+	if in.Kind != nil || len(in.Params) > 0 {
+		kind := cur.Kind
+		if in.Kind != nil {
+			kind = strings.ToLower(strings.TrimSpace(*in.Kind))
+		}
+		raw := []byte(cur.Params)
+		if len(in.Params) > 0 {
+			raw = in.Params
+		}
+		params, err := normalizeMethodParams(kind, raw)
+		if err != nil {
+			return nil, err
+		}
+		patch["kind"] = kind
+		patch["params"] = params
+	}
+	if len(patch) == 0 {
+		return cur, nil
+	}
+	return s.repo.Update(ctx, id, patch)
+}
+
+func (s *methodService) Delete(ctx context.Context, userID, id string) error {
+	m, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := methodWritable(m, userID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *methodService) ExpandPrescription(ctx context.Context, prescriptionID string) (*ExpandedPrescription, error) {
+	pr, err := s.repo.GetPrescription(ctx, prescriptionID)
+	if err != nil {
+		return nil, err
+	}
+	spec := PrescriptionSpec{Series: pr.Series, Reps: pr.Reps, RestSec: pr.RestSec, ToFailure: pr.ToFailure}
+	out := &ExpandedPrescription{PrescriptionID: pr.ID}
+	if pr.MethodID == nil {
+		out.Sets = ExpandPrescription(spec, nil)
+		return out, nil
+	}
+	m, err := s.repo.Get(ctx, *pr.MethodID)
+	if err != nil {
+		return nil, err
+	}
+	params, err := ParseMethodParams(m.Kind, m.Params)
+	if err != nil {
+		return nil, err
+	}
+	out.Method = m
+	out.Sets = ExpandPrescription(spec, params)
+	return out, nil
+}
+
+// normalizeMethodParams valida y re-serializa params con defaults aplicados.
+func normalizeMethodParams(kind string, raw []byte) (repository.JSONB, error) {
+	p, err := ParseMethodParams(kind, raw)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return repository.JSONB(b), nil
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/notify"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"gorm.io/gorm"
+)
+
+// NotifyEvent: lo que publica un servicio. Los destinatarios los decide
+// NotificationService según Kind.
+type NotifyEvent struct {
+	Kind       string // notify.Event*
+	ActorID    string
+	DiscipleID string
+	Email      string // destinatario directo (invitado aún sin cuenta)
+	Data       map[string]string
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, ev NotifyEvent) error
+	// NotifyTx encola dentro de tx: el aviso se confirma o se deshace junto
+	// con la escritura que lo origina.
+	NotifyTx(ctx context.Context, tx *gorm.DB, ev NotifyEvent) error
+}
+
+type NotificationService interface {
+	Notifier
+	// DispatchDue envía un lote de la outbox; devuelve cuántos salieron.
+	DispatchDue(ctx context.Context) (int, error)
+}
+
+const (
+	outboxBatch = 50
+	outboxLease = 5 * time.Minute
+	sendTimeout = 30 * time.Second
+)
+
+type notificationService struct {
+	repo     repository.NotificationRepository
+	channels map[string]notify.Channel
+}
+
+// NewNotificationService: sin canales, Notify no encola nada.
+func NewNotificationService(repo repository.NotificationRepository, channels ...notify.Channel) NotificationService {
+	m := map[string]notify.Channel{}
+	for _, ch := range channels {
+		if ch != nil {
+			m[ch.Name()] = ch
+		}
+	}
+	return &notificationService{repo: repo, channels: m}
+}
+
+// emit publica sin afectar la operación principal: un error solo se loguea.
+func emit(ctx context.Context, n Notifier, ev NotifyEvent) {
+	if n == nil {
+		return
+	}
+	if err := n.Notify(ctx, ev); err != nil {
+		log.Printf("[Notify] %s: %v", ev.Kind, err)
+	}
+}
+
+// emitTx encola en la transacción de la escritura: si el aviso no se puede
+// guardar, la escritura se deshace (el error sube).
+func emitTx(ctx context.Context, tx *gorm.DB, n Notifier, ev NotifyEvent) error {
+	if n == nil {
+		return nil
+	}
+	return n.NotifyTx(ctx, tx, ev)
+}
+
+func (s *notificationService) Notify(ctx context.Context, ev NotifyEvent) error {
+	return s.enqueue(ctx, s.repo, ev)
+}
+
+func (s *notificationService) NotifyTx(ctx context.Context, tx *gorm.DB, ev NotifyEvent) error {
+	return s.enqueue(ctx, s.repo.WithTx(tx), ev)
+}
+
+func (s *notificationService) enqueue(ctx context.Context, repo repository.NotificationRepository, ev NotifyEvent) error {
+	if len(s.channels) == 0 {
+		return nil
+	}
+	data := map[string]string{}
+	for k, v := range ev.Data {
+		data[k] = v
+	}
+	if err := fillNames(ctx, repo, ev, data); err != nil {
+		return err
+	}
+	recipients, err := recipientsFor(ctx, repo, ev, data)
+	if err != nil {
+		return err
+	}
+
+	var msgs []repository.OutboxMessage
+	if _, ok := s.channels[notify.ChannelEmail]; ok {
+		for _, r := range recipients {
+			if r.Email == "" {
+				continue
+			}
+			data["recipient_name"] = r.Name
+			subject, body, err := notify.Render(ev.Kind, data)
+			if err != nil {
+				return err
+			}
+			to := r.Email
+			msgs = append(msgs, repository.OutboxMessage{
+				Channel: notify.ChannelEmail, Event: ev.Kind, Recipient: &to, Subject: subject, Body: body,
+			})
+		}
+		delete(data, "recipient_name")
+	}
+	if _, ok := s.channels[notify.ChannelWebhook]; ok && !notify.Private(ev.Kind) {
+		raw, err := json.Marshal(map[string]any{
+			"event":       ev.Kind,
+			"actor_id":    ev.ActorID,
+			"disciple_id": ev.DiscipleID,
+			"data":        data,
+			"occurred_at": time.Now().UTC(),
+		})
+		if err != nil {
+			return err
+		}
+		payload := string(raw)
+		msgs = append(msgs, repository.OutboxMessage{
+			Channel: notify.ChannelWebhook, Event: ev.Kind, Payload: &payload,
+		})
+	}
+	return repo.Enqueue(ctx, msgs)
+}
+
+// fillNames agrega actor_name / disciple_name si el servicio no los trajo.
+func fillNames(ctx context.Context, repo repository.NotificationRepository, ev NotifyEvent, data map[string]string) error {
+	var ids []string
+	for _, id := range []string{ev.ActorID, ev.DiscipleID} {
+		if id != "" {
+			ids = append(ids, id)
+		}
+	}
+	users, err := repo.Recipients(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if u.ID == ev.ActorID && data["actor_name"] == "" {
+			data["actor_name"] = u.Name
+		}
+		if u.ID == ev.DiscipleID && data["disciple_name"] == "" {
+			data["disciple_name"] = u.Name
+		}
+	}
+	return nil
+}
+
+func recipientsFor(ctx context.Context, repo repository.NotificationRepository, ev NotifyEvent, data map[string]string) ([]repository.Recipient, error) {
+	switch ev.Kind {
+	case notify.EventInviteSent:
+		return []repository.Recipient{{Email: ev.Email, Name: data["name"]}}, nil
+	case notify.EventAssignmentCreated:
+		// autoasignación: no hay a quién avisar
+		if ev.DiscipleID == "" || ev.DiscipleID == ev.ActorID {
+			return nil, nil
+		}
+		return repo.Recipients(ctx, []string{ev.DiscipleID})
+	case notify.EventSessionCompleted, notify.EventCheckinSubmitted:
+		return repo.CoachesOf(ctx, ev.DiscipleID)
+	case notify.EventPasswordReset, notify.EventEmailVerification:
+		return repo.Recipients(ctx, []string{ev.ActorID})
+	default:
+		return nil, notify.ErrUnknownEvent
+	}
+}
+
+func (s *notificationService) DispatchDue(ctx context.Context) (int, error) {
+	if len(s.channels) == 0 {
+		return 0, nil
+	}
+	due, err := s.repo.ClaimDue(ctx, outboxBatch, outboxLease)
+	if err != nil {
+		log.Printf("[DispatchDue] claim error: %v", err)
+		return 0, err
+	}
+	sent := 0
+	for _, m := range due {
+		ch, ok := s.channels[m.Channel]
+		if !ok {
+			_ = s.repo.MarkFailed(ctx, m.ID, "channel_disabled", nil)
+			continue
+		}
+		msg := notify.Message{Event: m.Event, Subject: m.Subject, Body: m.Body}
+		if m.Recipient != nil {
+			msg.To = *m.Recipient
+		}
+		if m.Payload != nil {
+			msg.Payload = []byte(*m.Payload)
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		err := ch.Send(sendCtx, msg)
+		cancel()
+		if err == nil {
+			if err := s.repo.MarkSent(ctx, m.ID, time.Now()); err != nil {
+				log.Printf("[DispatchDue] mark sent %s: %v", m.ID, err)
+			}
+			sent++
+			continue
+		}
+
+		var next *time.Time
+		if m.Attempts < notify.MaxAttempts {
+			t := time.Now().Add(notify.Backoff(m.Attempts))
+			next = &t
+		}
+		log.Printf("[DispatchDue] %s %s intento %d: %v", m.Channel, m.ID, m.Attempts, err)
+		if err := s.repo.MarkFailed(ctx, m.ID, err.Error(), next); err != nil {
+			log.Printf("[DispatchDue] mark failed %s: %v", m.ID, err)
+		}
+	}
+	return sent, nil
+}
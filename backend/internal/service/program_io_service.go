@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/vicepalma/roma-system/backend/internal/programio"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/schedule"
+)
+
+// Import con ejercicios/métodos sin resolver: no se escribe nada.
+var ErrUnresolvedImport = errors.New("unresolved_exercises")
+
+type ProgramIOService interface {
+	Export(ctx context.Context, programID string) (*programio.Doc, error)
+	Import(ctx context.Context, ownerID, kind string, doc *programio.Doc, dryRun bool) (*ImportReport, error)
+}
+
+type ImportReport struct {
+	DryRun         bool                   `json:"dry_run"`
+	Ready          bool                   `json:"ready"` // todo resuelto, se puede importar
+	Program        *repository.ProgramRow `json:"program,omitempty"`
+	Weeks          int                    `json:"weeks"`
+	Days           int                    `json:"days"`
+	Prescriptions  int                    `json:"prescriptions"`
+	Matched        []MatchedExercise      `json:"matched"`    // resueltos por similitud (no exactos)
+	Unresolved     []UnresolvedExercise   `json:"unresolved"` // bloquean el import
+	UnknownMethods []string               `json:"unknown_methods"`
+}
+
+type MatchedExercise struct {
+	Name       string  `json:"name"`
+	ExerciseID string  `json:"exercise_id"`
+	Exercise   string  `json:"exercise"`
+	Score      float64 `json:"score"`
+}
+
+type UnresolvedExercise struct {
+	Name        string             `json:"name"`
+	Count       int                `json:"count"`
+	Suggestions []programio.Scored `json:"suggestions"`
+}
+
+func (r *ImportReport) Resolved() bool {
+	return len(r.Unresolved) == 0 && len(r.UnknownMethods) == 0
+}
+
+type programIOService struct {
+	programs  repository.ProgramRepository
+	exercises repository.ExerciseRepository
+	methods   repository.MethodRepository
+}
+
+func NewProgramIOService(p repository.ProgramRepository, e repository.ExerciseRepository, m repository.MethodRepository) ProgramIOService {
+	return &programIOService{programs: p, exercises: e, methods: m}
+}
+
+func (s *programIOService) Export(ctx context.Context, programID string) (*programio.Doc, error) {
+	t, err := s.programs.ExportTree(ctx, programID)
+	if err != nil {
+		return nil, err
+	}
+	doc := programio.New(programio.Meta{
+		Title:        t.Program.Title,
+		Notes:        t.Program.Notes,
+		BlockRepeats: t.Program.BlockRepeats,
+		EndBehavior:  t.Program.EndBehavior,
+		Tags:         t.Program.Tags,
+		Level:        t.Program.Level,
+		Goal:         t.Program.Goal,
+	})
+	for _, w := range t.Weeks {
+		wk := programio.Week{Week: w.WeekIndex, Days: []programio.Day{}}
+		for _, d := range w.Days {
+			day := programio.Day{Day: d.DayIndex, Title: d.Title, Notes: d.Notes, Rest: d.IsRest, Prescriptions: []programio.Prescription{}}
+			for _, p := range d.Prescriptions {
+				day.Prescriptions = append(day.Prescriptions, programio.Prescription{
+					Exercise:  p.ExerciseName,
+					Series:    p.Series,
+					Reps:      p.Reps,
+					RestSec:   p.RestSec,
+					ToFailure: p.ToFailure,
+					Tempo:     p.Tempo,
+					RIR:       p.RIR,
+					RPE:       p.RPE,
+					Method:    p.MethodKey,
+					Notes:     p.Notes,
+				})
+			}
+			wk.Days = append(wk.Days, day)
+		}
+		doc.Weeks = append(doc.Weeks, wk)
+	}
+	return doc, nil
+}
+
+// Import resuelve ejercicios (por id o nombre aproximado) y métodos (por key).
+// Con dryRun o con nombres sin resolver solo devuelve el reporte.
+func (s *programIOService) Import(ctx context.Context, ownerID, kind string, doc *programio.Doc, dryRun bool) (*ImportReport, error) {
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	end := schedule.EndLoop
+	if doc.Program.EndBehavior != "" {
+		e, ok := schedule.ParseEndBehavior(doc.Program.EndBehavior)
+		if !ok {
+			return nil, ErrInvalidSchedule
+		}
+		end = e
+	}
+	level := normalizePtr(doc.Program.Level)
+	if level != nil {
+		lvl := strings.ToLower(*level)
+		switch lvl {
+		case "beginner", "intermediate", "advanced":
+			level = &lvl
+		default:
+			return nil, ErrInvalidLevel
+		}
+	}
+
+	exs, _, err := s.exercises.Search(ctx, repository.ExerciseFilter{})
+	if err != nil {
+		return nil, err
+	}
+	cands := make([]programio.Candidate, 0, len(exs))
+	for _, e := range exs {
+		cands = append(cands, programio.Candidate{ID: e.ID, Name: e.Name})
+	}
+	matcher := programio.NewMatcher(cands)
+
+	ms, _, err := s.methods.Search(ctx, repository.MethodFilter{})
+	if err != nil {
+		return nil, err
+	}
+	methodByKey := make(map[string]string, len(ms))
+	for _, m := range ms {
+		methodByKey[m.Key] = m.ID
+	}
+
+	rep := &ImportReport{DryRun: dryRun, Matched: []MatchedExercise{}, Unresolved: []UnresolvedExercise{}, UnknownMethods: []string{}}
+	resolved := map[string]string{} // nombre normalizado -> exercise_id
+	unresolved := map[string]int{}  // nombre normalizado -> índice en rep.Unresolved
+	unknownMethod := map[string]bool{}
+
+	resolve := func(p *programio.Prescription) (exerciseID string, methodID *string) {
+		if p.ExerciseID != nil && *p.ExerciseID != "" {
+			if c, ok := matcher.ByID(*p.ExerciseID); ok {
+				exerciseID = c.ID
+			}
+		}
+		key := programio.Normalize(p.Exercise)
+		if exerciseID == "" {
+			if id, ok := resolved[key]; ok {
+				exerciseID = id
+			} else if i, ok := unresolved[key]; ok {
+				rep.Unresolved[i].Count++
+			} else if best, sugg, ok := matcher.Match(p.Exercise); ok {
+				resolved[key] = best.ID
+				exerciseID = best.ID
+				if best.Score < 1 {
+					rep.Matched = append(rep.Matched, MatchedExercise{Name: p.Exercise, ExerciseID: best.ID, Exercise: best.Name, Score: best.Score})
+				}
+			} else {
+				name := p.Exercise
+				if name == "" && p.ExerciseID != nil {
+					name = *p.ExerciseID
+				}
+				if sugg == nil {
+					sugg = []programio.Scored{}
+				}
+				unresolved[key] = len(rep.Unresolved)
+				rep.Unresolved = append(rep.Unresolved, UnresolvedExercise{Name: name, Count: 1, Suggestions: sugg})
+			}
+		}
+		if p.Method != nil && strings.TrimSpace(*p.Method) != "" {
+			k := strings.ToLower(strings.TrimSpace(*p.Method))
+			if id, ok := methodByKey[k]; ok {
+				methodID = &id
+			} else if !unknownMethod[k] {
+				unknownMethod[k] = true
+				rep.UnknownMethods = append(rep.UnknownMethods, k)
+			}
+		}
+		return exerciseID, methodID
+	}
+
+	blockRepeats := doc.Program.BlockRepeats
+	if blockRepeats < 1 {
+		blockRepeats = 1
+	}
+	tree := &repository.ProgramTree{Program: repository.ProgramRow{
+		OwnerID:      ownerID,
+		Title:        strings.TrimSpace(doc.Program.Title),
+		Notes:        normalizePtr(doc.Program.Notes),
+		Kind:         kind,
+		BlockRepeats: blockRepeats,
+		EndBehavior:  string(end),
+		Tags:         pq.StringArray(uniqueLower(doc.Program.Tags)),
+		Level:        level,
+		Goal:         normalizePtr(doc.Program.Goal),
+	}}
+	for _, w := range doc.Weeks {
+		rep.Weeks++
+		wt := repository.WeekTree{WeekIndex: w.Week}
+		for _, d := range w.Days {
+			rep.Days++
+			dt := repository.DayTree{DayIndex: d.Day, Title: normalizePtr(d.Title), Notes: normalizePtr(d.Notes), IsRest: d.Rest}
+			for i := range d.Prescriptions {
+				p := &d.Prescriptions[i]
+				rep.Prescriptions++
+				exID, methodID := resolve(p)
+				dt.Prescriptions = append(dt.Prescriptions, repository.PrescriptionTree{
+					ExerciseID: exID,
+					Series:     p.Series,
+					Reps:       strings.TrimSpace(p.Reps),
+					RestSec:    p.RestSec,
+					ToFailure:  p.ToFailure,
+					Tempo:      normalizePtr(p.Tempo),
+					RIR:        p.RIR,
+					RPE:        p.RPE,
+					MethodID:   methodID,
+					Notes:      normalizePtr(p.Notes),
+				})
+			}
+			if dt.Title == nil {
+				// program_days.title es NOT NULL; mismo default que AddDay
+				t := "Day " + strconv.Itoa(d.Day)
+				dt.Title = &t
+			}
+			wt.Days = append(wt.Days, dt)
+		}
+		tree.Weeks = append(tree.Weeks, wt)
+	}
+
+	rep.Ready = rep.Resolved()
+	if dryRun {
+		return rep, nil
+	}
+	if !rep.Ready {
+		return rep, ErrUnresolvedImport
+	}
+	prog, err := s.programs.ImportTree(ctx, tree)
+	if err != nil {
+		return nil, err
+	}
+	rep.Program = prog
+	return rep, nil
+}
@@ -2,15 +2,24 @@ package service
 
 import (
 	"context"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/vicepalma/roma-system/backend/internal/domain"
 	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/schedule"
 )
 
+// block_repeats < 1 o end_behavior fuera de stop|loop|hold
+var ErrInvalidSchedule = errors.New("invalid_schedule")
+
+// level fuera de beginner|intermediate|advanced
+var ErrInvalidLevel = errors.New("invalid_level")
+
 type CreateProgram struct {
 	Title      string  `json:"title" binding:"required,min=2"`
 	Notes      *string `json:"notes"`
@@ -18,9 +27,15 @@ type CreateProgram struct {
 }
 
 type UpdateProgram struct {
-	Title      *string `json:"title"`
-	Notes      *string `json:"notes"`
-	Visibility *string `json:"visibility"`
+	Title        *string `json:"title"`
+	Notes        *string `json:"notes"`
+	Visibility   *string `json:"visibility"`
+	BlockRepeats *int    `json:"block_repeats"`
+	EndBehavior  *string `json:"end_behavior"` // stop | loop | hold
+	// metadatos de plantilla
+	Tags  *[]string `json:"tags"`
+	Level *string   `json:"level"` // beginner | intermediate | advanced | "" (sin nivel)
+	Goal  *string   `json:"goal"`
 }
 
 type CreatePrescription struct {
@@ -57,7 +72,7 @@ type ProgramService interface {
 	ListMyPrograms(ctx context.Context, ownerID string, limit, offset int) ([]domain.Program, int64, error)
 	AddWeek(ctx context.Context, programID string, weekIndex int) (*domain.ProgramWeek, error)
 	DeleteWeek(ctx context.Context, programID, weekID string) error
-	AddDay(ctx context.Context, weekID string, dayIndex int, notes *string) (*domain.ProgramDay, error)
+	AddDay(ctx context.Context, weekID string, dayIndex int, notes *string, isRest bool) (*domain.ProgramDay, error)
 	AddPrescription(ctx context.Context, p *domain.Prescription) (*domain.Prescription, error)
 	Assign(ctx context.Context, programID, discipleID, assignedBy string, start time.Time, end *time.Time) (*domain.Assignment, error)
 	MyToday(ctx context.Context, discipleID string, date time.Time) (*domain.ProgramDay, []domain.Prescription, error)
@@ -73,7 +88,7 @@ type ProgramService interface {
 
 	ListWeeks(ctx context.Context, programID string) ([]repository.ProgramWeek, error)
 	ListDays(ctx context.Context, weekID string) ([]repository.ProgramDay, error)
-	UpdateDay(ctx context.Context, dayID string, notes *string, dayIndex *int) (*repository.ProgramDay, error)
+	UpdateDay(ctx context.Context, dayID string, notes *string, dayIndex *int, isRest *bool) (*repository.ProgramDay, error)
 	DeleteDay(ctx context.Context, dayID string) error
 
 	ListPrescriptions(ctx context.Context, dayID string) ([]repository.PrescriptionRow, error)
@@ -86,6 +101,9 @@ type ProgramService interface {
 	DeleteProgram(ctx context.Context, id string) error
 	CreateNextVersionClone(ctx context.Context, programID string) (*repository.ProgramRow, error)
 	CreateSelfAssignment(ctx context.Context, userID, programID string, start time.Time) (*domain.Assignment, error)
+
+	ListTemplates(ctx context.Context, f repository.TemplateFilter) ([]repository.ProgramTemplateRow, int64, error)
+	CloneTemplate(ctx context.Context, templateID, ownerID, kind string, title *string) (*repository.ProgramRow, error)
 }
 
 type programService struct{ repo repository.ProgramRepository }
@@ -125,9 +143,9 @@ func (s *programService) DeleteWeek(ctx context.Context, programID, weekID strin
 	return s.repo.DeleteWeek(ctx, programID, weekID)
 }
 
-func (s *programService) AddDay(ctx context.Context, weekID string, dayIndex int, notes *string) (*domain.ProgramDay, error) {
+func (s *programService) AddDay(ctx context.Context, weekID string, dayIndex int, notes *string, isRest bool) (*domain.ProgramDay, error) {
 	title := "Day " + strconv.Itoa(dayIndex)
-	d := &domain.ProgramDay{WeekID: weekID, DayIndex: dayIndex, Title: &title, Notes: notes}
+	d := &domain.ProgramDay{WeekID: weekID, DayIndex: dayIndex, Title: &title, Notes: notes, IsRest: isRest}
 	return d, s.repo.AddDay(ctx, d)
 }
 
@@ -209,6 +227,36 @@ func (s *programService) Update(ctx context.Context, id string, in UpdateProgram
 	if in.Visibility != nil {
 		patch["visibility"] = strings.TrimSpace(*in.Visibility)
 	}
+	if in.BlockRepeats != nil {
+		if *in.BlockRepeats < 1 {
+			return nil, ErrInvalidSchedule
+		}
+		patch["block_repeats"] = *in.BlockRepeats
+	}
+	if in.EndBehavior != nil {
+		end, ok := schedule.ParseEndBehavior(*in.EndBehavior)
+		if !ok {
+			return nil, ErrInvalidSchedule
+		}
+		patch["end_behavior"] = string(end)
+	}
+	if in.Tags != nil {
+		patch["tags"] = pq.StringArray(uniqueLower(*in.Tags))
+	}
+	if in.Level != nil {
+		lvl := strings.ToLower(strings.TrimSpace(*in.Level))
+		switch lvl {
+		case "":
+			patch["level"] = nil
+		case "beginner", "intermediate", "advanced":
+			patch["level"] = lvl
+		default:
+			return nil, ErrInvalidLevel
+		}
+	}
+	if in.Goal != nil {
+		patch["goal"] = normalizePtr(in.Goal)
+	}
 	return s.repo.Update(ctx, id, patch)
 }
 
@@ -250,7 +298,7 @@ func (s *programService) ListDays(ctx context.Context, weekID string) ([]reposit
 	return s.repo.ListDays(ctx, weekID)
 }
 
-func (s *programService) UpdateDay(ctx context.Context, dayID string, notes *string, dayIndex *int) (*repository.ProgramDay, error) {
+func (s *programService) UpdateDay(ctx context.Context, dayID string, notes *string, dayIndex *int, isRest *bool) (*repository.ProgramDay, error) {
 	patch := map[string]any{}
 	if notes != nil {
 		patch["notes"] = strings.TrimSpace(*notes)
@@ -258,6 +306,9 @@ func (s *programService) UpdateDay(ctx context.Context, dayID string, notes *str
 	if dayIndex != nil {
 		patch["day_index"] = *dayIndex
 	}
+	if isRest != nil {
+		patch["is_rest"] = *isRest
+	}
 	return s.repo.UpdateDay(ctx, dayID, patch)
 }
 
@@ -354,3 +405,19 @@ func (s *programService) CreateNextVersionClone(ctx context.Context, programID s
 func (s *programService) CreateSelfAssignment(ctx context.Context, userID, programID string, start time.Time) (*domain.Assignment, error) {
 	return s.repo.ActivateSelfAssignment(ctx, userID, programID, start)
 }
+
+func (s *programService) ListTemplates(ctx context.Context, f repository.TemplateFilter) ([]repository.ProgramTemplateRow, int64, error) {
+	f.Tags = uniqueLower(f.Tags)
+	f.Level = strings.ToLower(strings.TrimSpace(f.Level))
+	switch f.Level {
+	case "", "beginner", "intermediate", "advanced":
+	default:
+		return nil, 0, ErrInvalidLevel
+	}
+	return s.repo.SearchTemplates(ctx, f)
+}
+
+// CloneTemplate: kind lo decide el llamador según rol (coach_program | self_training).
+func (s *programService) CloneTemplate(ctx context.Context, templateID, ownerID, kind string, title *string) (*repository.ProgramRow, error) {
+	return s.repo.CloneTemplate(ctx, templateID, ownerID, kind, title)
+}
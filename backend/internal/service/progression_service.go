@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/vicepalma/roma-system/backend/internal/progression"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+)
+
+var (
+	ErrPrescriptionNotInAssignment = errors.New("prescription_not_in_assignment")
+	ErrEmptyOverride               = errors.New("empty_override")
+)
+
+type ProgressionService interface {
+	// scope: "prescription" | "method"
+	GetRule(ctx context.Context, scope, id string) (*repository.ProgressionRule, error)
+	// en scope "method" solo escribe el creador del método (ver methodWritable)
+	SetRule(ctx context.Context, scope, id, createdBy string, in SetProgressionRule) (*repository.ProgressionRule, error)
+	DeleteRule(ctx context.Context, scope, id, userID string) error
+
+	// Target: objetivo de la próxima sesión; nil si no hay regla ni override.
+	Target(ctx context.Context, assignmentID, prescriptionID string) (*progression.Target, error)
+	SetOverride(ctx context.Context, assignmentID, prescriptionID, coachID string, in SetProgressionOverride) (*repository.ProgressionOverride, error)
+	DeleteOverride(ctx context.Context, assignmentID, prescriptionID string) error
+}
+
+type SetProgressionRule struct {
+	Kind   string          `json:"kind" binding:"required"`
+	Params json.RawMessage `json:"params"`
+}
+
+type SetProgressionOverride struct {
+	Weight *float64 `json:"weight"`
+	Reps   *string  `json:"reps"`
+	RPE    *float32 `json:"rpe"`
+	Notes  *string  `json:"notes"`
+}
+
+type progressionService struct {
+	repo    repository.ProgressionRepository
+	methods repository.MethodRepository
+}
+
+func NewProgressionService(r repository.ProgressionRepository, m repository.MethodRepository) ProgressionService {
+	return &progressionService{repo: r, methods: m}
+}
+
+func (s *progressionService) GetRule(ctx context.Context, scope, id string) (*repository.ProgressionRule, error) {
+	return s.repo.GetRule(ctx, scope, id)
+}
+
+// SetRule valida params contra el kind y guarda la versión con defaults aplicados.
+func (s *progressionService) SetRule(ctx context.Context, scope, id, createdBy string, in SetProgressionRule) (*repository.ProgressionRule, error) {
+	if err := s.checkMethod(ctx, scope, id, createdBy); err != nil {
+		return nil, err
+	}
+	kind := progression.Kind(strings.ToLower(strings.TrimSpace(in.Kind)))
+	params, err := progression.ParseParams(kind, in.Params)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	rule := &repository.ProgressionRule{Kind: string(kind), Params: repository.JSONB(raw)}
+	if scope == "method" {
+		rule.MethodID = &id
+	} else {
+		rule.PrescriptionID = &id
+	}
+	if createdBy != "" {
+		rule.CreatedBy = &createdBy
+	}
+	if err := s.repo.SaveRule(ctx, rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func (s *progressionService) DeleteRule(ctx context.Context, scope, id, userID string) error {
+	if err := s.checkMethod(ctx, scope, id, userID); err != nil {
+		return err
+	}
+	return s.repo.DeleteRule(ctx, scope, id)
+}
+
+// checkMethod: la regla de un método es parte del método. Las de prescripción
+// ya pasan por la política de la ruta.
+func (s *progressionService) checkMethod(ctx context.Context, scope, id, userID string) error {
+	if scope != "method" {
+		return nil
+	}
+	m, err := s.methods.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return methodWritable(m, userID)
+}
+
+func (s *progressionService) Target(ctx context.Context, assignmentID, prescriptionID string) (*progression.Target, error) {
+	if err := s.checkPrescription(ctx, assignmentID, prescriptionID); err != nil {
+		return nil, err
+	}
+	targets, err := s.repo.Targets(ctx, assignmentID, []string{prescriptionID})
+	if err != nil {
+		return nil, err
+	}
+	t, ok := targets[prescriptionID]
+	if !ok {
+		return nil, nil
+	}
+	return &t, nil
+}
+
+func (s *progressionService) SetOverride(ctx context.Context, assignmentID, prescriptionID, coachID string, in SetProgressionOverride) (*repository.ProgressionOverride, error) {
+	if err := s.checkPrescription(ctx, assignmentID, prescriptionID); err != nil {
+		return nil, err
+	}
+	reps := normalizePtr(in.Reps)
+	if in.Weight == nil && reps == nil && in.RPE == nil {
+		return nil, ErrEmptyOverride
+	}
+	if in.Weight != nil && *in.Weight < 0 {
+		return nil, errors.New("invalid_params: weight must be >= 0")
+	}
+	if in.RPE != nil && (*in.RPE < 1 || *in.RPE > 10) {
+		return nil, errors.New("invalid_params: rpe must be 1..10")
+	}
+	o := &repository.ProgressionOverride{
+		AssignmentID:   assignmentID,
+		PrescriptionID: prescriptionID,
+		Weight:         in.Weight,
+		Reps:           reps,
+		RPE:            in.RPE,
+		Notes:          normalizePtr(in.Notes),
+		CreatedBy:      coachID,
+	}
+	if err := s.repo.SaveOverride(ctx, o); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (s *progressionService) DeleteOverride(ctx context.Context, assignmentID, prescriptionID string) error {
+	return s.repo.DeleteOverride(ctx, assignmentID, prescriptionID)
+}
+
+func (s *progressionService) checkPrescription(ctx context.Context, assignmentID, prescriptionID string) error {
+	ok, err := s.repo.PrescriptionInAssignment(ctx, assignmentID, prescriptionID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrPrescriptionNotInAssignment
+	}
+	return nil
+}
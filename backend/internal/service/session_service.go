@@ -3,11 +3,15 @@ package service
 import (
 	"context"
 	"errors"
+	"log"
 	"strings"
 	"time"
 
 	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/notify"
 	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/sessionsync"
+	"github.com/vicepalma/roma-system/backend/internal/timing"
 	"gorm.io/gorm"
 )
 
@@ -20,7 +24,12 @@ type repositorySetLog = struct {
 	Reps           int      `json:"reps"`
 	RPE            *float64 `json:"rpe,omitempty"`
 	ToFailure      bool     `json:"to_failure"`
+	SetType        string   `json:"set_type"`
+	ParentSetID    *string  `json:"parent_set_id,omitempty"`
 	CreatedAt      string   `json:"created_at"`
+
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
 }
 
 type SessionDetail struct {
@@ -32,6 +41,49 @@ type SessionDetail struct {
 	Status       string     `json:"status"`
 	EndedAt      *time.Time `json:"ended_at,omitempty"`
 	Notes        *string    `json:"notes,omitempty"`
+	// última serie o cardio registrado; auto_closed = la cerró el barrido
+	LastActivityAt *time.Time `json:"last_activity_at,omitempty"`
+	AutoClosed     bool       `json:"auto_closed"`
+	DurationSec    int64      `json:"duration_sec"`
+}
+
+var (
+	// ErrOpenSessionExists: el discípulo ya tiene otra sesión abierta.
+	ErrOpenSessionExists = repository.ErrOpenSessionExists
+	// ErrInvalidSetTiming: started_at posterior a completed_at, o una hora
+	// en el futuro (más allá del desfase de reloj tolerado).
+	ErrInvalidSetTiming = errors.New("invalid_set_timing")
+	// ErrInvalidSetType: tipo desconocido, o drop sin padre / padre en otro tipo.
+	ErrInvalidSetType = errors.New("invalid_set_type")
+	// ErrInvalidParentSet: el padre del drop no es una serie de la misma
+	// sesión y prescripción.
+	ErrInvalidParentSet = repository.ErrInvalidParentSet
+)
+
+func checkSetTiming(startedAt, completedAt *time.Time, now time.Time) error {
+	limit := now.Add(sessionsync.MaxClockSkew)
+	for _, t := range []*time.Time{startedAt, completedAt} {
+		if t != nil && t.After(limit) {
+			return ErrInvalidSetTiming
+		}
+	}
+	if startedAt != nil && completedAt != nil && startedAt.After(*completedAt) {
+		return ErrInvalidSetTiming
+	}
+	return nil
+}
+
+type SessionTimeline struct {
+	SessionID   string     `json:"session_id"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`
+	DurationSec int64      `json:"duration_sec"`
+	// primera y última hora registrada en series
+	FirstSetAt *time.Time       `json:"first_set_at,omitempty"`
+	LastSetAt  *time.Time       `json:"last_set_at,omitempty"`
+	Items      []TimelineItem   `json:"items"`
+	Rest       []RestCompliance `json:"rest"`
 }
 
 type SetPatch struct {
@@ -41,31 +93,74 @@ type SetPatch struct {
 	Reps           *int     `json:"reps,omitempty"`
 	RPE            *float64 `json:"rpe,omitempty"`
 	ToFailure      *bool    `json:"to_failure,omitempty"`
+	// SetType distinto de drop suelta el padre; ParentSetID sin tipo la
+	// convierte en drop
+	SetType     *string `json:"set_type,omitempty"`
+	ParentSetID *string `json:"parent_set_id,omitempty"`
+
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// NewSet: serie a registrar. SetIndex 0 agrega al final de la prescripción
+// (un drop, al final de su cadena); SetType vacío = working, o failure con
+// ToFailure. CompletedAt nil = ahora; StartedAt es opcional (ver internal/timing).
+type NewSet struct {
+	PrescriptionID string
+	SetIndex       int
+	Weight         *float64
+	Reps           int
+	RPE            *float32
+	ToFailure      bool
+	SetType        string
+	ParentSetID    *string
+	StartedAt      *time.Time
+	CompletedAt    *time.Time
+}
+
+// AddedSet: la serie guardada más los PR que marcó (vacío si ninguno).
+type AddedSet struct {
+	*domain.SetLog
+	Records []repository.PersonalRecord `json:"records"`
 }
 
 type SessionService interface {
 	Start(ctx context.Context, discipleID, assignmentID, dayID string, performedAt *time.Time, notes *string) (*domain.SessionLog, error)
 	Get(ctx context.Context, discipleID, sessionID string) (*domain.SessionLog, []domain.SetRow, []repository.CardioSegment, error)
-	AddSet(ctx context.Context, discipleID, sessionID, prescriptionID string, setIndex int, weight *float64, reps int, rpe *float32, toFailure bool) (*domain.SetLog, error)
+	// AddSet: el índice final lo asigna el repositorio. Solo las series
+	// efectivas (domain.IsWorkingSet) marcan PR.
+	AddSet(ctx context.Context, discipleID, sessionID string, in NewSet) (*AddedSet, error)
 	AddCardio(ctx context.Context, discipleID, sessionID, modality string, minutes int, hrMin, hrMax *int, notes *string) (*repository.CardioSegment, error)
 	ListSets(ctx context.Context, actorID, sessionID string, prescriptionID *string, limit, offset int) ([]repositorySetLog, int64, error)
 
 	GetSession(ctx context.Context, id string) (*SessionDetail, error)
+	// Timeline: series en orden cronológico con descansos y cumplimiento de rest_sec.
+	Timeline(ctx context.Context, id string) (*SessionTimeline, error)
 	PatchSession(ctx context.Context, id string, performedAt *time.Time, notes *string, status *string, endedAt *time.Time) (*SessionDetail, error)
 
 	UpdateSet(ctx context.Context, setID string, patch SetPatch) error
 	DeleteSet(ctx context.Context, setID string) error
 
 	GetActiveOpenSessionForMe(ctx context.Context, discipleID string) (*domain.SessionLog, error)
+	// CloseIdle cierra las sesiones abiertas sin actividad por más del umbral (barrido periódico).
+	CloseIdle(ctx context.Context) (int64, error)
+
+	// feed de PRs del discípulo, más recientes primero
+	ListRecords(ctx context.Context, discipleID string, f repository.RecordFilter) ([]repository.PersonalRecord, int64, error)
 }
 
 type sessionService struct {
 	repo     repository.SessionRepository
 	coachSvc CoachService
+	records  repository.RecordRepository
+	notify   Notifier
+	idle     time.Duration
 }
 
-func NewSessionService(repo repository.SessionRepository, coachSvc CoachService) SessionService {
-	return &sessionService{repo: repo, coachSvc: coachSvc}
+// NewSessionService: notifier nil desactiva el aviso de sesión completada;
+// idleClose 0 desactiva el cierre de sesiones inactivas.
+func NewSessionService(repo repository.SessionRepository, coachSvc CoachService, records repository.RecordRepository, notifier Notifier, idleClose time.Duration) SessionService {
+	return &sessionService{repo: repo, coachSvc: coachSvc, records: records, notify: notifier, idle: idleClose}
 }
 
 func (s *sessionService) Start(ctx context.Context, discipleID, assignmentID, dayID string, performedAt *time.Time, notes *string) (*domain.SessionLog, error) {
@@ -82,6 +177,22 @@ func (s *sessionService) Start(ctx context.Context, discipleID, assignmentID, da
 	if performedAt != nil {
 		sess.PerformedAt = *performedAt
 	}
+	// una sola abierta por discípulo; la anterior cede solo si ya está inactiva
+	prev, err := s.repo.GetLatestOpenByDisciple(ctx, discipleID)
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		closed := int64(0)
+		if s.idle > 0 {
+			if closed, err = s.repo.CloseIdle(ctx, time.Now().Add(-s.idle), prev.ID); err != nil {
+				return nil, err
+			}
+		}
+		if closed == 0 {
+			return nil, ErrOpenSessionExists
+		}
+	}
 	if err := s.repo.CreateSession(ctx, sess); err != nil {
 		return nil, err
 	}
@@ -101,24 +212,72 @@ func (s *sessionService) Get(ctx context.Context, discipleID, sessionID string)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, nil, nil, err
 	}
-	return sess, sets, cardio, nil
+	// drops anidados bajo su serie madre
+	return sess, domain.GroupDrops(sets), cardio, nil
 }
 
-func (s *sessionService) AddSet(ctx context.Context, discipleID, sessionID, prescriptionID string, setIndex int, weight *float64, reps int, rpe *float32, toFailure bool) (*domain.SetLog, error) {
+// checkSetType: solo los drops llevan padre, y todo drop lo lleva.
+func checkSetType(setType string, parentSetID *string) error {
+	if (setType == domain.SetDrop) != (parentSetID != nil) {
+		return ErrInvalidSetType
+	}
+	return nil
+}
+
+func (s *sessionService) AddSet(ctx context.Context, discipleID, sessionID string, in NewSet) (*AddedSet, error) {
 	// verifica pertenencia del session al usuario
 	if _, err := s.repo.GetSession(ctx, sessionID, discipleID); err != nil {
 		return nil, err
 	}
+	setType, toFailure, ok := domain.NormSetType(in.SetType, in.ToFailure)
+	if !ok {
+		return nil, ErrInvalidSetType
+	}
+	if err := checkSetType(setType, in.ParentSetID); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	completedAt := in.CompletedAt
+	if completedAt == nil {
+		completedAt = &now
+	}
+	if err := checkSetTiming(in.StartedAt, completedAt, now); err != nil {
+		return nil, err
+	}
 	set := &domain.SetLog{
 		SessionID:      sessionID,
-		PrescriptionID: prescriptionID,
-		SetIndex:       setIndex,
-		Weight:         weight,
-		Reps:           reps,
-		RPE:            rpe,
+		PrescriptionID: in.PrescriptionID,
+		SetIndex:       in.SetIndex,
+		Weight:         in.Weight,
+		Reps:           in.Reps,
+		RPE:            in.RPE,
 		ToFailure:      toFailure,
+		SetType:        setType,
+		ParentSetID:    in.ParentSetID,
+		StartedAt:      in.StartedAt,
+		CompletedAt:    completedAt,
+	}
+	if err := s.repo.AddSet(ctx, set); err != nil {
+		return nil, err
+	}
+	out := &AddedSet{SetLog: set, Records: []repository.PersonalRecord{}}
+	if !domain.IsWorkingSet(setType) {
+		return out, nil
+	}
+	// la serie ya quedó guardada: un fallo al detectar PRs no debe invalidarla
+	if recs, err := s.records.DetectForSet(ctx, set.ID); err != nil {
+		log.Printf("[AddSet] detect records set=%s -> %v", set.ID, err)
+	} else {
+		out.Records = recs
+	}
+	return out, nil
+}
+
+func (s *sessionService) ListRecords(ctx context.Context, discipleID string, f repository.RecordFilter) ([]repository.PersonalRecord, int64, error) {
+	if f.Offset < 0 {
+		f.Offset = 0
 	}
-	return set, s.repo.AddSet(ctx, set)
+	return s.records.Feed(ctx, discipleID, f)
 }
 
 func (s *sessionService) AddCardio(ctx context.Context, discipleID, sessionID, modality string, minutes int, hrMin, hrMax *int, notes *string) (*repository.CardioSegment, error) {
@@ -184,6 +343,10 @@ func (s *sessionService) ListSets(ctx context.Context, actorID, sessionID string
 			Reps:           it.Reps,
 			RPE:            rpePtr,
 			ToFailure:      it.ToFailure,
+			SetType:        it.SetType,
+			ParentSetID:    it.ParentSetID,
+			StartedAt:      it.StartedAt,
+			CompletedAt:    it.CompletedAt,
 		})
 	}
 	return out, total, nil
@@ -209,6 +372,45 @@ func (s *sessionService) UpdateSet(ctx context.Context, setID string, in SetPatc
 	if in.ToFailure != nil {
 		patch["to_failure"] = *in.ToFailure
 	}
+	if in.SetType != nil || in.ParentSetID != nil {
+		setType := domain.SetDrop
+		if in.SetType != nil {
+			t, failure, ok := domain.NormSetType(*in.SetType, false)
+			if !ok || strings.TrimSpace(*in.SetType) == "" {
+				return ErrInvalidSetType
+			}
+			setType = t
+			switch {
+			case failure:
+				patch["to_failure"] = true
+			case in.ToFailure == nil:
+				// dejar de ser failure apaga to_failure (NormSetType)
+				patch["to_failure"] = false
+			}
+		}
+		switch {
+		case setType != domain.SetDrop && in.ParentSetID != nil:
+			return ErrInvalidSetType
+		case setType != domain.SetDrop:
+			patch["parent_set_id"] = nil
+		case in.ParentSetID != nil:
+			patch["parent_set_id"] = *in.ParentSetID
+		}
+		// drop sin parent_set_id conserva el padre actual (la DB exige uno)
+		patch["set_type"] = setType
+	}
+	if in.StartedAt != nil || in.CompletedAt != nil {
+		// solo se valida el par si llegan ambos; la DB rechaza un inicio posterior al fin
+		if err := checkSetTiming(in.StartedAt, in.CompletedAt, time.Now()); err != nil {
+			return err
+		}
+		if in.StartedAt != nil {
+			patch["started_at"] = *in.StartedAt
+		}
+		if in.CompletedAt != nil {
+			patch["completed_at"] = *in.CompletedAt
+		}
+	}
 	if len(patch) == 0 {
 		return nil
 	}
@@ -233,9 +435,42 @@ func (s *sessionService) GetSession(ctx context.Context, id string) (*SessionDet
 		Status:       meta.Status,
 		EndedAt:      meta.EndedAt,
 		Notes:        meta.Notes,
+
+		LastActivityAt: meta.LastActivityAt,
+		AutoClosed:     meta.AutoClosed,
+		DurationSec:    int64(domain.SessionDuration(meta.PerformedAt, meta.LastActivityAt, meta.EndedAt).Seconds()),
 	}, nil
 }
 
+func (s *sessionService) Timeline(ctx context.Context, id string) (*SessionTimeline, error) {
+	meta, err := s.repo.GetSessionMeta(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.ListTimedSets(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	items, rest, _ := timeline(rows, nil)
+	out := &SessionTimeline{
+		SessionID:   meta.ID,
+		Status:      meta.Status,
+		StartedAt:   meta.PerformedAt,
+		EndedAt:     meta.EndedAt,
+		DurationSec: int64(domain.SessionDuration(meta.PerformedAt, meta.LastActivityAt, meta.EndedAt).Seconds()),
+		Items:       items,
+		Rest:        rest,
+	}
+	entries := make([]timing.Entry, len(items))
+	for i := range items {
+		entries[i] = items[i].Entry
+	}
+	if first, last, ok := timing.Span(entries); ok {
+		out.FirstSetAt, out.LastSetAt = &first, &last
+	}
+	return out, nil
+}
+
 func (s *sessionService) PatchSession(ctx context.Context, id string, performedAt *time.Time, notes *string, status *string, endedAt *time.Time) (*SessionDetail, error) {
 	patch := map[string]any{}
 
@@ -251,6 +486,8 @@ func (s *sessionService) PatchSession(ctx context.Context, id string, performedA
 			return nil, errors.New("invalid_status")
 		}
 		patch["status"] = v
+		// cambio manual: deja de contar como cierre automático
+		patch["auto_closed"] = false
 
 		// reglas simples de consistencia con ended_at
 		if v == "closed" && endedAt == nil {
@@ -269,15 +506,52 @@ func (s *sessionService) PatchSession(ctx context.Context, id string, performedA
 		}
 	}
 
+	// solo la transición abierta -> cerrada avisa al coach
+	wasOpen := false
+	if patch["status"] == "closed" {
+		if prev, err := s.GetSession(ctx, id); err == nil {
+			wasOpen = prev.Status != "closed"
+		}
+	}
+
 	if len(patch) > 0 {
 		patch["updated_at"] = time.Now()
+		// edición en vivo: para /sessions/sync cuenta el reloj del servidor
+		patch["client_updated_at"] = nil
 		if err := s.repo.UpdateSession(ctx, id, patch); err != nil {
 			return nil, err
 		}
 	}
-	return s.GetSession(ctx, id)
+	out, err := s.GetSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if wasOpen && out.Status == "closed" {
+		data := map[string]string{"session_id": out.ID, "assignment_id": out.AssignmentID}
+		if out.EndedAt != nil {
+			data["ended_at"] = out.EndedAt.Format("02/01/2006 15:04")
+		}
+		emit(ctx, s.notify, NotifyEvent{
+			Kind:       notify.EventSessionCompleted,
+			ActorID:    out.DiscipleID,
+			DiscipleID: out.DiscipleID,
+			Data:       data,
+		})
+	}
+	return out, nil
 }
 
 func (s *sessionService) GetActiveOpenSessionForMe(ctx context.Context, discipleID string) (*domain.SessionLog, error) {
 	return s.repo.GetLatestOpenByDisciple(ctx, discipleID)
 }
+
+func (s *sessionService) CloseIdle(ctx context.Context) (int64, error) {
+	if s.idle <= 0 {
+		return 0, nil
+	}
+	n, err := s.repo.CloseIdle(ctx, time.Now().Add(-s.idle), "")
+	if err != nil {
+		log.Printf("[CloseIdle] error: %v", err)
+	}
+	return n, err
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+)
+
+// patchRepo guarda el último patch de UpdateSet.
+type patchRepo struct {
+	repository.SessionRepository
+	patch map[string]any
+}
+
+func (r *patchRepo) UpdateSet(_ context.Context, _ string, patch map[string]any) error {
+	r.patch = patch
+	return nil
+}
+
+func TestUpdateSetTypeSyncsToFailure(t *testing.T) {
+	str := func(v string) *string { return &v }
+	yes := true
+	for _, tc := range []struct {
+		name string
+		in   SetPatch
+		want any // to_failure en el patch; nil = no se toca
+	}{
+		{"failure sets it", SetPatch{SetType: str("failure")}, true},
+		{"working clears it", SetPatch{SetType: str("working")}, false},
+		{"warmup clears it", SetPatch{SetType: str("warmup")}, false},
+		{"explicit flag wins", SetPatch{SetType: str("working"), ToFailure: &yes}, true},
+		{"no type leaves it", SetPatch{Reps: new(int)}, nil},
+	} {
+		repo := &patchRepo{}
+		svc := &sessionService{repo: repo}
+		if err := svc.UpdateSet(context.Background(), "set-1", tc.in); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got, ok := repo.patch["to_failure"]
+		if (tc.want == nil && ok) || (tc.want != nil && got != tc.want) {
+			t.Fatalf("%s: patch=%v", tc.name, repo.patch)
+		}
+	}
+}
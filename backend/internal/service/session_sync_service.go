@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/notify"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/sessionsync"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSyncRejected: algún ítem es inválido; no se aplicó nada (ver SyncResult).
+	ErrSyncRejected = errors.New("sync_rejected")
+	// ErrIdempotencyKeyReused: la llave ya se usó con otro cuerpo.
+	ErrIdempotencyKeyReused = errors.New("idempotency_key_reused")
+)
+
+// Las Idempotency-Key se recuerdan lo que un dispositivo puede tardar en reintentar.
+const syncKeyRetention = 30 * 24 * time.Hour
+
+// SyncResult: resultado por ítem más los PR de las series nuevas. Replayed
+// indica que es la respuesta guardada para la misma Idempotency-Key.
+type SyncResult struct {
+	SessionID string `json:"session_id"`
+	sessionsync.Result
+	Records  []repository.PersonalRecord `json:"records"`
+	Replayed bool                        `json:"-"`
+}
+
+type SessionSyncService interface {
+	// Sync aplica una sesión registrada offline de forma atómica. Con
+	// idemKey, reintentos con el mismo cuerpo devuelven la respuesta original.
+	Sync(ctx context.Context, discipleID, idemKey string, b sessionsync.Batch) (*SyncResult, error)
+	PurgeKeys(ctx context.Context) (int64, error)
+}
+
+type sessionSyncService struct {
+	repo    repository.SessionSyncRepository
+	records repository.RecordRepository
+	notify  Notifier
+}
+
+// NewSessionSyncService: notifier nil desactiva el aviso de sesión completada.
+func NewSessionSyncService(repo repository.SessionSyncRepository, records repository.RecordRepository, notifier Notifier) SessionSyncService {
+	return &sessionSyncService{repo: repo, records: records, notify: notifier}
+}
+
+func (s *sessionSyncService) Sync(ctx context.Context, discipleID, idemKey string, b sessionsync.Batch) (*SyncResult, error) {
+	if discipleID == "" || b.Session.ID == "" {
+		return nil, errors.New("missing required fields")
+	}
+	var req *repository.SyncRequest
+	if idemKey != "" {
+		raw, err := json.Marshal(b)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(raw)
+		req = &repository.SyncRequest{DiscipleID: discipleID, IdemKey: idemKey, RequestHash: hex.EncodeToString(sum[:])}
+		if out, err := s.replay(ctx, req); !errors.Is(err, gorm.ErrRecordNotFound) {
+			return out, err
+		}
+	}
+
+	out := &SyncResult{SessionID: b.Session.ID, Records: []repository.PersonalRecord{}}
+	var plan *sessionsync.Plan
+	err := s.repo.Apply(ctx, discipleID, &b, func(st sessionsync.State) (*sessionsync.Plan, error) {
+		p, res := sessionsync.Merge(st, b, time.Now())
+		out.Result = res
+		if res.Rejected() {
+			return nil, ErrSyncRejected
+		}
+		plan = &p
+		if req != nil {
+			raw, err := json.Marshal(out)
+			if err != nil {
+				return nil, err
+			}
+			req.Response = raw
+		}
+		return &p, nil
+	}, req)
+	switch {
+	case errors.Is(err, repository.ErrSyncKeyTaken):
+		// otra petición con la misma llave ganó la carrera
+		return s.replay(ctx, req)
+	case errors.Is(err, ErrSyncRejected):
+		return out, err
+	case err != nil:
+		return nil, err
+	}
+
+	// igual que AddSet: solo las series nuevas y efectivas marcan PR, y un
+	// fallo no invalida el lote
+	for _, x := range plan.InsertSets {
+		if !domain.IsWorkingSet(x.SetType) {
+			continue
+		}
+		recs, err := s.records.DetectForSet(ctx, x.ID)
+		if err != nil {
+			log.Printf("[SessionSync] detect records set=%s -> %v", x.ID, err)
+			continue
+		}
+		out.Records = append(out.Records, recs...)
+	}
+	if req != nil && len(out.Records) > 0 {
+		if raw, err := json.Marshal(out); err == nil {
+			if err := s.repo.SaveResponse(ctx, discipleID, idemKey, raw); err != nil {
+				log.Printf("[SessionSync] save response key=%s -> %v", idemKey, err)
+			}
+		}
+	}
+
+	if plan.Closed {
+		data := map[string]string{"session_id": b.Session.ID, "assignment_id": b.Session.AssignmentID}
+		if end := plan.Session.EndedAt; end != nil {
+			data["ended_at"] = end.Format("02/01/2006 15:04")
+		}
+		emit(ctx, s.notify, NotifyEvent{
+			Kind:       notify.EventSessionCompleted,
+			ActorID:    discipleID,
+			DiscipleID: discipleID,
+			Data:       data,
+		})
+	}
+	return out, nil
+}
+
+// replay devuelve la respuesta guardada para req.IdemKey (gorm.ErrRecordNotFound si no hay).
+func (s *sessionSyncService) replay(ctx context.Context, req *repository.SyncRequest) (*SyncResult, error) {
+	prev, err := s.repo.FindRequest(ctx, req.DiscipleID, req.IdemKey)
+	if err != nil {
+		return nil, err
+	}
+	if prev.RequestHash != req.RequestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	var out SyncResult
+	if err := json.Unmarshal(prev.Response, &out); err != nil {
+		return nil, err
+	}
+	out.Replayed = true
+	return &out, nil
+}
+
+func (s *sessionSyncService) PurgeKeys(ctx context.Context) (int64, error) {
+	n, err := s.repo.PurgeRequests(ctx, time.Now().Add(-syncKeyRetention))
+	if err != nil {
+		log.Printf("[PurgeSyncKeys] error: %v", err)
+		return 0, err
+	}
+	return n, nil
+}
@@ -0,0 +1,500 @@
+// Package sessionsync mezcla una sesión registrada offline (ids generados y
+// reloj del dispositivo) con lo que ya hay en el servidor, quizá subido en
+// parte desde otro dispositivo. No toca la DB: devuelve qué escribir y el
+// resultado por ítem.
+package sessionsync
+
+import (
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/domain"
+)
+
+// MaxClockSkew: cuánto puede adelantarse el reloj del dispositivo al del
+// servidor. Con más que eso ganaría todos los conflictos siguientes.
+const MaxClockSkew = 5 * time.Minute
+
+const (
+	StatusOpen   = "open"
+	StatusClosed = "closed"
+)
+
+// Estado de cada ítem en el resultado.
+const (
+	Created   = "created"
+	Updated   = "updated"
+	Unchanged = "unchanged"
+	Deleted   = "deleted"
+	Stale     = "stale"    // el servidor tiene algo más nuevo: se conserva lo del servidor
+	Rejected  = "rejected" // inválido: el lote completo no se aplica
+	Skipped   = "skipped"  // válido, pero el lote fue rechazado por otro ítem
+)
+
+// Motivos de stale/rejected.
+const (
+	ReasonNewerOnServer      = "newer_on_server"
+	ReasonDeletedOnServer    = "deleted_on_server"
+	ReasonSessionClosed      = "session_closed"
+	ReasonDuplicateID        = "duplicate_id"
+	ReasonIDConflict         = "id_conflict"
+	ReasonSessionMismatch    = "session_mismatch"
+	ReasonAssignmentInactive = "assignment_inactive"
+	ReasonDayNotInAssignment = "day_not_in_assignment"
+	ReasonPrescription       = "prescription_not_in_day"
+	ReasonMissingUpdatedAt   = "missing_updated_at"
+	ReasonClockSkew          = "clock_skew"
+	ReasonInvalidStatus      = "invalid_status"
+	ReasonInvalidStartedAt   = "invalid_started_at"
+	ReasonInvalidEndedAt     = "invalid_ended_at"
+	ReasonInvalidSetIndex    = "invalid_set_index"
+	ReasonInvalidReps        = "invalid_reps"
+	ReasonInvalidRPE         = "invalid_rpe"
+	ReasonInvalidWeight      = "invalid_weight"
+	ReasonInvalidTiming      = "invalid_timing"
+	ReasonInvalidSetType     = "invalid_set_type"
+	ReasonInvalidParent      = "invalid_parent_set"
+	ReasonInvalidModality    = "invalid_modality"
+	ReasonInvalidMinutes     = "invalid_minutes"
+)
+
+// Session: UpdatedAt es el reloj del dispositivo en su última edición.
+type Session struct {
+	ID           string
+	AssignmentID string
+	DayID        string
+	StartedAt    time.Time
+	Notes        *string
+	Status       string
+	EndedAt      *time.Time
+	UpdatedAt    time.Time
+}
+
+type Set struct {
+	ID             string
+	PrescriptionID string
+	SetIndex       int
+	Weight         *float64
+	Reps           int
+	RPE            *float32
+	ToFailure      bool
+	// SetType vacío = working (o failure con ToFailure); un drop lleva
+	// ParentSetID, que Merge resuelve a la serie raíz de la cadena
+	SetType     string
+	ParentSetID *string
+	StartedAt   *time.Time // opcionales; ver internal/timing
+	CompletedAt *time.Time
+	UpdatedAt   time.Time
+	Deleted     bool
+}
+
+type Cardio struct {
+	ID        string
+	Modality  string
+	Minutes   int
+	HRMin     *int
+	HRMax     *int
+	Notes     *string
+	UpdatedAt time.Time
+	Deleted   bool
+}
+
+// Batch: una sesión completa tal como la tiene el dispositivo.
+type Batch struct {
+	Session Session
+	Sets    []Set
+	Cardio  []Cardio
+}
+
+// State: lo que hay en el servidor. Session nil si la sesión no existe aún;
+// entonces AssignmentActive y DayInAssignment dicen si se puede crear.
+type State struct {
+	Session          *Session
+	Foreign          bool // el id de sesión es de otro discípulo
+	AssignmentActive bool
+	DayInAssignment  bool
+	Prescriptions    map[string]bool // prescripciones del día de la sesión
+	Sets             map[string]Set
+	Cardio           map[string]Cardio
+	ForeignIDs       map[string]bool      // ids de series/cardio de otra sesión
+	Tombstones       map[string]time.Time // borrados ya sincronizados
+}
+
+type ItemResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type Result struct {
+	Session ItemResult   `json:"session"`
+	Sets    []ItemResult `json:"sets"`
+	Cardio  []ItemResult `json:"cardio"`
+}
+
+func (r Result) Rejected() bool {
+	for _, it := range ptrs([]ItemResult{r.Session}, r.Sets, r.Cardio) {
+		if it.Status == Rejected {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan: escrituras a aplicar. Session es el estado final de la sesión si
+// hay que crearla o cambiarla; Closed marca la transición abierta -> cerrada.
+type Plan struct {
+	CreateSession bool
+	Session       *Session
+	Closed        bool
+
+	InsertSets, UpdateSets, DeleteSets       []Set
+	InsertCardio, UpdateCardio, DeleteCardio []Cardio
+}
+
+// Merge valida el lote completo y, si nada se rechaza, lo mezcla con el
+// estado del servidor: gana el reloj de dispositivo más reciente por ítem,
+// un borrado es definitivo y el cierre de la sesión no se deshace.
+func Merge(st State, b Batch, now time.Time) (Plan, Result) {
+	var plan Plan
+	res := Result{
+		Session: ItemResult{ID: b.Session.ID},
+		Sets:    make([]ItemResult, len(b.Sets)),
+		Cardio:  make([]ItemResult, len(b.Cardio)),
+	}
+	if b.Session.Status == "" {
+		b.Session.Status = StatusOpen
+	}
+	if b.Session.Status == StatusClosed && b.Session.EndedAt == nil {
+		end := b.Session.UpdatedAt
+		b.Session.EndedAt = &end
+	}
+
+	limit := now.Add(MaxClockSkew)
+	rejected := false
+	reject := func(it *ItemResult, reason string) {
+		it.Status, it.Reason = Rejected, reason
+		rejected = true
+	}
+	if why := checkSession(st, b.Session, limit); why != "" {
+		reject(&res.Session, why)
+	}
+	inBatch := make(map[string]Set, len(b.Sets))
+	for _, x := range b.Sets {
+		inBatch[x.ID] = x
+	}
+	seen := map[string]bool{}
+	for i := range b.Sets {
+		res.Sets[i].ID = b.Sets[i].ID
+		if why := checkSet(st, seen, inBatch, &b.Sets[i], limit); why != "" {
+			reject(&res.Sets[i], why)
+		}
+	}
+	for i, x := range b.Cardio {
+		res.Cardio[i].ID = x.ID
+		if why := checkCardio(st, seen, x, limit); why != "" {
+			reject(&res.Cardio[i], why)
+		}
+	}
+	if rejected {
+		sess := []ItemResult{res.Session}
+		for _, it := range ptrs(sess, res.Sets, res.Cardio) {
+			if it.Status == "" {
+				it.Status = Skipped
+			}
+		}
+		res.Session = sess[0]
+		return Plan{}, res
+	}
+
+	res.Session = mergeSession(&plan, st.Session, b.Session)
+	final := st.Session
+	if plan.Session != nil {
+		final = plan.Session
+	}
+	var closedAt *time.Time
+	if final.Status == StatusClosed {
+		closedAt = final.EndedAt
+	}
+
+	for i, x := range b.Sets {
+		// la madre ya se borró en el servidor (sus drops cayeron en cascada)
+		if x.ParentSetID != nil && !x.Deleted {
+			if _, gone := st.Tombstones[*x.ParentSetID]; gone {
+				res.Sets[i] = ItemResult{ID: x.ID, Status: Stale, Reason: ReasonDeletedOnServer}
+				continue
+			}
+		}
+		cur, exists := st.Sets[x.ID]
+		res.Sets[i] = mergeItem(x.ID, x.UpdatedAt, x.Deleted, st.Tombstones, closedAt,
+			exists, cur.UpdatedAt, func() bool { return sameSet(x, cur) },
+			func(status string) {
+				switch status {
+				case Created:
+					plan.InsertSets = append(plan.InsertSets, x)
+				case Updated:
+					plan.UpdateSets = append(plan.UpdateSets, x)
+				case Deleted:
+					plan.DeleteSets = append(plan.DeleteSets, x)
+				}
+			})
+	}
+	for i, x := range b.Cardio {
+		cur, exists := st.Cardio[x.ID]
+		res.Cardio[i] = mergeItem(x.ID, x.UpdatedAt, x.Deleted, st.Tombstones, closedAt,
+			exists, cur.UpdatedAt, func() bool { return sameCardio(x, cur) },
+			func(status string) {
+				switch status {
+				case Created:
+					plan.InsertCardio = append(plan.InsertCardio, x)
+				case Updated:
+					plan.UpdateCardio = append(plan.UpdateCardio, x)
+				case Deleted:
+					plan.DeleteCardio = append(plan.DeleteCardio, x)
+				}
+			})
+	}
+	return plan, res
+}
+
+func ptrs(groups ...[]ItemResult) []*ItemResult {
+	var out []*ItemResult
+	for _, g := range groups {
+		for i := range g {
+			out = append(out, &g[i])
+		}
+	}
+	return out
+}
+
+func checkClock(t, limit time.Time) string {
+	if t.IsZero() {
+		return ReasonMissingUpdatedAt
+	}
+	if t.After(limit) {
+		return ReasonClockSkew
+	}
+	return ""
+}
+
+func checkSession(st State, s Session, limit time.Time) string {
+	if why := checkClock(s.UpdatedAt, limit); why != "" {
+		return why
+	}
+	switch {
+	case st.Foreign:
+		return ReasonIDConflict
+	case s.Status != StatusOpen && s.Status != StatusClosed:
+		return ReasonInvalidStatus
+	case s.StartedAt.IsZero() || s.StartedAt.After(limit):
+		return ReasonInvalidStartedAt
+	case s.EndedAt != nil && (s.EndedAt.Before(s.StartedAt) || s.EndedAt.After(limit)):
+		return ReasonInvalidEndedAt
+	}
+	if cur := st.Session; cur != nil {
+		if cur.AssignmentID != s.AssignmentID || cur.DayID != s.DayID {
+			return ReasonSessionMismatch
+		}
+		return ""
+	}
+	if !st.AssignmentActive {
+		return ReasonAssignmentInactive
+	}
+	if !st.DayInAssignment {
+		return ReasonDayNotInAssignment
+	}
+	return ""
+}
+
+func checkID(st State, seen map[string]bool, id string) string {
+	if seen[id] {
+		return ReasonDuplicateID
+	}
+	seen[id] = true
+	if st.ForeignIDs[id] {
+		return ReasonIDConflict
+	}
+	return ""
+}
+
+// checkSet además normaliza el tipo de x y resuelve su padre a la raíz.
+func checkSet(st State, seen map[string]bool, batch map[string]Set, x *Set, limit time.Time) string {
+	if why := checkID(st, seen, x.ID); why != "" {
+		return why
+	}
+	if why := checkClock(x.UpdatedAt, limit); why != "" || x.Deleted {
+		return why
+	}
+	var ok bool
+	if x.SetType, x.ToFailure, ok = domain.NormSetType(x.SetType, x.ToFailure); !ok {
+		return ReasonInvalidSetType
+	}
+	if (x.SetType == domain.SetDrop) != (x.ParentSetID != nil) {
+		return ReasonInvalidSetType
+	}
+	if x.ParentSetID != nil {
+		root, ok := dropRoot(st, batch, *x.ParentSetID, x.PrescriptionID)
+		if !ok || root == x.ID {
+			return ReasonInvalidParent
+		}
+		x.ParentSetID = &root
+	}
+	switch {
+	case !st.Prescriptions[x.PrescriptionID]:
+		return ReasonPrescription
+	case x.SetIndex < 1:
+		return ReasonInvalidSetIndex
+	case x.Reps < 0:
+		return ReasonInvalidReps
+	case x.RPE != nil && (*x.RPE < 1 || *x.RPE > 10):
+		return ReasonInvalidRPE
+	case x.Weight != nil && *x.Weight < 0:
+		return ReasonInvalidWeight
+	case badTiming(*x, limit):
+		return ReasonInvalidTiming
+	}
+	return ""
+}
+
+// dropRoot: la madre debe estar en el lote (sin borrar) o en el servidor,
+// con la misma prescripción; un drop de un drop cuelga de la misma raíz. Una
+// madre ya borrada en el servidor se acepta: Merge deja el drop como stale.
+func dropRoot(st State, batch map[string]Set, parentID, prescriptionID string) (string, bool) {
+	if _, gone := st.Tombstones[parentID]; gone {
+		return parentID, true
+	}
+	p, ok := batch[parentID]
+	if !ok {
+		p, ok = st.Sets[parentID]
+	}
+	if !ok || p.Deleted || p.PrescriptionID != prescriptionID {
+		return "", false
+	}
+	if p.ParentSetID != nil {
+		return *p.ParentSetID, true
+	}
+	return parentID, true
+}
+
+// badTiming: horas en el futuro o inicio posterior al fin.
+func badTiming(x Set, limit time.Time) bool {
+	if x.StartedAt != nil && x.StartedAt.After(limit) || x.CompletedAt != nil && x.CompletedAt.After(limit) {
+		return true
+	}
+	return x.StartedAt != nil && x.CompletedAt != nil && x.StartedAt.After(*x.CompletedAt)
+}
+
+func checkCardio(st State, seen map[string]bool, x Cardio, limit time.Time) string {
+	if why := checkID(st, seen, x.ID); why != "" {
+		return why
+	}
+	if why := checkClock(x.UpdatedAt, limit); why != "" || x.Deleted {
+		return why
+	}
+	switch {
+	case x.Modality == "":
+		return ReasonInvalidModality
+	case x.Minutes <= 0:
+		return ReasonInvalidMinutes
+	}
+	return ""
+}
+
+// mergeSession: fecha y notas siguen al reloj más reciente; el cierre es
+// definitivo (un dispositivo que no lo vio no reabre) y gana el más tardío.
+func mergeSession(plan *Plan, cur *Session, in Session) ItemResult {
+	if cur == nil {
+		s := in
+		plan.CreateSession, plan.Session = true, &s
+		plan.Closed = s.Status == StatusClosed
+		return ItemResult{ID: in.ID, Status: Created}
+	}
+	next := *cur
+	newer := in.UpdatedAt.After(cur.UpdatedAt)
+	if newer {
+		next.StartedAt, next.Notes, next.UpdatedAt = in.StartedAt, in.Notes, in.UpdatedAt
+	}
+	if in.Status == StatusClosed {
+		if next.Status != StatusClosed || next.EndedAt == nil || in.EndedAt.After(*next.EndedAt) {
+			next.EndedAt = in.EndedAt
+		}
+		next.Status = StatusClosed
+	}
+
+	changed := !next.StartedAt.Equal(cur.StartedAt) || !eqPtr(next.Notes, cur.Notes) ||
+		next.Status != cur.Status || !eqTime(next.EndedAt, cur.EndedAt)
+	if changed {
+		plan.Session = &next
+		plan.Closed = cur.Status != StatusClosed && next.Status == StatusClosed
+		return ItemResult{ID: in.ID, Status: Updated}
+	}
+	if !newer && (!in.StartedAt.Equal(cur.StartedAt) || !eqPtr(in.Notes, cur.Notes)) {
+		return ItemResult{ID: in.ID, Status: Stale, Reason: ReasonNewerOnServer}
+	}
+	return ItemResult{ID: in.ID, Status: Unchanged}
+}
+
+// mergeItem decide una serie o segmento de cardio; apply recibe el estado
+// cuando hay algo que escribir.
+func mergeItem(id string, at time.Time, deleted bool, tombstones map[string]time.Time, closedAt *time.Time,
+	exists bool, curAt time.Time, same func() bool, apply func(string)) ItemResult {
+	out := func(status, reason string) ItemResult {
+		if status == Created || status == Updated || status == Deleted {
+			apply(status)
+		}
+		return ItemResult{ID: id, Status: status, Reason: reason}
+	}
+	if _, gone := tombstones[id]; gone {
+		if deleted {
+			return out(Unchanged, "")
+		}
+		return out(Stale, ReasonDeletedOnServer)
+	}
+	if closedAt != nil && at.After(*closedAt) {
+		return out(Stale, ReasonSessionClosed)
+	}
+	newer := !exists || at.After(curAt)
+	switch {
+	case deleted && newer:
+		return out(Deleted, "")
+	case deleted:
+		return out(Stale, ReasonNewerOnServer)
+	case !exists:
+		return out(Created, "")
+	case same():
+		return out(Unchanged, "")
+	case !newer:
+		return out(Stale, ReasonNewerOnServer)
+	}
+	return out(Updated, "")
+}
+
+func sameSet(a, b Set) bool {
+	return a.PrescriptionID == b.PrescriptionID && a.SetIndex == b.SetIndex && eqPtr(a.Weight, b.Weight) &&
+		a.Reps == b.Reps && eqPtr(a.RPE, b.RPE) && a.ToFailure == b.ToFailure &&
+		setType(a) == setType(b) && eqPtr(a.ParentSetID, b.ParentSetID)
+}
+
+// setType: tipo normalizado (vacío = working, o failure con to_failure).
+func setType(x Set) string {
+	t, _, _ := domain.NormSetType(x.SetType, x.ToFailure)
+	return t
+}
+
+func sameCardio(a, b Cardio) bool {
+	return a.Modality == b.Modality && a.Minutes == b.Minutes && eqPtr(a.HRMin, b.HRMin) &&
+		eqPtr(a.HRMax, b.HRMax) && eqPtr(a.Notes, b.Notes)
+}
+
+func eqPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func eqTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
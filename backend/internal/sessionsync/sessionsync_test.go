@@ -0,0 +1,264 @@
+package sessionsync
+
+import (
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2025, 5, 6, 18, 0, 0, 0, time.UTC)
+
+func at(min int) time.Time { return t0.Add(time.Duration(min) * time.Minute) }
+
+func f64(v float64) *float64    { return &v }
+func str(v string) *string      { return &v }
+func tp(v time.Time) *time.Time { return &v }
+
+func newState() State {
+	return State{
+		AssignmentActive: true,
+		DayInAssignment:  true,
+		Prescriptions:    map[string]bool{"p1": true, "p2": true},
+	}
+}
+
+func openSession(updated int) Session {
+	return Session{ID: "s1", AssignmentID: "a1", DayID: "d1", StartedAt: t0, Status: StatusOpen, UpdatedAt: at(updated)}
+}
+
+func statuses(items []ItemResult) []string {
+	out := make([]string, len(items))
+	for i, it := range items {
+		out[i] = it.Status
+		if it.Reason != "" {
+			out[i] += ":" + it.Reason
+		}
+	}
+	return out
+}
+
+func assertStatuses(t *testing.T, what string, got []ItemResult, want ...string) {
+	t.Helper()
+	g := statuses(got)
+	if len(g) != len(want) {
+		t.Fatalf("%s=%v want %v", what, g, want)
+	}
+	for i := range want {
+		if g[i] != want[i] {
+			t.Fatalf("%s=%v want %v", what, g, want)
+		}
+	}
+}
+
+func TestMergeNewSession(t *testing.T) {
+	b := Batch{
+		Session: Session{ID: "s1", AssignmentID: "a1", DayID: "d1", StartedAt: t0, Status: StatusClosed, UpdatedAt: at(60)},
+		Sets: []Set{
+			{ID: "x1", PrescriptionID: "p1", SetIndex: 1, Reps: 5, Weight: f64(100), UpdatedAt: at(5)},
+			{ID: "x2", PrescriptionID: "p1", SetIndex: 2, Reps: 5, UpdatedAt: at(8), Deleted: true},
+		},
+		Cardio: []Cardio{{ID: "c1", Modality: "bike", Minutes: 10, UpdatedAt: at(50)}},
+	}
+	plan, res := Merge(newState(), b, at(61))
+	if res.Rejected() || res.Session.Status != Created {
+		t.Fatalf("result=%+v", res)
+	}
+	assertStatuses(t, "sets", res.Sets, Created, Deleted)
+	assertStatuses(t, "cardio", res.Cardio, Created)
+	if !plan.CreateSession || !plan.Closed || plan.Session.EndedAt == nil || !plan.Session.EndedAt.Equal(at(60)) {
+		t.Fatalf("plan session=%+v closed=%v", plan.Session, plan.Closed)
+	}
+	if len(plan.InsertSets) != 1 || len(plan.DeleteSets) != 1 || len(plan.InsertCardio) != 1 {
+		t.Fatalf("plan=%+v", plan)
+	}
+}
+
+// Dos dispositivos subieron la misma sesión: por ítem gana el reloj más reciente.
+func TestMergeTwoDevices(t *testing.T) {
+	st := newState()
+	cur := openSession(30)
+	cur.Notes = str("desde el teléfono")
+	st.Session = &cur
+	st.Sets = map[string]Set{
+		"x1": {ID: "x1", PrescriptionID: "p1", SetIndex: 1, Reps: 5, UpdatedAt: at(10)},
+		"x2": {ID: "x2", PrescriptionID: "p1", SetIndex: 2, Reps: 6, UpdatedAt: at(20)},
+		"x3": {ID: "x3", PrescriptionID: "p2", SetIndex: 1, Reps: 8, UpdatedAt: at(15)},
+	}
+	st.Tombstones = map[string]time.Time{"x9": at(12)}
+
+	in := openSession(25)
+	in.Notes = str("desde el reloj")
+	b := Batch{Session: in, Sets: []Set{
+		{ID: "x1", PrescriptionID: "p1", SetIndex: 1, Reps: 4, UpdatedAt: at(18)}, // más nuevo
+		{ID: "x2", PrescriptionID: "p1", SetIndex: 2, Reps: 7, UpdatedAt: at(19)}, // más viejo
+		{ID: "x3", PrescriptionID: "p2", SetIndex: 1, Reps: 8, UpdatedAt: at(25)}, // igual
+		{ID: "x4", PrescriptionID: "p2", SetIndex: 2, Reps: 8, UpdatedAt: at(22)}, // nuevo
+		{ID: "x9", PrescriptionID: "p2", SetIndex: 3, Reps: 8, UpdatedAt: at(11)}, // borrado por el otro
+	}}
+	plan, res := Merge(st, b, at(40))
+	if res.Session.Status != Stale || res.Session.Reason != ReasonNewerOnServer || plan.Session != nil {
+		t.Fatalf("session=%+v plan=%+v", res.Session, plan.Session)
+	}
+	assertStatuses(t, "sets", res.Sets,
+		Updated, Stale+":"+ReasonNewerOnServer, Unchanged, Created, Stale+":"+ReasonDeletedOnServer)
+	if len(plan.UpdateSets) != 1 || plan.UpdateSets[0].Reps != 4 || len(plan.InsertSets) != 1 || plan.InsertSets[0].ID != "x4" {
+		t.Fatalf("plan=%+v", plan)
+	}
+}
+
+func TestMergeCloseIsSticky(t *testing.T) {
+	st := newState()
+	cur := openSession(30)
+	cur.Status, cur.EndedAt = StatusClosed, ptrTime(at(30))
+	st.Session = &cur
+	st.Sets = map[string]Set{"x1": {ID: "x1", PrescriptionID: "p1", SetIndex: 1, Reps: 5}}
+
+	// el otro dispositivo no vio el cierre: no reabre, pero sus notas más nuevas sí entran
+	in := openSession(35)
+	in.Notes = str("tarde")
+	b := Batch{Session: in, Sets: []Set{
+		{ID: "x1", PrescriptionID: "p1", SetIndex: 1, Reps: 6, UpdatedAt: at(20)},
+		{ID: "x2", PrescriptionID: "p1", SetIndex: 2, Reps: 5, UpdatedAt: at(34)},
+	}}
+	plan, res := Merge(st, b, at(40))
+	if res.Session.Status != Updated || plan.Session.Status != StatusClosed || plan.Closed || *plan.Session.Notes != "tarde" {
+		t.Fatalf("session=%+v plan=%+v", res.Session, plan.Session)
+	}
+	assertStatuses(t, "sets", res.Sets, Updated, Stale+":"+ReasonSessionClosed)
+
+	// dos cierres: queda el más tardío, sin aviso de nuevo
+	in = openSession(20)
+	in.Status, in.EndedAt = StatusClosed, ptrTime(at(45))
+	plan, res = Merge(st, Batch{Session: in}, at(50))
+	if res.Session.Status != Updated || !plan.Session.EndedAt.Equal(at(45)) || plan.Closed {
+		t.Fatalf("session=%+v plan=%+v", res.Session, plan.Session)
+	}
+
+	// cierre desde un dispositivo sobre una sesión abierta
+	open := openSession(30)
+	st.Session = &open
+	in = openSession(10)
+	in.Status = StatusClosed
+	plan, _ = Merge(st, Batch{Session: in}, at(50))
+	if !plan.Closed || !plan.Session.EndedAt.Equal(at(10)) {
+		t.Fatalf("plan=%+v", plan.Session)
+	}
+}
+
+func TestMergeDeletes(t *testing.T) {
+	st := newState()
+	cur := openSession(0)
+	st.Session = &cur
+	st.Sets = map[string]Set{
+		"x1": {ID: "x1", PrescriptionID: "p1", SetIndex: 1, Reps: 5, UpdatedAt: at(10)},
+		"x2": {ID: "x2", PrescriptionID: "p1", SetIndex: 2, Reps: 5, UpdatedAt: at(10)},
+	}
+	st.Tombstones = map[string]time.Time{"x3": at(5)}
+	b := Batch{Session: openSession(0), Sets: []Set{
+		{ID: "x1", UpdatedAt: at(11), Deleted: true},
+		{ID: "x2", UpdatedAt: at(9), Deleted: true}, // editada después en el servidor
+		{ID: "x3", UpdatedAt: at(6), Deleted: true},
+	}}
+	plan, res := Merge(st, b, at(20))
+	assertStatuses(t, "sets", res.Sets, Deleted, Stale+":"+ReasonNewerOnServer, Unchanged)
+	if len(plan.DeleteSets) != 1 || plan.DeleteSets[0].ID != "x1" {
+		t.Fatalf("plan=%+v", plan)
+	}
+}
+
+func TestMergeDropSets(t *testing.T) {
+	st := newState()
+	cur := openSession(0)
+	st.Session = &cur
+	st.Sets = map[string]Set{"x1": {ID: "x1", PrescriptionID: "p1", SetIndex: 1, Reps: 8, SetType: "working", UpdatedAt: at(5)}}
+	st.Tombstones = map[string]time.Time{"x9": at(4)}
+	b := Batch{Session: openSession(0), Sets: []Set{
+		{ID: "w1", PrescriptionID: "p1", SetIndex: 1, Reps: 12, SetType: "WARMUP", UpdatedAt: at(6)},
+		{ID: "d1", PrescriptionID: "p1", SetIndex: 2, Reps: 6, SetType: "drop", ParentSetID: str("x1"), UpdatedAt: at(6)},
+		{ID: "d2", PrescriptionID: "p1", SetIndex: 3, Reps: 4, SetType: "drop", ParentSetID: str("d1"), UpdatedAt: at(6)}, // drop del drop
+		{ID: "f1", PrescriptionID: "p1", SetIndex: 4, Reps: 3, ToFailure: true, UpdatedAt: at(6)},
+		{ID: "d3", PrescriptionID: "p1", SetIndex: 5, Reps: 4, SetType: "drop", ParentSetID: str("x9"), UpdatedAt: at(6)},
+	}}
+	plan, res := Merge(st, b, at(10))
+	assertStatuses(t, "sets", res.Sets, Created, Created, Created, Created, Stale+":"+ReasonDeletedOnServer)
+	if len(plan.InsertSets) != 4 {
+		t.Fatalf("plan=%+v", plan)
+	}
+	w, d1, d2, f := plan.InsertSets[0], plan.InsertSets[1], plan.InsertSets[2], plan.InsertSets[3]
+	if w.SetType != "warmup" || *d1.ParentSetID != "x1" || *d2.ParentSetID != "x1" || f.SetType != "failure" {
+		t.Fatalf("plan=%+v %+v %+v %+v", w, d1, d2, f)
+	}
+
+	// drop sin madre, madre de otra prescripción, madre borrada en el lote, tipo desconocido
+	b = Batch{Session: openSession(0), Sets: []Set{
+		{ID: "d1", PrescriptionID: "p1", SetIndex: 1, Reps: 6, SetType: "drop", UpdatedAt: at(6)},
+		{ID: "d2", PrescriptionID: "p2", SetIndex: 1, Reps: 6, SetType: "drop", ParentSetID: str("x1"), UpdatedAt: at(6)},
+		{ID: "y1", UpdatedAt: at(6), Deleted: true},
+		{ID: "d3", PrescriptionID: "p1", SetIndex: 1, Reps: 6, SetType: "drop", ParentSetID: str("y1"), UpdatedAt: at(6)},
+		{ID: "z1", PrescriptionID: "p1", SetIndex: 1, Reps: 6, SetType: "cluster", UpdatedAt: at(6)},
+	}}
+	_, res = Merge(st, b, at(10))
+	assertStatuses(t, "sets", res.Sets,
+		Rejected+":"+ReasonInvalidSetType,
+		Rejected+":"+ReasonInvalidParent,
+		Skipped,
+		Rejected+":"+ReasonInvalidParent,
+		Rejected+":"+ReasonInvalidSetType,
+	)
+}
+
+func TestMergeRejectsWholeBatch(t *testing.T) {
+	st := newState()
+	b := Batch{
+		Session: openSession(0),
+		Sets: []Set{
+			{ID: "x1", PrescriptionID: "p1", SetIndex: 1, Reps: 5, UpdatedAt: at(1)},
+			{ID: "x2", PrescriptionID: "otro", SetIndex: 1, Reps: 5, UpdatedAt: at(1)},
+			{ID: "x1", PrescriptionID: "p1", SetIndex: 2, Reps: 5, UpdatedAt: at(1)},
+			{ID: "x3", PrescriptionID: "p1", SetIndex: 0, Reps: 5, UpdatedAt: at(1)},
+			{ID: "x4", PrescriptionID: "p1", SetIndex: 1, Reps: 5, RPE: ptrF32(11), UpdatedAt: at(1)},
+			{ID: "x5", PrescriptionID: "p1", SetIndex: 1, Reps: 5},
+			{ID: "x6", PrescriptionID: "p1", SetIndex: 1, Reps: 5, UpdatedAt: at(30)},
+			{ID: "x7", PrescriptionID: "p1", SetIndex: 1, Reps: 5, StartedAt: tp(at(3)), CompletedAt: tp(at(2)), UpdatedAt: at(3)},
+		},
+		Cardio: []Cardio{{ID: "c1", Modality: "bike", UpdatedAt: at(1)}},
+	}
+	plan, res := Merge(st, b, at(10))
+	if !res.Rejected() || res.Session.Status != Skipped {
+		t.Fatalf("result=%+v", res)
+	}
+	assertStatuses(t, "sets", res.Sets,
+		Skipped,
+		Rejected+":"+ReasonPrescription,
+		Rejected+":"+ReasonDuplicateID,
+		Rejected+":"+ReasonInvalidSetIndex,
+		Rejected+":"+ReasonInvalidRPE,
+		Rejected+":"+ReasonMissingUpdatedAt,
+		Rejected+":"+ReasonClockSkew,
+		Rejected+":"+ReasonInvalidTiming,
+	)
+	assertStatuses(t, "cardio", res.Cardio, Rejected+":"+ReasonInvalidMinutes)
+	if plan.CreateSession || plan.Session != nil || len(plan.InsertSets) != 0 {
+		t.Fatalf("rejected batch produced plan %+v", plan)
+	}
+
+	// sesión: assignment inactivo, otra sesión con el mismo id, otro día
+	st.AssignmentActive = false
+	if _, res := Merge(st, Batch{Session: openSession(0)}, at(10)); res.Session.Reason != ReasonAssignmentInactive {
+		t.Fatalf("session=%+v", res.Session)
+	}
+	st.Foreign = true
+	if _, res := Merge(st, Batch{Session: openSession(0)}, at(10)); res.Session.Reason != ReasonIDConflict {
+		t.Fatalf("session=%+v", res.Session)
+	}
+	st = newState()
+	cur := openSession(0)
+	st.Session = &cur
+	in := openSession(0)
+	in.DayID = "d2"
+	if _, res := Merge(st, Batch{Session: in}, at(10)); res.Session.Reason != ReasonSessionMismatch {
+		t.Fatalf("session=%+v", res.Session)
+	}
+}
+
+func ptrTime(t time.Time) *time.Time { return &t }
+func ptrF32(v float32) *float32      { return &v }
@@ -0,0 +1,47 @@
+package strength
+
+type RecordKind string
+
+const (
+	RecordWeight RecordKind = "weight" // mayor carga
+	RecordReps   RecordKind = "reps"   // más reps con esa carga o más
+	RecordE1RM   RecordKind = "e1rm"
+	RecordVolume RecordKind = "volume" // kg·reps de la sesión en el ejercicio
+)
+
+// Bests: mejores marcas previas del ejercicio, sin contar la serie nueva.
+type Bests struct {
+	Sets          int     // series previas; 0 = primer registro (línea base, sin PR)
+	Weight        float64 // mayor carga
+	RepsAtLoad    int     // más reps con carga >= la de la serie nueva
+	E1RM          float64
+	SessionVolume float64 // mayor volumen de una sesión anterior
+}
+
+type Record struct {
+	Kind     RecordKind
+	Value    float64
+	Previous float64
+}
+
+// DetectRecords compara la serie nueva con las marcas previas.
+// sessionVolume es el volumen de la sesión actual, ya incluida la serie.
+func DetectRecords(f Formula, b Bests, s Set, sessionVolume float64) []Record {
+	if b.Sets == 0 {
+		return nil
+	}
+	var out []Record
+	if s.Weight > 0 && s.Weight > b.Weight {
+		out = append(out, Record{Kind: RecordWeight, Value: s.Weight, Previous: b.Weight})
+	}
+	if b.RepsAtLoad > 0 && s.Reps > b.RepsAtLoad {
+		out = append(out, Record{Kind: RecordReps, Value: float64(s.Reps), Previous: float64(b.RepsAtLoad)})
+	}
+	if e := E1RM(f, s.Weight, s.Reps, s.RPE); b.E1RM > 0 && e > b.E1RM {
+		out = append(out, Record{Kind: RecordE1RM, Value: round2(e), Previous: round2(b.E1RM)})
+	}
+	if b.SessionVolume > 0 && sessionVolume > b.SessionVolume {
+		out = append(out, Record{Kind: RecordVolume, Value: round2(sessionVolume), Previous: round2(b.SessionVolume)})
+	}
+	return out
+}
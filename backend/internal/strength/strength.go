@@ -0,0 +1,166 @@
+// Package strength estima 1RM a partir de series registradas y arma curvas de
+// fuerza por ejercicio (serie temporal, rep-max y pendiente). No toca la DB.
+package strength
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Formula string
+
+const (
+	Epley   Formula = "epley"
+	Brzycki Formula = "brzycki"
+)
+
+// Por sobre estas reps (contando las de reserva) las fórmulas dejan de ser fiables.
+const MaxReps = 20
+
+// Rep-max que se reportan por ejercicio.
+var RepMaxTargets = []int{1, 3, 5, 8, 10}
+
+const dateLayout = "2006-01-02"
+
+func ParseFormula(s string) (Formula, bool) {
+	switch Formula(strings.ToLower(strings.TrimSpace(s))) {
+	case "", Epley:
+		return Epley, true
+	case Brzycki:
+		return Brzycki, true
+	}
+	return "", false
+}
+
+// E1RM estima el 1RM; con RPE suma las reps en reserva (10 - RPE).
+// Devuelve 0 si la serie no sirve para estimar.
+func E1RM(f Formula, weight float64, reps int, rpe *float64) float64 {
+	if weight <= 0 || reps <= 0 {
+		return 0
+	}
+	r := float64(reps)
+	if rpe != nil && *rpe > 0 {
+		r += math.Max(0, 10-*rpe)
+	}
+	if r > MaxReps {
+		return 0
+	}
+	if r == 1 {
+		return weight
+	}
+	if f == Brzycki {
+		return weight * 36 / (37 - r)
+	}
+	return weight * (1 + r/30)
+}
+
+// Set: una serie con peso; Date es la fecha local (YYYY-MM-DD).
+type Set struct {
+	Date   string
+	Weight float64
+	Reps   int
+	RPE    *float64
+}
+
+// Point: mejor serie del día según el e1RM.
+type Point struct {
+	Date   string   `json:"date"`
+	E1RM   float64  `json:"e1rm"`
+	Weight float64  `json:"weight"`
+	Reps   int      `json:"reps"`
+	RPE    *float64 `json:"rpe,omitempty"`
+}
+
+type RepMax struct {
+	Reps   int     `json:"reps"`
+	Weight float64 `json:"weight"`
+	Date   string  `json:"date"`
+}
+
+// Series devuelve un punto por día, ordenado por fecha.
+func Series(f Formula, sets []Set) []Point {
+	byDate := map[string]Point{}
+	for _, s := range sets {
+		e := E1RM(f, s.Weight, s.Reps, s.RPE)
+		if e == 0 {
+			continue
+		}
+		if cur, ok := byDate[s.Date]; !ok || e > cur.E1RM {
+			byDate[s.Date] = Point{Date: s.Date, E1RM: round2(e), Weight: s.Weight, Reps: s.Reps, RPE: s.RPE}
+		}
+	}
+	out := make([]Point, 0, len(byDate))
+	for _, p := range byDate {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out
+}
+
+// RepMaxes: mayor peso levantado para al menos N reps, para cada objetivo.
+// Se omiten los N sin ninguna serie que llegue.
+func RepMaxes(sets []Set) []RepMax {
+	out := make([]RepMax, 0, len(RepMaxTargets))
+	for _, n := range RepMaxTargets {
+		var best RepMax
+		for _, s := range sets {
+			if s.Reps < n || s.Weight <= 0 {
+				continue
+			}
+			if s.Weight > best.Weight || (s.Weight == best.Weight && s.Date < best.Date) {
+				best = RepMax{Reps: n, Weight: s.Weight, Date: s.Date}
+			}
+		}
+		if best.Reps > 0 {
+			out = append(out, best)
+		}
+	}
+	return out
+}
+
+// Trend: pendiente por mínimos cuadrados del e1RM diario.
+type Trend struct {
+	KgPerWeek  float64 `json:"kg_per_week"`
+	PctPerWeek float64 `json:"pct_per_week"` // relativo al e1RM ajustado al inicio
+	Points     int     `json:"points"`
+}
+
+// Slope necesita al menos dos fechas distintas.
+func Slope(points []Point) (Trend, bool) {
+	t := Trend{Points: len(points)}
+	if len(points) < 2 {
+		return t, false
+	}
+	first, err := time.Parse(dateLayout, points[0].Date)
+	if err != nil {
+		return t, false
+	}
+	var sx, sy, sxx, sxy float64
+	n := float64(len(points))
+	for _, p := range points {
+		d, err := time.Parse(dateLayout, p.Date)
+		if err != nil {
+			return t, false
+		}
+		x := d.Sub(first).Hours() / 24
+		sx += x
+		sy += p.E1RM
+		sxx += x * x
+		sxy += x * p.E1RM
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return t, false
+	}
+	perDay := (n*sxy - sx*sy) / den
+	intercept := (sy - perDay*sx) / n
+	t.KgPerWeek = round2(perDay * 7)
+	if intercept > 0 {
+		t.PctPerWeek = round2(perDay * 7 / intercept * 100)
+	}
+	return t, true
+}
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
@@ -0,0 +1,113 @@
+package strength
+
+import (
+	"math"
+	"testing"
+)
+
+func f64(v float64) *float64 { return &v }
+
+func TestE1RM(t *testing.T) {
+	cases := []struct {
+		name    string
+		f       Formula
+		w       float64
+		reps    int
+		rpe     *float64
+		want    float64
+		epsilon float64
+	}{
+		{"single", Epley, 140, 1, nil, 140, 0},
+		{"epley 5", Epley, 100, 5, nil, 116.67, 0.01},
+		{"brzycki 5", Brzycki, 100, 5, nil, 112.5, 0.01},
+		{"rpe adds reserve", Epley, 100, 5, f64(8), 123.33, 0.01}, // 5 + 2 RIR
+		{"rpe 10 no reserve", Epley, 100, 5, f64(10), 116.67, 0.01},
+		{"single at rpe 9", Epley, 100, 1, f64(9), 106.67, 0.01},
+		{"too many reps", Epley, 40, 25, nil, 0, 0},
+		{"bodyweight", Epley, 0, 10, nil, 0, 0},
+	}
+	for _, tc := range cases {
+		got := E1RM(tc.f, tc.w, tc.reps, tc.rpe)
+		if math.Abs(got-tc.want) > tc.epsilon {
+			t.Errorf("%s: got %.2f want %.2f", tc.name, got, tc.want)
+		}
+	}
+	if _, ok := ParseFormula("Lombardi"); ok {
+		t.Fatal("unknown formula accepted")
+	}
+	if f, ok := ParseFormula(""); !ok || f != Epley {
+		t.Fatalf("default formula = %q", f)
+	}
+}
+
+func TestSeriesRepMaxesAndSlope(t *testing.T) {
+	sets := []Set{
+		{Date: "2025-03-01", Weight: 100, Reps: 5},
+		{Date: "2025-03-01", Weight: 90, Reps: 10}, // 120 > 116.67: mejor del día
+		{Date: "2025-03-08", Weight: 105, Reps: 5},
+		{Date: "2025-03-15", Weight: 110, Reps: 5},
+		{Date: "2025-03-15", Weight: 120, Reps: 1},
+	}
+	pts := Series(Epley, sets)
+	if len(pts) != 3 || pts[0].Date != "2025-03-01" || pts[0].Reps != 10 {
+		t.Fatalf("series = %+v", pts)
+	}
+
+	rm := RepMaxes(sets)
+	want := map[int]float64{1: 120, 3: 110, 5: 110, 8: 90, 10: 90}
+	if len(rm) != len(want) {
+		t.Fatalf("rep maxes = %+v", rm)
+	}
+	for _, r := range rm {
+		if want[r.Reps] != r.Weight {
+			t.Errorf("%dRM = %.1f want %.1f", r.Reps, r.Weight, want[r.Reps])
+		}
+	}
+
+	tr, ok := Slope(pts)
+	if !ok || tr.KgPerWeek <= 0 || tr.Points != 3 {
+		t.Fatalf("trend = %+v ok=%v", tr, ok)
+	}
+	// 120 -> 122.5 -> 128.33: pendiente ≈ 4.17 kg/semana
+	if math.Abs(tr.KgPerWeek-4.17) > 0.01 {
+		t.Fatalf("kg/week = %.2f", tr.KgPerWeek)
+	}
+	if _, ok := Slope(pts[:1]); ok {
+		t.Fatal("slope with one point")
+	}
+}
+
+func TestDetectRecords(t *testing.T) {
+	kinds := func(rs []Record) map[RecordKind]Record {
+		m := map[RecordKind]Record{}
+		for _, r := range rs {
+			m[r.Kind] = r
+		}
+		return m
+	}
+	if rs := DetectRecords(Epley, Bests{}, Set{Weight: 100, Reps: 5}, 500); rs != nil {
+		t.Fatalf("first set is a baseline, got %+v", rs)
+	}
+
+	b := Bests{Sets: 10, Weight: 100, E1RM: 116.67, SessionVolume: 1500} // nada con >= 102.5 kg
+	got := kinds(DetectRecords(Epley, b, Set{Weight: 102.5, Reps: 5}, 1600))
+	if len(got) != 3 || got[RecordWeight].Previous != 100 || got[RecordVolume].Value != 1600 {
+		t.Fatalf("heavier set: %+v", got)
+	}
+	if _, ok := got[RecordReps]; ok {
+		t.Fatal("rep PR needs a previous set at that load")
+	}
+
+	b.RepsAtLoad = 6 // mejor marca con 95 kg o más
+	got = kinds(DetectRecords(Epley, b, Set{Weight: 95, Reps: 8}, 760))
+	if len(got) != 2 || got[RecordReps].Value != 8 || got[RecordReps].Previous != 6 {
+		t.Fatalf("rep PR: %+v", got)
+	}
+	if _, ok := got[RecordE1RM]; !ok { // 95·(1+8/30) = 120.33
+		t.Fatal("want e1rm PR")
+	}
+
+	if rs := DetectRecords(Epley, b, Set{Weight: 80, Reps: 5}, 400); len(rs) != 0 {
+		t.Fatalf("no PR expected, got %+v", rs)
+	}
+}
@@ -0,0 +1,194 @@
+// Package timing arma la línea de tiempo de una sesión con las horas de cada
+// serie y compara el descanso real con el prescrito (rest_sec). No toca la DB.
+package timing
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/vicepalma/roma-system/backend/internal/domain"
+)
+
+// MinTolerance: margen mínimo (segundos) alrededor de rest_sec que aún cuenta
+// como cumplido; ver Tolerance.
+const MinTolerance = 10
+
+// Set: una serie registrada. StartedAt es opcional (el cliente puede marcar
+// solo el fin); RestTargetSec viene de la prescripción. SetType vacío = working.
+type Set struct {
+	ID             string     `json:"set_id"`
+	PrescriptionID string     `json:"prescription_id"`
+	SetIndex       int        `json:"set_index"`
+	SetType        string     `json:"set_type,omitempty"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+	RestTargetSec  *int       `json:"rest_target_sec,omitempty"`
+}
+
+// Entry: la serie en orden cronológico con su duración y el descanso previo.
+type Entry struct {
+	Set
+	DurationSec *int `json:"duration_sec,omitempty"`
+	// RestSec: del fin de la serie anterior de la sesión al inicio de esta.
+	// Sin started_at se mide hasta el fin (RestEstimated) y suma la duración
+	// de la serie.
+	RestSec       *int `json:"rest_sec,omitempty"`
+	RestEstimated bool `json:"rest_estimated,omitempty"`
+	// SamePrescription: la serie anterior es de la misma prescripción; solo
+	// esos descansos cuentan para el cumplimiento de rest_sec.
+	SamePrescription bool `json:"same_prescription"`
+	// RestExempt: descanso que no se compara con rest_sec (antes de un drop o
+	// de un calentamiento, o saliendo de un calentamiento).
+	RestExempt bool `json:"rest_exempt,omitempty"`
+}
+
+// Compliance: descansos entre series consecutivas de una prescripción.
+type Compliance struct {
+	PrescriptionID string `json:"prescription_id"`
+	TargetSec      int    `json:"target_sec"`
+	ToleranceSec   int    `json:"tolerance_sec"`
+	Intervals      int    `json:"intervals"`
+	Estimated      int    `json:"estimated"` // medidos sin started_at
+	AvgSec         int    `json:"avg_sec"`
+	MinSec         int    `json:"min_sec"`
+	MaxSec         int    `json:"max_sec"`
+	Within         int    `json:"within"`
+	Under          int    `json:"under"` // descansó menos de lo prescrito
+	Over           int    `json:"over"`
+	// Ratio: within / intervals (0..1)
+	Ratio float64 `json:"ratio"`
+}
+
+// Tolerance: 20% de rest_sec, mínimo MinTolerance (en FST-7 con 30 s, ±10 s).
+func Tolerance(targetSec int) int {
+	if t := targetSec / 5; t > MinTolerance {
+		return t
+	}
+	return MinTolerance
+}
+
+// Timeline ordena las series por hora (fin, o inicio si no hay fin) y calcula
+// duración y descanso previo. Las series sin hora quedan al final en el orden
+// recibido y sin descanso.
+func Timeline(sets []Set) []Entry {
+	out := make([]Entry, len(sets))
+	for i, s := range sets {
+		out[i] = Entry{Set: s}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := anchor(out[i].Set), anchor(out[j].Set)
+		switch {
+		case a == nil:
+			return false
+		case b == nil:
+			return true
+		}
+		return a.Before(*b)
+	})
+	for i := range out {
+		e := &out[i]
+		if e.StartedAt != nil && e.CompletedAt != nil {
+			d := seconds(e.CompletedAt.Sub(*e.StartedAt))
+			e.DurationSec = &d
+		}
+		if i == 0 || out[i-1].CompletedAt == nil {
+			continue
+		}
+		next, estimated := e.StartedAt, false
+		if next == nil {
+			next, estimated = e.CompletedAt, true
+		}
+		if next == nil {
+			continue
+		}
+		// solapadas (dos dispositivos a la vez): sin descanso
+		r := seconds(next.Sub(*out[i-1].CompletedAt))
+		e.RestSec, e.RestEstimated = &r, estimated
+		e.SamePrescription = out[i-1].PrescriptionID == e.PrescriptionID
+		e.RestExempt = e.SetType == domain.SetDrop || e.SetType == domain.SetWarmup ||
+			out[i-1].SetType == domain.SetWarmup
+	}
+	return out
+}
+
+// Summarize agrupa por prescripción, en orden de primera aparición, los
+// descansos entre series de la misma prescripción, salvo los RestExempt.
+// Omite las prescripciones sin rest_sec o sin descansos medidos. entries puede juntar varias sesiones,
+// cada una armada con Timeline.
+func Summarize(entries []Entry) []Compliance {
+	out := []Compliance{}
+	idx := map[string]int{}
+	sums := []int{}
+	for _, e := range entries {
+		if e.RestSec == nil || !e.SamePrescription || e.RestExempt || e.RestTargetSec == nil || *e.RestTargetSec <= 0 {
+			continue
+		}
+		i, ok := idx[e.PrescriptionID]
+		if !ok {
+			i = len(out)
+			idx[e.PrescriptionID] = i
+			target := *e.RestTargetSec
+			out = append(out, Compliance{
+				PrescriptionID: e.PrescriptionID, TargetSec: target, ToleranceSec: Tolerance(target),
+				MinSec: *e.RestSec, MaxSec: *e.RestSec,
+			})
+			sums = append(sums, 0)
+		}
+		c, r := &out[i], *e.RestSec
+		c.Intervals++
+		sums[i] += r
+		if e.RestEstimated {
+			c.Estimated++
+		}
+		c.MinSec, c.MaxSec = min(c.MinSec, r), max(c.MaxSec, r)
+		switch {
+		case r < c.TargetSec-c.ToleranceSec:
+			c.Under++
+		case r > c.TargetSec+c.ToleranceSec:
+			c.Over++
+		default:
+			c.Within++
+		}
+	}
+	for i := range out {
+		c := &out[i]
+		c.AvgSec = int(math.Round(float64(sums[i]) / float64(c.Intervals)))
+		c.Ratio = math.Round(float64(c.Within)/float64(c.Intervals)*1000) / 1000
+	}
+	return out
+}
+
+// Span: de la primera hora registrada a la última (la sesión sin la espera
+// previa a la primera serie). ok=false si ninguna serie tiene horas.
+func Span(entries []Entry) (start, end time.Time, ok bool) {
+	for _, e := range entries {
+		for _, t := range []*time.Time{e.StartedAt, e.CompletedAt} {
+			if t == nil {
+				continue
+			}
+			if !ok || t.Before(start) {
+				start = *t
+			}
+			if !ok || t.After(end) {
+				end = *t
+			}
+			ok = true
+		}
+	}
+	return start, end, ok
+}
+
+func anchor(s Set) *time.Time {
+	if s.CompletedAt != nil {
+		return s.CompletedAt
+	}
+	return s.StartedAt
+}
+
+func seconds(d time.Duration) int {
+	if d < 0 {
+		return 0
+	}
+	return int(d.Round(time.Second) / time.Second)
+}
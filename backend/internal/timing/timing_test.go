@@ -0,0 +1,125 @@
+package timing
+
+import (
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2025, 3, 3, 18, 0, 0, 0, time.UTC)
+
+func at(sec int) *time.Time {
+	t := t0.Add(time.Duration(sec) * time.Second)
+	return &t
+}
+
+func ip(v int) *int { return &v }
+
+func TestTimelineOrdersAndMeasuresRest(t *testing.T) {
+	// llegan por set_index; la 2 de "a" se hizo después de la 1 de "b"
+	sets := []Set{
+		{ID: "a1", PrescriptionID: "a", SetIndex: 1, StartedAt: at(0), CompletedAt: at(40), RestTargetSec: ip(30)},
+		{ID: "a2", PrescriptionID: "a", SetIndex: 2, StartedAt: at(200), CompletedAt: at(240), RestTargetSec: ip(30)},
+		{ID: "b1", PrescriptionID: "b", SetIndex: 1, StartedAt: at(80), CompletedAt: at(120)},
+		{ID: "b2", PrescriptionID: "b", SetIndex: 2}, // sin horas
+		{ID: "a3", PrescriptionID: "a", SetIndex: 3, CompletedAt: at(300), RestTargetSec: ip(30)},
+	}
+	got := Timeline(sets)
+	order := ""
+	for _, e := range got {
+		order += e.ID + " "
+	}
+	if order != "a1 b1 a2 a3 b2 " {
+		t.Fatalf("order=%q", order)
+	}
+	if got[0].RestSec != nil || *got[0].DurationSec != 40 {
+		t.Fatalf("first=%+v", got[0])
+	}
+	if *got[1].RestSec != 40 || got[1].SamePrescription {
+		t.Fatalf("b1=%+v", got[1])
+	}
+	if *got[2].RestSec != 80 || got[2].SamePrescription {
+		t.Fatalf("a2=%+v", got[2])
+	}
+	// sin started_at: se mide hasta el fin y queda marcado
+	if *got[3].RestSec != 60 || !got[3].RestEstimated || !got[3].SamePrescription || got[3].DurationSec != nil {
+		t.Fatalf("a3=%+v", got[3])
+	}
+	if got[4].RestSec != nil {
+		t.Fatalf("untimed=%+v", got[4])
+	}
+}
+
+func TestTimelineOverlapIsZeroRest(t *testing.T) {
+	got := Timeline([]Set{
+		{ID: "x", PrescriptionID: "a", StartedAt: at(0), CompletedAt: at(60)},
+		{ID: "y", PrescriptionID: "a", StartedAt: at(30), CompletedAt: at(90)},
+	})
+	if *got[1].RestSec != 0 {
+		t.Fatalf("overlap rest=%d", *got[1].RestSec)
+	}
+}
+
+func TestSummarizeFST7(t *testing.T) {
+	// FST-7: 7 series con 30 s de descanso (tolerancia ±10 s)
+	var sets []Set
+	rests := []int{30, 25, 45, 38, 15, 32}
+	end := 0
+	for i := 0; i < 7; i++ {
+		start := end
+		if i > 0 {
+			start = end + rests[i-1]
+		}
+		end = start + 20
+		sets = append(sets, Set{ID: string(rune('a' + i)), PrescriptionID: "fst", SetIndex: i + 1, StartedAt: at(start), CompletedAt: at(end), RestTargetSec: ip(30)})
+	}
+	// otra prescripción sin rest_sec no aparece
+	sets = append(sets, Set{ID: "z", PrescriptionID: "free", StartedAt: at(end + 60), CompletedAt: at(end + 90)},
+		Set{ID: "z2", PrescriptionID: "free", StartedAt: at(end + 120), CompletedAt: at(end + 150)})
+
+	got := Summarize(Timeline(sets))
+	if len(got) != 1 {
+		t.Fatalf("got=%+v", got)
+	}
+	c := got[0]
+	want := Compliance{PrescriptionID: "fst", TargetSec: 30, ToleranceSec: 10, Intervals: 6,
+		AvgSec: 31, MinSec: 15, MaxSec: 45, Within: 4, Under: 1, Over: 1, Ratio: 0.667}
+	if c != want {
+		t.Fatalf("compliance=%+v want %+v", c, want)
+	}
+}
+
+func TestSummarizeSkipsWarmupAndDrops(t *testing.T) {
+	// calentamiento, serie, drop inmediato, descanso real de 90 s y otra serie
+	sets := []Set{
+		{ID: "w", PrescriptionID: "a", SetType: "warmup", StartedAt: at(0), CompletedAt: at(30), RestTargetSec: ip(90)},
+		{ID: "s1", PrescriptionID: "a", SetType: "working", StartedAt: at(60), CompletedAt: at(100), RestTargetSec: ip(90)},
+		{ID: "d1", PrescriptionID: "a", SetType: "drop", StartedAt: at(105), CompletedAt: at(130), RestTargetSec: ip(90)},
+		{ID: "s2", PrescriptionID: "a", StartedAt: at(220), CompletedAt: at(260), RestTargetSec: ip(90)},
+	}
+	got := Timeline(sets)
+	if !got[1].RestExempt || !got[2].RestExempt || got[3].RestExempt {
+		t.Fatalf("exempt=%v %v %v", got[1].RestExempt, got[2].RestExempt, got[3].RestExempt)
+	}
+	c := Summarize(got)
+	if len(c) != 1 || c[0].Intervals != 1 || c[0].AvgSec != 90 || c[0].Within != 1 {
+		t.Fatalf("compliance=%+v", c)
+	}
+}
+
+func TestTolerance(t *testing.T) {
+	for target, want := range map[int]int{0: 10, 30: 10, 90: 18, 180: 36} {
+		if got := Tolerance(target); got != want {
+			t.Fatalf("Tolerance(%d)=%d want %d", target, got, want)
+		}
+	}
+}
+
+func TestSpan(t *testing.T) {
+	if _, _, ok := Span([]Entry{{}}); ok {
+		t.Fatal("span without times")
+	}
+	s, e, ok := Span(Timeline([]Set{{CompletedAt: at(100)}, {StartedAt: at(10), CompletedAt: at(50)}}))
+	if !ok || !s.Equal(*at(10)) || !e.Equal(*at(100)) {
+		t.Fatalf("span=%v..%v", s, e)
+	}
+}
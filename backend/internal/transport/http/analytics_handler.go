@@ -0,0 +1,140 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
+	"gorm.io/gorm"
+)
+
+type AnalyticsHandler struct {
+	svc   service.AnalyticsService
+	defTz string
+	db    *gorm.DB
+}
+
+func NewAnalyticsHandler(s service.AnalyticsService, defaultTZ string, db *gorm.DB) *AnalyticsHandler {
+	if defaultTZ == "" {
+		defaultTZ = "UTC"
+	}
+	return &AnalyticsHandler{svc: s, defTz: defaultTZ, db: db}
+}
+
+func (h *AnalyticsHandler) Register(r *gin.RouterGroup) {
+	grp := r.Group("/history")
+	{
+		// /api/history/strength?disciple_id=&exercise_id=&from=&to=&formula=epley|brzycki&tz=
+		grp.GET("/strength", h.strengthForQuery)
+		grp.GET("/disciples/:id/strength", h.strengthForDisciple)
+		// /api/history/rest?disciple_id=&exercise_id=&from=&to=&tz=: descanso real vs rest_sec
+		grp.GET("/rest", h.restForQuery)
+		grp.GET("/disciples/:id/rest", h.restForDisciple)
+	}
+}
+
+func (h *AnalyticsHandler) strengthForQuery(c *gin.Context) {
+	if discipleID, ok := h.queryDisciple(c); ok {
+		h.strength(c, discipleID)
+	}
+}
+
+// queryDisciple: ?disciple_id= (por defecto el propio usuario) con acceso de lectura.
+func (h *AnalyticsHandler) queryDisciple(c *gin.Context) (string, bool) {
+	discipleID := c.Query("disciple_id")
+	if discipleID == "" {
+		discipleID = security.UserID(c)
+	}
+	ok, err := security.Can(c, security.ResDisciple, discipleID, security.ActRead)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return "", false
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return "", false
+	}
+	return discipleID, true
+}
+
+// /disciples/:id/strength: acceso por RoutePolicies.
+func (h *AnalyticsHandler) strengthForDisciple(c *gin.Context) {
+	h.strength(c, c.Param("id"))
+}
+
+func (h *AnalyticsHandler) strength(c *gin.Context, discipleID string) {
+	if discipleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "disciple_id_required"})
+		return
+	}
+	q := service.StrengthQuery{Formula: c.Query("formula"), TZ: c.DefaultQuery("tz", h.defTz)}
+	if raw := strings.TrimSpace(c.Query("exercise_id")); raw != "" {
+		if _, err := uuid.Parse(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_exercise_id"})
+			return
+		}
+		q.ExerciseID = &raw
+	}
+	var ok bool
+	if q.From, ok = parseDateParam(c, "from"); !ok {
+		return
+	}
+	if q.To, ok = parseDateParam(c, "to"); !ok {
+		return
+	}
+
+	out, err := h.svc.Strength(c.Request.Context(), discipleID, q)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidFormula) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_formula", "detail": "epley|brzycki"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *AnalyticsHandler) restForQuery(c *gin.Context) {
+	if discipleID, ok := h.queryDisciple(c); ok {
+		h.rest(c, discipleID)
+	}
+}
+
+// /disciples/:id/rest: acceso por RoutePolicies.
+func (h *AnalyticsHandler) restForDisciple(c *gin.Context) {
+	h.rest(c, c.Param("id"))
+}
+
+func (h *AnalyticsHandler) rest(c *gin.Context, discipleID string) {
+	if discipleID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "disciple_id_required"})
+		return
+	}
+	q := service.RestQuery{TZ: c.DefaultQuery("tz", h.defTz)}
+	if raw := strings.TrimSpace(c.Query("exercise_id")); raw != "" {
+		if _, err := uuid.Parse(raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_exercise_id"})
+			return
+		}
+		q.ExerciseID = &raw
+	}
+	var ok bool
+	if q.From, ok = parseDateParam(c, "from"); !ok {
+		return
+	}
+	if q.To, ok = parseDateParam(c, "to"); !ok {
+		return
+	}
+
+	out, err := h.svc.Rest(c.Request.Context(), discipleID, q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
@@ -4,13 +4,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vicepalma/roma-system/backend/internal/blobstore"
+	"github.com/vicepalma/roma-system/backend/internal/notify"
 	"github.com/vicepalma/roma-system/backend/internal/repository"
 	"github.com/vicepalma/roma-system/backend/internal/security"
 	"github.com/vicepalma/roma-system/backend/internal/service"
@@ -58,6 +67,7 @@ func TestE2EAPIPermissionsWithCleanDB(t *testing.T) {
 
 	e2eAssertMe(t, r, coach1Token, "coach")
 	e2eAssertMe(t, r, disciple1Token, "disciple")
+	e2eAssertRefreshRotation(t, r, "disciple2.e2e@example.test")
 	e2eRequest(t, r, http.MethodGet, "/api/exercises", "", nil, http.StatusUnauthorized)
 
 	checkinID := e2ePostID(t, r, http.MethodPost, "/api/checkins", disciple1Token, gin.H{
@@ -66,15 +76,40 @@ func TestE2EAPIPermissionsWithCleanDB(t *testing.T) {
 		"notes":      "E2E check-in",
 	}, http.StatusCreated)
 	e2eAssertCheckinInList(t, r, disciple1Token, "/api/checkins", checkinID)
+	e2eAssertOutboxEvent(t, db, notify.ChannelWebhook, notify.EventCheckinSubmitted, checkinID, 1)
 	e2eAssertCheckinDetail(t, r, disciple1Token, checkinID, disciple1ID, 76.5, "E2E check-in")
 	e2eRequest(t, r, http.MethodGet, "/api/checkins/"+checkinID, disciple2Token, nil, http.StatusForbidden)
 	e2eAssertCheckinInList(t, r, coach1Token, "/api/coach/disciples/"+disciple1ID+"/checkins", checkinID)
 	e2eRequest(t, r, http.MethodGet, "/api/checkins/"+checkinID, coach1Token, nil, http.StatusOK)
 	e2eRequest(t, r, http.MethodGet, "/api/coach/disciples/"+disciple1ID+"/checkins", coach2Token, nil, http.StatusForbidden)
 	e2eRequest(t, r, http.MethodGet, "/api/checkins/"+checkinID, coach2Token, nil, http.StatusForbidden)
+	photoURL := e2eUploadCheckinPhoto(t, r, disciple1Token, checkinID, http.StatusCreated)
+	e2eUploadCheckinPhoto(t, r, coach1Token, checkinID, http.StatusForbidden)
+	e2eRequest(t, r, http.MethodGet, photoURL, "", nil, http.StatusOK)
+	e2eRequest(t, r, http.MethodGet, strings.Replace(photoURL, "sig=", "sig=x", 1), "", nil, http.StatusForbidden)
+	e2eRequest(t, r, http.MethodPatch, "/api/checkins/"+checkinID, disciple1Token, gin.H{"notes": "E2E check-in", "sleep_hours": 7.5}, http.StatusOK)
+	e2eRequest(t, r, http.MethodPatch, "/api/checkins/"+checkinID, disciple2Token, gin.H{"notes": "hijack"}, http.StatusForbidden)
+	e2eAssertCheckinInList(t, r, coach1Token, "/api/coach/checkins", checkinID)
+	e2eRequest(t, r, http.MethodPut, "/api/coach/checkins/"+checkinID+"/review", coach2Token, gin.H{"comment": "no"}, http.StatusForbidden)
+	e2eRequest(t, r, http.MethodPut, "/api/coach/checkins/"+checkinID+"/review", coach1Token, gin.H{"status": "reviewed", "comment": "Bien"}, http.StatusOK)
+	e2eAssertCheckinInList(t, r, coach1Token, "/api/coach/checkins?status=reviewed", checkinID)
+	e2eAssertCheckinInList(t, r, disciple1Token, "/api/checkins?status=reviewed&from=2026-07-01&to=2026-07-01", checkinID)
+	tmpCheckinID := e2ePostID(t, r, http.MethodPost, "/api/checkins", disciple1Token, gin.H{"checked_at": "2026-06-30"}, http.StatusCreated)
+	e2eRequest(t, r, http.MethodDelete, "/api/checkins/"+tmpCheckinID, disciple2Token, nil, http.StatusForbidden)
+	e2eRequest(t, r, http.MethodDelete, "/api/checkins/"+tmpCheckinID, disciple1Token, nil, http.StatusNoContent)
+	e2eRequest(t, r, http.MethodGet, "/api/checkins/"+tmpCheckinID, disciple1Token, nil, http.StatusNotFound)
 	e2eRequest(t, r, http.MethodPost, "/api/checkins", coach1Token, gin.H{"checked_at": "2026-07-01"}, http.StatusForbidden)
 	e2eRequest(t, r, http.MethodPost, "/api/checkins", disciple1Token, gin.H{"checked_at": "not-a-date"}, http.StatusBadRequest)
 	e2eRequest(t, r, http.MethodPost, "/api/checkins", disciple1Token, gin.H{"checked_at": "2026-07-01", "weight_kg": -1}, http.StatusBadRequest)
+	e2eRequest(t, r, http.MethodPut, "/api/coach/disciples/"+disciple1ID+"/checkins/requirements", coach1Token, gin.H{"metrics": []string{"waist_cm", "energy"}}, http.StatusOK)
+	e2eRequest(t, r, http.MethodPut, "/api/coach/disciples/"+disciple1ID+"/checkins/requirements", coach2Token, gin.H{"metrics": []string{"waist_cm"}}, http.StatusForbidden)
+	e2eRequest(t, r, http.MethodPut, "/api/coach/disciples/"+disciple1ID+"/checkins/requirements", coach1Token, gin.H{"metrics": []string{"shoe_size"}}, http.StatusBadRequest)
+	e2eRequest(t, r, http.MethodPost, "/api/checkins", disciple1Token, gin.H{"checked_at": "2026-07-08", "waist_cm": 82}, http.StatusUnprocessableEntity)
+	e2eRequest(t, r, http.MethodPost, "/api/checkins", disciple1Token, gin.H{"checked_at": "2026-07-08", "waist_cm": 82, "energy": 11}, http.StatusBadRequest)
+	e2ePostID(t, r, http.MethodPost, "/api/checkins", disciple1Token, gin.H{"checked_at": "2026-07-08", "waist_cm": 82, "energy": 7}, http.StatusCreated)
+	e2eRequest(t, r, http.MethodGet, "/api/checkins/trends?metrics=weight_kg,waist_cm&window=14", disciple1Token, nil, http.StatusOK)
+	e2eRequest(t, r, http.MethodGet, "/api/coach/disciples/"+disciple1ID+"/checkins/trends", coach1Token, nil, http.StatusOK)
+	e2eRequest(t, r, http.MethodGet, "/api/coach/disciples/"+disciple1ID+"/checkins/trends", coach2Token, nil, http.StatusForbidden)
 
 	exerciseID := e2eCreateExercise(t, r, coach1Token, "E2E Bench Press")
 	e2eRequest(t, r, http.MethodPost, "/api/exercises", disciple1Token, gin.H{"name": "E2E Disciple Forbidden", "primary_muscle": "chest"}, http.StatusForbidden)
@@ -152,6 +187,7 @@ func TestE2EAPIPermissionsWithCleanDB(t *testing.T) {
 		"set_index":       2,
 		"reps":            10,
 	}, http.StatusBadRequest)
+	e2eAssertSetTypes(t, r, disciple1Token, sessionID, prescriptionID, setID)
 	e2eRequest(t, r, http.MethodDelete, "/api/sessions/"+sessionID+"/sets/"+setID, disciple2Token, nil, http.StatusForbidden)
 	e2eRequest(t, r, http.MethodPatch, "/api/sessions/"+sessionID, disciple2Token, gin.H{
 		"status":   "closed",
@@ -176,13 +212,15 @@ func TestE2EAPIPermissionsWithCleanDB(t *testing.T) {
 		"day_id":        dayID,
 		"performed_at":  "2026-07-02T10:00:00Z",
 	}, http.StatusCreated)
+	e2eAssertConcurrentSets(t, r, disciple1Token, openSessionID, prescriptionID)
 	altProgramID := e2eCreateProgram(t, r, coach1Token, "E2E Coach Program Alt")
 	altWeekID := e2ePostID(t, r, http.MethodPost, "/api/programs/"+altProgramID+"/weeks", coach1Token, gin.H{"week_index": 1}, http.StatusCreated)
 	altDayID := e2ePostID(t, r, http.MethodPost, "/api/programs/"+altProgramID+"/weeks/"+altWeekID+"/days", coach1Token, gin.H{"day_index": 1}, http.StatusCreated)
-	e2ePostID(t, r, http.MethodPost, "/api/programs/days/"+altDayID+"/prescriptions", coach1Token, gin.H{
+	altPrescriptionID := e2ePostID(t, r, http.MethodPost, "/api/programs/days/"+altDayID+"/prescriptions", coach1Token, gin.H{
 		"exercise_id": exerciseID,
 		"series":      2,
 		"reps":        "12",
+		"rest_sec":    60,
 		"position":    1,
 	}, http.StatusCreated)
 	altAssignmentID := e2ePostID(t, r, http.MethodPost, "/api/coach/assignments", coach1Token, gin.H{
@@ -191,20 +229,26 @@ func TestE2EAPIPermissionsWithCleanDB(t *testing.T) {
 		"start_date":  "2026-07-03",
 	}, http.StatusCreated)
 	e2eRequest(t, r, http.MethodPost, "/api/coach/assignments/"+altAssignmentID+"/activate?disciple_id="+disciple1ID, coach1Token, nil, http.StatusNoContent)
-	altSessionID := e2ePostID(t, r, http.MethodPost, "/api/sessions", disciple1Token, gin.H{
+	altSession := gin.H{
 		"assignment_id": altAssignmentID,
 		"day_id":        altDayID,
 		"performed_at":  "2026-07-03T10:00:00Z",
-	}, http.StatusCreated)
+	}
+	e2eAssertOpenSessionConflict(t, r, disciple1Token, altSession, openSessionID)
+	e2eAgeSession(t, db, openSessionID, 5*time.Hour)
+	altSessionID := e2ePostID(t, r, http.MethodPost, "/api/sessions", disciple1Token, altSession, http.StatusCreated)
+	e2eAssertAutoClosed(t, r, disciple1Token, openSessionID)
 	e2eAssertHistoryFilter(t, r, disciple1Token, "&from=2026-07-01", []string{openSessionID, altSessionID}, []string{sessionID})
 	e2eAssertHistoryFilter(t, r, disciple1Token, "&to=2026-06-29", []string{sessionID}, []string{openSessionID, altSessionID})
-	e2eAssertHistoryFilter(t, r, disciple1Token, "&status=closed", []string{sessionID}, []string{openSessionID})
-	e2eAssertHistoryFilter(t, r, disciple1Token, "&status=open", []string{openSessionID, altSessionID}, []string{sessionID})
+	e2eAssertHistoryFilter(t, r, disciple1Token, "&status=closed", []string{sessionID, openSessionID}, []string{altSessionID})
+	e2eAssertHistoryFilter(t, r, disciple1Token, "&status=open", []string{altSessionID}, []string{sessionID, openSessionID})
 	e2eAssertHistoryFilter(t, r, disciple1Token, "&program_id="+programID, []string{sessionID, openSessionID}, []string{altSessionID})
 	e2eAssertHistoryFilter(t, r, disciple1Token, "&program_id="+altProgramID, []string{altSessionID}, []string{sessionID, openSessionID})
 	e2eRequest(t, r, http.MethodGet, "/api/history?group=session&status=done", disciple1Token, nil, http.StatusBadRequest)
 	e2eRequest(t, r, http.MethodGet, "/api/history?group=session&from=not-a-date", disciple1Token, nil, http.StatusBadRequest)
 	e2eRequest(t, r, http.MethodGet, "/api/history?group=session&program_id=not-a-uuid", disciple1Token, nil, http.StatusBadRequest)
+	e2eAssertSetTiming(t, r, disciple1Token, coach1Token, disciple1ID, altSessionID, altPrescriptionID)
+	e2eAssertSessionSync(t, r, disciple1Token, disciple2Token, altAssignmentID, altDayID, altPrescriptionID, prescriptionID)
 
 	e2eSetAssignmentActive(t, db, assignmentID, false)
 	e2eRequest(t, r, http.MethodPost, "/api/sessions", disciple1Token, gin.H{
@@ -297,6 +341,41 @@ func TestE2EAPIPermissionsWithCleanDB(t *testing.T) {
 	e2eAssertActiveSelfAssignmentCount(t, db, disciple3ID, 1)
 	e2eAssertActiveCoachAssignmentCount(t, db, disciple3ID, 1)
 
+	e2eAssertRoster(t, r, coach1Token, disciple1ID, 76.5)
+	e2eRequest(t, r, http.MethodGet, "/api/coach/roster?sort=bogus", coach1Token, nil, http.StatusBadRequest)
+	e2eRequest(t, r, http.MethodGet, "/api/coach/roster", disciple1Token, nil, http.StatusForbidden)
+
+	inviteCode := e2eCreateInvite(t, r, coach1Token, "disciple3.e2e@example.test")
+	// el código solo va por email al invitado, nunca al webhook
+	e2eAssertOutboxEvent(t, db, notify.ChannelEmail, notify.EventInviteSent, inviteCode, 1)
+	e2eAssertOutboxEvent(t, db, notify.ChannelWebhook, notify.EventInviteSent, "", 0)
+	e2eRequest(t, r, http.MethodPost, "/api/coach/invitations/"+inviteCode+"/accept", disciple2Token, nil, http.StatusForbidden)
+	e2eRequest(t, r, http.MethodPost, "/api/coach/invitations/"+inviteCode+"/accept", disciple3Token, nil, http.StatusOK)
+	e2eRequest(t, r, http.MethodPost, "/api/coach/invitations/"+inviteCode+"/accept", disciple3Token, nil, http.StatusConflict)
+	e2eRequest(t, r, http.MethodGet, "/api/coach/disciples/"+disciple3ID+"/checkins", coach1Token, nil, http.StatusOK)
+
+	staleCode := e2eCreateInvite(t, r, coach1Token, "new.e2e@example.test")
+	e2eRequest(t, r, http.MethodPost, "/api/coach/invitations/"+staleCode+"/revoke", coach2Token, nil, http.StatusNotFound)
+	freshCode := e2eResendInvite(t, r, coach1Token, staleCode)
+	e2eRequest(t, r, http.MethodPost, "/api/coach/invitations/"+staleCode+"/revoke", coach1Token, nil, http.StatusConflict)
+	e2eAssertInviteStatus(t, r, coach1Token, "revoked", staleCode)
+	e2eAssertInviteStatus(t, r, coach1Token, "accepted", inviteCode)
+	e2eRequest(t, r, http.MethodGet, "/api/coach/invitations", disciple1Token, nil, http.StatusForbidden)
+	e2eRequest(t, r, http.MethodPost, "/auth/signup/invite", "", gin.H{
+		"code": staleCode, "email": "new.e2e@example.test", "name": "E2E New", "password": e2ePassword,
+	}, http.StatusConflict)
+	e2eRequest(t, r, http.MethodPost, "/auth/signup/invite", "", gin.H{
+		"code": freshCode, "email": "other.e2e@example.test", "name": "E2E New", "password": e2ePassword,
+	}, http.StatusForbidden)
+	e2eRequest(t, r, http.MethodPost, "/auth/signup/invite", "", gin.H{
+		"code": freshCode, "email": "New.E2E@example.test", "name": "E2E New", "password": e2ePassword,
+	}, http.StatusCreated)
+	newToken, newID := e2eLogin(t, r, "new.e2e@example.test")
+	e2eAssertMe(t, r, newToken, "disciple")
+	e2eAssertEmailVerified(t, r, newToken, true)
+	e2eRequest(t, r, http.MethodGet, "/api/coach/disciples/"+newID+"/checkins", coach1Token, nil, http.StatusOK)
+	e2eAssertAccountTokens(t, r, db, "disciple2.e2e@example.test")
+
 	if coach1ID != e2eCoach1 {
 		t.Fatalf("coach id=%s want %s", coach1ID, e2eCoach1)
 	}
@@ -313,23 +392,44 @@ func e2eRouter(db *gorm.DB) *gin.Engine {
 	inviteRepo := repository.NewInviteRepository(db)
 	adRepo := repository.NewAssignmentDaysRepository(db)
 	checkinRepo := repository.NewCheckinRepository(db)
+	methodRepo := repository.NewMethodRepository(db)
 
 	histSvc := service.NewHistoryService(histRepo)
-	coachSvc := service.NewCoachService(coachRepo, histSvc, db, assignRepo)
-	sessSvc := service.NewSessionService(sessRepo, coachSvc)
-	checkinSvc := service.NewCheckinService(checkinRepo)
+	// webhook inalcanzable: el E2E solo verifica que los eventos se encolen
+	notifySvc := service.NewNotificationService(repository.NewNotificationRepository(db),
+		&notify.Webhook{URL: "http://127.0.0.1:1/roma-e2e"}, &notify.SMTP{Addr: "127.0.0.1:1"})
+	coachSvc := service.NewCoachService(coachRepo, histSvc, db, assignRepo, notifySvc)
+	sessSvc := service.NewSessionService(sessRepo, coachSvc, repository.NewRecordRepository(db), notifySvc, 4*time.Hour)
+	blobs, err := blobstore.NewFS(filepath.Join(os.TempDir(), "roma-e2e-blobs"))
+	if err != nil {
+		panic(err)
+	}
+	checkinSvc := service.NewCheckinService(checkinRepo, db, blobs, notifySvc)
+
+	authSessSvc := service.NewAuthSessionService(repository.NewAuthSessionRepository(db))
 
 	r := gin.New()
-	NewAuthHandler(userRepo, db).Register(r.Group("/"))
-	api := r.Group("/api", security.AuthRequired())
+	accountSvc := service.NewAccountService(userRepo, authSessSvc, security.ActionTokens{}, notifySvc, "")
+	NewAuthHandler(userRepo, authSessSvc, accountSvc, db).Register(r.Group("/"))
+	security.UseAuthorizer(security.NewAuthorizer(security.NewSQLPolicyStore(db), nil))
+	api := r.Group("/api", security.AuthRequired(), security.Authorize(RoutePolicies))
 	NewExerciseHandler(service.NewExerciseService(exRepo), db).Register(api)
+	NewMethodHandler(service.NewMethodService(methodRepo), db).Register(api)
 	NewProgramHandler(service.NewProgramService(progRepo), db).Register(api)
+	NewProgramIOHandler(service.NewProgramIOService(progRepo, exRepo, methodRepo), db).Register(api)
+	NewProgressionHandler(service.NewProgressionService(repository.NewProgressionRepository(db), methodRepo), db).Register(api)
 	NewSessionHandler(sessSvc, db).Register(api)
+	NewSessionSyncHandler(service.NewSessionSyncService(repository.NewSessionSyncRepository(db), repository.NewRecordRepository(db), notifySvc)).Register(api)
 	NewHistoryHandler(histSvc, "UTC", db).Register(api)
+	NewAnalyticsHandler(service.NewAnalyticsService(histRepo), "UTC", db).Register(api)
 	NewCoachHandler(coachSvc, histSvc, userRepo, db).Register(api)
-	NewInviteHandler(service.NewInviteService(inviteRepo, coachSvc, "")).Register(api)
+	inviteH := NewInviteHandler(service.NewInviteService(inviteRepo, db, coachSvc, "", notifySvc), authSessSvc, db)
+	inviteH.Register(api)
+	inviteH.RegisterPublic(r.Group("/"))
 	NewAssignmentDaysHandler(service.NewAssignmentDaysService(adRepo, coachSvc)).Register(api)
-	NewCheckinHandler(checkinSvc, db).Register(api)
+	checkinH := NewCheckinHandler(checkinSvc, db)
+	checkinH.Register(api)
+	checkinH.RegisterFiles(r)
 	NewMeHandler(histSvc, coachSvc, sessSvc).Register(api)
 	return r
 }
@@ -339,7 +439,7 @@ func cleanAndSeedE2EDB(t *testing.T, db *gorm.DB) {
 	for _, table := range []string{
 		"set_logs", "cardio_segments", "session_logs", "assignments", "prescriptions",
 		"program_days", "program_weeks", "program_versions", "programs", "exercises",
-		"coach_links", "master_disciple", "invite_codes", "invitations", "checkins",
+		"coach_links", "master_disciple", "invite_codes", "invitations", "checkins", "notification_outbox", "auth_sessions",
 		"user_flags", "methods", "users",
 	} {
 		if err := db.Exec("TRUNCATE TABLE " + table + " RESTART IDENTITY CASCADE").Error; err != nil {
@@ -400,6 +500,144 @@ func e2eLogin(t *testing.T, r http.Handler, email string) (string, string) {
 	return out.Tokens.Access, out.User.ID
 }
 
+// e2eAssertRefreshRotation: login en dos dispositivos, rotación, reuso que
+// revoca la familia, listado de sesiones y logout.
+func e2eAssertRefreshRotation(t *testing.T, r http.Handler, email string) {
+	t.Helper()
+	type pair struct {
+		Access  string `json:"access"`
+		Refresh string `json:"refresh"`
+	}
+	login := func() pair {
+		var out struct {
+			Tokens pair `json:"tokens"`
+		}
+		e2eDecode(t, e2eRequest(t, r, http.MethodPost, "/auth/login", "", gin.H{"email": email, "password": e2ePassword}, http.StatusOK), &out)
+		return out.Tokens
+	}
+	refresh := func(tok string, want int) pair {
+		var out struct {
+			Tokens pair `json:"tokens"`
+		}
+		e2eDecode(t, e2eRequest(t, r, http.MethodPost, "/auth/refresh", "", gin.H{"refresh": tok}, want), &out)
+		return out.Tokens
+	}
+
+	phone, laptop := login(), login()
+	rotated := refresh(phone.Refresh, http.StatusOK)
+	if rotated.Refresh == phone.Refresh {
+		t.Fatal("refresh token not rotated")
+	}
+	e2eAssertMe(t, r, rotated.Access, "disciple")
+
+	var list struct {
+		Items []struct {
+			ID      string `json:"id"`
+			Current bool   `json:"current"`
+		} `json:"items"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/auth/sessions", laptop.Access, nil, http.StatusOK), &list)
+	if len(list.Items) != 2 {
+		t.Fatalf("sessions=%d want 2", len(list.Items))
+	}
+	current := 0
+	for _, it := range list.Items {
+		if it.Current {
+			current++
+		}
+	}
+	if current != 1 {
+		t.Fatalf("current sessions=%d want 1", current)
+	}
+
+	// reusar el token ya rotado revoca la sesión: el rotado tampoco sirve
+	refresh(phone.Refresh, http.StatusUnauthorized)
+	refresh(rotated.Refresh, http.StatusUnauthorized)
+	refresh(laptop.Refresh, http.StatusOK)
+
+	e2eRequest(t, r, http.MethodPost, "/auth/logout", "", gin.H{"refresh": "unknown"}, http.StatusNoContent)
+	tablet := login()
+	e2eRequest(t, r, http.MethodPost, "/auth/logout", "", gin.H{"refresh": tablet.Refresh}, http.StatusNoContent)
+	refresh(tablet.Refresh, http.StatusUnauthorized)
+
+	e2eRequest(t, r, http.MethodDelete, "/auth/sessions/"+list.Items[0].ID, "", nil, http.StatusUnauthorized)
+	e2eRequest(t, r, http.MethodDelete, "/auth/sessions", laptop.Access, nil, http.StatusOK)
+}
+
+// e2eAssertAccountTokens: reset de password de un solo uso que revoca las
+// sesiones, cambio de password que conserva la actual y verificación de email.
+func e2eAssertAccountTokens(t *testing.T, r http.Handler, db *gorm.DB, email string) {
+	t.Helper()
+	type pair struct {
+		Access  string `json:"access"`
+		Refresh string `json:"refresh"`
+	}
+	login := func() pair {
+		var out struct {
+			Tokens pair `json:"tokens"`
+		}
+		e2eDecode(t, e2eRequest(t, r, http.MethodPost, "/auth/login", "", gin.H{"email": email, "password": e2ePassword}, http.StatusOK), &out)
+		return out.Tokens
+	}
+	refresh := func(tok string, want int) {
+		e2eRequest(t, r, http.MethodPost, "/auth/refresh", "", gin.H{"refresh": tok}, want)
+	}
+
+	before := login()
+	e2eRequest(t, r, http.MethodPost, "/auth/password/forgot", "", gin.H{"email": "nobody.e2e@example.test"}, http.StatusAccepted)
+	e2eRequest(t, r, http.MethodPost, "/auth/password/forgot", "", gin.H{"email": email}, http.StatusAccepted)
+	resetToken := e2eOutboxToken(t, db, notify.EventPasswordReset, email)
+	e2eRequest(t, r, http.MethodPost, "/auth/password/reset", "", gin.H{"token": "nope", "password": e2ePassword}, http.StatusBadRequest)
+	e2eRequest(t, r, http.MethodPost, "/auth/password/reset", "", gin.H{"token": resetToken, "password": e2ePassword}, http.StatusNoContent)
+	e2eRequest(t, r, http.MethodPost, "/auth/password/reset", "", gin.H{"token": resetToken, "password": e2ePassword}, http.StatusGone)
+	refresh(before.Refresh, http.StatusUnauthorized)
+
+	keep, other := login(), login()
+	e2eRequest(t, r, http.MethodPost, "/auth/password", keep.Access, gin.H{"current_password": "wrong", "password": e2ePassword}, http.StatusUnauthorized)
+	e2eRequest(t, r, http.MethodPost, "/auth/password", keep.Access, gin.H{"current_password": e2ePassword, "password": e2ePassword}, http.StatusNoContent)
+	refresh(other.Refresh, http.StatusUnauthorized)
+	refresh(keep.Refresh, http.StatusOK)
+
+	e2eAssertEmailVerified(t, r, keep.Access, false)
+	e2eRequest(t, r, http.MethodPost, "/auth/verify-email/send", keep.Access, nil, http.StatusAccepted)
+	verifyToken := e2eOutboxToken(t, db, notify.EventEmailVerification, email)
+	e2eRequest(t, r, http.MethodPost, "/auth/verify-email", "", gin.H{"token": verifyToken}, http.StatusNoContent)
+	e2eRequest(t, r, http.MethodPost, "/auth/verify-email", "", gin.H{"token": verifyToken}, http.StatusGone)
+	e2eAssertEmailVerified(t, r, keep.Access, true)
+	e2eRequest(t, r, http.MethodPost, "/auth/verify-email/send", keep.Access, nil, http.StatusConflict)
+}
+
+// e2eOutboxToken saca el token del último email encolado (sin APP_BASE_URL
+// el enlace es el token tal cual).
+func e2eOutboxToken(t *testing.T, db *gorm.DB, event, email string) string {
+	t.Helper()
+	var body string
+	if err := db.Raw(
+		`SELECT body FROM notification_outbox WHERE channel = 'email' AND event = ? AND recipient = ? ORDER BY created_at DESC LIMIT 1`,
+		event, email,
+	).Scan(&body).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range strings.Fields(body) {
+		if strings.Count(f, ".") == 2 && strings.HasPrefix(f, "ey") {
+			return f
+		}
+	}
+	t.Fatalf("no %s token for %s in outbox: %q", event, email, body)
+	return ""
+}
+
+func e2eAssertEmailVerified(t *testing.T, r http.Handler, token string, want bool) {
+	t.Helper()
+	var out struct {
+		EmailVerified bool `json:"email_verified"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/me", token, nil, http.StatusOK), &out)
+	if out.EmailVerified != want {
+		t.Fatalf("/me email_verified=%v want %v", out.EmailVerified, want)
+	}
+}
+
 func e2eAssertMe(t *testing.T, r http.Handler, token string, role string) {
 	t.Helper()
 	resp := e2eRequest(t, r, http.MethodGet, "/me", token, nil, http.StatusOK)
@@ -444,6 +682,100 @@ func e2eAssertCheckinDetail(t *testing.T, r http.Handler, token, checkinID, disc
 	}
 }
 
+func e2eAssertRoster(t *testing.T, r http.Handler, token, discipleID string, weight float64) {
+	t.Helper()
+	var out struct {
+		Items []struct {
+			DiscipleID    string   `json:"disciple_id"`
+			LastSessionAt *string  `json:"last_session_at"`
+			LastWeightKg  *float64 `json:"last_weight_kg"`
+		} `json:"items"`
+		Total int `json:"total"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/api/coach/roster?sort=inactive", token, nil, http.StatusOK), &out)
+	if out.Total != 1 || len(out.Items) != 1 || out.Items[0].DiscipleID != discipleID {
+		t.Fatalf("roster=%+v", out)
+	}
+	it := out.Items[0]
+	if it.LastSessionAt == nil || it.LastWeightKg == nil || *it.LastWeightKg != weight {
+		t.Fatalf("roster item=%+v", it)
+	}
+}
+
+func e2eUploadCheckinPhoto(t *testing.T, r http.Handler, token, checkinID string, want int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("photo", "progress.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(fw, img); err != nil {
+		t.Fatal(err)
+	}
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/checkins/"+checkinID+"/photos", &body)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != want {
+		t.Fatalf("upload photo status=%d want=%d body=%s", w.Code, want, w.Body.String())
+	}
+	var out struct {
+		URL string `json:"url"`
+	}
+	if want == http.StatusCreated {
+		e2eDecode(t, w.Body.Bytes(), &out)
+	}
+	return out.URL
+}
+
+func e2eCreateInvite(t *testing.T, r http.Handler, token, email string) string {
+	t.Helper()
+	var out struct {
+		Code string `json:"code"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodPost, "/api/coach/invitations", token, gin.H{"email": email}, http.StatusCreated), &out)
+	if out.Code == "" {
+		t.Fatal("invite without code")
+	}
+	return out.Code
+}
+
+func e2eResendInvite(t *testing.T, r http.Handler, token, code string) string {
+	t.Helper()
+	var out struct {
+		Code string `json:"code"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodPost, "/api/coach/invitations/"+code+"/resend", token, nil, http.StatusCreated), &out)
+	if out.Code == "" || out.Code == code {
+		t.Fatalf("resend code=%q (old %q)", out.Code, code)
+	}
+	return out.Code
+}
+
+func e2eAssertInviteStatus(t *testing.T, r http.Handler, token, status, code string) {
+	t.Helper()
+	var out struct {
+		Items []struct {
+			Code   string `json:"code"`
+			Status string `json:"status"`
+		} `json:"items"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/api/coach/invitations?status="+status, token, nil, http.StatusOK), &out)
+	for _, it := range out.Items {
+		if it.Code == code {
+			if it.Status != status {
+				t.Fatalf("invite %s status=%s want %s", code, it.Status, status)
+			}
+			return
+		}
+	}
+	t.Fatalf("invite %s not listed as %s: %+v", code, status, out.Items)
+}
+
 func e2eCreateExercise(t *testing.T, r http.Handler, token, name string) string {
 	t.Helper()
 	return e2ePostID(t, r, http.MethodPost, "/api/exercises", token, gin.H{"name": name, "primary_muscle": "chest"}, http.StatusCreated)
@@ -505,6 +837,301 @@ func e2eAssertSessionDetailMeta(t *testing.T, r http.Handler, token, sessionID,
 	}
 }
 
+// e2eAssertOpenSessionConflict: con otra sesión abierta y reciente no se abre
+// una segunda; el 409 indica cuál retomar.
+func e2eAssertOpenSessionConflict(t *testing.T, r http.Handler, token string, body gin.H, openID string) {
+	t.Helper()
+	var out struct {
+		Error     string `json:"error"`
+		SessionID string `json:"session_id"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodPost, "/api/sessions", token, body, http.StatusConflict), &out)
+	if out.Error != "open_session_exists" || out.SessionID != openID {
+		t.Fatalf("open session conflict=%+v want session_id=%s", out, openID)
+	}
+}
+
+// e2eAgeSession simula una sesión olvidada: atrasa su alta y su última actividad.
+func e2eAgeSession(t *testing.T, db *gorm.DB, sessionID string, d time.Duration) {
+	t.Helper()
+	secs := int(d.Seconds())
+	if err := db.Exec(`
+		UPDATE session_logs
+		SET created_at = created_at - ? * interval '1 second',
+		    last_activity_at = last_activity_at - ? * interval '1 second'
+		WHERE id = ?`, secs, secs, sessionID).Error; err != nil {
+		t.Fatalf("age session: %v", err)
+	}
+}
+
+// e2eAssertAutoClosed: la sesión inactiva quedó cerrada en su última serie.
+func e2eAssertAutoClosed(t *testing.T, r http.Handler, token, sessionID string) {
+	t.Helper()
+	var out struct {
+		Session struct {
+			Status         string     `json:"status"`
+			AutoClosed     bool       `json:"auto_closed"`
+			EndedAt        *time.Time `json:"ended_at"`
+			LastActivityAt *time.Time `json:"last_activity_at"`
+			DurationSec    int64      `json:"duration_sec"`
+		} `json:"session"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/api/sessions/"+sessionID, token, nil, http.StatusOK), &out)
+	s := out.Session
+	if s.Status != "closed" || !s.AutoClosed || s.EndedAt == nil || s.LastActivityAt == nil || !s.EndedAt.Equal(*s.LastActivityAt) || s.DurationSec <= 0 {
+		t.Fatalf("auto-closed session=%+v", s)
+	}
+}
+
+// e2eAssertSetTiming: dos series con horas y 60 s de descanso (rest_sec 60);
+// la línea de tiempo y el reporte del coach lo cuentan como cumplido.
+func e2eAssertSetTiming(t *testing.T, r http.Handler, token, coachToken, discipleID, sessionID, prescriptionID string) {
+	t.Helper()
+	base := time.Now().UTC().Add(-10 * time.Minute).Truncate(time.Second)
+	ts := func(sec int) string { return base.Add(time.Duration(sec) * time.Second).Format(time.RFC3339) }
+	for _, w := range [][2]int{{0, 40}, {100, 140}} {
+		e2ePostID(t, r, http.MethodPost, "/api/sessions/"+sessionID+"/sets", token, gin.H{
+			"prescription_id": prescriptionID, "reps": 12, "started_at": ts(w[0]), "completed_at": ts(w[1]),
+		}, http.StatusCreated)
+	}
+	e2eRequest(t, r, http.MethodPost, "/api/sessions/"+sessionID+"/sets", token, gin.H{
+		"prescription_id": prescriptionID, "reps": 12, "started_at": ts(200), "completed_at": ts(150),
+	}, http.StatusBadRequest)
+
+	var tl struct {
+		Items []struct {
+			RestSec          *int `json:"rest_sec"`
+			SamePrescription bool `json:"same_prescription"`
+		} `json:"items"`
+		Rest []struct {
+			Within int `json:"within"`
+		} `json:"rest"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/api/sessions/"+sessionID+"/timeline", coachToken, nil, http.StatusOK), &tl)
+	if len(tl.Items) != 2 || tl.Items[1].RestSec == nil || *tl.Items[1].RestSec != 60 || !tl.Items[1].SamePrescription ||
+		len(tl.Rest) != 1 || tl.Rest[0].Within != 1 {
+		t.Fatalf("timeline=%+v", tl)
+	}
+
+	var rep struct {
+		Prescriptions []struct {
+			PrescriptionID string  `json:"prescription_id"`
+			TargetSec      int     `json:"target_sec"`
+			Ratio          float64 `json:"ratio"`
+		} `json:"prescriptions"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/api/history/disciples/"+discipleID+"/rest", coachToken, nil, http.StatusOK), &rep)
+	found := false
+	for _, p := range rep.Prescriptions {
+		found = found || p.PrescriptionID == prescriptionID && p.TargetSec == 60 && p.Ratio == 1
+	}
+	if !found {
+		t.Fatalf("rest report=%+v", rep)
+	}
+}
+
+// e2eAssertSetTypes: calentamiento antes de la serie efectiva y dos drops
+// (el segundo colgado del primero); el historial por defecto solo cuenta la
+// efectiva y set_types=all suma todo.
+func e2eAssertSetTypes(t *testing.T, r http.Handler, token, sessionID, prescriptionID, workingID string) {
+	t.Helper()
+	path := "/api/sessions/" + sessionID + "/sets"
+	e2ePostID(t, r, http.MethodPost, path, token, gin.H{
+		"prescription_id": prescriptionID, "set_index": 1, "set_type": "warmup", "weight": 40, "reps": 10,
+	}, http.StatusCreated)
+	dropID := e2ePostID(t, r, http.MethodPost, path, token, gin.H{
+		"prescription_id": prescriptionID, "set_type": "drop", "parent_set_id": workingID, "weight": 80, "reps": 5,
+	}, http.StatusCreated)
+	e2ePostID(t, r, http.MethodPost, path, token, gin.H{
+		"prescription_id": prescriptionID, "set_type": "drop", "parent_set_id": dropID, "weight": 60, "reps": 5,
+	}, http.StatusCreated)
+	for _, bad := range []gin.H{
+		{"prescription_id": prescriptionID, "set_type": "drop", "reps": 5},
+		{"prescription_id": prescriptionID, "set_type": "working", "parent_set_id": workingID, "reps": 5},
+		{"prescription_id": prescriptionID, "set_type": "cluster", "reps": 5},
+		{"prescription_id": prescriptionID, "set_type": "drop", "parent_set_id": uuid.NewString(), "reps": 5},
+	} {
+		e2eRequest(t, r, http.MethodPost, path, token, bad, http.StatusBadRequest)
+	}
+
+	type setRow struct {
+		ID       string   `json:"id"`
+		SetIndex int      `json:"set_index"`
+		SetType  string   `json:"set_type"`
+		Drops    []setRow `json:"drops"`
+	}
+	var detail struct {
+		Sets []setRow `json:"sets"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/api/sessions/"+sessionID, token, nil, http.StatusOK), &detail)
+	if len(detail.Sets) != 2 || detail.Sets[0].SetType != "warmup" || detail.Sets[1].ID != workingID ||
+		len(detail.Sets[1].Drops) != 2 || detail.Sets[1].Drops[0].ID != dropID || detail.Sets[1].Drops[1].SetIndex != 4 {
+		t.Fatalf("session sets=%+v", detail.Sets)
+	}
+
+	var hist struct {
+		Items []struct {
+			SessionID string  `json:"session_id"`
+			Sets      int     `json:"sets"`
+			Volume    float64 `json:"volume"`
+		} `json:"items"`
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/api/history?group=session&set_types=all", token, nil, http.StatusOK), &hist)
+	found := false
+	for _, it := range hist.Items {
+		found = found || it.SessionID == sessionID && it.Sets == 4 && it.Volume == 1900
+	}
+	if !found {
+		t.Fatalf("history set_types=all=%+v", hist.Items)
+	}
+	e2eRequest(t, r, http.MethodGet, "/api/history?group=session&set_types=some", token, nil, http.StatusBadRequest)
+}
+
+// e2eAssertConcurrentSets: doble toque y dos dispositivos a la vez; el
+// servidor asigna set_index sin duplicados ni huecos.
+func e2eAssertConcurrentSets(t *testing.T, r http.Handler, token, sessionID, prescriptionID string) {
+	t.Helper()
+	fire := func(n int, body gin.H) {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		codes := make(chan int, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := httptest.NewRequest(http.MethodPost, "/api/sessions/"+sessionID+"/sets", bytes.NewReader(raw))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				codes <- w.Code
+			}()
+		}
+		wg.Wait()
+		close(codes)
+		for code := range codes {
+			if code != http.StatusCreated {
+				t.Fatalf("concurrent add set status=%d", code)
+			}
+		}
+	}
+	type setItem struct {
+		ID       string `json:"id"`
+		SetIndex int    `json:"set_index"`
+		Reps     int    `json:"reps"`
+	}
+	sets := func() []setItem {
+		var list struct {
+			Items []setItem `json:"items"`
+		}
+		e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/api/sessions/"+sessionID+"/sets?prescription_id="+prescriptionID, token, nil, http.StatusOK), &list)
+		for i, it := range list.Items {
+			if it.SetIndex != i+1 {
+				t.Fatalf("set indexes not 1..n: %+v", list.Items)
+			}
+		}
+		return list.Items
+	}
+
+	fire(8, gin.H{"prescription_id": prescriptionID, "reps": 10})
+	if got := sets(); len(got) != 8 {
+		t.Fatalf("after appends sets=%+v", got)
+	}
+	// insertar entre series: las 4 quedan al principio y las anteriores se corren
+	fire(4, gin.H{"prescription_id": prescriptionID, "set_index": 1, "reps": 99})
+	got := sets()
+	if len(got) != 12 || got[3].Reps != 99 || got[4].Reps != 10 {
+		t.Fatalf("after inserts sets=%+v", got)
+	}
+	e2eRequest(t, r, http.MethodDelete, "/api/sessions/"+sessionID+"/sets/"+got[5].ID, token, nil, http.StatusNoContent)
+	if got := sets(); len(got) != 11 {
+		t.Fatalf("after delete sets=%+v", got)
+	}
+}
+
+// e2eAssertSessionSync: un teléfono sube la sesión con Idempotency-Key, el
+// reloj sube después su versión (más vieja en una serie, con cierre y un borrado).
+func e2eAssertSessionSync(t *testing.T, r http.Handler, token, otherToken, assignmentID, dayID, prescriptionID, otherDayPrescriptionID string) {
+	t.Helper()
+	sessionID, setA, setB := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	phone := gin.H{
+		"session": gin.H{"id": sessionID, "assignment_id": assignmentID, "day_id": dayID,
+			"started_at": "2026-07-04T10:00:00Z", "updated_at": "2026-07-04T10:30:00Z"},
+		"sets": []gin.H{
+			{"id": setA, "prescription_id": prescriptionID, "set_index": 1, "reps": 12, "weight": 40, "updated_at": "2026-07-04T10:10:00Z"},
+			{"id": setB, "prescription_id": prescriptionID, "set_index": 2, "reps": 11, "weight": 40, "updated_at": "2026-07-04T10:20:00Z"},
+		},
+	}
+	var res struct {
+		SessionID string `json:"session_id"`
+		Session   struct{ Status string }
+		Sets      []struct{ ID, Status, Reason string }
+	}
+	e2eDecode(t, e2eSync(t, r, token, "phone-1", phone, http.StatusOK, ""), &res)
+	if res.SessionID != sessionID || res.Session.Status != "created" || len(res.Sets) != 2 || res.Sets[1].Status != "created" {
+		t.Fatalf("first sync=%+v", res)
+	}
+	e2eSync(t, r, token, "phone-1", phone, http.StatusOK, "true")
+	phone["sets"] = []gin.H{}
+	e2eSync(t, r, token, "phone-1", phone, http.StatusConflict, "")
+
+	watch := gin.H{
+		"session": gin.H{"id": sessionID, "assignment_id": assignmentID, "day_id": dayID,
+			"started_at": "2026-07-04T10:00:00Z", "status": "closed", "ended_at": "2026-07-04T11:00:00Z", "updated_at": "2026-07-04T11:00:00Z"},
+		"sets": []gin.H{
+			{"id": setA, "prescription_id": prescriptionID, "set_index": 1, "reps": 10, "weight": 40, "updated_at": "2026-07-04T10:05:00Z"},
+			{"id": setB, "updated_at": "2026-07-04T10:40:00Z", "deleted": true},
+		},
+	}
+	e2eDecode(t, e2eSync(t, r, token, "", watch, http.StatusOK, ""), &res)
+	if res.Session.Status != "updated" || res.Sets[0].Status != "stale" || res.Sets[1].Status != "deleted" {
+		t.Fatalf("second sync=%+v", res)
+	}
+	var detail struct {
+		Session struct{ Status string }
+		Sets    []struct {
+			ID   string
+			Reps int
+		}
+	}
+	e2eDecode(t, e2eRequest(t, r, http.MethodGet, "/api/sessions/"+sessionID, token, nil, http.StatusOK), &detail)
+	if detail.Session.Status != "closed" || len(detail.Sets) != 1 || detail.Sets[0].ID != setA || detail.Sets[0].Reps != 12 {
+		t.Fatalf("synced session=%+v", detail)
+	}
+
+	// un ítem inválido rechaza el lote completo
+	bad := gin.H{
+		"session": gin.H{"id": uuid.NewString(), "assignment_id": assignmentID, "day_id": dayID,
+			"started_at": "2026-07-05T10:00:00Z", "updated_at": "2026-07-05T10:30:00Z"},
+		"sets": []gin.H{{"id": uuid.NewString(), "prescription_id": otherDayPrescriptionID, "set_index": 1, "reps": 5, "updated_at": "2026-07-05T10:10:00Z"}},
+	}
+	e2eSync(t, r, token, "", bad, http.StatusUnprocessableEntity, "")
+	e2eSync(t, r, otherToken, "", phone, http.StatusForbidden, "")
+}
+
+func e2eSync(t *testing.T, r http.Handler, token, key string, body any, want int, replayed string) []byte {
+	t.Helper()
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/sessions/sync", bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != want || w.Header().Get("Idempotent-Replayed") != replayed {
+		t.Fatalf("sync status=%d replayed=%q want %d %q body=%s", w.Code, w.Header().Get("Idempotent-Replayed"), want, replayed, w.Body.String())
+	}
+	return w.Body.Bytes()
+}
+
 func e2eAssertHistorySession(t *testing.T, r http.Handler, token, sessionID, programTitle string, weekIndex, dayIndex, exercisesCount, sets int, volume float64) {
 	t.Helper()
 	resp := e2eRequest(t, r, http.MethodGet, "/api/history?group=session", token, nil, http.StatusOK)
@@ -558,6 +1185,20 @@ func e2eAssertHistoryFilter(t *testing.T, r http.Handler, token, query string, w
 	}
 }
 
+func e2eAssertOutboxEvent(t *testing.T, db *gorm.DB, channel, event, contains string, want int64) {
+	t.Helper()
+	var n int64
+	if err := db.Raw(
+		`SELECT COUNT(*) FROM notification_outbox WHERE channel = ? AND event = ? AND status = 'pending' AND COALESCE(payload::text, body) LIKE ?`,
+		channel, event, "%"+contains+"%",
+	).Scan(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	if n != want {
+		t.Fatalf("outbox %s %s with %s: %d rows want %d", channel, event, contains, n, want)
+	}
+}
+
 func e2eSetAssignmentActive(t *testing.T, db *gorm.DB, assignmentID string, active bool) {
 	t.Helper()
 	if err := db.Exec(`UPDATE assignments SET is_active = ? WHERE id = ?`, active, assignmentID).Error; err != nil {
@@ -2,6 +2,7 @@ package http
 
 import (
 	"database/sql"
+	"errors"
 	"net/http"
 	"strings"
 
@@ -9,16 +10,40 @@ import (
 	"github.com/vicepalma/roma-system/backend/internal/domain"
 	"github.com/vicepalma/roma-system/backend/internal/repository"
 	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
 	"gorm.io/gorm"
 )
 
 type AuthHandler struct {
-	users repository.UserRepository
-	db    *gorm.DB
+	users    repository.UserRepository
+	sessions service.AuthSessionService
+	accounts service.AccountService
+	db       *gorm.DB
 }
 
-func NewAuthHandler(u repository.UserRepository, db *gorm.DB) *AuthHandler {
-	return &AuthHandler{users: u, db: db}
+func NewAuthHandler(u repository.UserRepository, sessions service.AuthSessionService, accounts service.AccountService, db *gorm.DB) *AuthHandler {
+	return &AuthHandler{users: u, sessions: sessions, accounts: accounts, db: db}
+}
+
+// issueTokens abre una sesión de refresh y firma el access con su id (sid).
+func issueTokens(c *gin.Context, sessions service.AuthSessionService, userID string) (security.TokenPair, error) {
+	sess, err := sessions.Start(c.Request.Context(), userID, clientMeta(c))
+	if err != nil {
+		return security.TokenPair{}, err
+	}
+	return signPair(sess)
+}
+
+func signPair(sess *service.IssuedSession) (security.TokenPair, error) {
+	access, err := security.NewAccessToken(sess.UserID, sess.SessionID)
+	if err != nil {
+		return security.TokenPair{}, err
+	}
+	return security.TokenPair{AccessToken: access, RefreshToken: sess.RefreshToken}, nil
+}
+
+func clientMeta(c *gin.Context) service.ClientMeta {
+	return service.ClientMeta{UserAgent: c.Request.UserAgent(), IP: c.ClientIP()}
 }
 
 type registerReq struct {
@@ -47,6 +72,17 @@ func (h *AuthHandler) Register(r *gin.RouterGroup) {
 		g.POST("/register", h.register)
 		g.POST("/login", h.login)
 		g.POST("/refresh", h.refresh)
+		g.POST("/logout", h.logout)
+		g.POST("/password/forgot", h.forgotPassword)
+		g.POST("/password/reset", h.resetPassword)
+		g.POST("/verify-email", h.verifyEmail)
+		g.POST("/password", security.AuthRequired(), h.changePassword)
+		g.POST("/verify-email/send", security.AuthRequired(), h.sendVerification)
+
+		auth := g.Group("/sessions", security.AuthRequired())
+		auth.GET("", h.listSessions)
+		auth.DELETE("", h.revokeOtherSessions)
+		auth.DELETE("/:id", h.revokeSession)
 	}
 	// /me protegido
 	r.GET("/me", security.AuthRequired(), h.me)
@@ -73,7 +109,9 @@ func (h *AuthHandler) register(c *gin.Context) {
 		c.JSON(http.StatusConflict, gin.H{"error": "email_in_use"})
 		return
 	}
-	tokens, err := security.GenerateTokens(u.ID)
+	// el alta no falla si el correo de verificación no se pudo encolar
+	_ = h.accounts.SendVerification(c.Request.Context(), u.ID)
+	tokens, err := issueTokens(c, h.sessions, u.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_error"})
 		return
@@ -107,7 +145,9 @@ func (h *AuthHandler) signup(c *gin.Context) {
 		c.JSON(http.StatusConflict, gin.H{"error": "email_in_use"})
 		return
 	}
-	tokens, err := security.GenerateTokens(u.ID)
+	// el alta no falla si el correo de verificación no se pudo encolar
+	_ = h.accounts.SendVerification(c.Request.Context(), u.ID)
+	tokens, err := issueTokens(c, h.sessions, u.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_error"})
 		return
@@ -139,7 +179,7 @@ func (h *AuthHandler) login(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_credentials"})
 		return
 	}
-	tokens, err := security.GenerateTokens(u.ID)
+	tokens, err := issueTokens(c, h.sessions, u.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_error"})
 		return
@@ -147,35 +187,90 @@ func (h *AuthHandler) login(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"user": gin.H{"id": u.ID, "name": u.Name, "email": u.Email, "role": u.Role}, "tokens": tokens})
 }
 
+type refreshReq struct {
+	Refresh string `json:"refresh" binding:"required"`
+}
+
+// refresh rota el token: el anterior queda usado y reusarlo revoca la sesión.
 func (h *AuthHandler) refresh(c *gin.Context) {
-	type refreshReq struct {
-		Refresh string `json:"refresh" binding:"required"`
+	var req refreshReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request"})
+		return
+	}
+	sess, err := h.sessions.Refresh(c.Request.Context(), req.Refresh, clientMeta(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrRefreshReused):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "token_reused"})
+		case errors.Is(err, service.ErrRefreshInvalid):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "token_error"})
+		}
+		return
+	}
+	tokens, err := signPair(sess)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_error"})
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+func (h *AuthHandler) logout(c *gin.Context) {
 	var req refreshReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request"})
 		return
 	}
-	tok, claims, err := security.ParseAndValidate(req.Refresh)
-	if err != nil || !tok.Valid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+	if err := h.sessions.Logout(c.Request.Context(), req.Refresh); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error"})
 		return
 	}
-	if typ, _ := claims["typ"].(string); typ != "refresh" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "wrong_token_type"})
+	c.Status(http.StatusNoContent)
+}
+
+type sessionView struct {
+	repository.AuthSession
+	Current bool `json:"current"`
+}
+
+func (h *AuthHandler) listSessions(c *gin.Context) {
+	items, err := h.sessions.List(c.Request.Context(), security.MustUserID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error"})
 		return
 	}
-	sub, _ := claims["sub"].(string)
-	if sub == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "no_sub"})
+	current := security.SessionID(c)
+	out := make([]sessionView, 0, len(items))
+	for _, it := range items {
+		out = append(out, sessionView{AuthSession: it, Current: it.ID == current})
+	}
+	c.JSON(http.StatusOK, gin.H{"items": out})
+}
+
+func (h *AuthHandler) revokeSession(c *gin.Context) {
+	err := h.sessions.Revoke(c.Request.Context(), security.MustUserID(c), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session_not_found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error"})
 		return
 	}
-	tokens, err := security.GenerateTokens(sub)
+	c.Status(http.StatusNoContent)
+}
+
+// revokeOtherSessions cierra todas las sesiones salvo la del token actual.
+func (h *AuthHandler) revokeOtherSessions(c *gin.Context) {
+	n, err := h.sessions.RevokeOthers(c.Request.Context(), security.MustUserID(c), security.SessionID(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_error"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+	c.JSON(http.StatusOK, gin.H{"revoked": n})
 }
 
 func (h *AuthHandler) me(c *gin.Context) {
@@ -197,13 +292,128 @@ func (h *AuthHandler) me(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":    u.ID,
-		"email": u.Email,
-		"name":  u.Name,
-		"role":  u.Role,
+		"id":             u.ID,
+		"email":          u.Email,
+		"name":           u.Name,
+		"role":           u.Role,
+		"email_verified": u.EmailVerifiedAt != nil,
 	})
 }
 
+type forgotReq struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword responde 202 exista o no la cuenta.
+func (h *AuthHandler) forgotPassword(c *gin.Context) {
+	var req forgotReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	if err := h.accounts.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "reset_error"})
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+type resetReq struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+func (h *AuthHandler) resetPassword(c *gin.Context) {
+	var req resetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	hash, err := security.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "hash_error"})
+		return
+	}
+	if err := h.accounts.ResetPassword(c.Request.Context(), req.Token, hash); err != nil {
+		accountError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type changePasswordReq struct {
+	Current  string `json:"current_password" binding:"required"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+// changePassword exige la password actual y cierra las demás sesiones.
+func (h *AuthHandler) changePassword(c *gin.Context) {
+	var req changePasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	u, err := h.users.FindByID(c.Request.Context(), security.MustUserID(c))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	if ok, _ := security.CheckPassword(req.Current, u.PasswordHash); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_credentials"})
+		return
+	}
+	hash, err := security.HashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "hash_error"})
+		return
+	}
+	if err := h.accounts.ChangePassword(c.Request.Context(), u.ID, hash, security.SessionID(c)); err != nil {
+		accountError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+type verifyReq struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (h *AuthHandler) verifyEmail(c *gin.Context) {
+	var req verifyReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	if err := h.accounts.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		accountError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *AuthHandler) sendVerification(c *gin.Context) {
+	if err := h.accounts.SendVerification(c.Request.Context(), security.MustUserID(c)); err != nil {
+		accountError(c, err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+func accountError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrActionTokenInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_token"})
+	case errors.Is(err, service.ErrActionTokenUsed):
+		c.JSON(http.StatusGone, gin.H{"error": "token_used"})
+	case errors.Is(err, service.ErrAlreadyVerified):
+		c.JSON(http.StatusConflict, gin.H{"error": "email_already_verified"})
+	case errors.Is(err, service.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error"})
+	}
+}
+
 func normalizeSignupRole(raw string) string {
 	switch strings.ToLower(strings.TrimSpace(raw)) {
 	case "coach":
@@ -6,11 +6,16 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/vicepalma/roma-system/backend/internal/domain"
 	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
+	"gorm.io/gorm"
 )
 
 type fakeUserRepo struct {
@@ -25,6 +30,27 @@ func (r *fakeUserRepo) FindByID(_ context.Context, id string) (*domain.User, err
 func (r *fakeUserRepo) FindByEmail(_ context.Context, email string) (*domain.User, error) {
 	return r.byEmail[email], nil
 }
+func (r *fakeUserRepo) UpdatePassword(context.Context, string, string) error { return nil }
+
+// ReplacePassword es condicional como el real: el hash debe seguir igual.
+func (r *fakeUserRepo) ReplacePassword(_ context.Context, id, oldHash, newHash string) (bool, error) {
+	u := r.byID[id]
+	if u == nil || u.PasswordHash != oldHash {
+		return false, nil
+	}
+	u.PasswordHash = newHash
+	return true, nil
+}
+func (r *fakeUserRepo) MarkEmailVerified(context.Context, string, time.Time) error {
+	return nil
+}
+
+type fakeSessions struct{ service.AuthSessionService }
+
+func (fakeSessions) Start(_ context.Context, userID string, _ service.ClientMeta) (*service.IssuedSession, error) {
+	return &service.IssuedSession{SessionID: "sess-1", UserID: userID, RefreshToken: "opaque"}, nil
+}
+func (fakeSessions) RevokeOthers(context.Context, string, string) (int64, error) { return 0, nil }
 
 func TestAuthLoginReturnsPersistedRole(t *testing.T) {
 	gin.SetMode(gin.TestMode)
@@ -38,7 +64,7 @@ func TestAuthLoginReturnsPersistedRole(t *testing.T) {
 		"disciple@example.test": {ID: "disciple-1", Email: "disciple@example.test", Name: "Disciple", PasswordHash: hash, Role: "disciple"},
 	}}
 	r := gin.New()
-	NewAuthHandler(repo, nil).Register(r.Group("/"))
+	NewAuthHandler(repo, fakeSessions{}, nil, nil).Register(r.Group("/"))
 
 	for _, tc := range []struct {
 		email string
@@ -76,7 +102,7 @@ func TestMeRequiresTokenAndReturnsRole(t *testing.T) {
 		"coach-1": {ID: "coach-1", Email: "coach@example.test", Name: "Coach", Role: "coach"},
 	}}
 	r := gin.New()
-	NewAuthHandler(repo, nil).Register(r.Group("/"))
+	NewAuthHandler(repo, fakeSessions{}, nil, nil).Register(r.Group("/"))
 
 	w := httptest.NewRecorder()
 	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me", nil))
@@ -84,13 +110,13 @@ func TestMeRequiresTokenAndReturnsRole(t *testing.T) {
 		t.Fatalf("unauthenticated /me status=%d", w.Code)
 	}
 
-	tokens, err := security.GenerateTokens("coach-1")
+	access, err := security.NewAccessToken("coach-1", "")
 	if err != nil {
 		t.Fatal(err)
 	}
 	w = httptest.NewRecorder()
 	req := httptest.NewRequest(http.MethodGet, "/me", nil)
-	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	req.Header.Set("Authorization", "Bearer "+access)
 	r.ServeHTTP(w, req)
 	if w.Code != http.StatusOK {
 		t.Fatalf("authenticated /me status=%d body=%s", w.Code, w.Body.String())
@@ -105,3 +131,125 @@ func TestMeRequiresTokenAndReturnsRole(t *testing.T) {
 		t.Fatalf("role=%q want coach", out.Role)
 	}
 }
+
+type tokenNotifier struct{ token string }
+
+func (n *tokenNotifier) Notify(_ context.Context, ev service.NotifyEvent) error {
+	n.token = ev.Data["reset_url"] // sin baseURL el link es el token
+	return nil
+}
+func (n *tokenNotifier) NotifyTx(ctx context.Context, _ *gorm.DB, ev service.NotifyEvent) error {
+	return n.Notify(ctx, ev)
+}
+
+// racingUserRepo: otro reset cambia el hash justo después de que el
+// servicio lo leyó y verificó el token.
+type racingUserRepo struct{ *fakeUserRepo }
+
+func (r racingUserRepo) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	u, _ := r.fakeUserRepo.FindByID(ctx, id)
+	snapshot := *u
+	u.PasswordHash = "hash-concurrent"
+	return &snapshot, nil
+}
+
+// Los tokens de cuenta e invitación comparten llave con el access token,
+// pero no sirven como bearer.
+func TestAuthRejectsNonAccessTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	repo := &fakeUserRepo{byID: map[string]*domain.User{
+		"coach-1": {ID: "coach-1", Email: "coach@example.test", Name: "Coach", Role: "coach"},
+	}}
+	r := gin.New()
+	NewAuthHandler(repo, fakeSessions{}, nil, nil).Register(r.Group("/"))
+
+	sign := map[string]func() (string, error){
+		"reset": func() (string, error) {
+			return security.ActionTokens{}.Sign(service.PurposePasswordReset, "coach-1", "bnd", time.Hour)
+		},
+		"verify": func() (string, error) {
+			return security.ActionTokens{}.Sign(service.PurposeEmailVerify, "coach-1", "coach@example.test", time.Hour)
+		},
+		"invite": func() (string, error) { return security.SignInvite("coach-1", "disciple@example.test", time.Hour) },
+	}
+	for name, f := range sign {
+		tok, err := f()
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+tok)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("%s token on /me status=%d want 401", name, w.Code)
+		}
+	}
+}
+
+func TestResetPasswordLosesRace(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	u := &domain.User{ID: "disciple-1", Email: "disciple@example.test", PasswordHash: "hash-old"}
+	repo := &fakeUserRepo{byID: map[string]*domain.User{u.ID: u}, byEmail: map[string]*domain.User{u.Email: u}}
+	notifier := &tokenNotifier{}
+	accounts := service.NewAccountService(racingUserRepo{repo}, fakeSessions{}, security.ActionTokens{}, notifier, "")
+	if err := accounts.RequestPasswordReset(context.Background(), u.Email); err != nil || notifier.token == "" {
+		t.Fatalf("forgot err=%v token=%q", err, notifier.token)
+	}
+	r := gin.New()
+	NewAuthHandler(repo, fakeSessions{}, accounts, nil).Register(r.Group("/"))
+
+	body, _ := json.Marshal(map[string]string{"token": notifier.token, "password": "secret123"})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/reset", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusGone || u.PasswordHash != "hash-concurrent" {
+		t.Fatalf("status=%d hash=%q body=%s", w.Code, u.PasswordHash, w.Body.String())
+	}
+}
+
+// Cambiar o resetear la password revoca las sesiones; los access tokens que
+// emitieron dejan de valer sin esperar a que venzan.
+func TestRevokedSessionRejectsAccessToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	db, mock, cleanup := mockGorm(t)
+	defer cleanup()
+	security.UseSessionCheck(db)
+	t.Cleanup(func() { security.UseSessionCheck(nil) })
+
+	repo := &fakeUserRepo{byID: map[string]*domain.User{
+		"coach-1": {ID: "coach-1", Email: "coach@example.test", Name: "Coach", Role: "coach"},
+	}}
+	r := gin.New()
+	NewAuthHandler(repo, fakeSessions{}, nil, nil).Register(r.Group("/"))
+	access, err := security.NewAccessToken("coach-1", "sess-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		active int
+		want   int
+	}{
+		{1, http.StatusOK},
+		{0, http.StatusUnauthorized}, // revocada por RevokeOthers
+	} {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "auth_sessions" WHERE id = $1 AND revoked_at IS NULL`)).
+			WithArgs("sess-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tc.active))
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+access)
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("active=%d status=%d want %d", tc.active, w.Code, tc.want)
+		}
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -8,6 +8,11 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/blobstore"
+	"github.com/vicepalma/roma-system/backend/internal/bodymetrics"
+	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/photo"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
 	"github.com/vicepalma/roma-system/backend/internal/security"
 	"github.com/vicepalma/roma-system/backend/internal/service"
 	"gorm.io/gorm"
@@ -25,17 +30,59 @@ func NewCheckinHandler(svc service.CheckinService, db *gorm.DB) *CheckinHandler
 func (h *CheckinHandler) Register(r *gin.RouterGroup) {
 	r.POST("/checkins", security.RequireRole(h.db, "disciple"), h.create)
 	r.GET("/checkins", security.RequireRole(h.db, "disciple"), h.listMine)
+	r.GET("/checkins/metrics", security.RequireRole(h.db, "disciple"), h.myMetrics)
+	r.GET("/checkins/trends", security.RequireRole(h.db, "disciple"), h.myTrends)
 	r.GET("/checkins/:id", h.get)
+	r.PATCH("/checkins/:id", security.RequireRole(h.db, "disciple"), h.update)
+	r.DELETE("/checkins/:id", security.RequireRole(h.db, "disciple"), h.remove)
+	r.POST("/checkins/:id/photos", security.RequireRole(h.db, "disciple"), h.uploadPhoto)
+	r.DELETE("/checkins/:id/photos/:photoId", security.RequireRole(h.db, "disciple"), h.deletePhoto)
+	r.GET("/coach/checkins", security.RequireRole(h.db, "coach"), h.inbox)
+	r.PUT("/coach/checkins/:id/review", security.RequireRole(h.db, "coach"), h.review)
 	r.GET("/coach/disciples/:id/checkins", security.RequireRole(h.db, "coach"), h.listForCoach)
+	r.GET("/coach/disciples/:id/checkins/trends", security.RequireRole(h.db, "coach"), h.trendsForCoach)
+	r.GET("/coach/disciples/:id/checkins/requirements",
+		security.RequireRole(h.db, "coach"), h.getRequirements)
+	r.PUT("/coach/disciples/:id/checkins/requirements",
+		security.RequireRole(h.db, "coach"), h.putRequirements)
 }
 
-func (h *CheckinHandler) create(c *gin.Context) {
-	type req struct {
-		CheckedAt string   `json:"checked_at"`
-		WeightKG  *float64 `json:"weight_kg"`
-		Notes     *string  `json:"notes"`
+type checkinReq struct {
+	CheckedAt  string   `json:"checked_at"`
+	WeightKG   *float64 `json:"weight_kg"`
+	Notes      *string  `json:"notes"`
+	WaistCM    *float64 `json:"waist_cm"`
+	ChestCM    *float64 `json:"chest_cm"`
+	ArmCM      *float64 `json:"arm_cm"`
+	ThighCM    *float64 `json:"thigh_cm"`
+	BodyFatPct *float64 `json:"body_fat_pct"`
+	RestingHR  *int     `json:"resting_hr"`
+	SleepHours *float64 `json:"sleep_hours"`
+	Energy     *int     `json:"energy"`
+	Stress     *int     `json:"stress"`
+	Soreness   *int     `json:"soreness"`
+}
+
+func (b checkinReq) input(checkedAt time.Time) service.CreateCheckin {
+	return service.CreateCheckin{
+		CheckedAt:  checkedAt,
+		WeightKG:   b.WeightKG,
+		Notes:      cleanOptionalText(b.Notes),
+		WaistCM:    b.WaistCM,
+		ChestCM:    b.ChestCM,
+		ArmCM:      b.ArmCM,
+		ThighCM:    b.ThighCM,
+		BodyFatPct: b.BodyFatPct,
+		RestingHR:  b.RestingHR,
+		SleepHours: b.SleepHours,
+		Energy:     b.Energy,
+		Stress:     b.Stress,
+		Soreness:   b.Soreness,
 	}
-	var body req
+}
+
+func (h *CheckinHandler) create(c *gin.Context) {
+	var body checkinReq
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
 		return
@@ -48,43 +95,170 @@ func (h *CheckinHandler) create(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_weight"})
 		return
 	}
-	checkin, err := h.svc.Create(c.Request.Context(), security.UserID(c), checkedAt, body.WeightKG, cleanOptionalText(body.Notes))
+	checkin, err := h.svc.Create(c.Request.Context(), security.UserID(c), body.input(checkedAt))
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidCheckin) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request"})
 			return
 		}
+		if h.metricError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
 	}
+	signPhotos(checkin)
 	c.JSON(http.StatusCreated, checkin)
 }
 
-func (h *CheckinHandler) listMine(c *gin.Context) {
-	h.listByDisciple(c, security.UserID(c))
+// update: PATCH parcial; solo el dueño y dentro del plazo de edición.
+func (h *CheckinHandler) update(c *gin.Context) {
+	var body checkinReq
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	var checkedAt time.Time
+	if strings.TrimSpace(body.CheckedAt) != "" {
+		var ok bool
+		if checkedAt, ok = parseCheckinDate(c, body.CheckedAt); !ok {
+			return
+		}
+	}
+	checkin, err := h.svc.Update(c.Request.Context(), security.UserID(c), c.Param("id"), body.input(checkedAt))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCheckin) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_weight"})
+			return
+		}
+		if h.metricError(c, err) || h.ownerError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	signPhotos(checkin)
+	c.JSON(http.StatusOK, checkin)
 }
 
-func (h *CheckinHandler) listForCoach(c *gin.Context) {
-	discipleID := c.Param("id")
-	ok, err := security.CanAccessDisciple(h.db.WithContext(c.Request.Context()), security.UserID(c), discipleID)
+func (h *CheckinHandler) remove(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), security.UserID(c), c.Param("id")); err != nil {
+		if h.ownerError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ownerError: no existe, no es del usuario o venció el plazo de edición.
+func (h *CheckinHandler) ownerError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	case errors.Is(err, service.ErrNotCheckinOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	case errors.Is(err, service.ErrEditWindow):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "window_hours": int(service.CheckinEditWindow.Hours())})
+	default:
+		return false
+	}
+	return true
+}
+
+// review: {"status":"reviewed"|"pending","comment":"..."}; solo un coach vinculado (política checkin:execute).
+func (h *CheckinHandler) review(c *gin.Context) {
+	var body struct {
+		Status  string  `json:"status"`
+		Comment *string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	checkin, err := h.svc.Get(c.Request.Context(), c.Param("id"))
 	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	coachID := security.UserID(c)
+	out, err := h.svc.Review(c.Request.Context(), coachID, checkin.ID, strings.ToLower(strings.TrimSpace(body.Status)), cleanOptionalText(body.Comment))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidReview) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
 	}
+	signPhotos(out)
+	c.JSON(http.StatusOK, out)
+}
+
+// inbox: check-ins de todos los discípulos del coach; por defecto solo pending
+// (status=all para todos).
+func (h *CheckinHandler) inbox(c *gin.Context) {
+	f, ok := parseCheckinFilter(c, domain.CheckinPending)
 	if !ok {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		return
 	}
+	limit, offset := parseCheckinPag(c.DefaultQuery("limit", "50")), parseCheckinPag(c.DefaultQuery("offset", "0"))
+	items, total, err := h.svc.Inbox(c.Request.Context(), security.UserID(c), f, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	for i := range items {
+		signPhotos(&items[i].Checkin)
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": limit, "offset": offset})
+}
+
+// parseCheckinFilter: ?status=pending|reviewed|all&from=YYYY-MM-DD&to=YYYY-MM-DD
+func parseCheckinFilter(c *gin.Context, defStatus string) (repository.CheckinFilter, bool) {
+	var f repository.CheckinFilter
+	switch st := strings.ToLower(strings.TrimSpace(c.DefaultQuery("status", defStatus))); st {
+	case "", "all":
+	case domain.CheckinPending, domain.CheckinReviewed:
+		f.Status = &st
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_status"})
+		return f, false
+	}
+	var ok bool
+	if f.From, ok = parseDateParam(c, "from"); !ok {
+		return f, false
+	}
+	if f.To, ok = parseDateParam(c, "to"); !ok {
+		return f, false
+	}
+	return f, true
+}
+
+func (h *CheckinHandler) listMine(c *gin.Context) {
+	h.listByDisciple(c, security.UserID(c))
+}
+
+func (h *CheckinHandler) listForCoach(c *gin.Context) {
+	discipleID := c.Param("id")
 	h.listByDisciple(c, discipleID)
 }
 
 func (h *CheckinHandler) listByDisciple(c *gin.Context, discipleID string) {
+	f, ok := parseCheckinFilter(c, "all")
+	if !ok {
+		return
+	}
 	limit, offset := parseCheckinPag(c.DefaultQuery("limit", "50")), parseCheckinPag(c.DefaultQuery("offset", "0"))
-	items, total, err := h.svc.List(c.Request.Context(), discipleID, limit, offset)
+	items, total, err := h.svc.List(c.Request.Context(), discipleID, f, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
 	}
+	for i := range items {
+		signPhotos(&items[i])
+	}
 	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": limit, "offset": offset})
 }
 
@@ -94,16 +268,187 @@ func (h *CheckinHandler) get(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
 		return
 	}
-	ok, err := security.CanAccessDisciple(h.db.WithContext(c.Request.Context()), security.UserID(c), checkin.DiscipleID)
+	signPhotos(checkin)
+	c.JSON(http.StatusOK, checkin)
+}
+
+// RegisterFiles: descarga con URL firmada, fuera del grupo autenticado.
+func (h *CheckinHandler) RegisterFiles(r gin.IRouter) {
+	r.GET(security.BlobURLPrefix+"*key", h.serveFile)
+}
+
+// signPhotos completa URLs firmadas; solo se llama después de validar acceso
+// al discípulo, así la URL es la credencial (dueño o coach vinculado).
+func signPhotos(ch *domain.Checkin) {
+	if ch.Photos == nil {
+		ch.Photos = []domain.CheckinPhoto{}
+	}
+	ttl := security.BlobURLTTL()
+	for i := range ch.Photos {
+		p := &ch.Photos[i]
+		p.URL, _ = security.SignBlobURL(p.Key, ttl)
+		p.ThumbURL, _ = security.SignBlobURL(p.ThumbKey, ttl)
+	}
+}
+
+// uploadPhoto: multipart con el campo "photo" (JPEG o PNG).
+func (h *CheckinHandler) uploadPhoto(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, photo.MaxBytes+1<<20)
+	fh, err := c.FormFile("photo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "photo_required", "detail": err.Error()})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	defer f.Close()
+
+	p, err := h.svc.AddPhoto(c.Request.Context(), security.UserID(c), c.Param("id"), f)
+	if err != nil {
+		h.photoError(c, err)
+		return
+	}
+	ttl := security.BlobURLTTL()
+	p.URL, _ = security.SignBlobURL(p.Key, ttl)
+	p.ThumbURL, _ = security.SignBlobURL(p.ThumbKey, ttl)
+	c.JSON(http.StatusCreated, p)
+}
+
+func (h *CheckinHandler) deletePhoto(c *gin.Context) {
+	if err := h.svc.DeletePhoto(c.Request.Context(), security.UserID(c), c.Param("id"), c.Param("photoId")); err != nil {
+		h.photoError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *CheckinHandler) photoError(c *gin.Context, err error) {
+	if h.ownerError(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrPhotoNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	case errors.Is(err, service.ErrPhotoLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "max": service.MaxCheckinPhotos})
+	case errors.Is(err, photo.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, photo.ErrUnsupported):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPhotosDisabled):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+	}
+}
+
+func (h *CheckinHandler) serveFile(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+	if err := security.VerifyBlobURL(key, c.Query("exp"), c.Query("sig")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	rc, err := h.svc.OpenBlob(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) || errors.Is(err, blobstore.ErrInvalidKey) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	defer rc.Close()
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, -1, photo.ContentJPG, rc, nil)
+}
+
+// metricError responde 400 para métricas inválidas o faltantes.
+func (h *CheckinHandler) metricError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrInvalidMetric):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_metric", "detail": err.Error()})
+	case errors.Is(err, service.ErrMissingMetrics):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "missing_required_metrics", "detail": err.Error()})
+	default:
+		return false
+	}
+	return true
+}
+
+// myMetrics: catálogo de métricas y las que exige el coach.
+func (h *CheckinHandler) myMetrics(c *gin.Context) {
+	req, err := h.svc.Requirements(c.Request.Context(), security.UserID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"metrics": bodymetrics.All, "required": req.Metrics})
+}
+
+func (h *CheckinHandler) myTrends(c *gin.Context) {
+	h.trends(c, security.UserID(c))
+}
+
+func (h *CheckinHandler) trendsForCoach(c *gin.Context) {
+	discipleID := c.Param("id")
+	h.trends(c, discipleID)
+}
+
+// trends: ?metrics=weight_kg,waist_cm&window=28&from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *CheckinHandler) trends(c *gin.Context, discipleID string) {
+	from, ok := parseDateParam(c, "from")
 	if !ok {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		return
 	}
-	c.JSON(http.StatusOK, checkin)
+	to, ok := parseDateParam(c, "to")
+	if !ok {
+		return
+	}
+	var metrics []string
+	if v := c.Query("metrics"); v != "" {
+		metrics = strings.Split(v, ",")
+	}
+	out, err := h.svc.Trends(c.Request.Context(), discipleID, metrics, atoiOrZero(c.Query("window")), from, to)
+	if err != nil {
+		if h.metricError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *CheckinHandler) getRequirements(c *gin.Context) {
+	req, err := h.svc.Requirements(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.JSON(http.StatusOK, req)
+}
+
+func (h *CheckinHandler) putRequirements(c *gin.Context) {
+	var body struct {
+		Metrics []string `json:"metrics"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	req, err := h.svc.SetRequirements(c.Request.Context(), security.UserID(c), c.Param("id"), body.Metrics)
+	if err != nil {
+		if h.metricError(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.JSON(http.StatusOK, req)
 }
 
 func parseCheckinDate(c *gin.Context, raw string) (time.Time, bool) {
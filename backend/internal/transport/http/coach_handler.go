@@ -51,22 +51,14 @@ func (h *CoachHandler) Register(r *gin.RouterGroup) {
 		grp.GET("/links", h.listLinks)
 
 		grp.GET("/disciples", security.RequireRole(h.db, "coach"), h.listDisciples)
-		grp.GET("/disciples/:id/today",
-			security.RequireRole(h.db, "coach"),
-			security.RequireCoachOf(h.svc, "id"),
-			h.getTodayForDisciple,
-		)
+		grp.GET("/roster", security.RequireRole(h.db, "coach"), h.roster)
+		grp.GET("/disciples/:id/today", security.RequireRole(h.db, "coach"), h.getTodayForDisciple)
 		grp.POST("/assignments", security.RequireRole(h.db, "coach"), h.assignProgram)
 		grp.GET("/assignments", security.RequireRole(h.db, "coach"), h.listAssignments)
-		grp.GET("/disciples/:id/overview",
-			security.RequireRole(h.db, "coach"),
-			security.RequireCoachOf(h.svc, "id"),
-			h.getOverview,
-		)
-		grp.PATCH("/assignments/:id", security.RequireRole(h.db, "coach"), h.requireAssignmentAccess, h.patchAssignment)
-		grp.GET("/assignments/:id/calendar", h.requireAssignmentAccess, h.assignmentCalendar)
-		grp.POST("/assignments/:id/activate", h.requireAssignmentAccess, h.activateAssignment)
-		// grp.POST("/invitations", security.RequireCoachOfSelf(h.svc), h.createInvite) // o AuthRequired si no tienes rol
+		grp.GET("/disciples/:id/overview", security.RequireRole(h.db, "coach"), h.getOverview)
+		grp.PATCH("/assignments/:id", security.RequireRole(h.db, "coach"), h.patchAssignment)
+		grp.GET("/assignments/:id/calendar", h.assignmentCalendar)
+		grp.POST("/assignments/:id/activate", h.activateAssignment)
 		// grp.POST("/invitations/:code/accept", security.AuthRequired(), h.acceptInvite)
 	}
 }
@@ -177,7 +169,7 @@ func (h *CoachHandler) assignProgram(c *gin.Context) {
 			return
 		}
 	}
-	ok, err := security.IsProgramOwner(h.db.WithContext(c.Request.Context()), coachID, req.ProgramID)
+	ok, err := security.Can(c, security.ResProgram, req.ProgramID, security.ActExecute)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
@@ -195,19 +187,6 @@ func (h *CoachHandler) assignProgram(c *gin.Context) {
 	c.JSON(http.StatusCreated, row)
 }
 
-func (h *CoachHandler) requireAssignmentAccess(c *gin.Context) {
-	ok, err := security.CanAccessAssignment(h.db.WithContext(c.Request.Context()), security.UserID(c), c.Param("id"))
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
-		return
-	}
-	if !ok {
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-		return
-	}
-	c.Next()
-}
-
 // @Summary Overview del discípulo (hoy + pivot + adherencia)
 // @Description Requiere que el solicitante sea coach del discípulo.
 // @Tags coach
@@ -322,8 +301,12 @@ func (h *CoachHandler) getTodayForDisciple(c *gin.Context) {
 	if tz == "" {
 		tz = "UTC"
 	}
+	mode, ok := parseScheduleMode(c)
+	if !ok {
+		return
+	}
 
-	me, err := h.hist.GetMeTodayFor(ctx, discipleID, tz)
+	me, err := h.hist.GetMeTodayFor(ctx, discipleID, tz, mode)
 	if err != nil {
 		// Si no hay "hoy", devolvemos 200 con shape vacío (para que el front no caiga)
 		if errors.Is(err, service.ErrNoDay) || errors.Is(err, repository.ErrNoDay) {
@@ -369,8 +352,9 @@ func (h *CoachHandler) getTodayForDisciple(c *gin.Context) {
 func (h *CoachHandler) patchAssignment(c *gin.Context) {
 	id := c.Param("id")
 	var body struct {
-		EndDate  *string `json:"end_date"`  // "YYYY-MM-DD"
-		IsActive *bool   `json:"is_active"` // true/false
+		EndDate  *string `json:"end_date"`      // "YYYY-MM-DD"
+		IsActive *bool   `json:"is_active"`     // true/false
+		Schedule *string `json:"schedule_mode"` // calendar | next_undone
 	}
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request"})
@@ -385,7 +369,7 @@ func (h *CoachHandler) patchAssignment(c *gin.Context) {
 		}
 		endPtr = &d
 	}
-	asg, err := h.svc.UpdateAssignment(c.Request.Context(), id, endPtr, body.IsActive)
+	asg, err := h.svc.UpdateAssignment(c.Request.Context(), id, endPtr, body.IsActive, body.Schedule)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot_update"})
 		return
@@ -404,6 +388,11 @@ func (h *CoachHandler) assignmentCalendar(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_range"})
 		return
 	}
+	// hasta ~1 año (bloques de 12 semanas con holgura)
+	if d := to.Sub(from); d > 366*24*time.Hour || d < -366*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_range", "detail": "max 366 days"})
+		return
+	}
 
 	items, err := h.svc.AssignmentCalendar(c.Request.Context(), id, from, to)
 	if err != nil {
@@ -455,3 +444,60 @@ func (h *CoachHandler) activateAssignment(c *gin.Context) {
 	}
 	c.Status(http.StatusNoContent)
 }
+
+// @Summary Panel del coach: todos los discípulos con sus agregados
+// @Tags coach
+// @Security BearerAuth
+// @Produce json
+// @Param query query string false "Filtro por nombre o email"
+// @Param inactive_days query int false "Solo sin sesiones hace más de N días"
+// @Param open_session query bool false "Con/sin sesión abierta"
+// @Param pr_days query int false "Ventana de PRs nuevos (por defecto 7)"
+// @Param sort query string false "name|last_session|inactive|adherence|weight_trend|prs"
+// @Param order query string false "asc|desc"
+// @Param tz query string false "IANA TZ (por defecto UTC)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /api/coach/roster [get]
+func (h *CoachHandler) roster(c *gin.Context) {
+	q := service.RosterQuery{
+		TZ:     c.DefaultQuery("tz", "UTC"),
+		Sort:   c.Query("sort"),
+		Order:  c.DefaultQuery("order", "asc"),
+		PRDays: atoiOrZero(c.Query("pr_days")),
+	}
+	if v := strings.TrimSpace(c.Query("query")); v != "" {
+		q.Query = &v
+	}
+	if v := c.Query("inactive_days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_inactive_days"})
+			return
+		}
+		q.InactiveDays = &n
+	}
+	if v := c.Query("open_session"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_open_session"})
+			return
+		}
+		q.OpenSession = &b
+	}
+	q.Limit, q.Offset = parsePag(c.DefaultQuery("limit", "50")), parsePag(c.Query("offset"))
+	if q.Limit == 0 || q.Limit > 200 {
+		q.Limit = 50
+	}
+
+	items, total, err := h.svc.Roster(c.Request.Context(), security.MustUserID(c), q)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRosterSort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "roster_failed", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": q.Limit, "offset": q.Offset})
+}
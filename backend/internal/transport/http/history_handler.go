@@ -34,6 +34,7 @@ func (h *HistoryHandler) Register(r *gin.RouterGroup) {
 		grp.GET("/summary", h.summary) // nuevos endpoints de resumen
 		grp.GET("/summary/pivot", h.summaryPivot)
 		grp.GET("/prs", h.prs)
+		grp.GET("/adherence", h.adherence) // plan vs registrado  /api/history/adherence?disciple_id=&days=&tz=
 
 		// /api/disciples/:id/sessions?from=&to=&tz=&limit=&offset=
 		grp.GET("/disciples/:id/sessions", h.sessions)
@@ -83,9 +84,6 @@ func (h *HistoryHandler) history(c *gin.Context) {
 
 func (h *HistoryHandler) sessions(c *gin.Context) {
 	discipleID := c.Param("id")
-	if !h.canReadDisciple(c, discipleID) {
-		return
-	}
 	tz := c.DefaultQuery("tz", h.defTz)
 	from, ok := parseDateParam(c, "from")
 	if !ok {
@@ -111,9 +109,6 @@ func (h *HistoryHandler) sessions(c *gin.Context) {
 
 func (h *HistoryHandler) planVsDone(c *gin.Context) {
 	discipleID := c.Param("id")
-	if !h.canReadDisciple(c, discipleID) {
-		return
-	}
 	tz := c.DefaultQuery("tz", h.defTz)
 	from, to := parseDates(c.Query("from")), parseDates(c.Query("to"))
 	limit, offset := parsePag(c.DefaultQuery("limit", "50")), parsePag(c.DefaultQuery("offset", "0"))
@@ -126,8 +121,9 @@ func (h *HistoryHandler) planVsDone(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"items": data, "total": total, "limit": limit, "offset": offset})
 }
 
+// canReadDisciple: disciple_id por query (las rutas /disciples/:id las cubre RoutePolicies).
 func (h *HistoryHandler) canReadDisciple(c *gin.Context, discipleID string) bool {
-	ok, err := security.CanAccessDisciple(h.db.WithContext(c.Request.Context()), security.UserID(c), discipleID)
+	ok, err := security.Can(c, security.ResDisciple, discipleID, security.ActRead)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return false
@@ -143,13 +139,17 @@ func (h *HistoryHandler) summary(c *gin.Context) {
 	days, _ := strconv.Atoi(c.DefaultQuery("days", "14"))
 	mode := strings.ToLower(c.DefaultQuery("mode", "by_exercise"))
 	tz := c.DefaultQuery("tz", "America/Santiago")
+	allSetTypes, ok := parseSetTypes(c)
+	if !ok {
+		return
+	}
 
 	userID, _ := c.Get(security.CtxUserID)
 	uid := userID.(string)
 
 	switch mode {
 	case "by_muscle":
-		rows, err := h.svc.GetDailyByMuscle(c, uid, days, tz)
+		rows, err := h.svc.GetDailyByMuscle(c, uid, days, tz, allSetTypes)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 			return
@@ -157,7 +157,7 @@ func (h *HistoryHandler) summary(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"mode": "by_muscle", "items": rows, "days": clamp(days)})
 	default:
 		includeCatalog := strings.EqualFold(c.DefaultQuery("include", ""), "catalog")
-		rows, catalog, err := h.svc.GetDailyByExercise(c, uid, days, includeCatalog, tz)
+		rows, catalog, err := h.svc.GetDailyByExercise(c, uid, days, includeCatalog, tz, allSetTypes)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 			return
@@ -180,6 +180,25 @@ func (h *HistoryHandler) prs(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"items": rows})
 }
 
+func (h *HistoryHandler) adherence(c *gin.Context) {
+	discipleID := c.Query("disciple_id")
+	if discipleID == "" {
+		discipleID = security.UserID(c)
+	}
+	if !h.canReadDisciple(c, discipleID) {
+		return
+	}
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "28"))
+	tz := c.DefaultQuery("tz", h.defTz)
+
+	out, err := h.svc.GetPlanAdherence(c.Request.Context(), discipleID, days, tz)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
 func clamp(n int) int {
 	if n <= 0 || n > 180 {
 		return 14
@@ -193,20 +212,24 @@ func (h *HistoryHandler) summaryPivot(c *gin.Context) {
 	includeCatalog := strings.EqualFold(c.DefaultQuery("include", ""), "catalog")
 	metric := c.DefaultQuery("metric", "volume")
 	tz := c.DefaultQuery("tz", "America/Santiago")
+	allSetTypes, ok := parseSetTypes(c)
+	if !ok {
+		return
+	}
 
 	userID, _ := c.Get(security.CtxUserID)
 	uid := userID.(string)
 
 	switch mode {
 	case "by_muscle":
-		resp, err := h.svc.GetPivotByMuscle(c, uid, days, metric, tz)
+		resp, err := h.svc.GetPivotByMuscle(c, uid, days, metric, tz, allSetTypes)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 			return
 		}
 		c.JSON(http.StatusOK, resp)
 	default:
-		resp, err := h.svc.GetPivotByExercise(c, uid, days, includeCatalog, metric, tz)
+		resp, err := h.svc.GetPivotByExercise(c, uid, days, includeCatalog, metric, tz, allSetTypes)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
 			return
@@ -260,7 +283,22 @@ func parseHistorySessionFilter(c *gin.Context) (repository.HistorySessionFilter,
 		}
 		filter.ProgramID = &raw
 	}
-	return filter, true
+	all, ok := parseSetTypes(c)
+	filter.AllSetTypes = all
+	return filter, ok
+}
+
+// parseSetTypes: ?set_types=working (por defecto) cuenta solo series efectivas;
+// all incluye calentamiento y drops. Responde 400 si el valor no es válido.
+func parseSetTypes(c *gin.Context) (all bool, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(c.Query("set_types"))) {
+	case "", "working":
+		return false, true
+	case "all":
+		return true, true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_set_types"})
+	return false, false
 }
 func parsePag(s string) int {
 	n, _ := strconv.Atoi(s)
@@ -1,13 +1,16 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/domain"
 	"github.com/vicepalma/roma-system/backend/internal/security"
 	"github.com/vicepalma/roma-system/backend/internal/service"
+	"gorm.io/gorm"
 )
 
 type createInviteRequest struct {
@@ -16,24 +19,59 @@ type createInviteRequest struct {
 	TTLHours string `json:"ttl_hours"` // opcional, string para flexibilidad
 	BaseURL  string `json:"base_url"`  // opcional si quieres sobreescribir el baseURL del service
 }
-type createRequest struct {
-	Email    string  `json:"email" binding:"required,email"`
-	Name     *string `json:"name"`
-	TTLHours *int    `json:"ttl_hours"` // default 72
+type resendInviteRequest struct {
+	TTLHours int `json:"ttl_hours"` // default 72
+}
+type inviteSignupRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Name     string `json:"name" binding:"required,min=2"`
+	Password string `json:"password" binding:"required,min=6"`
 }
 type InviteHandler struct {
-	svc service.InviteService
+	svc      service.InviteService
+	sessions service.AuthSessionService
+	db       *gorm.DB
 }
 
-func NewInviteHandler(s service.InviteService) *InviteHandler { return &InviteHandler{svc: s} }
+func NewInviteHandler(s service.InviteService, sessions service.AuthSessionService, db *gorm.DB) *InviteHandler {
+	return &InviteHandler{svc: s, sessions: sessions, db: db}
+}
 
 func (h *InviteHandler) Register(r *gin.RouterGroup) {
 	grp := r.Group("/coach")
 	{
 		grp.POST("/invitations", h.createInvite)
 		grp.POST("/invitations/:code/accept", h.acceptInvite)
-		// grp.POST("/invitations", h.create)
-		// grp.POST("/invitations/:code/accept", h.accept)
+
+		onlyCoach := security.RequireRole(h.db, "coach")
+		grp.GET("/invitations", onlyCoach, h.listInvites)
+		grp.POST("/invitations/:code/revoke", onlyCoach, h.revokeInvite)
+		grp.POST("/invitations/:code/resend", onlyCoach, h.resendInvite)
+	}
+}
+
+// RegisterPublic: alta de cuenta con código de invitación (sin token).
+func (h *InviteHandler) RegisterPublic(r *gin.RouterGroup) {
+	r.POST("/auth/signup/invite", h.signupWithInvite)
+}
+
+func inviteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInviteInvalid):
+		c.JSON(http.StatusNotFound, gin.H{"error": "invalid_code"})
+	case errors.Is(err, service.ErrInviteExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "invite_expired"})
+	case errors.Is(err, service.ErrInviteNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": "invite_not_pending"})
+	case errors.Is(err, service.ErrInviteEmailMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": "invite_email_mismatch"})
+	case errors.Is(err, service.ErrEmailInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "email_in_use"})
+	case errors.Is(err, service.ErrInviteStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_status"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	}
 }
 
@@ -73,59 +111,81 @@ func (h *InviteHandler) acceptInvite(c *gin.Context) {
 	}
 	res, err := h.svc.AcceptInvite(c.Request.Context(), code, discipleID)
 	if err != nil {
-		switch err.Error() {
-		case "invalid_code":
-			c.JSON(http.StatusNotFound, gin.H{"error": "invalid_code"})
-		case "invite_expired":
-			c.JSON(http.StatusGone, gin.H{"error": "invite_expired"})
-		case "invite_not_pending":
-			c.JSON(http.StatusConflict, gin.H{"error": "invite_not_pending"})
-		default:
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		}
+		inviteError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, res)
 }
 
-func (h *InviteHandler) create(c *gin.Context) {
-	coachID := security.UserID(c)
-	if coachID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
+func (h *InviteHandler) listInvites(c *gin.Context) {
+	var status *string
+	if v := strings.TrimSpace(c.Query("status")); v != "" {
+		status = &v
 	}
-	var req createRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+	limit := parsePag(c.DefaultQuery("limit", "50"))
+	offset := parsePag(c.DefaultQuery("offset", "0"))
+	items, total, err := h.svc.ListInvites(c.Request.Context(), security.MustUserID(c), status, limit, offset)
+	if err != nil {
+		inviteError(c, err)
 		return
 	}
-	ttl := 72
-	if req.TTLHours != nil && *req.TTLHours > 0 {
-		ttl = *req.TTLHours
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": limit, "offset": offset})
+}
+
+func (h *InviteHandler) revokeInvite(c *gin.Context) {
+	if err := h.svc.RevokeInvite(c.Request.Context(), security.MustUserID(c), c.Param("code")); err != nil {
+		inviteError(c, err)
+		return
 	}
-	name := ""
-	if req.Name != nil {
-		name = *req.Name
+	c.Status(http.StatusNoContent)
+}
+
+func (h *InviteHandler) resendInvite(c *gin.Context) {
+	var req resendInviteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+			return
+		}
 	}
-	out, err := h.svc.CreateInvite(c.Request.Context(), coachID, req.Email, name, ttl)
+	res, err := h.svc.ResendInvite(c.Request.Context(), security.MustUserID(c), c.Param("code"), req.TTLHours)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		inviteError(c, err)
 		return
 	}
-	c.JSON(http.StatusCreated, out)
+	c.JSON(http.StatusCreated, res)
 }
 
-func (h *InviteHandler) accept(c *gin.Context) {
-	discipleID := security.UserID(c)
-	if discipleID == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+func (h *InviteHandler) signupWithInvite(c *gin.Context) {
+	var req inviteSignupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
 		return
 	}
-	code := c.Param("code")
-	out, err := h.svc.AcceptInvite(c.Request.Context(), code, discipleID)
+	hash, err := security.HashPassword(req.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "hash_error"})
+		return
+	}
+	u := &domain.User{
+		Name:         strings.TrimSpace(req.Name),
+		Email:        req.Email,
+		PasswordHash: hash,
+	}
+	res, err := h.svc.SignupWithInvite(c.Request.Context(), strings.TrimSpace(req.Code), u)
+	if err != nil {
+		inviteError(c, err)
+		return
+	}
+	tokens, err := issueTokens(c, h.sessions, u.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "token_error"})
 		return
 	}
-	c.JSON(http.StatusOK, out)
+	c.JSON(http.StatusCreated, gin.H{
+		"user":          gin.H{"id": u.ID, "email": u.Email, "name": u.Name, "role": u.Role},
+		"link":          res,
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+	})
 }
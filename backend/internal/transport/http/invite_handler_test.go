@@ -0,0 +1,115 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/notify"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
+)
+
+type fakeInviteService struct {
+	service.InviteService
+	signupErr error
+}
+
+func (s *fakeInviteService) SignupWithInvite(_ context.Context, _ string, u *domain.User) (*service.AcceptResult, error) {
+	if s.signupErr != nil {
+		return nil, s.signupErr
+	}
+	u.ID, u.Role = "disciple-new", "disciple"
+	return &service.AcceptResult{LinkID: "link-1", Status: repository.InviteAccepted}, nil
+}
+
+func TestSignupWithInviteMapsErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("JWT_SECRET", "test-secret")
+	body := []byte(`{"code":"ABC","email":"new@example.test","name":"New","password":"secret123"}`)
+
+	for _, tc := range []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusCreated},
+		{service.ErrInviteEmailMismatch, http.StatusForbidden},
+		{service.ErrInviteExpired, http.StatusGone},
+		{service.ErrInviteNotPending, http.StatusConflict},
+		{service.ErrEmailInUse, http.StatusConflict},
+		{service.ErrInviteInvalid, http.StatusNotFound},
+	} {
+		r := gin.New()
+		NewInviteHandler(&fakeInviteService{signupErr: tc.err}, fakeSessions{}, nil).RegisterPublic(r.Group("/"))
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/signup/invite", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want {
+			t.Fatalf("err=%v status=%d want %d body=%s", tc.err, w.Code, tc.want, w.Body.String())
+		}
+		if tc.err != nil {
+			continue
+		}
+		var out struct {
+			AccessToken string `json:"access_token"`
+			User        struct {
+				ID string `json:"id"`
+			} `json:"user"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if out.AccessToken == "" || out.User.ID != "disciple-new" {
+			t.Fatalf("unexpected body: %s", w.Body.String())
+		}
+	}
+}
+
+// La invitación y su email se guardan en la misma transacción: si la outbox
+// falla, la invitación no queda.
+func TestCreateInviteRollsBackWithoutOutbox(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock, cleanup := mockGorm(t)
+	defer cleanup()
+
+	notifier := service.NewNotificationService(repository.NewNotificationRepository(db),
+		&notify.Webhook{URL: "http://127.0.0.1:1/hook"}, &notify.SMTP{Addr: "127.0.0.1:1"})
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(func(c *gin.Context) {
+		c.Set(security.CtxUserID, "coach-1")
+		c.Next()
+	})
+	NewInviteHandler(service.NewInviteService(repository.NewInviteRepository(db), db, nil, "", notifier), fakeSessions{}, db).Register(api)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "invite_codes"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("invite-1"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, email FROM users WHERE id IN`)).
+		WithArgs("coach-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow("coach-1", "Coach", "coach@example.test"))
+	mock.ExpectQuery(`INSERT INTO "notification_outbox"`).
+		WillReturnError(errors.New("outbox unavailable"))
+	mock.ExpectRollback()
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/coach/invitations", bytes.NewReader([]byte(`{"email":"ana@example.test","name":"Ana"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code == http.StatusCreated {
+		t.Fatalf("invite created without its email: %s", w.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
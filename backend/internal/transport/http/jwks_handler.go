@@ -0,0 +1,30 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+)
+
+// JWKSHandler publica las llaves públicas de firma para que otros servicios
+// verifiquen tokens de ROMA sin compartir secreto. Las mismas llaves firman
+// invitaciones y tokens de cuenta: un access token se reconoce por
+// iss=security.AccessIssuer ("roma") y aud=security.AccessAudience ("roma-api").
+type JWKSHandler struct{}
+
+func NewJWKSHandler() *JWKSHandler { return &JWKSHandler{} }
+
+func (h *JWKSHandler) Register(r *gin.Engine) {
+	r.GET("/.well-known/jwks.json", h.jwks)
+}
+
+func (h *JWKSHandler) jwks(c *gin.Context) {
+	set := security.JWKSet{Keys: []security.JWK{}}
+	// en modo HS256 no hay nada publicable
+	if m := security.CurrentKeys(); m != nil {
+		set = m.JWKS()
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, set)
+}
@@ -6,6 +6,8 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/schedule"
 	"github.com/vicepalma/roma-system/backend/internal/security"
 	"github.com/vicepalma/roma-system/backend/internal/service"
 )
@@ -35,10 +37,14 @@ func (h *MeHandler) GetToday(c *gin.Context) {
 	userID, _ := c.Get(security.CtxUserID)
 	discipleID := userID.(string)
 	tz := c.DefaultQuery("tz", "America/Santiago")
+	mode, ok := parseScheduleMode(c)
+	if !ok {
+		return
+	}
 
-	out, err := h.hist.GetMeTodayFor(c.Request.Context(), discipleID, tz)
+	out, err := h.hist.GetMeTodayFor(c.Request.Context(), discipleID, tz, mode)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, repository.ErrNoDay) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "no_day", "detail": "no assignment/day for today"})
 			return
 		}
@@ -51,8 +57,12 @@ func (h *MeHandler) GetToday(c *gin.Context) {
 func (h *MeHandler) GetTodayForDisciple(c *gin.Context) {
 	discipleID := c.Param("id")
 	tz := c.DefaultQuery("tz", "America/Santiago")
+	mode, ok := parseScheduleMode(c)
+	if !ok {
+		return
+	}
 
-	out, err := h.hist.GetMeTodayFor(c.Request.Context(), discipleID, tz) // <-- usa HistoryService
+	out, err := h.hist.GetMeTodayFor(c.Request.Context(), discipleID, tz, mode) // <-- usa HistoryService
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error", "detail": err.Error()})
 		return
@@ -60,6 +70,20 @@ func (h *MeHandler) GetTodayForDisciple(c *gin.Context) {
 	c.JSON(http.StatusOK, out)
 }
 
+// ?mode=calendar|next_undone (opcional). Vacío => modo del assignment.
+func parseScheduleMode(c *gin.Context) (string, bool) {
+	raw := c.Query("mode")
+	if raw == "" {
+		return "", true
+	}
+	m, ok := schedule.ParseMode(raw)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_mode", "detail": "mode must be calendar or next_undone"})
+		return "", false
+	}
+	return string(m), true
+}
+
 func (h *MeHandler) getActiveAssignment(c *gin.Context) {
 	uid := security.UserID(c)
 	if uid == "" {
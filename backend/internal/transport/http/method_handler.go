@@ -0,0 +1,141 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
+	"gorm.io/gorm"
+)
+
+type MethodHandler struct {
+	svc service.MethodService
+	db  *gorm.DB
+}
+
+func NewMethodHandler(s service.MethodService, db *gorm.DB) *MethodHandler {
+	return &MethodHandler{svc: s, db: db}
+}
+
+func (h *MethodHandler) Register(r *gin.RouterGroup) {
+	g := r.Group("/methods")
+	{
+		g.GET("", h.list)
+		g.POST("", security.RequireRole(h.db, "coach"), h.create)
+		g.GET("/:id", h.get)
+		g.PUT("/:id", security.RequireRole(h.db, "coach"), h.update)
+		g.DELETE("/:id", security.RequireRole(h.db, "coach"), h.delete)
+	}
+	// series esperadas de una prescripción según su método
+	r.GET("/programs/prescriptions/:id/expanded", h.expand)
+}
+
+func (h *MethodHandler) list(c *gin.Context) {
+	limit := atoiOrZero(c.Query("limit"))
+	offset := atoiOrZero(c.Query("offset"))
+	items, total, err := h.svc.List(c.Request.Context(), repository.MethodFilter{
+		Query:  c.Query("query"),
+		Kind:   c.Query("kind"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":  items,
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (h *MethodHandler) create(c *gin.Context) {
+	var body service.CreateMethod
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	m, err := h.svc.Create(c.Request.Context(), security.UserID(c), body)
+	if err != nil {
+		h.writeMethodError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, m)
+}
+
+func (h *MethodHandler) get(c *gin.Context) {
+	m, err := h.svc.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func (h *MethodHandler) update(c *gin.Context) {
+	var body service.UpdateMethod
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	m, err := h.svc.Update(c.Request.Context(), security.UserID(c), c.Param("id"), body)
+	if err != nil {
+		h.writeMethodError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, m)
+}
+
+func (h *MethodHandler) delete(c *gin.Context) {
+	if err := h.svc.Delete(c.Request.Context(), security.UserID(c), c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound), errors.Is(err, service.ErrMethodReadOnly), errors.Is(err, service.ErrNotMethodOwner):
+			h.writeMethodError(c, err)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot_delete"})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *MethodHandler) expand(c *gin.Context) {
+	out, err := h.svc.ExpandPrescription(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+			return
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "cannot_expand", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+func (h *MethodHandler) writeMethodError(c *gin.Context, err error) {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	case errors.Is(err, service.ErrInvalidMethodKind):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_kind", "detail": "straight|fst7|drop_set|rest_pause|superset|cluster"})
+	case errors.Is(err, service.ErrMethodReadOnly):
+		c.JSON(http.StatusForbidden, gin.H{"error": "method_read_only"})
+	case errors.Is(err, service.ErrNotMethodOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	case errors.Is(err, service.ErrInvalidMethodKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_key"})
+	case strings.HasPrefix(msg, "invalid_params"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_params", "detail": err.Error()})
+	case strings.Contains(msg, "unique") || strings.Contains(msg, "duplicate"):
+		c.JSON(http.StatusConflict, gin.H{"error": "key_already_exists"})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,183 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
+)
+
+type fakeMethodService struct{}
+
+func (fakeMethodService) List(context.Context, repository.MethodFilter) ([]repository.Method, int64, error) {
+	return []repository.Method{}, 0, nil
+}
+func (fakeMethodService) Create(_ context.Context, createdBy string, in service.CreateMethod) (*repository.Method, error) {
+	if _, err := service.ParseMethodParams(in.Kind, in.Params); err != nil {
+		return nil, err
+	}
+	return &repository.Method{ID: "method-1", Key: in.Key, Name: in.Name, Kind: in.Kind, CreatedBy: &createdBy}, nil
+}
+func (fakeMethodService) Get(context.Context, string) (*repository.Method, error) { return nil, nil }
+func (fakeMethodService) Update(context.Context, string, string, service.UpdateMethod) (*repository.Method, error) {
+	return nil, nil
+}
+func (fakeMethodService) Delete(context.Context, string, string) error { return nil }
+func (fakeMethodService) ExpandPrescription(context.Context, string) (*service.ExpandedPrescription, error) {
+	return nil, nil
+}
+
+func TestMethodPermissionsAndParams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock, cleanup := mockGorm(t)
+	defer cleanup()
+
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(func(c *gin.Context) {
+		switch c.GetHeader("X-Test-User") {
+		case "coach":
+			c.Set(security.CtxUserID, "coach-1")
+		case "disciple":
+			c.Set(security.CtxUserID, "disciple-1")
+		}
+		c.Next()
+	})
+	NewMethodHandler(fakeMethodService{}, db).Register(api)
+
+	post := func(user, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/methods", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	expectRole := func(userID, role string) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT role FROM "users"`)).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(role))
+	}
+
+	expectRole("disciple-1", "disciple")
+	if w := post("disciple", `{"key":"ds","name":"Drop","kind":"drop_set"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("disciple create method status=%d want 403", w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/methods", nil)
+	req.Header.Set("X-Test-User", "disciple")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("disciple list methods status=%d want 200", w.Code)
+	}
+
+	expectRole("coach-1", "coach")
+	if w := post("coach", `{"key":"ds","name":"Drop","kind":"drop_set","params":{"drops":3,"drop_percent":20}}`); w.Code != http.StatusCreated {
+		t.Fatalf("coach create method status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	cases := []struct {
+		name  string
+		body  string
+		error string
+	}{
+		{"unknown kind", `{"key":"x1","name":"X","kind":"german_volume"}`, "invalid_kind"},
+		{"unknown param", `{"key":"x2","name":"X","kind":"fst7","params":{"sets":7}}`, "invalid_params"},
+		{"out of range", `{"key":"x3","name":"X","kind":"cluster","params":{"clusters":1}}`, "invalid_params"},
+	}
+	for _, tc := range cases {
+		expectRole("coach-1", "coach")
+		w := post("coach", tc.body)
+		if w.Code != http.StatusBadRequest || !bytes.Contains(w.Body.Bytes(), []byte(tc.error)) {
+			t.Fatalf("%s: status=%d body=%s want 400 %s", tc.name, w.Code, w.Body.String(), tc.error)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Solo el creador edita o borra un método (y su regla de progresión); los
+// sembrados, sin created_by, son de solo lectura.
+func TestMethodWritesOwnerOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock, cleanup := mockGorm(t)
+	defer cleanup()
+
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(func(c *gin.Context) {
+		c.Set(security.CtxUserID, "coach-1")
+		c.Next()
+	})
+	methods := repository.NewMethodRepository(db)
+	NewMethodHandler(service.NewMethodService(methods), db).Register(api)
+	NewProgressionHandler(service.NewProgressionService(repository.NewProgressionRepository(db), methods), db).Register(api)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+	expectMethod := func(id string, createdBy any) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT role FROM "users"`)).
+			WithArgs("coach-1").
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("coach"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "methods" WHERE id = $1`)).
+			WithArgs(id, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "key", "name", "kind", "created_by"}).
+				AddRow(id, "m-"+id, "Método", "straight", createdBy))
+	}
+
+	cases := []struct {
+		name, method, path, body string
+		createdBy                any
+		want                     int
+		error                    string
+	}{
+		{"seeded update", http.MethodPut, "/api/methods/m1", `{"name":"Otro"}`, nil, http.StatusForbidden, "method_read_only"},
+		{"seeded delete", http.MethodDelete, "/api/methods/m1", ``, nil, http.StatusForbidden, "method_read_only"},
+		{"foreign update", http.MethodPut, "/api/methods/m2", `{"name":"Otro"}`, "coach-2", http.StatusForbidden, "forbidden"},
+		{"foreign delete", http.MethodDelete, "/api/methods/m2", ``, "coach-2", http.StatusForbidden, "forbidden"},
+		{"seeded progression", http.MethodPut, "/api/methods/m1/progression", `{"kind":"double"}`, nil, http.StatusForbidden, "method_read_only"},
+		{"foreign progression delete", http.MethodDelete, "/api/methods/m2/progression", ``, "coach-2", http.StatusForbidden, "forbidden"},
+	}
+	for _, tc := range cases {
+		id := "m1"
+		if tc.createdBy != nil {
+			id = "m2"
+		}
+		expectMethod(id, tc.createdBy)
+		w := send(tc.method, tc.path, tc.body)
+		if w.Code != tc.want || !bytes.Contains(w.Body.Bytes(), []byte(tc.error)) {
+			t.Fatalf("%s: status=%d body=%s want %d %s", tc.name, w.Code, w.Body.String(), tc.want, tc.error)
+		}
+	}
+
+	// el creador sí borra
+	expectMethod("m3", "coach-1")
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "methods" WHERE id = $1`)).
+		WithArgs("m3").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if w := send(http.MethodDelete, "/api/methods/m3", ``); w.Code != http.StatusNoContent {
+		t.Fatalf("owner delete status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
@@ -10,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/vicepalma/roma-system/backend/internal/domain"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
 	"github.com/vicepalma/roma-system/backend/internal/security"
 	"github.com/vicepalma/roma-system/backend/internal/service"
 	"gorm.io/gorm"
@@ -33,53 +35,46 @@ func NewProgramHandler(s service.ProgramService, db *gorm.DB) *ProgramHandler {
 	return &ProgramHandler{svc: s, db: db}
 }
 
+// El acceso a cada programa/semana/día/prescripción lo aplica
+// security.Authorize según RoutePolicies (route_policies.go).
 func (h *ProgramHandler) Register(r *gin.RouterGroup) {
 	g := r.Group("/programs")
 	{
-		g.GET("", h.listMine)                          // GET /programs
-		g.POST("", h.createProgram)                    // POST /programs
-		g.GET("/:id", h.requireProgramReadable, h.get) // GET /programs/:id
-		g.PUT("/:id", security.RequireProgramMutable(h.db, "id"), h.update)
-		g.DELETE("/:id", security.RequireProgramMutable(h.db, "id"), h.delete)
-		g.POST("/:id/version", security.RequireRole(h.db, "coach"), security.RequireProgramOwner(h.db, "id"), h.version)
-		g.GET("/:id/versions", security.RequireProgramMutable(h.db, "id"), h.versions)
+		g.GET("", h.listMine)       // GET /programs
+		g.POST("", h.createProgram) // POST /programs
+		g.GET("/:id", h.get)        // GET /programs/:id
+		g.PUT("/:id", h.update)
+		g.DELETE("/:id", h.delete)
+		g.POST("/:id/version", security.RequireRole(h.db, "coach"), h.version)
+		g.GET("/:id/versions", h.versions)
 		g.POST("/:id/self-assignment", h.createSelfAssignment)
 
-		g.POST("/:id/weeks", security.RequireProgramMutable(h.db, "id"), h.addWeek)
-		g.GET("/:id/weeks", h.requireProgramReadable, h.listWeeks)
-		g.POST("/:id/weeks/:weekId/days", security.RequireProgramMutable(h.db, "id"), h.addDay)
-		g.GET("/:id/weeks/:weekId/days", h.requireProgramReadable, h.listDays)
-		g.PUT("/:id/weeks/:weekId/days/:dayId", security.RequireProgramMutable(h.db, "id"), h.updateDay)
-		g.DELETE("/:id/weeks/:weekId/days/:dayId", security.RequireProgramMutable(h.db, "id"), h.deleteDay)
+		// Plantillas públicas
+		g.GET("/templates", h.listTemplates)
+		g.POST("/templates/:id/clone", h.cloneTemplate)
 
-		g.GET("/programs/:id/weeks/:weekId/days", h.requireProgramReadable, h.listDays)
-		g.PUT("/programs/:id/weeks/:weekId/days/:dayId", security.RequireProgramMutable(h.db, "id"), h.updateDay)
-		g.DELETE("/programs/:id/weeks/:weekId/days/:dayId", security.RequireProgramMutable(h.db, "id"), h.deleteDay)
+		g.POST("/:id/weeks", h.addWeek)
+		g.GET("/:id/weeks", h.listWeeks)
+		g.POST("/:id/weeks/:weekId/days", h.addDay)
+		g.GET("/:id/weeks/:weekId/days", h.listDays)
+		g.PUT("/:id/weeks/:weekId/days/:dayId", h.updateDay)
+		g.DELETE("/:id/weeks/:weekId/days/:dayId", h.deleteDay)
+
+		g.GET("/programs/:id/weeks/:weekId/days", h.listDays)
+		g.PUT("/programs/:id/weeks/:weekId/days/:dayId", h.updateDay)
+		g.DELETE("/programs/:id/weeks/:weekId/days/:dayId", h.deleteDay)
 
 		// Prescripciones
-		g.GET("/days/:dayId/prescriptions", security.RequireDayReadable(h.db, "dayId"), h.listPresc)
-		g.POST("/days/:dayId/prescriptions", security.RequireProgramMutableByDay(h.db, "dayId"), h.addPrescription)
-		g.PUT("/prescriptions/:id", security.RequireProgramMutableByPrescription(h.db, "id"), h.updatePresc)
-		g.DELETE("/prescriptions/:id", security.RequireProgramMutableByPrescription(h.db, "id"), h.deletePresc)
+		g.GET("/days/:dayId/prescriptions", h.listPresc)
+		g.POST("/days/:dayId/prescriptions", h.addPrescription)
+		g.PUT("/prescriptions/:id", h.updatePresc)
+		g.DELETE("/prescriptions/:id", h.deletePresc)
 		g.PATCH("/prescriptions/reorder", security.RequireRole(h.db, "coach"), h.reorderPresc)
-		g.DELETE("/:id/weeks/:weekId", security.RequireProgramMutable(h.db, "id"), h.deleteWeek)
+		g.DELETE("/:id/weeks/:weekId", h.deleteWeek)
 	}
 
 }
 
-func (h *ProgramHandler) requireProgramReadable(c *gin.Context) {
-	ok, err := security.IsProgramReadable(h.db.WithContext(c.Request.Context()), userID(c), c.Param("id"))
-	if err != nil {
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
-		return
-	}
-	if !ok {
-		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-		return
-	}
-	c.Next()
-}
-
 func userID(c *gin.Context) string {
 	v, _ := c.Get(security.CtxUserID)
 	if s, ok := v.(string); ok {
@@ -142,17 +137,78 @@ func (h *ProgramHandler) createProgram(c *gin.Context) {
 	c.JSON(http.StatusCreated, p)
 }
 
-func (h *ProgramHandler) createSelfAssignment(c *gin.Context) {
-	programID := c.Param("id")
-	ok, err := security.IsProgramMutable(h.db.WithContext(c.Request.Context()), userID(c), programID)
+// Biblioteca de plantillas: programas de coach con visibility=public.
+// Filtros: query, tag (repetible o separado por coma), level, goal, days_per_week.
+func (h *ProgramHandler) listTemplates(c *gin.Context) {
+	var tags []string
+	for _, t := range c.QueryArray("tag") {
+		tags = append(tags, strings.Split(t, ",")...)
+	}
+	f := repository.TemplateFilter{
+		Query:       c.Query("query"),
+		Tags:        tags,
+		Level:       c.Query("level"),
+		Goal:        c.Query("goal"),
+		DaysPerWeek: parseInt(c.Query("days_per_week"), 0),
+		Limit:       parseInt(c.Query("limit"), 20),
+		Offset:      parseInt(c.Query("offset"), 0),
+	}
+	items, total, err := h.svc.ListTemplates(c.Request.Context(), f)
+	if errors.Is(err, service.ErrInvalidLevel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_level", "detail": "beginner|intermediate|advanced"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": f.Limit, "offset": f.Offset})
+}
+
+// programKindForRole: coach -> coach_program, disciple -> self_training.
+func programKindForRole(role string) (string, bool) {
+	switch role {
+	case "coach":
+		return "coach_program", true
+	case "disciple":
+		return "self_training", true
+	}
+	return "", false
+}
+
+// Clona la plantilla como programa propio según el rol del llamador.
+func (h *ProgramHandler) cloneTemplate(c *gin.Context) {
+	var body struct {
+		Title *string `json:"title"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil && err != io.EOF {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request"})
+		return
+	}
+	role, err := security.RoleOf(h.db.WithContext(c.Request.Context()), userID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
 	}
+	kind, ok := programKindForRole(role)
 	if !ok {
 		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
 		return
 	}
+	p, err := h.svc.CloneTemplate(c.Request.Context(), c.Param("id"), userID(c), kind, body.Title)
+	if errors.Is(err, repository.ErrTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+func (h *ProgramHandler) createSelfAssignment(c *gin.Context) {
+	programID := c.Param("id")
 	kind, err := security.ProgramKind(h.db.WithContext(c.Request.Context()), programID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
@@ -219,13 +275,14 @@ func (h *ProgramHandler) addDay(c *gin.Context) {
 	type req struct {
 		DayIndex int     `json:"day_index" binding:"required,min=1"`
 		Notes    *string `json:"notes"`
+		IsRest   bool    `json:"is_rest"`
 	}
 	var body req
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(400, gin.H{"error": "bad_request"})
 		return
 	}
-	d, err := h.svc.AddDay(c, weekID, body.DayIndex, body.Notes)
+	d, err := h.svc.AddDay(c, weekID, body.DayIndex, body.Notes, body.IsRest)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "db_error", "detail": err.Error()})
 		return
@@ -310,6 +367,14 @@ func (h *ProgramHandler) update(c *gin.Context) {
 		return
 	}
 	p, err := h.svc.Update(c.Request.Context(), id, in)
+	if errors.Is(err, service.ErrInvalidSchedule) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_schedule", "detail": "block_repeats >= 1, end_behavior stop|loop|hold"})
+		return
+	}
+	if errors.Is(err, service.ErrInvalidLevel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_level", "detail": "beginner|intermediate|advanced"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
 		return
@@ -387,6 +452,7 @@ func (h *ProgramHandler) updateDay(c *gin.Context) {
 	type body struct {
 		DayIndex *int    `json:"day_index"`
 		Notes    *string `json:"notes"`
+		IsRest   *bool   `json:"is_rest"`
 	}
 	var b body
 	if err := c.ShouldBindJSON(&b); err != nil {
@@ -394,7 +460,7 @@ func (h *ProgramHandler) updateDay(c *gin.Context) {
 		return
 	}
 	dayID := c.Param("dayId")
-	d, err := h.svc.UpdateDay(c.Request.Context(), dayID, b.Notes, b.DayIndex)
+	d, err := h.svc.UpdateDay(c.Request.Context(), dayID, b.Notes, b.DayIndex, b.IsRest)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
 		return
@@ -453,7 +519,7 @@ func (h *ProgramHandler) reorderPresc(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request"})
 		return
 	}
-	ok, err := security.IsProgramOwnerByDay(h.db.WithContext(c.Request.Context()), userID(c), b.DayID)
+	ok, err := security.Can(c, security.ResDay, b.DayID, security.ActMutate)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
@@ -32,7 +32,7 @@ func (fakeProgramService) AddWeek(context.Context, string, int) (*domain.Program
 	return nil, nil
 }
 func (fakeProgramService) DeleteWeek(context.Context, string, string) error { return nil }
-func (fakeProgramService) AddDay(context.Context, string, int, *string) (*domain.ProgramDay, error) {
+func (fakeProgramService) AddDay(context.Context, string, int, *string, bool) (*domain.ProgramDay, error) {
 	return nil, nil
 }
 func (fakeProgramService) AddPrescription(context.Context, *domain.Prescription) (*domain.Prescription, error) {
@@ -67,7 +67,7 @@ func (fakeProgramService) ListWeeks(context.Context, string) ([]repository.Progr
 func (fakeProgramService) ListDays(context.Context, string) ([]repository.ProgramDay, error) {
 	return nil, nil
 }
-func (fakeProgramService) UpdateDay(context.Context, string, *string, *int) (*repository.ProgramDay, error) {
+func (fakeProgramService) UpdateDay(context.Context, string, *string, *int, *bool) (*repository.ProgramDay, error) {
 	return nil, nil
 }
 func (fakeProgramService) DeleteDay(context.Context, string) error { return nil }
@@ -95,6 +95,13 @@ func (fakeProgramService) CreateSelfAssignment(context.Context, string, string,
 	return &domain.Assignment{ID: "assignment-1"}, nil
 }
 
+func (fakeProgramService) ListTemplates(context.Context, repository.TemplateFilter) ([]repository.ProgramTemplateRow, int64, error) {
+	return []repository.ProgramTemplateRow{}, 0, nil
+}
+func (fakeProgramService) CloneTemplate(_ context.Context, templateID, ownerID, kind string, _ *string) (*repository.ProgramRow, error) {
+	return &repository.ProgramRow{ID: "clone-1", OwnerID: ownerID, Kind: kind, SourceProgramID: &templateID}, nil
+}
+
 func TestProgramPermissions(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	db, mock, cleanup := mockGorm(t)
@@ -113,6 +120,9 @@ func TestProgramPermissions(t *testing.T) {
 		}
 		c.Next()
 	})
+	security.UseAuthorizer(security.NewAuthorizer(security.NewSQLPolicyStore(db), nil))
+	defer security.UseAuthorizer(nil)
+	api.Use(security.Authorize(RoutePolicies))
 	NewProgramHandler(fakeProgramService{}, db).Register(api)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`SELECT role FROM "users"`)).
@@ -139,14 +149,13 @@ func TestProgramPermissions(t *testing.T) {
 		t.Fatalf("coach create program status=%d body=%s", w.Code, w.Body.String())
 	}
 
-	mock.ExpectQuery(regexp.QuoteMeta(`SELECT role FROM "users"`)).
-		WithArgs("coach-2").
-		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("coach"))
-	mock.ExpectQuery(`SELECT count\(\*\) FROM "programs"`).
-		WithArgs("program-1", "coach-2").
-		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	const programID = "55555555-5555-4555-8555-555555555555"
+	mock.ExpectQuery(`SELECT p.id AS program_id, .* FROM programs AS p WHERE p.id = \$1`).
+		WithArgs(programID).
+		WillReturnRows(sqlmock.NewRows([]string{"program_id", "program_owner", "program_kind", "program_visibility"}).
+			AddRow(programID, "coach-1", "coach_program", "private"))
 	w = httptest.NewRecorder()
-	req = httptest.NewRequest(http.MethodPut, "/api/programs/program-1", bytes.NewReader([]byte(`{"title":"Updated"}`)))
+	req = httptest.NewRequest(http.MethodPut, "/api/programs/"+programID, bytes.NewReader([]byte(`{"title":"Updated"}`)))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Test-User", "foreign-coach")
 	r.ServeHTTP(w, req)
@@ -166,6 +175,22 @@ func TestProgramPermissions(t *testing.T) {
 		t.Fatalf("disciple create self-training status=%d body=%s", w.Code, w.Body.String())
 	}
 
+	for _, tc := range []struct{ user, id, role, kind string }{
+		{"coach", "coach-1", "coach", `"Kind":"coach_program"`},
+		{"disciple", "disciple-1", "disciple", `"Kind":"self_training"`},
+	} {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT role FROM "users"`)).
+			WithArgs(tc.id).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(tc.role))
+		w = httptest.NewRecorder()
+		req = httptest.NewRequest(http.MethodPost, "/api/programs/templates/template-1/clone", nil)
+		req.Header.Set("X-Test-User", tc.user)
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated || !bytes.Contains(w.Body.Bytes(), []byte(tc.kind)) {
+			t.Fatalf("%s clone template status=%d body=%s", tc.user, w.Code, w.Body.String())
+		}
+	}
+
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatal(err)
 	}
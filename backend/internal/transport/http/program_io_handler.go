@@ -0,0 +1,134 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/programio"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
+	"gorm.io/gorm"
+)
+
+const maxImportBytes = 2 << 20
+
+type ProgramIOHandler struct {
+	svc service.ProgramIOService
+	db  *gorm.DB
+}
+
+func NewProgramIOHandler(s service.ProgramIOService, db *gorm.DB) *ProgramIOHandler {
+	return &ProgramIOHandler{svc: s, db: db}
+}
+
+func (h *ProgramIOHandler) Register(r *gin.RouterGroup) {
+	r.GET("/programs/:id/export", h.export)
+	r.POST("/programs/import", h.importProgram)
+}
+
+// GET /programs/:id/export?format=json|csv
+func (h *ProgramIOHandler) export(c *gin.Context) {
+	doc, err := h.svc.Export(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	name := fileSlug(doc.Program.Title)
+	if wantsCSV(c) {
+		var buf bytes.Buffer
+		if err := programio.EncodeCSV(&buf, doc); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="`+name+`.json"`)
+	c.JSON(http.StatusOK, doc)
+}
+
+// POST /programs/import?format=json|csv&dry_run=true
+// CSV: título por ?title= (el CSV no trae metadatos del programa).
+// Con ejercicios sin resolver responde 422 con el reporte y no crea nada.
+func (h *ProgramIOHandler) importProgram(c *gin.Context) {
+	role, err := security.RoleOf(h.db.WithContext(c.Request.Context()), userID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	kind, ok := programKindForRole(role)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	var doc *programio.Doc
+	if wantsCSV(c) {
+		doc, err = programio.DecodeCSV(body, programio.Meta{
+			Title:       c.DefaultQuery("title", "Imported program"),
+			EndBehavior: c.Query("end_behavior"),
+		})
+	} else {
+		doc, err = programio.DecodeJSON(body)
+	}
+	if errors.Is(err, programio.ErrUnsupportedVersion) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported_version", "detail": "max version 1"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+
+	dryRun := parseBoolQuery(c.Query("dry_run"))
+	rep, err := h.svc.Import(c.Request.Context(), userID(c), kind, doc, dryRun)
+	switch {
+	case errors.Is(err, service.ErrUnresolvedImport):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "unresolved_exercises", "report": rep})
+	case errors.Is(err, service.ErrInvalidSchedule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_schedule", "detail": "end_behavior stop|loop|hold"})
+	case errors.Is(err, service.ErrInvalidLevel):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_level", "detail": "beginner|intermediate|advanced"})
+	case errors.Is(err, programio.ErrInvalidDoc):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_program", "detail": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+	case dryRun:
+		c.JSON(http.StatusOK, rep)
+	default:
+		c.JSON(http.StatusCreated, rep)
+	}
+}
+
+func wantsCSV(c *gin.Context) bool {
+	if f := strings.ToLower(c.Query("format")); f != "" {
+		return f == "csv"
+	}
+	return strings.HasPrefix(c.ContentType(), "text/csv")
+}
+
+func parseBoolQuery(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// fileSlug: nombre de archivo seguro a partir del título.
+func fileSlug(title string) string {
+	s := strings.Trim(strings.ReplaceAll(programio.Normalize(title), " ", "-"), "-")
+	if s == "" {
+		return "program"
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
+)
+
+// Export e import contra los repositorios reales (SQL en sqlmock): el
+// programa se lee de "programs" y el import crea el árbol en una transacción.
+func TestProgramExportImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock, cleanup := mockGorm(t)
+	defer cleanup()
+
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(func(c *gin.Context) {
+		c.Set(security.CtxUserID, "coach-1")
+		c.Next()
+	})
+	svc := service.NewProgramIOService(repository.NewProgramRepository(db), repository.NewExerciseRepository(db), repository.NewMethodRepository(db))
+	NewProgramIOHandler(svc, db).Register(api)
+
+	const programID = "55555555-5555-4555-8555-555555555555"
+	mock.ExpectQuery(`SELECT \* FROM "programs" WHERE id = \$1`).
+		WithArgs(programID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "title", "kind", "block_repeats", "end_behavior"}).
+			AddRow(programID, "coach-1", "Fuerza Base", "coach_program", 1, "loop"))
+	mock.ExpectQuery(`FROM program_weeks w\s+LEFT JOIN program_days d`).
+		WithArgs(programID).
+		WillReturnRows(sqlmock.NewRows([]string{"day_id", "week_index", "day_index", "title", "notes", "is_rest"}).
+			AddRow("day-1", 1, 1, "Empuje", nil, false))
+	mock.ExpectQuery(`FROM prescriptions pr`).
+		WithArgs(programID).
+		WillReturnRows(sqlmock.NewRows([]string{"day_id", "exercise_id", "exercise_name", "series", "reps", "rest_sec", "to_failure"}).
+			AddRow("day-1", "ex-1", "Press banca", 4, "8-10", 90, false))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/programs/"+programID+"/export", nil))
+	if w.Code != http.StatusOK || !bytes.Contains(w.Body.Bytes(), []byte(`"exercise":"Press banca"`)) ||
+		w.Header().Get("Content-Disposition") != `attachment; filename="fuerza-base.json"` {
+		t.Fatalf("export status=%d headers=%v body=%s", w.Code, w.Header(), w.Body.String())
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT role FROM "users"`)).
+		WithArgs("coach-1").
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("coach"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "exercises"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercises"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "primary_muscle"}).AddRow("ex-1", "Press banca", "chest"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "methods"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "methods"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "name"}))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO programs`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "title", "kind"}).AddRow("program-2", "coach-1", "Fuerza Base", "coach_program"))
+	mock.ExpectQuery(`INSERT INTO program_weeks`).
+		WithArgs("program-2", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("week-2"))
+	mock.ExpectQuery(`INSERT INTO program_days`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("day-2"))
+	mock.ExpectExec(`INSERT INTO prescriptions`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	body := w.Body.Bytes()
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/programs/import", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || !bytes.Contains(w.Body.Bytes(), []byte(`"ID":"program-2"`)) {
+		t.Fatalf("import status=%d body=%s", w.Code, w.Body.String())
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/progression"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
+	"gorm.io/gorm"
+)
+
+type ProgressionHandler struct {
+	svc service.ProgressionService
+	db  *gorm.DB
+}
+
+func NewProgressionHandler(s service.ProgressionService, db *gorm.DB) *ProgressionHandler {
+	return &ProgressionHandler{svc: s, db: db}
+}
+
+func (h *ProgressionHandler) Register(r *gin.RouterGroup) {
+	// regla por prescripción (tiene prioridad sobre la del método)
+	r.GET("/programs/prescriptions/:id/progression", h.getRule("prescription"))
+	r.PUT("/programs/prescriptions/:id/progression", h.setRule("prescription"))
+	r.DELETE("/programs/prescriptions/:id/progression", h.deleteRule("prescription"))
+
+	// regla por defecto del método
+	r.GET("/methods/:id/progression", h.getRule("method"))
+	r.PUT("/methods/:id/progression", security.RequireRole(h.db, "coach"), h.setRule("method"))
+	r.DELETE("/methods/:id/progression", security.RequireRole(h.db, "coach"), h.deleteRule("method"))
+
+	// objetivo de la próxima sesión y override del coach
+	r.GET("/assignments/:assignmentId/prescriptions/:prescriptionId/target", h.target)
+	r.PUT("/coach/assignments/:id/overrides/:prescriptionId", security.RequireRole(h.db, "coach"), h.setOverride)
+	r.DELETE("/coach/assignments/:id/overrides/:prescriptionId", security.RequireRole(h.db, "coach"), h.deleteOverride)
+}
+
+func (h *ProgressionHandler) getRule(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule, err := h.svc.GetRule(c.Request.Context(), scope, c.Param("id"))
+		if err != nil {
+			h.writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rule)
+	}
+}
+
+func (h *ProgressionHandler) setRule(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body service.SetProgressionRule
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+			return
+		}
+		rule, err := h.svc.SetRule(c.Request.Context(), scope, c.Param("id"), security.UserID(c), body)
+		if err != nil {
+			h.writeError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, rule)
+	}
+}
+
+func (h *ProgressionHandler) deleteRule(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := h.svc.DeleteRule(c.Request.Context(), scope, c.Param("id"), security.UserID(c)); err != nil {
+			h.writeError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func (h *ProgressionHandler) target(c *gin.Context) {
+	t, err := h.svc.Target(c.Request.Context(), c.Param("assignmentId"), c.Param("prescriptionId"))
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	if t == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no_progression"})
+		return
+	}
+	c.JSON(http.StatusOK, t)
+}
+
+func (h *ProgressionHandler) setOverride(c *gin.Context) {
+	var body service.SetProgressionOverride
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	o, err := h.svc.SetOverride(c.Request.Context(), c.Param("id"), c.Param("prescriptionId"), security.UserID(c), body)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+func (h *ProgressionHandler) deleteOverride(c *gin.Context) {
+	if err := h.svc.DeleteOverride(c.Request.Context(), c.Param("id"), c.Param("prescriptionId")); err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ProgressionHandler) writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	case errors.Is(err, service.ErrMethodReadOnly):
+		c.JSON(http.StatusForbidden, gin.H{"error": "method_read_only"})
+	case errors.Is(err, service.ErrNotMethodOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+	case errors.Is(err, progression.ErrInvalidKind):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_kind", "detail": "double|linear|percent_wave|rpe"})
+	case errors.Is(err, service.ErrPrescriptionNotInAssignment):
+		c.JSON(http.StatusNotFound, gin.H{"error": "prescription_not_in_assignment"})
+	case errors.Is(err, service.ErrEmptyOverride):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "empty_override", "detail": "weight, reps or rpe required"})
+	case strings.HasPrefix(err.Error(), "invalid_params"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_params", "detail": err.Error()})
+	case strings.Contains(err.Error(), "foreign key"):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+	}
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/progression"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
+)
+
+type fakeProgressionService struct{}
+
+func (fakeProgressionService) GetRule(context.Context, string, string) (*repository.ProgressionRule, error) {
+	return nil, nil
+}
+func (fakeProgressionService) SetRule(_ context.Context, scope, id, _ string, in service.SetProgressionRule) (*repository.ProgressionRule, error) {
+	if _, err := progression.ParseParams(progression.Kind(in.Kind), in.Params); err != nil {
+		return nil, err
+	}
+	return &repository.ProgressionRule{ID: "rule-1", MethodID: &id, Kind: in.Kind}, nil
+}
+func (fakeProgressionService) DeleteRule(context.Context, string, string, string) error { return nil }
+func (fakeProgressionService) Target(context.Context, string, string) (*progression.Target, error) {
+	return nil, nil
+}
+func (fakeProgressionService) SetOverride(_ context.Context, assignmentID, prescriptionID, coachID string, _ service.SetProgressionOverride) (*repository.ProgressionOverride, error) {
+	return &repository.ProgressionOverride{AssignmentID: assignmentID, PrescriptionID: prescriptionID, CreatedBy: coachID}, nil
+}
+func (fakeProgressionService) DeleteOverride(context.Context, string, string) error { return nil }
+
+func TestProgressionRulesAndOverrides(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, mock, cleanup := mockGorm(t)
+	defer cleanup()
+
+	r := gin.New()
+	api := r.Group("/api")
+	api.Use(func(c *gin.Context) {
+		switch c.GetHeader("X-Test-User") {
+		case "coach":
+			c.Set(security.CtxUserID, "coach-1")
+		case "disciple":
+			c.Set(security.CtxUserID, "disciple-1")
+		}
+		c.Next()
+	})
+	// junto a los handlers con los que comparte prefijos de rutas
+	NewMethodHandler(fakeMethodService{}, db).Register(api)
+	NewProgressionHandler(fakeProgressionService{}, db).Register(api)
+
+	put := func(user, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, path, bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Test-User", user)
+		r.ServeHTTP(w, req)
+		return w
+	}
+	expectRole := func(userID, role string) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT role FROM "users"`)).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(role))
+	}
+
+	expectRole("disciple-1", "disciple")
+	if w := put("disciple", "/api/methods/m1/progression", `{"kind":"double"}`); w.Code != http.StatusForbidden {
+		t.Fatalf("disciple set method rule status=%d want 403", w.Code)
+	}
+
+	expectRole("coach-1", "coach")
+	if w := put("coach", "/api/methods/m1/progression", `{"kind":"sideways"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unknown kind status=%d want 400", w.Code)
+	}
+
+	expectRole("coach-1", "coach")
+	if w := put("coach", "/api/methods/m1/progression", `{"kind":"double","params":{"rep_min":12,"rep_max":8}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid params status=%d want 400 body=%s", w.Code, w.Body.String())
+	}
+
+	expectRole("coach-1", "coach")
+	if w := put("coach", "/api/methods/m1/progression", `{"kind":"double"}`); w.Code != http.StatusOK {
+		t.Fatalf("set method rule status=%d want 200 body=%s", w.Code, w.Body.String())
+	}
+
+	expectRole("disciple-1", "disciple")
+	if w := put("disciple", "/api/coach/assignments/a1/overrides/p1", `{"weight":100}`); w.Code != http.StatusForbidden {
+		t.Fatalf("disciple override status=%d want 403", w.Code)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,146 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/vicepalma/roma-system/backend/internal/security"
+)
+
+func policy(res security.Resource, act security.Action, param string) security.RoutePolicy {
+	return security.RoutePolicy{Resource: res, Action: act, Param: param}
+}
+
+// authOnly: sin recurso en el path; basta la autenticación (y RequireRole si
+// la ruta lo declara). El handler o el servicio filtran por el usuario.
+var authOnly = security.RoutePolicy{}
+
+// RoutePolicies declara la política de cada ruta /api. Toda ruta registrada
+// debe aparecer aquí (route_policies_test lo verifica).
+var RoutePolicies = security.RoutePolicies{
+	// programas
+	"GET /api/programs":                      authOnly,
+	"POST /api/programs":                     authOnly,
+	"GET /api/programs/:id":                  policy(security.ResProgram, security.ActRead, "id"),
+	"PUT /api/programs/:id":                  policy(security.ResProgram, security.ActMutate, "id"),
+	"DELETE /api/programs/:id":               policy(security.ResProgram, security.ActMutate, "id"),
+	"POST /api/programs/:id/version":         policy(security.ResProgram, security.ActExecute, "id"),
+	"GET /api/programs/:id/versions":         policy(security.ResProgram, security.ActMutate, "id"),
+	"POST /api/programs/:id/self-assignment": policy(security.ResProgram, security.ActMutate, "id"),
+	"GET /api/programs/:id/export":           policy(security.ResProgram, security.ActRead, "id"),
+	"POST /api/programs/import":              authOnly,
+	"GET /api/programs/templates":            authOnly,
+	"POST /api/programs/templates/:id/clone": authOnly, // el servicio exige plantilla pública
+
+	"POST /api/programs/:id/weeks":                                policy(security.ResProgram, security.ActMutate, "id"),
+	"GET /api/programs/:id/weeks":                                 policy(security.ResProgram, security.ActRead, "id"),
+	"DELETE /api/programs/:id/weeks/:weekId":                      policy(security.ResProgram, security.ActMutate, "id"),
+	"POST /api/programs/:id/weeks/:weekId/days":                   policy(security.ResProgram, security.ActMutate, "id"),
+	"GET /api/programs/:id/weeks/:weekId/days":                    policy(security.ResProgram, security.ActRead, "id"),
+	"PUT /api/programs/:id/weeks/:weekId/days/:dayId":             policy(security.ResProgram, security.ActMutate, "id"),
+	"DELETE /api/programs/:id/weeks/:weekId/days/:dayId":          policy(security.ResProgram, security.ActMutate, "id"),
+	"GET /api/programs/programs/:id/weeks/:weekId/days":           policy(security.ResProgram, security.ActRead, "id"),
+	"PUT /api/programs/programs/:id/weeks/:weekId/days/:dayId":    policy(security.ResProgram, security.ActMutate, "id"),
+	"DELETE /api/programs/programs/:id/weeks/:weekId/days/:dayId": policy(security.ResProgram, security.ActMutate, "id"),
+
+	"GET /api/programs/days/:dayId/prescriptions":        policy(security.ResDay, security.ActRead, "dayId"),
+	"POST /api/programs/days/:dayId/prescriptions":       policy(security.ResDay, security.ActMutate, "dayId"),
+	"PUT /api/programs/prescriptions/:id":                policy(security.ResPrescription, security.ActMutate, "id"),
+	"DELETE /api/programs/prescriptions/:id":             policy(security.ResPrescription, security.ActMutate, "id"),
+	"PATCH /api/programs/prescriptions/reorder":          authOnly, // day_id en el body: security.Can en el handler
+	"GET /api/programs/prescriptions/:id/expanded":       policy(security.ResPrescription, security.ActRead, "id"),
+	"GET /api/programs/prescriptions/:id/progression":    policy(security.ResPrescription, security.ActRead, "id"),
+	"PUT /api/programs/prescriptions/:id/progression":    policy(security.ResPrescription, security.ActMutate, "id"),
+	"DELETE /api/programs/prescriptions/:id/progression": policy(security.ResPrescription, security.ActMutate, "id"),
+
+	// catálogo (ejercicios y métodos): RequireRole para escribir
+	"GET /api/exercises":                  authOnly,
+	"POST /api/exercises":                 authOnly,
+	"GET /api/exercises/:id":              authOnly,
+	"PUT /api/exercises/:id":              authOnly,
+	"DELETE /api/exercises/:id":           authOnly,
+	"GET /api/methods":                    authOnly,
+	"POST /api/methods":                   authOnly,
+	"GET /api/methods/:id":                authOnly,
+	"PUT /api/methods/:id":                authOnly,
+	"DELETE /api/methods/:id":             authOnly,
+	"GET /api/methods/:id/progression":    authOnly,
+	"PUT /api/methods/:id/progression":    authOnly,
+	"DELETE /api/methods/:id/progression": authOnly,
+
+	// asignaciones
+	"GET /api/assignments/:assignmentId/days":                                 authOnly, // el servicio filtra por solicitante (404)
+	"GET /api/assignments/:assignmentId/prescriptions/:prescriptionId/target": policy(security.ResAssignment, security.ActRead, "assignmentId"),
+	"POST /api/coach/assignments":                                             authOnly, // program_id en el body: security.Can en el handler
+	"GET /api/coach/assignments":                                              authOnly,
+	"PATCH /api/coach/assignments/:id":                                        policy(security.ResAssignment, security.ActMutate, "id"),
+	"GET /api/coach/assignments/:id/calendar":                                 policy(security.ResAssignment, security.ActRead, "id"),
+	"POST /api/coach/assignments/:id/activate":                                policy(security.ResAssignment, security.ActMutate, "id"),
+	"PUT /api/coach/assignments/:id/overrides/:prescriptionId":                policy(security.ResAssignment, security.ActMutate, "id"),
+	"DELETE /api/coach/assignments/:id/overrides/:prescriptionId":             policy(security.ResAssignment, security.ActMutate, "id"),
+
+	// sesiones
+	"POST /api/sessions":                   authOnly, // assignment_id en el body: security.Can en el handler
+	"POST /api/sessions/sync":              authOnly, // ídem
+	"GET /api/sessions/:id":                policy(security.ResSession, security.ActRead, "id"),
+	"PATCH /api/sessions/:id":              policy(security.ResSession, security.ActMutate, "id"),
+	"GET /api/sessions/:id/sets":           policy(security.ResSession, security.ActRead, "id"),
+	"GET /api/sessions/:id/timeline":       policy(security.ResSession, security.ActRead, "id"),
+	"POST /api/sessions/:id/sets":          policy(security.ResSession, security.ActExecute, "id"),
+	"POST /api/sessions/:id/cardio":        policy(security.ResSession, security.ActExecute, "id"),
+	"DELETE /api/sessions/:id/sets/:setId": policy(security.ResSet, security.ActMutate, "setId"),
+
+	// propio usuario
+	"GET /api/me/today":             authOnly,
+	"GET /api/me/today/disciple":    authOnly,
+	"GET /api/me/records":           authOnly,
+	"GET /api/me/assignment/active": authOnly,
+	"GET /api/me/session/active":    authOnly,
+
+	// check-ins (los del discípulo se filtran por usuario en el servicio)
+	"POST /api/checkins":                       authOnly,
+	"GET /api/checkins":                        authOnly,
+	"GET /api/checkins/metrics":                authOnly,
+	"GET /api/checkins/trends":                 authOnly,
+	"GET /api/checkins/:id":                    {Resource: security.ResCheckin, Action: security.ActRead, Param: "id", Missing: http.StatusNotFound},
+	"PATCH /api/checkins/:id":                  authOnly,
+	"DELETE /api/checkins/:id":                 authOnly,
+	"POST /api/checkins/:id/photos":            authOnly,
+	"DELETE /api/checkins/:id/photos/:photoId": authOnly,
+	"GET /api/coach/checkins":                  authOnly,
+	"PUT /api/coach/checkins/:id/review":       {Resource: security.ResCheckin, Action: security.ActExecute, Param: "id", Missing: http.StatusNotFound},
+
+	// coach sobre un discípulo
+	"GET /api/coach/disciples/:id/today":                 policy(security.ResDisciple, security.ActRead, "id"),
+	"GET /api/coach/disciples/:id/overview":              policy(security.ResDisciple, security.ActRead, "id"),
+	"GET /api/coach/disciples/:id/records":               policy(security.ResDisciple, security.ActRead, "id"),
+	"GET /api/coach/disciples/:id/checkins":              policy(security.ResDisciple, security.ActRead, "id"),
+	"GET /api/coach/disciples/:id/checkins/trends":       policy(security.ResDisciple, security.ActRead, "id"),
+	"GET /api/coach/disciples/:id/checkins/requirements": policy(security.ResDisciple, security.ActRead, "id"),
+	"PUT /api/coach/disciples/:id/checkins/requirements": policy(security.ResDisciple, security.ActMutate, "id"),
+	"GET /api/coach/disciples":                           authOnly,
+	"GET /api/coach/roster":                              authOnly,
+
+	// vínculos e invitaciones (el servicio valida participante/código)
+	"POST /api/coach/links":                    authOnly,
+	"GET /api/coach/links":                     authOnly,
+	"PATCH /api/coach/links/:id":               authOnly,
+	"POST /api/coach/invitations":              authOnly,
+	"GET /api/coach/invitations":               authOnly,
+	"POST /api/coach/invitations/:code/revoke": authOnly,
+	"POST /api/coach/invitations/:code/resend": authOnly,
+	"POST /api/coach/invitations/:code/accept": authOnly,
+
+	// historial: disciple_id por query se valida en el handler (security.Can);
+	// summary y prs son siempre del propio usuario
+	"GET /api/history":                        authOnly,
+	"GET /api/history/summary":                authOnly,
+	"GET /api/history/summary/pivot":          authOnly,
+	"GET /api/history/prs":                    authOnly,
+	"GET /api/history/adherence":              authOnly,
+	"GET /api/history/strength":               authOnly,
+	"GET /api/history/rest":                   authOnly,
+	"GET /api/history/disciples/:id/sessions": policy(security.ResDisciple, security.ActRead, "id"),
+	"GET /api/history/disciples/:id/days":     policy(security.ResDisciple, security.ActRead, "id"),
+	"GET /api/history/disciples/:id/strength": policy(security.ResDisciple, security.ActRead, "id"),
+	"GET /api/history/disciples/:id/rest":     policy(security.ResDisciple, security.ActRead, "id"),
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+)
+
+func TestRoutePoliciesCoverEveryRoute(t *testing.T) {
+	db, _, cleanup := mockGorm(t)
+	defer cleanup()
+	r := e2eRouter(db)
+	t.Cleanup(func() { security.UseAuthorizer(nil) })
+
+	registered := map[string]bool{}
+	for _, rt := range r.Routes() {
+		if !strings.HasPrefix(rt.Path, "/api/") {
+			continue
+		}
+		key := rt.Method + " " + rt.Path
+		registered[key] = true
+		p, ok := RoutePolicies[key]
+		if !ok {
+			t.Errorf("route %s has no entry in RoutePolicies", key)
+			continue
+		}
+		if p.Resource != "" && !strings.Contains(rt.Path+"/", "/:"+p.Param+"/") {
+			t.Errorf("route %s: policy param %q not in path", key, p.Param)
+		}
+	}
+	for key := range RoutePolicies {
+		if !registered[key] {
+			t.Errorf("RoutePolicies entry %s matches no route", key)
+		}
+	}
+}
+
+// policyStore: todo id resuelve a un recurso de "owner" (coach dueño de un
+// coach_program privado y, a la vez, discípulo); "coach" está vinculado a él
+// y tiene el programa asignado; "outsider" no tiene relación.
+type policyStore struct{}
+
+func (policyStore) Role(context.Context, string) (string, error) { return "coach", nil }
+func (policyStore) IsCoachOf(_ context.Context, coach, disciple string) (bool, error) {
+	return coach == "coach" && disciple == "owner", nil
+}
+func (policyStore) Target(context.Context, security.Resource, string) (*security.Target, error) {
+	return &security.Target{ProgramID: "program", ProgramOwner: "owner", ProgramKind: "coach_program", ProgramVisibility: "private", DiscipleID: "owner"}, nil
+}
+func (policyStore) ProgramAssigned(_ context.Context, _, actor string) (bool, error) {
+	return actor == "coach", nil
+}
+
+func TestRoutePoliciesDecisions(t *testing.T) {
+	security.UseAuthorizer(security.NewAuthorizer(policyStore{}, nil))
+	t.Cleanup(func() { security.UseAuthorizer(nil) })
+
+	owner, coach, all := []string{"owner"}, []string{"coach"}, []string{"owner", "coach"}
+	allowed := map[security.RoutePolicy][]string{}
+	for res, acts := range map[security.Resource]map[security.Action][]string{
+		security.ResProgram:      {security.ActRead: all, security.ActMutate: owner, security.ActExecute: owner},
+		security.ResDay:          {security.ActRead: all, security.ActMutate: owner},
+		security.ResPrescription: {security.ActRead: all, security.ActMutate: owner},
+		security.ResDisciple:     {security.ActRead: all, security.ActMutate: all},
+		security.ResAssignment:   {security.ActRead: all, security.ActMutate: all, security.ActExecute: owner},
+		security.ResSession:      {security.ActRead: all, security.ActMutate: owner, security.ActExecute: owner},
+		security.ResSet:          {security.ActMutate: owner},
+		security.ResCheckin:      {security.ActRead: all, security.ActExecute: coach},
+	} {
+		for act, actors := range acts {
+			allowed[security.RoutePolicy{Resource: res, Action: act}] = actors
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set(security.CtxUserID, c.GetHeader("X-Actor")) })
+	r.Use(security.Authorize(RoutePolicies))
+	for key := range RoutePolicies {
+		method, path, _ := strings.Cut(key, " ")
+		r.Handle(method, path, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+
+	for key, p := range RoutePolicies {
+		method, path, _ := strings.Cut(key, " ")
+		segs := strings.Split(path, "/")
+		for i, s := range segs {
+			if strings.HasPrefix(s, ":") {
+				segs[i] = "x"
+			}
+		}
+		url := strings.Join(segs, "/")
+
+		actors := []string{"owner", "coach", "outsider"}
+		if p.Resource != "" {
+			var ok bool
+			if actors, ok = allowed[security.RoutePolicy{Resource: p.Resource, Action: p.Action}]; !ok {
+				t.Errorf("%s: no expectation for %s:%s", key, p.Resource, p.Action)
+				continue
+			}
+		}
+		for _, actor := range []string{"owner", "coach", "outsider"} {
+			want := http.StatusForbidden
+			for _, a := range actors {
+				if a == actor {
+					want = http.StatusNoContent
+				}
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(method, url, nil)
+			req.Header.Set("X-Actor", actor)
+			r.ServeHTTP(w, req)
+			if w.Code != want {
+				t.Errorf("%s as %s: status=%d want %d", key, actor, w.Code, want)
+			}
+		}
+	}
+}
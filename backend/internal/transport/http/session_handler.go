@@ -1,12 +1,14 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/repository"
 	"github.com/vicepalma/roma-system/backend/internal/security"
 	"github.com/vicepalma/roma-system/backend/internal/service"
 	"gorm.io/gorm"
@@ -21,14 +23,20 @@ func NewSessionHandler(s service.SessionService, db *gorm.DB) *SessionHandler {
 	return &SessionHandler{svc: s, db: db}
 }
 
+// Dueño/coach de sesiones y sets: RoutePolicies; aquí quedan los chequeos de estado.
 func (h *SessionHandler) Register(r *gin.RouterGroup) {
 	r.POST("/sessions", h.start)           // crea sesión
 	r.GET("/sessions/:id", h.get)          // detalle + sets + cardio
 	r.POST("/sessions/:id/sets", h.addSet) // agrega set
 	r.GET("/sessions/:id/sets", h.GetSets)
+	r.GET("/sessions/:id/timeline", h.timeline) // series por hora, descansos y cumplimiento de rest_sec
 	r.DELETE("/sessions/:id/sets/:setId", h.deleteSet)
 	r.PATCH("/sessions/:id", h.patchSession)    // notas/fecha
 	r.POST("/sessions/:id/cardio", h.addCardio) // agrega cardio
+
+	// feed de PRs: /api/me/records y /api/coach/disciples/:id/records?exercise_id=&kind=&limit=&offset=
+	r.GET("/me/records", h.myRecords)
+	r.GET("/coach/disciples/:id/records", security.RequireRole(h.db, "coach"), h.discipleRecords)
 }
 
 func uid(c *gin.Context) string {
@@ -60,7 +68,7 @@ func (h *SessionHandler) start(c *gin.Context) {
 		}
 		ts = &t
 	}
-	ok, err := security.IsAssignmentOwnedByDisciple(h.db.WithContext(c.Request.Context()), uid(c), body.AssignmentID)
+	ok, err := security.Can(c, security.ResAssignment, body.AssignmentID, security.ActExecute)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
@@ -88,6 +96,15 @@ func (h *SessionHandler) start(c *gin.Context) {
 		return
 	}
 	sess, err := h.svc.Start(c, uid(c), body.AssignmentID, body.DayID, ts, body.Notes)
+	if errors.Is(err, service.ErrOpenSessionExists) {
+		// el cliente puede retomar la abierta en vez de crear otra
+		out := gin.H{"error": "open_session_exists"}
+		if open, _ := h.svc.GetActiveOpenSessionForMe(c.Request.Context(), uid(c)); open != nil {
+			out["session_id"] = open.ID
+		}
+		c.JSON(http.StatusConflict, out)
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": "db_error", "detail": err.Error()})
 		return
@@ -97,16 +114,6 @@ func (h *SessionHandler) start(c *gin.Context) {
 
 func (h *SessionHandler) get(c *gin.Context) {
 	id := c.Param("id")
-	db := h.db.WithContext(c.Request.Context())
-	ok, err := security.CanAccessSession(db, uid(c), id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
-		return
-	}
-	if !ok {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-		return
-	}
 	meta, err := h.svc.GetSession(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(404, gin.H{"error": "not_found"})
@@ -124,7 +131,7 @@ func (h *SessionHandler) get(c *gin.Context) {
 		DayIndex     int     `json:"day_index"`
 		DayTitle     *string `json:"day_title,omitempty"`
 	}
-	_ = db.Raw(`
+	_ = h.db.WithContext(c.Request.Context()).Raw(`
 		SELECT a.program_id, p.title AS program_title, w.week_index, d.day_index, d.title AS day_title
 		FROM session_logs s
 		JOIN assignments a ON a.id = s.assignment_id
@@ -140,27 +147,25 @@ func (h *SessionHandler) addSet(c *gin.Context) {
 	id := c.Param("id")
 	type req struct {
 		PrescriptionID string   `json:"prescription_id" binding:"required"`
-		SetIndex       int      `json:"set_index" binding:"required,min=1"`
+		SetIndex       int      `json:"set_index" binding:"omitempty,min=1"` // opcional: sin él va al final; con él inserta ahí
 		Weight         *float64 `json:"weight"`
 		Reps           int      `json:"reps" binding:"required,min=0"`
 		RPE            *float32 `json:"rpe"`
 		ToFailure      bool     `json:"to_failure"`
+		// warmup|working|drop|backoff|amrap|failure (por defecto working);
+		// un drop lleva parent_set_id y sin set_index va tras su cadena
+		SetType     string  `json:"set_type"`
+		ParentSetID *string `json:"parent_set_id" binding:"omitempty,uuid"`
+		// RFC3339 opcionales: sin completed_at la serie termina al registrarla
+		StartedAt   *time.Time `json:"started_at"`
+		CompletedAt *time.Time `json:"completed_at"`
 	}
 	var body req
 	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(400, gin.H{"error": "bad_request", "detail": err.Error()})
 		return
 	}
-	ok, err := security.IsSessionOwnedByDisciple(h.db.WithContext(c.Request.Context()), uid(c), id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
-		return
-	}
-	if !ok {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-		return
-	}
-	ok, err = security.IsSessionOpen(h.db.WithContext(c.Request.Context()), id)
+	ok, err := security.IsSessionOpen(h.db.WithContext(c.Request.Context()), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
@@ -178,7 +183,29 @@ func (h *SessionHandler) addSet(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "prescription_not_in_session_day"})
 		return
 	}
-	row, err := h.svc.AddSet(c, uid(c), id, body.PrescriptionID, body.SetIndex, body.Weight, body.Reps, body.RPE, body.ToFailure)
+	row, err := h.svc.AddSet(c, uid(c), id, service.NewSet{
+		PrescriptionID: body.PrescriptionID,
+		SetIndex:       body.SetIndex,
+		Weight:         body.Weight,
+		Reps:           body.Reps,
+		RPE:            body.RPE,
+		ToFailure:      body.ToFailure,
+		SetType:        body.SetType,
+		ParentSetID:    body.ParentSetID,
+		StartedAt:      body.StartedAt,
+		CompletedAt:    body.CompletedAt,
+	})
+	switch {
+	case errors.Is(err, service.ErrInvalidSetTiming):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_set_timing"})
+		return
+	case errors.Is(err, service.ErrInvalidSetType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_set_type"})
+		return
+	case errors.Is(err, service.ErrInvalidParentSet):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_parent_set"})
+		return
+	}
 	if err != nil {
 		c.JSON(500, gin.H{"error": "db_error", "detail": err.Error()})
 		return
@@ -186,6 +213,19 @@ func (h *SessionHandler) addSet(c *gin.Context) {
 	c.JSON(201, row)
 }
 
+func (h *SessionHandler) timeline(c *gin.Context) {
+	out, err := h.svc.Timeline(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not_found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, out)
+}
+
 func (h *SessionHandler) addCardio(c *gin.Context) {
 	id := c.Param("id")
 	type req struct {
@@ -200,16 +240,7 @@ func (h *SessionHandler) addCardio(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "bad_request"})
 		return
 	}
-	ok, err := security.IsSessionOwnedByDisciple(h.db.WithContext(c.Request.Context()), uid(c), id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
-		return
-	}
-	if !ok {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-		return
-	}
-	ok, err = security.IsSessionOpen(h.db.WithContext(c.Request.Context()), id)
+	ok, err := security.IsSessionOpen(h.db.WithContext(c.Request.Context()), id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
@@ -286,16 +317,7 @@ func (h *SessionHandler) GetSets(c *gin.Context) {
 func (h *SessionHandler) deleteSet(c *gin.Context) {
 	_ = c.Param("id")
 	setID := c.Param("setId")
-	ok, err := security.IsSetOwnedByDisciple(h.db.WithContext(c.Request.Context()), uid(c), setID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
-		return
-	}
-	if !ok {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-		return
-	}
-	ok, err = security.IsSetSessionOpen(h.db.WithContext(c.Request.Context()), setID)
+	ok, err := security.IsSetSessionOpen(h.db.WithContext(c.Request.Context()), setID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
 		return
@@ -313,15 +335,6 @@ func (h *SessionHandler) deleteSet(c *gin.Context) {
 
 func (h *SessionHandler) patchSession(c *gin.Context) {
 	id := c.Param("id")
-	ok, err := security.IsSessionOwnedByDisciple(h.db.WithContext(c.Request.Context()), uid(c), id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
-		return
-	}
-	if !ok {
-		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
-		return
-	}
 	var body struct {
 		PerformedAt *string `json:"performed_at"` // ISO8601
 		Notes       *string `json:"notes"`
@@ -380,3 +393,33 @@ func (h *SessionHandler) patchSession(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, out)
 }
+
+func (h *SessionHandler) myRecords(c *gin.Context) {
+	h.listRecords(c, uid(c))
+}
+
+func (h *SessionHandler) discipleRecords(c *gin.Context) {
+	h.listRecords(c, c.Param("id"))
+}
+
+func (h *SessionHandler) listRecords(c *gin.Context, discipleID string) {
+	f := repository.RecordFilter{Limit: parsePag(c.DefaultQuery("limit", "50")), Offset: parsePag(c.DefaultQuery("offset", "0"))}
+	if raw := strings.TrimSpace(c.Query("exercise_id")); raw != "" {
+		f.ExerciseID = &raw
+	}
+	if raw := strings.ToLower(strings.TrimSpace(c.Query("kind"))); raw != "" {
+		switch raw {
+		case "weight", "reps", "e1rm", "volume":
+			f.Kind = &raw
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid_kind", "detail": "weight|reps|e1rm|volume"})
+			return
+		}
+	}
+	items, total, err := h.svc.ListRecords(c.Request.Context(), discipleID, f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": f.Limit, "offset": f.Offset})
+}
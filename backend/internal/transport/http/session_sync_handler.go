@@ -0,0 +1,133 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vicepalma/roma-system/backend/internal/security"
+	"github.com/vicepalma/roma-system/backend/internal/service"
+	"github.com/vicepalma/roma-system/backend/internal/sessionsync"
+)
+
+type SessionSyncHandler struct {
+	svc service.SessionSyncService
+}
+
+func NewSessionSyncHandler(s service.SessionSyncService) *SessionSyncHandler {
+	return &SessionSyncHandler{svc: s}
+}
+
+// POST /api/sessions/sync: sesión completa registrada offline (ids del
+// cliente, updated_at del dispositivo). Header opcional Idempotency-Key.
+func (h *SessionSyncHandler) Register(r *gin.RouterGroup) {
+	r.POST("/sessions/sync", h.sync)
+}
+
+type syncSessionReq struct {
+	ID           string     `json:"id" binding:"required,uuid"`
+	AssignmentID string     `json:"assignment_id" binding:"required,uuid"`
+	DayID        string     `json:"day_id" binding:"required,uuid"`
+	StartedAt    time.Time  `json:"started_at"`
+	Notes        *string    `json:"notes"`
+	Status       string     `json:"status"` // open (por defecto) | closed
+	EndedAt      *time.Time `json:"ended_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type syncSetReq struct {
+	ID             string     `json:"id" binding:"required,uuid"`
+	PrescriptionID string     `json:"prescription_id"`
+	SetIndex       int        `json:"set_index"`
+	Weight         *float64   `json:"weight"`
+	Reps           int        `json:"reps"`
+	RPE            *float32   `json:"rpe"`
+	ToFailure      bool       `json:"to_failure"`
+	SetType        string     `json:"set_type"`
+	ParentSetID    *string    `json:"parent_set_id" binding:"omitempty,uuid"`
+	StartedAt      *time.Time `json:"started_at"`
+	CompletedAt    *time.Time `json:"completed_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Deleted        bool       `json:"deleted"`
+}
+
+type syncCardioReq struct {
+	ID          string    `json:"id" binding:"required,uuid"`
+	Modality    string    `json:"modality"`
+	Minutes     int       `json:"minutes"`
+	TargetHRMin *int      `json:"target_hr_min"`
+	TargetHRMax *int      `json:"target_hr_max"`
+	Notes       *string   `json:"notes"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Deleted     bool      `json:"deleted"`
+}
+
+const maxIdempotencyKey = 200
+
+func (h *SessionSyncHandler) sync(c *gin.Context) {
+	var body struct {
+		Session syncSessionReq  `json:"session" binding:"required"`
+		Sets    []syncSetReq    `json:"sets" binding:"max=500,dive"`
+		Cardio  []syncCardioReq `json:"cardio" binding:"max=50,dive"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": err.Error()})
+		return
+	}
+	key := c.GetHeader("Idempotency-Key")
+	if len(key) > maxIdempotencyKey {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bad_request", "detail": "Idempotency-Key too long"})
+		return
+	}
+	ok, err := security.Can(c, security.ResAssignment, body.Session.AssignmentID, security.ActExecute)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+		return
+	}
+
+	s := body.Session
+	b := sessionsync.Batch{Session: sessionsync.Session{
+		ID: s.ID, AssignmentID: s.AssignmentID, DayID: s.DayID, StartedAt: s.StartedAt,
+		Notes: s.Notes, Status: s.Status, EndedAt: s.EndedAt, UpdatedAt: s.UpdatedAt,
+	}}
+	for _, x := range body.Sets {
+		b.Sets = append(b.Sets, sessionsync.Set{
+			ID: x.ID, PrescriptionID: x.PrescriptionID, SetIndex: x.SetIndex, Weight: x.Weight, Reps: x.Reps,
+			RPE: x.RPE, ToFailure: x.ToFailure, SetType: x.SetType, ParentSetID: x.ParentSetID,
+			StartedAt: x.StartedAt, CompletedAt: x.CompletedAt,
+			UpdatedAt: x.UpdatedAt, Deleted: x.Deleted,
+		})
+	}
+	for _, x := range body.Cardio {
+		b.Cardio = append(b.Cardio, sessionsync.Cardio{
+			ID: x.ID, Modality: x.Modality, Minutes: x.Minutes, HRMin: x.TargetHRMin, HRMax: x.TargetHRMax,
+			Notes: x.Notes, UpdatedAt: x.UpdatedAt, Deleted: x.Deleted,
+		})
+	}
+
+	out, err := h.svc.Sync(c.Request.Context(), uid(c), key, b)
+	switch {
+	case errors.Is(err, service.ErrSyncRejected):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "sync_rejected", "result": out})
+		return
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		c.JSON(http.StatusConflict, gin.H{"error": "idempotency_key_reused"})
+		return
+	case errors.Is(err, service.ErrOpenSessionExists):
+		// carrera con otra sesión abierta creada a la vez
+		c.JSON(http.StatusConflict, gin.H{"error": "open_session_exists"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "db_error", "detail": err.Error()})
+		return
+	}
+	if out.Replayed {
+		c.Header("Idempotent-Replayed", "true")
+	}
+	c.JSON(http.StatusOK, out)
+}
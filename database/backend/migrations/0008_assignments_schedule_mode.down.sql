@@ -0,0 +1,5 @@
+ALTER TABLE assignments
+DROP CONSTRAINT IF EXISTS chk_assignments_schedule_mode;
+
+ALTER TABLE assignments
+DROP COLUMN IF EXISTS schedule_mode;
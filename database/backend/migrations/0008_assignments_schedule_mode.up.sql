@@ -0,0 +1,8 @@
+ALTER TABLE assignments
+ADD COLUMN IF NOT EXISTS schedule_mode TEXT NOT NULL DEFAULT 'calendar';
+
+ALTER TABLE assignments
+DROP CONSTRAINT IF EXISTS chk_assignments_schedule_mode;
+
+ALTER TABLE assignments
+ADD CONSTRAINT chk_assignments_schedule_mode CHECK (schedule_mode IN ('calendar', 'next_undone'));
@@ -0,0 +1,14 @@
+ALTER TABLE program_days
+DROP COLUMN IF EXISTS is_rest;
+
+ALTER TABLE programs
+DROP CONSTRAINT IF EXISTS chk_programs_end_behavior;
+
+ALTER TABLE programs
+DROP CONSTRAINT IF EXISTS chk_programs_block_repeats;
+
+ALTER TABLE programs
+DROP COLUMN IF EXISTS end_behavior;
+
+ALTER TABLE programs
+DROP COLUMN IF EXISTS block_repeats;
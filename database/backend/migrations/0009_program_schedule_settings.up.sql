@@ -0,0 +1,20 @@
+ALTER TABLE programs
+ADD COLUMN IF NOT EXISTS block_repeats INT NOT NULL DEFAULT 1;
+
+ALTER TABLE programs
+ADD COLUMN IF NOT EXISTS end_behavior TEXT NOT NULL DEFAULT 'loop';
+
+ALTER TABLE programs
+DROP CONSTRAINT IF EXISTS chk_programs_block_repeats;
+
+ALTER TABLE programs
+ADD CONSTRAINT chk_programs_block_repeats CHECK (block_repeats >= 1);
+
+ALTER TABLE programs
+DROP CONSTRAINT IF EXISTS chk_programs_end_behavior;
+
+ALTER TABLE programs
+ADD CONSTRAINT chk_programs_end_behavior CHECK (end_behavior IN ('stop', 'loop', 'hold'));
+
+ALTER TABLE program_days
+ADD COLUMN IF NOT EXISTS is_rest BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,8 @@
+ALTER TABLE methods
+DROP CONSTRAINT IF EXISTS chk_methods_kind;
+
+ALTER TABLE methods
+DROP COLUMN IF EXISTS created_by;
+
+ALTER TABLE methods
+DROP COLUMN IF EXISTS kind;
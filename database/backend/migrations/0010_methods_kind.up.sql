@@ -0,0 +1,15 @@
+ALTER TABLE methods
+ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'straight';
+
+ALTER TABLE methods
+ADD COLUMN IF NOT EXISTS created_by UUID NULL REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE methods
+DROP CONSTRAINT IF EXISTS chk_methods_kind;
+
+ALTER TABLE methods
+ADD CONSTRAINT chk_methods_kind CHECK (kind IN ('straight', 'fst7', 'drop_set', 'rest_pause', 'superset', 'cluster'));
+
+UPDATE methods
+SET kind = 'fst7'
+WHERE key = 'fst7' AND kind = 'straight';
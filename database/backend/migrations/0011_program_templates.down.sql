@@ -0,0 +1,21 @@
+DROP INDEX IF EXISTS idx_programs_source;
+DROP INDEX IF EXISTS idx_programs_tags;
+DROP INDEX IF EXISTS idx_programs_public_templates;
+
+ALTER TABLE programs
+DROP CONSTRAINT IF EXISTS chk_programs_level;
+
+ALTER TABLE programs
+DROP COLUMN IF EXISTS source_version;
+
+ALTER TABLE programs
+DROP COLUMN IF EXISTS source_program_id;
+
+ALTER TABLE programs
+DROP COLUMN IF EXISTS goal;
+
+ALTER TABLE programs
+DROP COLUMN IF EXISTS level;
+
+ALTER TABLE programs
+DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,28 @@
+ALTER TABLE programs
+ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE programs
+ADD COLUMN IF NOT EXISTS level TEXT NULL;
+
+ALTER TABLE programs
+ADD COLUMN IF NOT EXISTS goal TEXT NULL;
+
+ALTER TABLE programs
+ADD COLUMN IF NOT EXISTS source_program_id UUID NULL REFERENCES programs(id) ON DELETE SET NULL;
+
+ALTER TABLE programs
+ADD COLUMN IF NOT EXISTS source_version INT NULL;
+
+ALTER TABLE programs
+DROP CONSTRAINT IF EXISTS chk_programs_level;
+
+ALTER TABLE programs
+ADD CONSTRAINT chk_programs_level CHECK (level IS NULL OR level IN ('beginner', 'intermediate', 'advanced'));
+
+CREATE INDEX IF NOT EXISTS idx_programs_public_templates
+ON programs(visibility, kind)
+WHERE visibility = 'public';
+
+CREATE INDEX IF NOT EXISTS idx_programs_tags ON programs USING GIN (tags);
+
+CREATE INDEX IF NOT EXISTS idx_programs_source ON programs(source_program_id);
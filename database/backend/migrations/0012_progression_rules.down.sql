@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_sets_prescription;
+
+DROP TABLE IF EXISTS progression_overrides;
+
+DROP INDEX IF EXISTS ux_progression_rules_method;
+DROP INDEX IF EXISTS ux_progression_rules_prescription;
+
+DROP TABLE IF EXISTS progression_rules;
@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS progression_rules (
+  id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  prescription_id UUID NULL REFERENCES prescriptions(id) ON DELETE CASCADE,
+  method_id       UUID NULL REFERENCES methods(id) ON DELETE CASCADE,
+  kind            TEXT NOT NULL CHECK (kind IN ('double', 'linear', 'percent_wave', 'rpe')),
+  params          JSONB NOT NULL DEFAULT '{}',
+  created_by      UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK ((prescription_id IS NOT NULL) <> (method_id IS NOT NULL)) -- XOR: prescripción o método
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_progression_rules_prescription
+ON progression_rules(prescription_id)
+WHERE prescription_id IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_progression_rules_method
+ON progression_rules(method_id)
+WHERE method_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS progression_overrides (
+  id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  assignment_id   UUID NOT NULL REFERENCES assignments(id) ON DELETE CASCADE,
+  prescription_id UUID NOT NULL REFERENCES prescriptions(id) ON DELETE CASCADE,
+  weight          NUMERIC(8,2) NULL CHECK (weight IS NULL OR weight >= 0),
+  reps            TEXT NULL,
+  rpe             NUMERIC(3,1) NULL CHECK (rpe IS NULL OR rpe BETWEEN 1 AND 10),
+  notes           TEXT NULL,
+  created_by      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  UNIQUE (assignment_id, prescription_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sets_prescription ON set_logs(prescription_id);
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS personal_records (
+  id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  disciple_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  exercise_id  UUID NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+  session_id   UUID NOT NULL REFERENCES session_logs(id) ON DELETE CASCADE,
+  set_log_id   UUID NOT NULL REFERENCES set_logs(id) ON DELETE CASCADE,
+  kind         TEXT NOT NULL CHECK (kind IN ('weight', 'reps', 'e1rm', 'volume')),
+  value        NUMERIC(10,2) NOT NULL,          -- kg, reps, kg estimados o kg·reps según kind
+  previous     NUMERIC(10,2) NULL,              -- mejor marca anterior
+  weight       NUMERIC(8,2) NULL,               -- carga de la serie (para reps = "a este peso")
+  reps         INT NULL,
+  achieved_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_records_disciple
+ON personal_records(disciple_id, achieved_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_personal_records_exercise
+ON personal_records(disciple_id, exercise_id, kind);
+
+-- un PR de volumen por sesión y ejercicio; se actualiza con cada serie
+CREATE UNIQUE INDEX IF NOT EXISTS ux_personal_records_session_volume
+ON personal_records(session_id, exercise_id)
+WHERE kind = 'volume';
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_sess_disciple_performed;
@@ -0,0 +1,3 @@
+-- Roster del coach: última sesión / sesión abierta por discípulo
+CREATE INDEX IF NOT EXISTS idx_sess_disciple_performed
+  ON session_logs(disciple_id, performed_at DESC);
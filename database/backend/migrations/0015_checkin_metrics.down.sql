@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS checkin_requirements;
+
+ALTER TABLE checkins
+  DROP COLUMN IF EXISTS soreness,
+  DROP COLUMN IF EXISTS stress,
+  DROP COLUMN IF EXISTS energy,
+  DROP COLUMN IF EXISTS sleep_hours,
+  DROP COLUMN IF EXISTS resting_hr,
+  DROP COLUMN IF EXISTS body_fat_pct,
+  DROP COLUMN IF EXISTS thigh_cm,
+  DROP COLUMN IF EXISTS arm_cm,
+  DROP COLUMN IF EXISTS chest_cm,
+  DROP COLUMN IF EXISTS waist_cm;
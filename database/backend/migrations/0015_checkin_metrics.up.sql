@@ -0,0 +1,20 @@
+-- Métricas corporales y escalas subjetivas en check-ins
+ALTER TABLE checkins
+  ADD COLUMN IF NOT EXISTS waist_cm     NUMERIC(5,1) NULL CHECK (waist_cm IS NULL OR waist_cm > 0),
+  ADD COLUMN IF NOT EXISTS chest_cm     NUMERIC(5,1) NULL CHECK (chest_cm IS NULL OR chest_cm > 0),
+  ADD COLUMN IF NOT EXISTS arm_cm       NUMERIC(5,1) NULL CHECK (arm_cm IS NULL OR arm_cm > 0),
+  ADD COLUMN IF NOT EXISTS thigh_cm     NUMERIC(5,1) NULL CHECK (thigh_cm IS NULL OR thigh_cm > 0),
+  ADD COLUMN IF NOT EXISTS body_fat_pct NUMERIC(4,1) NULL CHECK (body_fat_pct IS NULL OR (body_fat_pct > 0 AND body_fat_pct <= 75)),
+  ADD COLUMN IF NOT EXISTS resting_hr   SMALLINT     NULL CHECK (resting_hr IS NULL OR resting_hr BETWEEN 20 AND 250),
+  ADD COLUMN IF NOT EXISTS sleep_hours  NUMERIC(4,2) NULL CHECK (sleep_hours IS NULL OR sleep_hours BETWEEN 0 AND 24),
+  ADD COLUMN IF NOT EXISTS energy       SMALLINT     NULL CHECK (energy IS NULL OR energy BETWEEN 1 AND 10),
+  ADD COLUMN IF NOT EXISTS stress       SMALLINT     NULL CHECK (stress IS NULL OR stress BETWEEN 1 AND 10),
+  ADD COLUMN IF NOT EXISTS soreness     SMALLINT     NULL CHECK (soreness IS NULL OR soreness BETWEEN 1 AND 10);
+
+-- Métricas que el coach exige en cada check-in del discípulo
+CREATE TABLE IF NOT EXISTS checkin_requirements (
+  disciple_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  metrics     TEXT[] NOT NULL DEFAULT '{}',
+  updated_by  UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+  updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
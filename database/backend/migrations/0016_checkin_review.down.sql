@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_checkins_disciple_review;
+
+ALTER TABLE checkins
+  DROP COLUMN IF EXISTS updated_at,
+  DROP COLUMN IF EXISTS coach_comment,
+  DROP COLUMN IF EXISTS reviewed_at,
+  DROP COLUMN IF EXISTS reviewed_by,
+  DROP COLUMN IF EXISTS review_status;
@@ -0,0 +1,12 @@
+-- Revisión del coach y edición de check-ins
+ALTER TABLE checkins
+  ADD COLUMN IF NOT EXISTS review_status TEXT NOT NULL DEFAULT 'pending'
+    CHECK (review_status IN ('pending', 'reviewed')),
+  ADD COLUMN IF NOT EXISTS reviewed_by   UUID NULL REFERENCES users(id) ON DELETE SET NULL,
+  ADD COLUMN IF NOT EXISTS reviewed_at   TIMESTAMPTZ NULL,
+  ADD COLUMN IF NOT EXISTS coach_comment TEXT NULL,
+  ADD COLUMN IF NOT EXISTS updated_at    TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- Inbox del coach: pendientes por discípulo y fecha
+CREATE INDEX IF NOT EXISTS idx_checkins_disciple_review
+  ON checkins(disciple_id, review_status, checked_at DESC);
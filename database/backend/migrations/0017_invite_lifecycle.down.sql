@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_invite_codes_pending_expires;
+DROP INDEX IF EXISTS idx_invite_codes_coach_created;
@@ -0,0 +1,10 @@
+-- Ciclo de vida de invitaciones: pending | accepted | expired | revoked
+UPDATE invite_codes SET status = 'revoked' WHERE status = 'cancelled';
+
+-- Listado del coach por estado
+CREATE INDEX IF NOT EXISTS idx_invite_codes_coach_created
+  ON invite_codes(coach_id, created_at DESC);
+
+-- Barrido de vencidas
+CREATE INDEX IF NOT EXISTS idx_invite_codes_pending_expires
+  ON invite_codes(expires_at) WHERE status = 'pending';
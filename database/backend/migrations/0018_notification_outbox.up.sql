@@ -0,0 +1,20 @@
+-- Outbox de notificaciones salientes (email / webhook) con reintentos
+CREATE TABLE IF NOT EXISTS notification_outbox (
+  id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  channel         TEXT NOT NULL,
+  event           TEXT NOT NULL,
+  recipient       TEXT NULL,
+  subject         TEXT NOT NULL DEFAULT '',
+  body            TEXT NOT NULL DEFAULT '',
+  payload         JSONB NULL,
+  status          TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending','sent','failed')),
+  attempts        INT NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_error      TEXT NULL,
+  created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+  sent_at         TIMESTAMPTZ NULL
+);
+
+-- Despacho: pendientes vencidas en orden
+CREATE INDEX IF NOT EXISTS idx_outbox_pending_next
+  ON notification_outbox(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,26 @@
+-- Sesiones de login (una por dispositivo) = familia de refresh tokens
+CREATE TABLE IF NOT EXISTS auth_sessions (
+  id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  user_agent    TEXT NULL,
+  ip            TEXT NULL,
+  created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_used_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at    TIMESTAMPTZ NOT NULL,
+  revoked_at    TIMESTAMPTZ NULL,
+  revoke_reason TEXT NULL -- logout | revoked | reuse
+);
+
+CREATE INDEX IF NOT EXISTS idx_auth_sessions_user_active
+  ON auth_sessions(user_id) WHERE revoked_at IS NULL;
+
+-- Refresh tokens: solo el hash SHA-256; used_at marca los ya rotados
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  token_hash TEXT PRIMARY KEY,
+  session_id UUID NOT NULL REFERENCES auth_sessions(id) ON DELETE CASCADE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at    TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session ON refresh_tokens(session_id);
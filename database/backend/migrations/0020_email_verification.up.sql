@@ -0,0 +1,3 @@
+-- Verificación de email; las cuentas existentes se dan por verificadas
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ NULL;
+UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL;
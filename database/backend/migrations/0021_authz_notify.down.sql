@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS trg_users_role_authz ON users;
+DROP TRIGGER IF EXISTS trg_coach_links_authz ON coach_links;
+DROP FUNCTION IF EXISTS roma_authz_notify();
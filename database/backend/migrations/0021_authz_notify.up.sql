@@ -0,0 +1,27 @@
+-- Invalidación de la caché de autorización en proceso (security.AuthzCache):
+-- cambios de rol o de vínculos coach-discípulo se avisan por NOTIFY roma_authz
+-- con payload 'user:<id>' o 'links:<coach_id>'.
+CREATE OR REPLACE FUNCTION roma_authz_notify()
+RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_TABLE_NAME = 'users' THEN
+    PERFORM pg_notify('roma_authz', 'user:' || COALESCE(NEW.id, OLD.id)::text);
+  ELSE
+    PERFORM pg_notify('roma_authz', 'links:' || COALESCE(NEW.coach_id, OLD.coach_id)::text);
+    IF TG_OP = 'UPDATE' AND NEW.coach_id <> OLD.coach_id THEN
+      PERFORM pg_notify('roma_authz', 'links:' || OLD.coach_id::text);
+    END IF;
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_coach_links_authz ON coach_links;
+CREATE TRIGGER trg_coach_links_authz
+AFTER INSERT OR UPDATE OR DELETE ON coach_links
+FOR EACH ROW EXECUTE PROCEDURE roma_authz_notify();
+
+DROP TRIGGER IF EXISTS trg_users_role_authz ON users;
+CREATE TRIGGER trg_users_role_authz
+AFTER UPDATE OF role OR DELETE ON users
+FOR EACH ROW EXECUTE PROCEDURE roma_authz_notify();
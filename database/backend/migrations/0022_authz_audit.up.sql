@@ -0,0 +1,16 @@
+-- Auditoría de decisiones del authorizer (denegaciones y escrituras permitidas)
+CREATE TABLE IF NOT EXISTS authz_audit (
+  id          BIGSERIAL PRIMARY KEY,
+  at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+  actor_id    TEXT NOT NULL DEFAULT '',
+  resource    TEXT NOT NULL,
+  resource_id TEXT NOT NULL DEFAULT '',
+  action      TEXT NOT NULL,
+  allowed     BOOLEAN NOT NULL,
+  rule        TEXT NOT NULL DEFAULT '',
+  reason      TEXT NOT NULL DEFAULT '',
+  route       TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_authz_audit_actor_at ON authz_audit(actor_id, at DESC);
+CREATE INDEX IF NOT EXISTS idx_authz_audit_resource_at ON authz_audit(resource, resource_id, at DESC);
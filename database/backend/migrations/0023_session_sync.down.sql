@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS session_sync_requests;
+DROP TABLE IF EXISTS session_sync_tombstones;
+ALTER TABLE cardio_segments DROP COLUMN IF EXISTS client_updated_at;
+ALTER TABLE set_logs        DROP COLUMN IF EXISTS client_updated_at;
+ALTER TABLE session_logs    DROP COLUMN IF EXISTS client_updated_at;
@@ -0,0 +1,28 @@
+-- Sync offline de sesiones: reloj del dispositivo de la última escritura
+-- sincronizada (gana la más reciente al mezclar dos dispositivos)
+ALTER TABLE session_logs    ADD COLUMN IF NOT EXISTS client_updated_at TIMESTAMPTZ NULL;
+ALTER TABLE set_logs        ADD COLUMN IF NOT EXISTS client_updated_at TIMESTAMPTZ NULL;
+ALTER TABLE cardio_segments ADD COLUMN IF NOT EXISTS client_updated_at TIMESTAMPTZ NULL;
+
+-- Series/cardio borradas offline: otro dispositivo con datos viejos no las revive
+CREATE TABLE IF NOT EXISTS session_sync_tombstones (
+  id         UUID PRIMARY KEY,
+  session_id UUID NOT NULL REFERENCES session_logs(id) ON DELETE CASCADE,
+  kind       TEXT NOT NULL CHECK (kind IN ('set','cardio')),
+  deleted_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_tombstones_session ON session_sync_tombstones(session_id);
+
+-- Respuesta de cada lote aplicado, por Idempotency-Key del discípulo
+CREATE TABLE IF NOT EXISTS session_sync_requests (
+  disciple_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  idem_key     TEXT NOT NULL,
+  request_hash TEXT NOT NULL,
+  session_id   UUID NOT NULL,
+  response     JSONB NOT NULL,
+  created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (disciple_id, idem_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_requests_created ON session_sync_requests(created_at);
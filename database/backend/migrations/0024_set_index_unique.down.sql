@@ -0,0 +1 @@
+ALTER TABLE set_logs DROP CONSTRAINT IF EXISTS ux_set_logs_session_prescription_index;
@@ -0,0 +1,14 @@
+-- set_index lo asigna el servidor: 1..n sin huecos por (sesión, prescripción).
+-- Primero se normalizan los duplicados o huecos que dejó el índice del cliente.
+UPDATE set_logs s SET set_index = r.rn
+FROM (
+  SELECT id, row_number() OVER (PARTITION BY session_id, prescription_id ORDER BY set_index, id) AS rn
+  FROM set_logs
+) r
+WHERE s.id = r.id AND s.set_index <> r.rn;
+
+-- Diferible: insertar entre series o renumerar al borrar corre varios índices
+-- en una misma sentencia (ver SET CONSTRAINTS en session_repo.go)
+ALTER TABLE set_logs
+  ADD CONSTRAINT ux_set_logs_session_prescription_index
+  UNIQUE (session_id, prescription_id, set_index) DEFERRABLE INITIALLY IMMEDIATE;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS ux_session_logs_one_open;
+ALTER TABLE session_logs DROP COLUMN IF EXISTS auto_closed;
+ALTER TABLE session_logs DROP COLUMN IF EXISTS last_activity_at;
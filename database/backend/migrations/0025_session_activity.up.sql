@@ -0,0 +1,32 @@
+-- Última serie/cardio registrada: base de la duración de una sesión abierta
+-- y del ended_at cuando el barrido la cierra por inactividad
+ALTER TABLE session_logs ADD COLUMN IF NOT EXISTS last_activity_at TIMESTAMPTZ NULL;
+ALTER TABLE session_logs ADD COLUMN IF NOT EXISTS auto_closed BOOLEAN NOT NULL DEFAULT false;
+
+-- Histórico: las series en vivo no guardan hora; se aproxima con updated_at
+-- de la sesión si tiene registros y ningún reloj de dispositivo
+UPDATE session_logs s SET last_activity_at = COALESCE(x.at, s.updated_at)
+FROM (
+  SELECT session_id, MAX(client_updated_at) AS at
+  FROM (
+    SELECT session_id, client_updated_at FROM set_logs
+    UNION ALL
+    SELECT session_id, client_updated_at FROM cardio_segments WHERE session_id IS NOT NULL
+  ) r
+  GROUP BY session_id
+) x
+WHERE s.id = x.session_id;
+
+-- Una sola sesión abierta por discípulo: se cierran todas menos la más reciente
+UPDATE session_logs s
+SET status = 'closed', auto_closed = true,
+    ended_at = GREATEST(s.performed_at, COALESCE(s.last_activity_at, s.performed_at)),
+    updated_at = now()
+WHERE s.status = 'open'
+  AND EXISTS (
+    SELECT 1 FROM session_logs o
+    WHERE o.disciple_id = s.disciple_id AND o.status = 'open'
+      AND (o.performed_at, o.created_at, o.id) > (s.performed_at, s.created_at, s.id)
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_session_logs_one_open ON session_logs(disciple_id) WHERE status = 'open';
@@ -0,0 +1,3 @@
+ALTER TABLE set_logs DROP CONSTRAINT IF EXISTS ck_set_logs_timing;
+ALTER TABLE set_logs DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE set_logs DROP COLUMN IF EXISTS started_at;
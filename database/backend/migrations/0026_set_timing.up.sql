@@ -0,0 +1,12 @@
+-- Horas de cada serie: base de la línea de tiempo de la sesión y del
+-- descanso real contra prescriptions.rest_sec
+ALTER TABLE set_logs ADD COLUMN IF NOT EXISTS started_at   TIMESTAMPTZ NULL;
+ALTER TABLE set_logs ADD COLUMN IF NOT EXISTS completed_at TIMESTAMPTZ NULL;
+
+-- Series sincronizadas: la última edición en el dispositivo es la mejor
+-- aproximación al fin
+UPDATE set_logs SET completed_at = client_updated_at
+WHERE completed_at IS NULL AND client_updated_at IS NOT NULL;
+
+ALTER TABLE set_logs ADD CONSTRAINT ck_set_logs_timing
+  CHECK (started_at IS NULL OR completed_at IS NULL OR started_at <= completed_at);
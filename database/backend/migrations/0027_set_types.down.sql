@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_set_logs_parent;
+ALTER TABLE set_logs DROP CONSTRAINT IF EXISTS ck_set_logs_drop_parent;
+ALTER TABLE set_logs DROP CONSTRAINT IF EXISTS ck_set_logs_set_type;
+ALTER TABLE set_logs DROP COLUMN IF EXISTS parent_set_id;
+ALTER TABLE set_logs DROP COLUMN IF EXISTS set_type;
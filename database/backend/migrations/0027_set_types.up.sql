@@ -0,0 +1,14 @@
+-- Tipo de serie: calentamiento y drops no cuentan como series efectivas en
+-- historial, volumen ni PR. Los drops cuelgan de su serie madre.
+ALTER TABLE set_logs ADD COLUMN IF NOT EXISTS set_type TEXT NOT NULL DEFAULT 'working';
+ALTER TABLE set_logs ADD COLUMN IF NOT EXISTS parent_set_id UUID NULL REFERENCES set_logs(id) ON DELETE CASCADE;
+
+UPDATE set_logs SET set_type = 'failure' WHERE to_failure AND set_type = 'working';
+
+ALTER TABLE set_logs ADD CONSTRAINT ck_set_logs_set_type
+  CHECK (set_type IN ('warmup','working','drop','backoff','amrap','failure'));
+-- solo un drop tiene madre, y todo drop la tiene
+ALTER TABLE set_logs ADD CONSTRAINT ck_set_logs_drop_parent
+  CHECK ((set_type = 'drop') = (parent_set_id IS NOT NULL));
+
+CREATE INDEX IF NOT EXISTS idx_set_logs_parent ON set_logs(parent_set_id) WHERE parent_set_id IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS trg_auth_sessions_authz ON auth_sessions;
+DROP FUNCTION IF EXISTS roma_session_notify();
@@ -0,0 +1,15 @@
+-- Revocar una sesión de refresh invalida al instante los access tokens que
+-- emitió (security.AuthzCache cachea si la sesión sigue activa): se avisa por
+-- NOTIFY roma_authz con payload 'session:<id>'.
+CREATE OR REPLACE FUNCTION roma_session_notify()
+RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM pg_notify('roma_authz', 'session:' || COALESCE(NEW.id, OLD.id)::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_auth_sessions_authz ON auth_sessions;
+CREATE TRIGGER trg_auth_sessions_authz
+AFTER UPDATE OF revoked_at OR DELETE ON auth_sessions
+FOR EACH ROW EXECUTE PROCEDURE roma_session_notify();